@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+
+	sync "github.com/lbryio/lbry.go/ytsync"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	abandonKeepChannel bool
+	abandonStateDir    string
+	abandonAPIURL      string
+	abandonAPICAFile   string
+	abandonAPIInsecure bool
+	abandonAPIAuth     string
+)
+
+// newAbandonCmd returns the `ytsync abandon` command
+func newAbandonCmd() *cobra.Command {
+	abandonCmd := &cobra.Command{
+		Use:   "abandon <youtube_channel_id>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Abandon the claims published for a channel and send its credits back to lbrycrd",
+		Long: "Abandon the claims published for a channel, as recorded in the local state, along with its channel claim. " +
+			"The credits left in its wallet are sent back to lbrycrd, its videos are marked as failed on the sync API " +
+			"and the channel goes back to pending. Prints a JSON report of what was abandoned.",
+		Run: ytsyncAbandon,
+	}
+	abandonCmd.Flags().BoolVar(&abandonKeepChannel, "keep-channel", false, "Only abandon the videos, keep the channel claim")
+	abandonCmd.Flags().StringVar(&abandonStateDir, "state-dir", "", "Directory where the sync state is kept between runs (Default: ~/.ytsync)")
+	abandonCmd.Flags().StringVar(&abandonAPIURL, "api-url", "", "URL of the sync API (Default: the LBRY_API environment variable)")
+	abandonCmd.Flags().StringVar(&abandonAPICAFile, "api-ca-file", "", "PEM file with extra CA certificates to trust when connecting to the sync API over TLS")
+	abandonCmd.Flags().BoolVar(&abandonAPIInsecure, "api-insecure", false, "Skip TLS certificate verification when connecting to the sync API")
+	abandonCmd.Flags().StringVar(&abandonAPIAuth, "api-auth", "token", "How the requests to the sync API are authenticated with LBRY_API_TOKEN: token (sent as auth_token), bearer or hmac")
+	return abandonCmd
+}
+
+func ytsyncAbandon(cmd *cobra.Command, args []string) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Error("could not detect system hostname")
+		hostname = "ytsync-unknown"
+	}
+	if abandonAPIURL == "" {
+		abandonAPIURL = os.Getenv("LBRY_API")
+	}
+	apiToken := os.Getenv("LBRY_API_TOKEN")
+	if abandonAPIURL == "" {
+		log.Errorln("An API URL was not defined. Please use --api-url or set the environment variable LBRY_API")
+		return
+	}
+	if apiToken == "" {
+		log.Errorln("An API Token was not defined. Please set the environment variable LBRY_API_TOKEN")
+		return
+	}
+	apiTLSConfig, err := sdk.TLSConfig(abandonAPICAFile, abandonAPIInsecure)
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	apiAuth, err := sdk.ParseAuthMode(abandonAPIAuth)
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	api := sdk.NewAPIConfig(abandonAPIURL, apiToken, hostname, apiTLSConfig)
+	api.SetAuth(apiAuth)
+	if abandonStateDir == "" {
+		usr, err := user.Current()
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+		abandonStateDir = usr.HomeDir + "/.ytsync"
+	}
+
+	sm := sync.SyncManager{
+		APIConfig:     api,
+		StateDir:      abandonStateDir,
+		LbrycrdString: os.Getenv("LBRYCRD_STRING"),
+		AwsS3ID:       os.Getenv("AWS_S3_ID"),
+		AwsS3Secret:   os.Getenv("AWS_S3_SECRET"),
+		AwsS3Region:   os.Getenv("AWS_S3_REGION"),
+		AwsS3Bucket:   os.Getenv("AWS_S3_BUCKET"),
+		StopGroup:     stopGroup,
+	}
+	report, err := sm.Abandon(args[0], abandonKeepChannel)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	}
+	if err != nil {
+		log.Errorln(err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultConfigName is the name of the config file looked up in ~/.lbry when --config isn't set. Viper picks the
+	// format from the extension: ytsync.yaml, ytsync.yml or ytsync.toml.
+	defaultConfigName = "ytsync"
+	// configEnvPrefix prefixes the environment variables that set flags, e.g. YTSYNC_CONCURRENT_JOBS
+	configEnvPrefix = "YTSYNC"
+)
+
+// configEnvSettings are the settings that used to be environment variables only. They can be set in the config file
+// too, under these keys, but the environment variable wins if it's set.
+var configEnvSettings = map[string]string{
+	"api-token":            "LBRY_API_TOKEN",
+	"youtube-api-key":      "YOUTUBE_API_KEY",
+	"blobs-dir":            "BLOBS_DIRECTORY",
+	"lbrycrd":              "LBRYCRD_STRING",
+	"aws-s3-id":            "AWS_S3_ID",
+	"aws-s3-secret":        "AWS_S3_SECRET",
+	"aws-s3-region":        "AWS_S3_REGION",
+	"aws-s3-bucket":        "AWS_S3_BUCKET",
+	"slack-token":          "SLACK_TOKEN",
+	"slack-channel":        "SLACK_CHANNEL",
+	"slack-signing-secret": "SLACK_SIGNING_SECRET",
+	"discord-webhook-url":  "DISCORD_WEBHOOK_URL",
+	"notify-webhook-url":   "NOTIFY_WEBHOOK_URL",
+	"refill-token":         "REFILL_TOKEN",
+	"control-token":        "CONTROL_TOKEN",
+	"wallet-backup-key":    "WALLET_BACKUP_KEY",
+}
+
+var configFile string
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&configFile, "config", "", "YAML or TOML file with default values for the flags, see the ytsync README (Default: ~/.lbry/ytsync.yaml if it exists)")
+	RootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return loadConfig(cmd)
+	}
+}
+
+// loadConfig sets the flags of cmd that were not given on the command line from the environment and the config file.
+// Flags win over environment variables, which win over the config file. A flag can be set for every command with its
+// name as the key, or for one command only under a section named after the command.
+func loadConfig(cmd *cobra.Command) error {
+	v := viper.New()
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		usr, err := user.Current()
+		if err != nil {
+			return errors.Err(err)
+		}
+		v.SetConfigName(defaultConfigName)
+		v.AddConfigPath(filepath.Join(usr.HomeDir, ".lbry"))
+	}
+	err := v.ReadInConfig()
+	if err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok || configFile != "" {
+			return errors.Prefix("could not read the config file", err)
+		}
+	} else {
+		log.Debugf("using the config file %s", v.ConfigFileUsed())
+	}
+
+	var flagErr error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if flagErr != nil || f.Changed || f.Name == "config" {
+			return
+		}
+		env := configEnvPrefix + "_" + strings.ToUpper(strings.Replace(f.Name, "-", "_", -1))
+		value, source := os.Getenv(env), env
+		if value == "" {
+			for _, key := range []string{cmd.Name() + "." + f.Name, f.Name} {
+				if v.IsSet(key) {
+					value, source = configValue(v.Get(key)), key+" in the config"
+					break
+				}
+			}
+		}
+		if value == "" {
+			return
+		}
+		err := cmd.Flags().Set(f.Name, value)
+		if err != nil {
+			flagErr = errors.Err("invalid value for %s from %s: %s", f.Name, source, err.Error())
+		}
+	})
+	if flagErr != nil {
+		return flagErr
+	}
+
+	for key, env := range configEnvSettings {
+		if os.Getenv(env) != "" || !v.IsSet(key) {
+			continue
+		}
+		err := os.Setenv(env, configValue(v.Get(key)))
+		if err != nil {
+			return errors.Err(err)
+		}
+	}
+	return nil
+}
+
+// configValue turns a value of the config into what the flag would be given on the command line. Lists become comma
+// separated values.
+func configValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, len(list))
+		for i, item := range list {
+			values[i] = fmt.Sprint(item)
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(value)
+}
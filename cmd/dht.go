@@ -51,7 +51,12 @@ func dhtPingCmd(cmd *cobra.Command, args []string) {
 	for {
 		peers, err := d.FindNode("012b66fc7052d9a0c8cb563b8ede7662003ba65f425c2661b5c6919d445deeb31469be8b842d6faeea3f2b3ebcaec845")
 		if err != nil {
-			time.Sleep(time.Second * 1)
+			select {
+			case <-stopGroup.Ch():
+				log.Println("stopped")
+				return
+			case <-time.After(time.Second * 1):
+			}
 			continue
 		}
 
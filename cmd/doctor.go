@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	sync "github.com/lbryio/lbry.go/ytsync"
+
+	"github.com/spf13/cobra"
+)
+
+// doctor makes the ytsync command check the server instead of syncing, see newDoctorCmd
+var doctor bool
+
+// newDoctorCmd returns the `ytsync doctor` command. It takes all the flags of the ytsync command.
+func newDoctorCmd(ytSyncCmd *cobra.Command) *cobra.Command {
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Args:  cobra.NoArgs,
+		Short: "Check that the sync server is ready to sync channels",
+		Long: "Check that the sync server is ready to sync channels with the configuration it would sync with: the " +
+			"daemons answer and their wallets are unlocked, lbrycrd holds enough LBC for the refills, the disk " +
+			"holding the blobs has room for downloads, yt-dlp is the latest release, the YouTube API key is valid " +
+			"and the API is reachable. Prints a JSON report with the outcome of each check and exits with status 1 " +
+			"if any failed.",
+		Run: ytsyncDoctor,
+	}
+	doctorCmd.Flags().AddFlagSet(ytSyncCmd.Flags())
+	return doctorCmd
+}
+
+func ytsyncDoctor(cmd *cobra.Command, args []string) {
+	doctor = true
+	ytSync(cmd, args)
+}
+
+// runDoctor prints the report of the checks of the server, and exits with status 1 if any failed
+func runDoctor(sm sync.SyncManager) {
+	report := sm.Doctor()
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if !report.OK {
+		os.Exit(1)
+	}
+}
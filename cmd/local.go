@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"time"
+
+	sync "github.com/lbryio/lbry.go/ytsync"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	localStateDir          string
+	localChannelClaimID    string
+	localThumbnailHost     string
+	localMaxTries          int
+	localConcurrentJobs    int
+	localMaxVideoSize      int
+	localThumbnailTime     time.Duration
+	localChannelBid        float64
+	localVideoBid          float64
+	localVideoNameConflict string
+	localMaxBid            float64
+	localValidate          []string
+)
+
+// newLocalCmd returns the `ytsync local` command
+func newLocalCmd() *cobra.Command {
+	localCmd := &cobra.Command{
+		Use:   "local <dir> <lbry_channel_name>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Publish the video files of a directory into a lbry channel",
+		Long: "Publish the video files of a directory into a lbry channel, with the daemon that's running and its " +
+			"wallet. The metadata of each video is read from VIDEO.json or VIDEO.nfo next to it, and its thumbnail " +
+			"from VIDEO.jpg or VIDEO.png, or taken from a frame of the video. The published videos are recorded in " +
+			"the state dir, so running it again only publishes the new ones. Prints a JSON summary of the sync.",
+		Run: ytsyncLocal,
+	}
+	localCmd.Flags().StringVar(&localStateDir, "state-dir", "", "Directory where the sync state is kept between runs (Default: ~/.ytsync)")
+	localCmd.Flags().StringVar(&localChannelClaimID, "channel-claim-id", "", "Claim ID of the lbry channel to publish into, if the wallet has several channels")
+	localCmd.Flags().StringVar(&localThumbnailHost, "thumbnail-host", "", "Where thumbnails are uploaded to, as for the ytsync command (Default: the AWS_S3 bucket)")
+	localCmd.Flags().IntVar(&localMaxTries, "max-tries", defaultMaxTries, "Number of times to try a publish that fails")
+	localCmd.Flags().IntVar(&localConcurrentJobs, "concurrent-jobs", 1, "how many videos to publish concurrently")
+	localCmd.Flags().IntVar(&localMaxVideoSize, "max-size", 2048, "Maximum video size to publish (in MB)")
+	localCmd.Flags().DurationVar(&localThumbnailTime, "thumbnail-timestamp", 5*time.Second, "Position in the video of the frame used for the videos without a thumbnail")
+	localCmd.Flags().Float64Var(&localChannelBid, "channel-bid", 0.01, "LBC bid of the channel claim, if it has to be created")
+	localCmd.Flags().Float64Var(&localVideoBid, "video-bid", 0.01, "LBC bid of each video claim")
+	localCmd.Flags().StringVar(&localVideoNameConflict, "video-name-conflict", "", "What to do if a video claim name is held by someone else: append-suffix, bid-higher (up to --max-bid), skip or take-over. By default names are claimed regardless")
+	localCmd.Flags().Float64Var(&localMaxBid, "max-bid", 1, "Highest amount of LBC to bid with --video-name-conflict=bid-higher")
+	localCmd.Flags().StringSliceVar(&localValidate, "validate", sources.DefaultValidationRules, "Comma separated RULE=ACTION checks of the videos before they are published, as for the ytsync command")
+	return localCmd
+}
+
+func ytsyncLocal(cmd *cobra.Command, args []string) {
+	if localMaxTries < 1 {
+		log.Errorln("setting --max-tries less than 1 doesn't make sense")
+		return
+	}
+	if localConcurrentJobs < 1 {
+		log.Errorln("setting --concurrent-jobs less than 1 doesn't make sense")
+		return
+	}
+	if localChannelBid < 0 || localVideoBid < 0 {
+		log.Errorln("setting a bid less than 0 doesn't make sense")
+		return
+	}
+	var videoResolver sources.NameResolver
+	if localVideoNameConflict != "" {
+		var err error
+		videoResolver, err = sources.ParseNameResolver(localVideoNameConflict, localMaxBid)
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+	}
+
+	validationRules, err := sources.ParseValidationRules(localValidate)
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+
+	awsS3ID := os.Getenv("AWS_S3_ID")
+	awsS3Secret := os.Getenv("AWS_S3_SECRET")
+	var thumbnailHost sources.ThumbnailHost
+	if localThumbnailHost != "" {
+		var err error
+		thumbnailHost, err = sources.ParseThumbnailHost(localThumbnailHost, awsS3ID, awsS3Secret)
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+	} else if awsS3ID == "" || awsS3Secret == "" {
+		log.Errorln("There is nowhere to upload the thumbnails to. Please use --thumbnail-host or set the environment variables AWS_S3_ID and AWS_S3_SECRET")
+		return
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	if localStateDir == "" {
+		localStateDir = usr.HomeDir + "/.ytsync"
+	}
+	blobsDir := os.Getenv("BLOBS_DIRECTORY")
+	if blobsDir == "" {
+		blobsDir = usr.HomeDir + "/.lbrynet/blobfiles/"
+	}
+
+	sm := sync.SyncManager{
+		MaxTries:              localMaxTries,
+		ConcurrentVideos:      localConcurrentJobs,
+		VideoConflictResolver: videoResolver,
+		LbryChannelClaimID:    localChannelClaimID,
+		BlobsDir:              blobsDir,
+		MaxVideoSize:          localMaxVideoSize,
+		AwsS3ID:               awsS3ID,
+		AwsS3Secret:           awsS3Secret,
+		ThumbnailTimestamp:    localThumbnailTime,
+		StateDir:              localStateDir,
+		ClaimAmounts:          sync.ClaimAmounts{ChannelBid: localChannelBid, VideoBid: localVideoBid},
+		ThumbnailHost:         thumbnailHost,
+		StopGroup:             stopGroup,
+		ValidationRules:       validationRules,
+	}
+	summary, err := sm.SyncLocal(args[0], args[1])
+	if summary != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(summary)
+	}
+	if err != nil {
+		log.Errorln(err.Error())
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/lbryio/lbry.go/util"
+	sync "github.com/lbryio/lbry.go/ytsync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var retryFailed []string
+
+// newRetryFailedCmd returns the `ytsync retry-failed` command. It takes all the flags of the ytsync command.
+func newRetryFailedCmd(ytSyncCmd *cobra.Command) *cobra.Command {
+	retryFailedCmd := &cobra.Command{
+		Use:   "retry-failed <youtube_channel_id>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Sync the videos of a channel that failed, and only those",
+		Long: "Sync the videos of a channel that failed before, according to the API (or its database) and the local " +
+			"state, and only those. --categories picks which failures are retried, by their root cause as in the " +
+			"triage report: " + strings.Join(sync.FailureCategories(), ", ") + ". The videos are retried even if they " +
+			"are over --videos-limit or failed in a way that is never retried.",
+		Run: ytsyncRetryFailed,
+	}
+	retryFailedCmd.Flags().AddFlagSet(ytSyncCmd.Flags())
+	retryFailedCmd.Flags().StringSliceVar(&retryFailed, "categories", nil, "Comma separated root causes of the failures to retry, e.g. \"download error,publish timeout\"")
+	return retryFailedCmd
+}
+
+func ytsyncRetryFailed(cmd *cobra.Command, args []string) {
+	if len(retryFailed) == 0 {
+		log.Errorf("pick the failures to retry with --categories, among: %s", strings.Join(sync.FailureCategories(), ", "))
+		return
+	}
+	for i, category := range retryFailed {
+		retryFailed[i] = strings.TrimSpace(category)
+		if !util.InSlice(retryFailed[i], sync.FailureCategories()) {
+			log.Errorf("unknown failure category %q, the categories are: %s", retryFailed[i], strings.Join(sync.FailureCategories(), ", "))
+			return
+		}
+	}
+	channelID = args[0]
+	singleRun = true
+	ytSync(cmd, nil)
+}
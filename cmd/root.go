@@ -3,7 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/lbryio/lbry.go/stop"
+
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -16,11 +21,30 @@ var RootCmd = &cobra.Command{
 	//	Run: func(cmd *cobra.Command, args []string) { },
 }
 
+// stopGroup is stopped when the process gets SIGINT or SIGTERM. Commands that run for a while stop with it, usually by
+// making it the parent of their own group.
+var stopGroup = stop.New()
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	stopOnSignal(stopGroup)
 	if err := RootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// stopOnSignal stops grp on the first SIGINT or SIGTERM. The second one exits right away.
+func stopOnSignal(grp *stop.Group) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Infof("Got %s, stopping. Send it again to exit right away", sig)
+		grp.Stop()
+		<-signals
+		log.Errorln("Exiting without cleaning up")
+		os.Exit(1)
+	}()
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// defaultServeAddr is where `ytsync serve` listens if --status-addr is not set
+const defaultServeAddr = ":8081"
+
+// newServeCmd returns the `ytsync serve` command. It takes all the flags of the ytsync command.
+func newServeCmd(ytSyncCmd *cobra.Command) *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Args:  cobra.NoArgs,
+		Short: "Run as a sync node controlled over HTTP",
+		Long: "Run as a long-lived service (like --daemon) with the status server listening on --status-addr " +
+			"(Default: " + defaultServeAddr + "). Besides the status and health of the node, the server lets the " +
+			"running channels be paused, resumed and cancelled, videos be failed and polls of the API be triggered. " +
+			"If CONTROL_TOKEN is set, the requests that change anything must send it as a bearer token.",
+		Run: ytsyncServe,
+	}
+	serveCmd.Flags().AddFlagSet(ytSyncCmd.Flags())
+	return serveCmd
+}
+
+func ytsyncServe(cmd *cobra.Command, args []string) {
+	daemonMode = true
+	if statusAddr == "" {
+		statusAddr = defaultServeAddr
+	}
+	ytSync(cmd, args)
+}
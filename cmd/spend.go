@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+
+	sync "github.com/lbryio/lbry.go/ytsync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	spendStateDir     string
+	spendTransactions bool
+)
+
+// newSpendCmd returns the `ytsync spend` command
+func newSpendCmd() *cobra.Command {
+	spendCmd := &cobra.Command{
+		Use:   "spend <youtube_channel_id>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Show how much LBC a channel spent over every sync",
+		Long: "Show how much LBC a channel spent over every sync, on its channel claim, the claims of its videos, their " +
+			"updates and supports, fees included, as recorded in the local state. Prints it as JSON.",
+		Run: ytsyncSpend,
+	}
+	spendCmd.Flags().StringVar(&spendStateDir, "state-dir", "", "Directory where the sync state is kept between runs (Default: ~/.ytsync)")
+	spendCmd.Flags().BoolVar(&spendTransactions, "transactions", false, "List every transaction of the channel too")
+	return spendCmd
+}
+
+func ytsyncSpend(cmd *cobra.Command, args []string) {
+	if spendStateDir == "" {
+		usr, err := user.Current()
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+		spendStateDir = usr.HomeDir + "/.ytsync"
+	}
+
+	sm := sync.SyncManager{StateDir: spendStateDir}
+	spend, err := sm.Spend(args[0], spendTransactions)
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(spend)
+}
@@ -1,11 +1,6 @@
 package cmd
 
 import (
-	"os"
-	"os/signal"
-	"sync"
-	"syscall"
-
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -20,16 +15,8 @@ func init() {
 }
 
 func test(cmd *cobra.Command, args []string) {
-	var wg sync.WaitGroup
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		<-c
-		log.Println("got signal")
-	}()
 	log.Println("waiting for ctrl+c")
-	wg.Wait()
+	<-stopGroup.Ch()
+	log.Println("got signal")
 	log.Println("done waiting")
 }
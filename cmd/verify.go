@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"time"
+
+	sync "github.com/lbryio/lbry.go/ytsync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyStateDir        string
+	verifyMetadataConfig  string
+	verifyYoutubeCacheTTL time.Duration
+	verifyResign          bool
+)
+
+// newVerifyCmd returns the `ytsync verify` command
+func newVerifyCmd() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify <youtube_channel_id> <lbry_channel_name>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Compare the videos of a youtube channel with the claims of its lbry channel",
+		Long: "Compare the videos of a youtube channel with the claims of its lbry channel, resolved by the local daemon. " +
+			"Prints a JSON report of the videos that are missing, published more than once, or published with a " +
+			"different title, thumbnail or length, and the claims of the wallet that aren't signed by the channel claim.",
+		Run: ytsyncVerify,
+	}
+	verifyCmd.Flags().StringVar(&verifyStateDir, "state-dir", "", "Directory where the sync state is kept between runs (Default: ~/.ytsync)")
+	verifyCmd.Flags().StringVar(&verifyMetadataConfig, "metadata-config", "", "The --metadata-config the channel was synced with, so that the titles are compared with the customized ones")
+	verifyCmd.Flags().DurationVar(&verifyYoutubeCacheTTL, "youtube-cache-ttl", 0, "Reuse the YouTube API responses about channels and videos for this long, kept in the state dir, so that repeated verifications don't spend quota on them again (Default: no cache)")
+	verifyCmd.Flags().BoolVar(&verifyResign, "resign", false, "Publish the claims that aren't signed by the channel claim again in the channel, e.g. after the channel name was taken over")
+	return verifyCmd
+}
+
+func ytsyncVerify(cmd *cobra.Command, args []string) {
+	youtubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
+	if youtubeAPIKey == "" {
+		log.Errorln("A Youtube API key was not defined. Please set the environment variable YOUTUBE_API_KEY")
+		return
+	}
+	if verifyStateDir == "" {
+		usr, err := user.Current()
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+		verifyStateDir = usr.HomeDir + "/.ytsync"
+	}
+	if _, err := os.Stat(verifyStateDir); os.IsNotExist(err) {
+		log.Warnf("%s does not exist, claims are matched to videos by the link in their description only", verifyStateDir)
+		verifyStateDir = ""
+	}
+
+	sm := sync.SyncManager{
+		YoutubeAPIKey:   youtubeAPIKey,
+		YoutubeQuota:    sync.DefaultYoutubeQuota,
+		StateDir:        verifyStateDir,
+		StopGroup:       stopGroup,
+		YoutubeCacheTTL: verifyYoutubeCacheTTL,
+		ResignClaims:    verifyResign,
+	}
+	if verifyMetadataConfig != "" {
+		var err error
+		sm.MetadataConfig, err = sync.LoadMetadataConfig(verifyMetadataConfig)
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+	}
+
+	report, err := sm.Verify(args[0], args[1])
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/lbryio/lbry.go/errors"
+	sync "github.com/lbryio/lbry.go/ytsync"
+	"github.com/lbryio/lbry.go/ytsync/walletbackup"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	walletRestoreOutput string
+	walletCmdBucket     string
+	walletDaemonURL     string
+)
+
+// newWalletCmd returns the `ytsync wallet` command and its subcommands
+func newWalletCmd() *cobra.Command {
+	walletCmd := &cobra.Command{
+		Use:   "wallet",
+		Short: "List and restore the backups of the wallets of the channels",
+		Long: "List and restore the encrypted backups of the wallets of the channels, taken before each sync when " +
+			"WALLET_BACKUP_KEY is set, and move the certificates of their channel claims between wallets. The AWS_S3 " +
+			"environment variables give access to the bucket.",
+	}
+	walletCmd.PersistentFlags().StringVar(&walletCmdBucket, "wallet-backup-bucket", "", "S3 bucket the wallets are backed up to (Default: AWS_S3_BUCKET)")
+
+	listCmd := &cobra.Command{
+		Use:   "list <youtube_channel_id>",
+		Args:  cobra.ExactArgs(1),
+		Short: "List the backups of the wallet of a channel, oldest first, in JSON",
+		Run:   ytsyncWalletList,
+	}
+	restoreCmd := &cobra.Command{
+		Use:   "restore <youtube_channel_id> [backup]",
+		Args:  cobra.RangeArgs(1, 2),
+		Short: "Restore the wallet of a channel from a backup, the latest one by default",
+		Long: "Restore the wallet of a channel from a backup, the latest one if none is named. The wallet the next sync " +
+			"of the channel starts from is replaced, after being backed up itself. Don't restore the wallet of a " +
+			"channel that is syncing.",
+		Run: ytsyncWalletRestore,
+	}
+	restoreCmd.Flags().StringVar(&walletRestoreOutput, "output", "", "Write the decrypted wallet to this file instead of replacing the wallet of the channel")
+
+	exportCertificateCmd := &cobra.Command{
+		Use:   "export-certificate <youtube_channel_id> <claim_id>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Save the certificate of a channel claim with the wallet backups of a channel",
+		Long: "Export the certificate of a channel claim from the wallet of a running daemon and save it, encrypted, " +
+			"with the wallet backups of the youtube channel. Syncs do it on their own once the channel claim is set up.",
+		Run: ytsyncWalletExportCertificate,
+	}
+	importCertificateCmd := &cobra.Command{
+		Use:   "import-certificate <youtube_channel_id>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Import the saved certificate of the channel claim of a channel into a wallet",
+		Long: "Import the certificate saved for the youtube channel into the wallet of a running daemon, so it can " +
+			"publish to the channel claim. Syncs do it on their own when their wallet can't sign for the channel.",
+		Run: ytsyncWalletImportCertificate,
+	}
+	for _, c := range []*cobra.Command{exportCertificateCmd, importCertificateCmd} {
+		c.Flags().StringVar(&walletDaemonURL, "daemon-url", "", "API of the daemon holding the wallet (Default: http://localhost:5279)")
+	}
+
+	walletCmd.AddCommand(listCmd)
+	walletCmd.AddCommand(restoreCmd)
+	walletCmd.AddCommand(exportCertificateCmd)
+	walletCmd.AddCommand(importCertificateCmd)
+	return walletCmd
+}
+
+// walletBackupStore returns where the wallets are backed up, or nil if WALLET_BACKUP_KEY isn't set
+func walletBackupStore(bucket string, keep int) (*walletbackup.Store, error) {
+	hexKey := os.Getenv("WALLET_BACKUP_KEY")
+	if hexKey == "" {
+		return nil, nil
+	}
+	key, err := walletbackup.ParseKey(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	if bucket == "" {
+		bucket = os.Getenv("AWS_S3_BUCKET")
+	}
+	if bucket == "" {
+		return nil, errors.Err("no bucket to back the wallets up to. Please use --wallet-backup-bucket or set the environment variable AWS_S3_BUCKET")
+	}
+	if keep < 0 {
+		return nil, errors.Err("setting --wallet-backup-keep less than 0 doesn't make sense")
+	}
+	return &walletbackup.Store{
+		Key:    key,
+		ID:     os.Getenv("AWS_S3_ID"),
+		Secret: os.Getenv("AWS_S3_SECRET"),
+		Region: os.Getenv("AWS_S3_REGION"),
+		Bucket: bucket,
+		Keep:   keep,
+	}, nil
+}
+
+// walletManager returns a manager that can get to the wallets and their backups
+func walletManager() (*sync.SyncManager, error) {
+	store, err := walletBackupStore(walletCmdBucket, 0)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, errors.Err("A wallet backup key was not defined. Please set the environment variable WALLET_BACKUP_KEY")
+	}
+	return &sync.SyncManager{
+		AwsS3ID:       os.Getenv("AWS_S3_ID"),
+		AwsS3Secret:   os.Getenv("AWS_S3_SECRET"),
+		AwsS3Region:   os.Getenv("AWS_S3_REGION"),
+		AwsS3Bucket:   os.Getenv("AWS_S3_BUCKET"),
+		WalletBackups: store,
+		StopGroup:     stopGroup,
+	}, nil
+}
+
+func ytsyncWalletList(cmd *cobra.Command, args []string) {
+	sm, err := walletManager()
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	backups, err := sm.WalletBackupsOf(args[0])
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(backups)
+}
+
+func ytsyncWalletRestore(cmd *cobra.Command, args []string) {
+	sm, err := walletManager()
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	name := ""
+	if len(args) > 1 {
+		name = args[1]
+	}
+
+	if walletRestoreOutput != "" {
+		backup, wallet, err := sm.WalletBackups.Fetch(args[0], name)
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+		err = ioutil.WriteFile(walletRestoreOutput, wallet, 0600)
+		if err != nil {
+			log.Errorln(err.Error())
+			return
+		}
+		log.Infof("wrote the backup %s of the wallet of %s to %s", backup.Name, args[0], walletRestoreOutput)
+		return
+	}
+
+	backup, err := sm.RestoreWallet(args[0], name)
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	log.Infof("restored the wallet of %s from the backup %s", args[0], backup.Name)
+}
+
+func ytsyncWalletExportCertificate(cmd *cobra.Command, args []string) {
+	sm, err := walletManager()
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	err = sm.ExportCertificate(walletDaemonURL, args[0], args[1])
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	log.Infof("saved the certificate of channel claim %s for %s", args[1], args[0])
+}
+
+func ytsyncWalletImportCertificate(cmd *cobra.Command, args []string) {
+	sm, err := walletManager()
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	err = sm.ImportCertificate(walletDaemonURL, args[0])
+	if err != nil {
+		log.Errorln(err.Error())
+		return
+	}
+	log.Infof("imported the certificate of the channel claim of %s", args[0])
+}
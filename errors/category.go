@@ -0,0 +1,115 @@
+package errors
+
+import (
+	base "errors"
+	"reflect"
+
+	"github.com/go-errors/errors"
+)
+
+// Category tells which part of the system an error comes from, so it can be handled without matching its message
+type Category int
+
+const (
+	Uncategorized Category = iota
+	Network                // the remote end could not be reached or timed out
+	YouTubeAPI             // the YouTube API refused the request
+	Daemon                 // the lbry daemon failed or answered with an error
+	Wallet                 // a wallet is missing, broken or out of funds
+	Blockchain             // lbrycrd or the chain itself, e.g. a mempool conflict
+	API                    // the sync API refused the request or answered with something that makes no sense
+)
+
+func (c Category) String() string {
+	switch c {
+	case Uncategorized:
+		return "uncategorized"
+	case Network:
+		return "network"
+	case YouTubeAPI:
+		return "youtube api"
+	case Daemon:
+		return "daemon"
+	case Wallet:
+		return "wallet"
+	case Blockchain:
+		return "blockchain"
+	case API:
+		return "api"
+	}
+	return "unknown"
+}
+
+// Coded is an error with a category and a code. The code is a short string that narrows the category down, e.g.
+// "timeout" or "quota_exceeded". The message is the message of Err, so wrapping an error doesn't change it.
+type Coded struct {
+	Err      error
+	Category Category
+	Code     string
+}
+
+func (e *Coded) Error() string { return e.Err.Error() }
+
+// Unwrap returns the error that was categorized
+func (e *Coded) Unwrap() error { return e.Err }
+
+// New returns a categorized error with no stack trace attached, to be used as a sentinel error
+func New(category Category, code, text string) error {
+	return &Coded{Err: base.New(text), Category: category, Code: code}
+}
+
+// Categorize attaches a category and a code to err. The result still matches err with Is and As.
+func Categorize(err error, category Category, code string) error {
+	if err == nil {
+		return nil
+	}
+	return errors.Wrap(&Coded{Err: err, Category: category, Code: code}, 1)
+}
+
+// CategoryOf returns the category of the first categorized error in the chain of err
+func CategoryOf(err error) Category {
+	var coded *Coded
+	if As(err, &coded) {
+		return coded.Category
+	}
+	return Uncategorized
+}
+
+// CodeOf returns the code of the first categorized error in the chain of err
+func CodeOf(err error) string {
+	var coded *Coded
+	if As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
+
+// As finds the first error in the chain of err that can be assigned to target, which must be a non-nil pointer, and
+// sets target to it. The chain is followed through stack traces, pkg/errors causes and Unwrap methods.
+func As(err error, target interface{}) bool {
+	v := reflect.ValueOf(target)
+	if target == nil || v.Kind() != reflect.Ptr || v.IsNil() {
+		panic("errors: target must be a non-nil pointer")
+	}
+	targetType := v.Type().Elem()
+	for ; err != nil; err = next(err) {
+		if reflect.TypeOf(err).AssignableTo(targetType) {
+			v.Elem().Set(reflect.ValueOf(err))
+			return true
+		}
+	}
+	return false
+}
+
+// next returns the error err wraps, or nil if it doesn't wrap any
+func next(err error) error {
+	switch e := err.(type) {
+	case *errors.Error:
+		return e.Err
+	case causer:
+		return e.Cause()
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	}
+	return nil
+}
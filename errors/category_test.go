@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"testing"
+)
+
+var errSentinel = New(Daemon, "unavailable", "the daemon is unavailable")
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
+
+func TestCategoryOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		category Category
+		code     string
+	}{
+		{nil, Uncategorized, ""},
+		{Base("plain"), Uncategorized, ""},
+		{Err("with a trace"), Uncategorized, ""},
+		{errSentinel, Daemon, "unavailable"},
+		{Err(errSentinel), Daemon, "unavailable"},
+		{Prefix("starting", errSentinel), Daemon, "unavailable"},
+		{Categorize(Err("quotaExceeded"), YouTubeAPI, "quota_exceeded"), YouTubeAPI, "quota_exceeded"},
+		{Prefix("outer", Categorize(Categorize(Base("inner"), Network, "timeout"), Daemon, "")), Daemon, ""},
+	}
+	for _, test := range tests {
+		if c := CategoryOf(test.err); c != test.category {
+			t.Errorf("%v: expected category %s, got %s", test.err, test.category, c)
+		}
+		if c := CodeOf(test.err); c != test.code {
+			t.Errorf("%v: expected code %q, got %q", test.err, test.code, c)
+		}
+	}
+}
+
+func TestCategorizeKeepsMessage(t *testing.T) {
+	err := Categorize(Base("connection refused"), Network, "unreachable")
+	if err.Error() != "connection refused" {
+		t.Errorf("expected the message to be unchanged, got %q", err.Error())
+	}
+	if Categorize(nil, Network, "") != nil {
+		t.Error("expected nil to stay nil")
+	}
+}
+
+func TestIsThroughCategories(t *testing.T) {
+	base := Base("boom")
+	if !Is(Prefix("outer", Categorize(base, Wallet, "")), base) {
+		t.Error("expected the categorized error to match the error it wraps")
+	}
+	if !Is(Err(errSentinel), errSentinel) {
+		t.Error("expected a wrapped sentinel to match itself")
+	}
+	if Is(Err("boom"), base) {
+		t.Error("errors with the same message are not the same error")
+	}
+	if !Is(nil, nil) {
+		t.Error("expected nil to match nil")
+	}
+}
+
+func TestAs(t *testing.T) {
+	original := &testError{msg: "boom"}
+	var target *testError
+	if !As(Prefix("outer", Categorize(original, Daemon, "")), &target) || target != original {
+		t.Errorf("expected to find the original error, got %v", target)
+	}
+	if As(Err("boom"), &target) {
+		t.Error("expected no match")
+	}
+	if Unwrap(Categorize(original, Daemon, "")) != original {
+		t.Error("expected Unwrap to see through categories")
+	}
+}
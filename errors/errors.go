@@ -47,32 +47,29 @@ func Unwrap(err error) error {
 		return nil
 	}
 
-	deeper := true
-	for deeper {
-		deeper = false
-		if e, ok := err.(*errors.Error); ok {
-			err = e.Err
-			deeper = true
-		}
-		if c, ok := err.(causer); ok {
-			err = c.Cause()
-			deeper = true
-		}
+	for deeper := next(err); deeper != nil; deeper = next(err) {
+		err = deeper
 	}
 
 	return err
 }
 
-// Is compares two wrapped errors to determine if the underlying errors are the same
+// Is compares two wrapped errors to determine if the underlying errors are the same. Every error in the chain of e is
+// compared, so it also sees through categorized errors.
 // It also interops with errors from pkg/errors
 func Is(e error, original error) bool {
-	if c, ok := e.(causer); ok {
-		e = c.Cause()
-	}
 	if c, ok := original.(causer); ok {
 		original = c.Cause()
 	}
-	return errors.Is(e, original)
+	if e == nil {
+		return original == nil
+	}
+	for ; e != nil; e = next(e) {
+		if errors.Is(e, original) {
+			return true
+		}
+	}
+	return false
 }
 
 // Prefix prefixes the message of the error with the given string
@@ -91,11 +88,14 @@ func Trace(err error) string {
 	return string(Err(err).(*errors.Error).Stack())
 }
 
-// FullTrace returns the error type, message, and stack trace
+// FullTrace returns the error type, message, and stack trace. For a MultiError, it returns those of every error.
 func FullTrace(err error) string {
 	if err == nil {
 		return ""
 	}
+	if m, ok := Unwrap(err).(*MultiError); ok {
+		return m.FullTrace()
+	}
 	return Err(err).(*errors.Error).ErrorStack()
 }
 
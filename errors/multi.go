@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError collects several errors so they can be handled as one. The zero value is an empty MultiError, ready to
+// use. It's not safe for concurrent use.
+type MultiError struct {
+	Errors []error
+}
+
+// Append adds the errors that are not nil. The errors of another MultiError are added one by one.
+func (m *MultiError) Append(errs ...error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if other, ok := err.(*MultiError); ok {
+			m.Errors = append(m.Errors, other.Errors...)
+			continue
+		}
+		m.Errors = append(m.Errors, err)
+	}
+}
+
+// Len returns how many errors were collected
+func (m *MultiError) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.Errors)
+}
+
+// ErrorOrNil returns m if it collected any errors, and nil otherwise. Return this instead of m itself, or the caller
+// gets an error that is not nil even when nothing went wrong.
+func (m *MultiError) ErrorOrNil() error {
+	if m.Len() == 0 {
+		return nil
+	}
+	return m
+}
+
+// Error lists the messages of all the errors, one per line
+func (m *MultiError) Error() string {
+	if m.Len() == 1 {
+		return m.Errors[0].Error()
+	}
+	lines := make([]string, len(m.Errors))
+	for i, err := range m.Errors {
+		lines[i] = "* " + err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred:\n%s", len(m.Errors), strings.Join(lines, "\n"))
+}
+
+// FullTrace returns the type, message and stack trace of all the errors
+func (m *MultiError) FullTrace() string {
+	traces := make([]string, m.Len())
+	for i, err := range m.Errors {
+		traces[i] = fmt.Sprintf("error %d of %d: %s", i+1, len(m.Errors), FullTrace(err))
+	}
+	return strings.Join(traces, "\n")
+}
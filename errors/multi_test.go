@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiErrorEmpty(t *testing.T) {
+	var m MultiError
+	m.Append(nil, nil)
+	if m.ErrorOrNil() != nil {
+		t.Errorf("expected nil, got %v", m.ErrorOrNil())
+	}
+	var nilMulti *MultiError
+	if nilMulti.ErrorOrNil() != nil || nilMulti.Len() != 0 {
+		t.Error("expected a nil MultiError to be empty")
+	}
+}
+
+func TestMultiErrorAppend(t *testing.T) {
+	var m MultiError
+	m.Append(Base("first"))
+	if m.Error() != "first" {
+		t.Errorf("expected a single error to keep its message, got %q", m.Error())
+	}
+
+	var other MultiError
+	other.Append(Err("second"), nil, Err("third"))
+	m.Append(&other)
+	if m.Len() != 3 {
+		t.Fatalf("expected 3 errors, got %d", m.Len())
+	}
+	expected := "3 errors occurred:\n* first\n* second\n* third"
+	if m.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, m.Error())
+	}
+}
+
+func TestMultiErrorFullTrace(t *testing.T) {
+	var m MultiError
+	m.Append(Err("first"), Err("second"))
+	trace := FullTrace(Prefix("sync", m.ErrorOrNil()))
+	for _, s := range []string{"error 1 of 2", "first", "error 2 of 2", "second", "TestMultiErrorFullTrace"} {
+		if !strings.Contains(trace, s) {
+			t.Errorf("expected %q in the trace:\n%s", s, trace)
+		}
+	}
+}
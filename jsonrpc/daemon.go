@@ -1,6 +1,7 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -23,6 +24,15 @@ const DefaultPort = 5279
 type Client struct {
 	conn    jsonrpc.RPCClient
 	address string
+	timeout time.Duration
+	ctx     context.Context
+	observe func(method string, took time.Duration, err error)
+}
+
+// SetObserver makes the client call observe after every call to the daemon, with how long it took and the error it
+// returned, if any
+func (d *Client) SetObserver(observe func(method string, took time.Duration, err error)) {
+	d.observe = observe
 }
 
 func NewClient(address string) *Client {
@@ -108,13 +118,26 @@ func debugParams(params map[string]interface{}) string {
 
 func (d *Client) callNoDecode(command string, params map[string]interface{}) (interface{}, error) {
 	log.Debugln("jsonrpc: " + command + " " + debugParams(params))
+	if d.observe == nil {
+		return d.send(command, params)
+	}
+	started := time.Now()
+	result, err := d.send(command, params)
+	d.observe(command, time.Since(started), err)
+	return result, err
+}
+
+func (d *Client) send(command string, params map[string]interface{}) (interface{}, error) {
 	r, err := d.conn.Call(command, params)
 	if err != nil {
-		return nil, errors.Wrap(err, 0)
+		if d.ctx != nil && d.ctx.Err() != nil {
+			return nil, errors.Err(d.ctx.Err())
+		}
+		return nil, errors.Categorize(err, errors.Network, transportErrorCode(err))
 	}
 
 	if r.Error != nil {
-		return nil, errors.Err("Error in daemon: " + r.Error.Message)
+		return nil, categorizeDaemonError(&DaemonError{Method: command, Code: r.Error.Code, Message: r.Error.Message})
 	}
 
 	return r.Result, nil
@@ -129,9 +152,34 @@ func (d *Client) call(response interface{}, command string, params map[string]in
 }
 
 func (d *Client) SetRPCTimeout(timeout time.Duration) {
-	d.conn = jsonrpc.NewClientWithOpts(d.address, &jsonrpc.RPCClientOpts{
-		HTTPClient: &http.Client{Timeout: timeout},
-	})
+	d.timeout = timeout
+	d.conn = d.newConn()
+}
+
+// WithContext returns a copy of the client whose calls are aborted when ctx is done. Aborted calls return ctx.Err().
+func (d *Client) WithContext(ctx context.Context) *Client {
+	c := *d
+	c.ctx = ctx
+	c.conn = c.newConn()
+	return &c
+}
+
+func (d *Client) newConn() jsonrpc.RPCClient {
+	httpClient := &http.Client{Timeout: d.timeout}
+	if d.ctx != nil {
+		httpClient.Transport = &contextTransport{ctx: d.ctx, base: http.DefaultTransport}
+	}
+	return jsonrpc.NewClientWithOpts(d.address, &jsonrpc.RPCClientOpts{HTTPClient: httpClient})
+}
+
+// contextTransport attaches a context to every request so they can be cancelled
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
 }
 
 func (d *Client) Commands() (*CommandsResponse, error) {
@@ -322,6 +370,48 @@ func (d *Client) FileList(options FileListOptions) (*FileListResponse, error) {
 	})
 }
 
+// FileDelete stops seeding the stream of the claim and deletes its blobs, and the downloaded file if there is one
+func (d *Client) FileDelete(claimID string) (*FileDeleteResponse, error) {
+	response := new(FileDeleteResponse)
+	return response, d.call(response, "file_delete", map[string]interface{}{
+		"claim_id":                 claimID,
+		"delete_from_download_dir": true,
+	})
+}
+
+// ClaimListMine returns the claims of the wallet
+func (d *Client) ClaimListMine() (*ClaimListMineResponse, error) {
+	response := new(ClaimListMineResponse)
+	return response, d.call(response, "claim_list_mine", map[string]interface{}{})
+}
+
+// ClaimAbandon abandons a claim of the wallet. Its bid goes back to the wallet once the transaction is confirmed.
+func (d *Client) ClaimAbandon(claimID string) (*ClaimAbandonResponse, error) {
+	response := new(ClaimAbandonResponse)
+	return response, d.call(response, "claim_abandon", map[string]interface{}{
+		"claim_id": claimID,
+	})
+}
+
+// ClaimNewSupport supports a claim with credits from the wallet. The support can be abandoned like a claim.
+func (d *Client) ClaimNewSupport(name, claimID string, amount float64) (*ClaimNewSupportResponse, error) {
+	response := new(ClaimNewSupportResponse)
+	return response, d.call(response, "claim_new_support", map[string]interface{}{
+		"name":     name,
+		"claim_id": claimID,
+		"amount":   amount,
+	})
+}
+
+// WalletSend sends credits from the wallet to an address
+func (d *Client) WalletSend(amount float64, address string) (*WalletSendResponse, error) {
+	response := new(WalletSendResponse)
+	return response, d.call(response, "wallet_send", map[string]interface{}{
+		"amount":  amount,
+		"address": address,
+	})
+}
+
 func (d *Client) Resolve(url string) (*ResolveResponse, error) {
 	response := new(ResolveResponse)
 	return response, d.call(response, "resolve", map[string]interface{}{
@@ -329,12 +419,55 @@ func (d *Client) Resolve(url string) (*ResolveResponse, error) {
 	})
 }
 
+// ChannelOptions is the metadata of a channel claim. Only the fields that are set are sent, daemons that don't support
+// channel metadata reject the parameters.
+type ChannelOptions struct {
+	Title        *string
+	Description  *string
+	ThumbnailURL *string // the avatar of the channel
+	CoverURL     *string // the banner of the channel
+	WebsiteURL   *string
+	ClaimAddress *string // where the channel claim is sent to
+}
+
+func (o ChannelOptions) addTo(params map[string]interface{}) {
+	for key, value := range map[string]*string{
+		"title":         o.Title,
+		"description":   o.Description,
+		"thumbnail_url": o.ThumbnailURL,
+		"cover_url":     o.CoverURL,
+		"website_url":   o.WebsiteURL,
+		"claim_address": o.ClaimAddress,
+	} {
+		if value != nil {
+			params[key] = *value
+		}
+	}
+}
+
 func (d *Client) ChannelNew(name string, amount float64) (*ChannelNewResponse, error) {
+	return d.ChannelNewWithOptions(name, amount, ChannelOptions{})
+}
+
+// ChannelNewWithOptions creates a channel claim with the given metadata
+func (d *Client) ChannelNewWithOptions(name string, amount float64, options ChannelOptions) (*ChannelNewResponse, error) {
 	response := new(ChannelNewResponse)
-	return response, d.call(response, "channel_new", map[string]interface{}{
+	params := map[string]interface{}{
 		"channel_name": name,
 		"amount":       amount,
-	})
+	}
+	options.addTo(params)
+	return response, d.call(response, "channel_new", params)
+}
+
+// ChannelUpdate changes the metadata of a channel claim of the wallet
+func (d *Client) ChannelUpdate(claimID string, options ChannelOptions) (*ChannelUpdateResponse, error) {
+	response := new(ChannelUpdateResponse)
+	params := map[string]interface{}{
+		"claim_id": claimID,
+	}
+	options.addTo(params)
+	return response, d.call(response, "channel_update", params)
 }
 
 func (d *Client) ChannelList() (*ChannelListResponse, error) {
@@ -342,6 +475,23 @@ func (d *Client) ChannelList() (*ChannelListResponse, error) {
 	return response, d.call(response, "channel_list", map[string]interface{}{})
 }
 
+// ChannelExport returns the signing information of a channel claim of the wallet, for ChannelImport
+func (d *Client) ChannelExport(claimID string) (*ChannelExportResponse, error) {
+	response := new(ChannelExportResponse)
+	return response, d.call(response, "channel_export", map[string]interface{}{
+		"claim_id": claimID,
+	})
+}
+
+// ChannelImport adds the signing information of a channel claim, exported by ChannelExport from another wallet, to
+// the wallet so it can publish to the channel
+func (d *Client) ChannelImport(serialized string) error {
+	_, err := d.callNoDecode("channel_import", map[string]interface{}{
+		"serialized_certificate_info": serialized,
+	})
+	return err
+}
+
 type PublishOptions struct {
 	Fee           *Fee
 	Title         *string
@@ -357,13 +507,14 @@ type PublishOptions struct {
 	ChannelID     *string
 	ClaimAddress  *string
 	ChangeAddress *string
+	Tags          []string
+	Sources       map[string]string // publishes a claim for a stream that was uploaded already, e.g. {"lbry_sd_hash": ...}
 }
 
 func (d *Client) Publish(name, filePath string, bid float64, options PublishOptions) (*PublishResponse, error) {
 	response := new(PublishResponse)
-	return response, d.call(response, "publish", map[string]interface{}{
+	params := map[string]interface{}{
 		"name":           name,
-		"file_path":      filePath,
 		"bid":            bid,
 		"fee":            options.Fee,
 		"title":          options.Title,
@@ -379,7 +530,19 @@ func (d *Client) Publish(name, filePath string, bid float64, options PublishOpti
 		"channel_id":     options.ChannelID,
 		"claim_address":  options.ClaimAddress,
 		"change_address": options.ChangeAddress,
-	})
+	}
+	if len(options.Tags) > 0 {
+		// only sent when set, daemons that don't know about tags reject the parameter
+		params["tags"] = options.Tags
+	}
+	// the daemon needs either a file or the sources of a stream it already has
+	if filePath != "" {
+		params["file_path"] = filePath
+	}
+	if len(options.Sources) > 0 {
+		params["sources"] = options.Sources
+	}
+	return response, d.call(response, "publish", params)
 }
 
 func (d *Client) BlobAnnounce(blobHash, sdHash, streamHash *string) (*BlobAnnounceResponse, error) {
@@ -433,6 +596,16 @@ func (d *Client) WalletUnusedAddress() (*WalletUnusedAddressResponse, error) {
 	return &response, nil
 }
 
+// ClaimListByChannel returns a page of the claims in the channel at url. Pages start at 1.
+func (d *Client) ClaimListByChannel(url string, page, pageSize uint64) (*ClaimListByChannelResponse, error) {
+	response := new(ClaimListByChannelResponse)
+	return response, d.call(response, "claim_list_by_channel", map[string]interface{}{
+		"uri":       url,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
 func (d *Client) NumClaimsInChannel(url string) (uint64, error) {
 	response := new(NumClaimsInChannelResponse)
 	err := d.call(response, "claim_list_by_channel", map[string]interface{}{
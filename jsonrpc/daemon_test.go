@@ -2,7 +2,53 @@ package jsonrpc
 
 import (
 	"testing"
+
+	"github.com/lbryio/lbry.go/errors"
 )
 
 func TestStatus(t *testing.T) {
 }
+
+func TestDaemonError(t *testing.T) {
+	err := errors.Err(&DaemonError{Method: "publish", Code: -32500, Message: "Cannot publish empty file"})
+	if err.Error() != "Error in daemon: Cannot publish empty file" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	e, ok := AsDaemonError(err)
+	if !ok {
+		t.Fatal("expected a DaemonError")
+	}
+	if e.Method != "publish" || e.Code != -32500 {
+		t.Errorf("unexpected error %s", e.String())
+	}
+	if IsDaemonError(errors.Err("connection refused")) {
+		t.Error("a transport error is not a DaemonError")
+	}
+}
+
+func TestDaemonErrorCategory(t *testing.T) {
+	err := categorizeDaemonError(&DaemonError{Method: "publish", Code: -32500, Message: "Insufficient funds, please deposit additional LBC"})
+	if errors.CategoryOf(err) != errors.Wallet || errors.CodeOf(err) != ErrorCodeInsufficientFunds {
+		t.Errorf("expected a wallet error, got %s (%s)", errors.CategoryOf(err), errors.CodeOf(err))
+	}
+	if !IsDaemonError(err) {
+		t.Error("a categorized DaemonError is still a DaemonError")
+	}
+	err = categorizeDaemonError(&DaemonError{Method: "publish", Message: "Cannot publish empty file"})
+	if errors.CategoryOf(err) != errors.Daemon {
+		t.Errorf("expected a daemon error, got %s", errors.CategoryOf(err))
+	}
+}
+
+func TestTransportErrorCode(t *testing.T) {
+	tests := map[string]string{
+		"dial tcp 127.0.0.1:5279: i/o timeout":                                        ErrorCodeTimeout,
+		"net/http: request canceled (Client.Timeout exceeded while awaiting headers)": ErrorCodeTimeout,
+		"dial tcp 127.0.0.1:5279: connect: connection refused":                        ErrorCodeUnreachable,
+	}
+	for msg, code := range tests {
+		if c := transportErrorCode(errors.Base(msg)); c != code {
+			t.Errorf("%q: expected %s, got %s", msg, code, c)
+		}
+	}
+}
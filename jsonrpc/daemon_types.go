@@ -226,6 +226,7 @@ type StatusResponse struct {
 		Blocks         int    `json:"blocks"`
 		BlocksBehind   int    `json:"blocks_behind"`
 		IsEncrypted    bool   `json:"is_encrypted"`
+		IsLocked       bool   `json:"is_locked"`
 	} `json:"wallet"`
 }
 
@@ -284,6 +285,26 @@ type StreamAvailabilityResponse struct {
 type GetResponse File
 type FileListResponse []File
 
+type FileDeleteResponse bool
+
+type ClaimListMineResponse []Claim
+
+type ClaimAbandonResponse struct {
+	Fee  decimal.Decimal `json:"fee"`
+	Txid string          `json:"txid"`
+}
+
+type ClaimNewSupportResponse struct {
+	Fee  decimal.Decimal `json:"fee"`
+	Nout int             `json:"nout"`
+	Txid string          `json:"txid"`
+}
+
+type WalletSendResponse struct {
+	Fee  decimal.Decimal `json:"fee"`
+	Txid string          `json:"txid"`
+}
+
 type ResolveResponse map[string]ResolveResponseItem
 type ResolveResponseItem struct {
 	Certificate     *Claim  `json:"certificate,omitempty"`
@@ -301,6 +322,10 @@ type ChannelNewResponse struct {
 	Txid    string          `json:"txid"`
 }
 
+type ChannelUpdateResponse ChannelNewResponse
+
+type ChannelExportResponse string
+
 type ChannelListResponse []struct {
 	Address            string            `json:"address"`
 	Amount             decimal.Decimal   `json:"amount"`
@@ -357,6 +382,12 @@ type WalletNewAddressResponse string
 
 type WalletUnusedAddressResponse string
 
+type ClaimListByChannelResponse map[string]struct {
+	ClaimsInChannel []Claim `json:"claims_in_channel,omitempty"`
+	ReturnedPage    uint64  `json:"returned_page,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
 type NumClaimsInChannelResponse map[string]struct {
 	ClaimsInChannel uint64 `json:"claims_in_channel,omitempty"`
 	Error           string `json:"error,omitempty"`
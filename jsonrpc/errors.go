@@ -0,0 +1,90 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// Codes of the categorized errors the client returns, see errors.CodeOf
+const (
+	ErrorCodeTimeout           = "timeout"            // the daemon didn't answer in time
+	ErrorCodeUnreachable       = "unreachable"        // the daemon could not be reached
+	ErrorCodeInsufficientFunds = "insufficient_funds" // the wallet of the daemon can't pay for the call
+	ErrorCodeMempoolConflict   = "mempool_conflict"   // the transaction conflicts with one that isn't confirmed yet
+)
+
+// insufficientFundsMessages are the messages the daemon answers with when its wallet is short of credits
+var insufficientFundsMessages = []string{
+	"NotEnoughFunds",
+	"Not enough funds",
+	"Insufficient funds",
+}
+
+// mempoolConflictMessages are the messages lbrycrd rejects transactions with until a block is mined
+var mempoolConflictMessages = []string{
+	"txn-mempool-conflict",
+	"too-long-mempool-chain",
+}
+
+// DaemonError is returned when the daemon received a call and answered with an error
+type DaemonError struct {
+	Method  string
+	Code    int
+	Message string
+}
+
+// Error keeps the format of the messages the daemon errors always had, callers match on it
+func (e *DaemonError) Error() string {
+	return "Error in daemon: " + e.Message
+}
+
+// String includes the method and code, for logs
+func (e *DaemonError) String() string {
+	return fmt.Sprintf("%s failed with code %d: %s", e.Method, e.Code, e.Message)
+}
+
+// AsDaemonError returns the DaemonError err wraps, if any. Errors that are not DaemonErrors mean the daemon could not
+// be reached or its response could not be decoded.
+func AsDaemonError(err error) (*DaemonError, bool) {
+	var e *DaemonError
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// IsDaemonError returns true if the daemon answered err
+func IsDaemonError(err error) bool {
+	_, ok := AsDaemonError(err)
+	return ok
+}
+
+// categorizeDaemonError adds a stack trace and a category to e. The daemon doesn't give the wallet and blockchain
+// errors codes of their own, so they are told apart by their message.
+func categorizeDaemonError(e *DaemonError) error {
+	for _, msg := range insufficientFundsMessages {
+		if strings.Contains(e.Message, msg) {
+			return errors.Categorize(e, errors.Wallet, ErrorCodeInsufficientFunds)
+		}
+	}
+	for _, msg := range mempoolConflictMessages {
+		if strings.Contains(e.Message, msg) {
+			return errors.Categorize(e, errors.Blockchain, ErrorCodeMempoolConflict)
+		}
+	}
+	return errors.Categorize(e, errors.Daemon, "")
+}
+
+// transportErrorCode tells timeouts apart from the daemon being unreachable. The jsonrpc library only keeps the
+// message of the errors of the http client, so it falls back on that.
+func transportErrorCode(err error) string {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return ErrorCodeTimeout
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded") {
+		return ErrorCodeTimeout
+	}
+	return ErrorCodeUnreachable
+}
@@ -0,0 +1,269 @@
+// Package metrics keeps counters and gauges and exposes them in the Prometheus text format, so they can be scraped
+// without pulling in the whole Prometheus client.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type kind string
+
+const (
+	kindCounter kind = "counter"
+	kindGauge   kind = "gauge"
+)
+
+// Registry holds metrics and writes them out
+type Registry struct {
+	mux     sync.RWMutex
+	metrics map[string]*metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// Default is the registry used by the package level functions
+var Default = NewRegistry()
+
+// NewCounter creates a counter in the default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewGauge creates a gauge in the default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// Handler serves the metrics of the default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// NewCounter creates a counter. Its values must be given for each of the labels, in order, when it is updated. It
+// panics if a metric with the same name was already created, like defining the same variable twice would.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, kindCounter, labels)}
+}
+
+// NewGauge creates a gauge. Its values must be given for each of the labels, in order, when it is updated. It
+// panics if a metric with the same name was already created.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, kindGauge, labels)}
+}
+
+func (r *Registry) register(name, help string, k kind, labels []string) *metric {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("metrics: " + name + " is already registered")
+	}
+	m := &metric{name: name, help: help, kind: k, labels: labels, values: make(map[string]*sample)}
+	r.metrics[name] = m
+	return m
+}
+
+// WriteTo writes all the metrics in the Prometheus text format, sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mux.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]*metric, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		metrics = append(metrics, r.metrics[name])
+	}
+	r.mux.RUnlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, m := range metrics {
+		m.write(cw)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.(*bufio.Writer).Flush()
+	}
+	return cw.n, cw.err
+}
+
+// Handler serves the metrics of the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// Counter is a value that only goes up, like the number of videos published
+type Counter struct {
+	m *metric
+}
+
+// Inc adds 1 to the counter
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v to the counter. It panics if v is negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.m.name + " can't go down")
+	}
+	c.m.add(v, labelValues)
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.m.get(labelValues)
+}
+
+// Gauge is a value that goes up and down, like the disk usage
+type Gauge struct {
+	m *metric
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.m.set(v, labelValues)
+}
+
+// Add adds v, which can be negative, to the gauge
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.m.add(v, labelValues)
+}
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.m.get(labelValues)
+}
+
+type metric struct {
+	name   string
+	help   string
+	kind   kind
+	labels []string
+
+	mux    sync.Mutex
+	values map[string]*sample
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+func (m *metric) sample(labelValues []string) *sample {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", m.name, len(m.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := m.values[key]
+	if !ok {
+		s = &sample{labelValues: append([]string(nil), labelValues...)}
+		m.values[key] = s
+	}
+	return s
+}
+
+func (m *metric) add(v float64, labelValues []string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.sample(labelValues).value += v
+}
+
+func (m *metric) set(v float64, labelValues []string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.sample(labelValues).value = v
+}
+
+func (m *metric) get(labelValues []string) float64 {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if s, ok := m.values[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (m *metric) write(w io.Writer) {
+	m.mux.Lock()
+	keys := make([]string, 0, len(m.values))
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	samples := make([]sample, 0, len(keys))
+	for _, key := range keys {
+		samples = append(samples, *m.values[key])
+	}
+	m.mux.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+	if len(m.labels) == 0 && len(samples) == 0 {
+		// unlabelled metrics are always there, even before they're updated
+		fmt.Fprintf(w, "%s 0\n", m.name)
+		return
+	}
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s%s %s\n", m.name, m.formatLabels(s.labelValues), formatValue(s.value))
+	}
+}
+
+func (m *metric) formatLabels(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = m.labels[i] + `="` + escapeLabel(v) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// countingWriter counts the bytes written and remembers the first error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
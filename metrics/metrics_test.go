@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func dump(t *testing.T, r *Registry) string {
+	buf := &bytes.Buffer{}
+	n, err := r.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo reported %d bytes, wrote %d", n, buf.Len())
+	}
+	return buf.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("videos_published_total", "Videos published")
+	c.Inc()
+	c.Add(2.5)
+	if v := c.Value(); v != 3.5 {
+		t.Errorf("expected 3.5, got %v", v)
+	}
+	expected := "# HELP videos_published_total Videos published\n# TYPE videos_published_total counter\nvideos_published_total 3.5\n"
+	if out := dump(t, r); out != expected {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestCounterCantGoDown(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewRegistry().NewCounter("c", "").Add(-1)
+}
+
+func TestUnusedMetricIsZero(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("disk_usage_ratio", "Disk usage")
+	if out := dump(t, r); !strings.Contains(out, "\ndisk_usage_ratio 0\n") {
+		t.Errorf("expected the gauge to be reported as 0, got:\n%s", out)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("failures_total", "Failures", "reason")
+	c.Inc("quota exceeded")
+	c.Inc("quota exceeded")
+	c.Inc(`weird "reason"` + "\n")
+	if v := c.Value("quota exceeded"); v != 2 {
+		t.Errorf("expected 2, got %v", v)
+	}
+	if v := c.Value("never happened"); v != 0 {
+		t.Errorf("expected 0, got %v", v)
+	}
+	out := dump(t, r)
+	if !strings.Contains(out, `failures_total{reason="quota exceeded"} 2`) {
+		t.Errorf("missing labelled sample:\n%s", out)
+	}
+	if !strings.Contains(out, `failures_total{reason="weird \"reason\"\n"} 1`) {
+		t.Errorf("label value not escaped:\n%s", out)
+	}
+	if strings.Contains(out, "never happened") {
+		t.Errorf("reading a value should not create a sample:\n%s", out)
+	}
+}
+
+func TestWrongLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewRegistry().NewGauge("g", "", "a", "b").Set(1, "only one")
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("channels_running", "Running channels")
+	g.Add(3)
+	g.Add(-1)
+	if v := g.Value(); v != 2 {
+		t.Errorf("expected 2, got %v", v)
+	}
+	g.Set(0.75)
+	if out := dump(t, r); !strings.Contains(out, "# TYPE channels_running gauge\nchannels_running 0.75\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestSortedOutput(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("b_total", "").Inc()
+	g := r.NewGauge("a", "", "x")
+	g.Set(1, "z")
+	g.Set(2, "y")
+	out := dump(t, r)
+	if strings.Index(out, "# HELP a ") > strings.Index(out, "# HELP b_total ") {
+		t.Errorf("metrics not sorted by name:\n%s", out)
+	}
+	if strings.Index(out, `a{x="y"}`) > strings.Index(out, `a{x="z"}`) {
+		t.Errorf("samples not sorted:\n%s", out)
+	}
+}
+
+func TestDuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("c", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	r.NewGauge("c", "")
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("c", "", "worker")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Inc("w")
+			}
+		}()
+	}
+	wg.Wait()
+	if v := c.Value("w"); v != 1000 {
+		t.Errorf("expected 1000, got %v", v)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "Requests").Inc()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "requests_total 1\n") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
@@ -0,0 +1,158 @@
+// Package names turns titles into claim names. Names are lowercase ASCII letters, digits and dashes, made of the
+// first words of the title. Letters with accents lose them, and Cyrillic and Greek letters are transliterated. Emoji
+// and other symbols are dropped, as are the scripts there is no transliteration for. Titles that leave less than
+// MinLength characters get a name made out of their hash instead.
+package names
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxLength is the longest name Claim returns, suffix included
+	MaxLength = 40
+	// MinLength is the shortest name made out of the words of a title. Shorter ones are replaced by a hash of the title.
+	MinLength = 2
+
+	// hashLength is how much of the hash of the title the names that replace short ones are made of
+	hashLength = 15
+	// wholeWordsLength is the length under which a name gets a truncated word appended, rather than being cut short
+	wholeWordsLength = 20
+)
+
+// invalidChars can't be part of a claim name, on top of whitespace and control characters
+const invalidChars = "=&#:$@%?;/\\\"<>{}|^~[]`"
+
+// Claim returns the claim name for a title. Attempts after the first one get "-N" appended, for when the previous
+// names were taken. The name is never longer than MaxLength, and always valid.
+func Claim(title string, attempt int) string {
+	suffix := ""
+	if attempt > 1 {
+		suffix = "-" + strconv.Itoa(attempt)
+	}
+	name := truncate(Slug(title), MaxLength-len(suffix))
+	if len(name) < MinLength {
+		return hashName(title, attempt)
+	}
+	return name + suffix
+}
+
+// Unique returns the first name Claim comes up with for the title that isn't in taken, and adds it to taken
+func Unique(title string, taken map[string]bool) string {
+	for attempt := 1; ; attempt++ {
+		name := Claim(title, attempt)
+		if !taken[name] {
+			taken[name] = true
+			return name
+		}
+	}
+}
+
+// Slug turns s into lowercase words of ASCII letters and digits, separated by single dashes. It's not length limited.
+func Slug(s string) string {
+	var b slugBuilder
+	for _, r := range s {
+		if latin, ok := transliterate(r); ok {
+			b.write(latin)
+			continue
+		}
+		// compatibility decomposition splits accented letters into the letter and its accents, and turns the
+		// likes of fullwidth letters and ligatures into plain ones
+		for _, d := range norm.NFKD.String(string(r)) {
+			switch {
+			case d < unicode.MaxASCII && (unicode.IsLetter(d) || unicode.IsDigit(d)):
+				b.write(string(unicode.ToLower(d)))
+			case d <= unicode.MaxASCII:
+				b.separate()
+			case isDropped(d):
+				// accents, emoji and the like disappear without splitting the word they're in
+			default:
+				if latin, ok := transliterate(d); ok {
+					b.write(latin)
+				} else {
+					b.separate()
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// slugBuilder puts a single dash between words, and none at the start or the end
+type slugBuilder struct {
+	strings.Builder
+	dash bool
+}
+
+func (b *slugBuilder) write(s string) {
+	if s == "" {
+		return
+	}
+	if b.dash && b.Len() > 0 {
+		b.WriteByte('-')
+	}
+	b.dash = false
+	b.WriteString(s)
+}
+
+// separate ends the current word
+func (b *slugBuilder) separate() {
+	b.dash = true
+}
+
+// IsValid returns true if name can be claimed. Claim names can hold any character except whitespace, control
+// characters and a few that have a meaning in lbry URLs.
+func IsValid(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || r == unicode.ReplacementChar || strings.ContainsRune(invalidChars, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate shortens a slug to at most maxLen characters. Whole words are kept when possible, but a name that would end
+// up shorter than wholeWordsLength is filled up with the start of the next word instead.
+func truncate(slug string, maxLen int) string {
+	if len(slug) <= maxLen {
+		return slug
+	}
+	words := strings.Split(slug, "-")
+	name := words[0]
+	if len(name) > maxLen {
+		return name[:maxLen]
+	}
+	for _, word := range words[1:] {
+		longer := name + "-" + word
+		if len(longer) > maxLen {
+			if len(name) < wholeWordsLength {
+				name = strings.TrimRight(longer[:maxLen], "-")
+			}
+			break
+		}
+		name = longer
+	}
+	return name
+}
+
+// hashName returns a name made out of the hash of the title, for titles that don't make a long enough name
+func hashName(title string, attempt int) string {
+	hash := md5.Sum([]byte(title))
+	return hex.EncodeToString(hash[:])[:hashLength] + "-" + strconv.Itoa(attempt)
+}
+
+// isDropped returns true for the characters that are removed from titles: combining marks like accents, emoji and
+// other symbols, and invisible formatting characters like the joiners emoji sequences are made of
+func isDropped(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.So, unicode.Sk, unicode.Cf) ||
+		(r >= 0xFE00 && r <= 0xFE0F) // variation selectors
+}
@@ -0,0 +1,159 @@
+package names
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		title string
+		slug  string
+	}{
+		{"", ""},
+		{"Hello World", "hello-world"},
+		{"  --Hello,   World!!--  ", "hello-world"},
+		{"Top 10 Tips & Tricks (2018)", "top-10-tips-tricks-2018"},
+		{"a^b`c", "a-b-c"},
+		{"Café Olé", "cafe-ole"},
+		{"Crème brûlée à la française", "creme-brulee-a-la-francaise"},
+		{"Ångström Øresund Æble", "angstrom-oresund-aeble"},
+		{"Straße Łódź", "strasse-lodz"},
+		{"naïve coöperation", "naive-cooperation"},
+		{"Привет мир", "privet-mir"},
+		{"Щука и Ёжик", "shchuka-i-yozhik"},
+		{"Объявление", "obyavlenie"},
+		{"Україна", "ukrayina"},
+		{"Καλημέρα κόσμε", "kalimera-kosme"},
+		{"Ψάρι", "psari"},
+		{"Party 🎉 time", "party-time"},
+		{"Party🎉time", "partytime"},
+		{"🔥🔥 Hot 🔥🔥", "hot"},
+		{"Family 👨‍👩‍👧 trip", "family-trip"},
+		{"Heart ❤️ you", "heart-you"},
+		{"Ｆｕｌｌｗｉｄｔｈ １２３", "fullwidth-123"},
+		{"ﬁnal ﬂight", "final-flight"},
+		{"x² + y²", "x2-y2"},
+		{"日本語のタイトル", ""},
+		{"Video: 日本語 part 2", "video-part-2"},
+		{"zero​width", "zerowidth"},
+		{"tab\tnew\nline", "tab-new-line"},
+	}
+	for _, test := range tests {
+		if slug := Slug(test.title); slug != test.slug {
+			t.Errorf("Slug(%q): expected %q, got %q", test.title, test.slug, slug)
+		}
+	}
+}
+
+func TestClaim(t *testing.T) {
+	tests := []struct {
+		title   string
+		attempt int
+		name    string
+	}{
+		{"Hello World", 1, "hello-world"},
+		{"Hello World", 2, "hello-world-2"},
+		{"Hello World", 13, "hello-world-13"},
+		{"Привет мир", 3, "privet-mir-3"},
+		{"This is a very long title that goes on and on and on forever", 1, "this-is-a-very-long-title-that-goes-on"},
+		{"This is a very long title that goes on and on and on forever", 2, "this-is-a-very-long-title-that-goes-on-2"},
+		{"This is a very long title that goes on and on and on forever", 100, "this-is-a-very-long-title-that-goes-100"},
+		{"Supercalifragilisticexpialidocious and more words", 1, "supercalifragilisticexpialidocious-and"},
+		{strings.Repeat("a", 50), 1, strings.Repeat("a", 40)},
+		{strings.Repeat("a", 50), 2, strings.Repeat("a", 38) + "-2"},
+		{"Hi " + strings.Repeat("b", 50), 1, "hi-" + strings.Repeat("b", 37)},
+		{"", 1, "d41d8cd98f00b20-1"},
+		{"a", 1, "0cc175b9c0f1b6a-1"},
+		{"a", 2, "0cc175b9c0f1b6a-2"},
+		{"日本語のタイトル", 1, "3ae504d8cbd8918-1"},
+		{"🎉🎉🎉", 4, "27370732532dc0b-4"},
+	}
+	for _, test := range tests {
+		name := Claim(test.title, test.attempt)
+		if name != test.name {
+			t.Errorf("Claim(%q, %d): expected %q, got %q", test.title, test.attempt, test.name, name)
+		}
+		if len(name) > MaxLength {
+			t.Errorf("Claim(%q, %d): %q is longer than %d characters", test.title, test.attempt, name, MaxLength)
+		}
+		if !IsValid(name) {
+			t.Errorf("Claim(%q, %d): %q is not valid", test.title, test.attempt, name)
+		}
+	}
+}
+
+func TestClaimNeverTooLong(t *testing.T) {
+	titles := []string{
+		strings.Repeat("word ", 30),
+		strings.Repeat("щ", 30),
+		strings.Repeat("ß", 30),
+		strings.Repeat("ab ", 30),
+		strings.Repeat("🎉 x ", 30),
+	}
+	for _, title := range titles {
+		for _, attempt := range []int{1, 2, 10, 1000} {
+			name := Claim(title, attempt)
+			if len(name) > MaxLength || len(name) < MinLength {
+				t.Errorf("Claim(%q, %d): %q is %d characters long", title, attempt, name, len(name))
+			}
+			if strings.HasPrefix(name, "-") || strings.Contains(name, "--") {
+				t.Errorf("Claim(%q, %d): %q has a stray dash", title, attempt, name)
+			}
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	taken := map[string]bool{"hello-world": true}
+	tests := []struct {
+		title string
+		name  string
+	}{
+		{"Hello World", "hello-world-2"},
+		{"Hello, World!", "hello-world-3"},
+		{"hello world", "hello-world-4"},
+		{"Привет мир", "privet-mir"},
+		{"Privet Mir", "privet-mir-2"},
+		{"", "d41d8cd98f00b20-1"},
+		{"", "d41d8cd98f00b20-2"},
+	}
+	for _, test := range tests {
+		if name := Unique(test.title, taken); name != test.name {
+			t.Errorf("Unique(%q): expected %q, got %q", test.title, test.name, name)
+		}
+		if !taken[test.name] {
+			t.Errorf("Unique(%q): expected %q to be taken", test.title, test.name)
+		}
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"hello-world", true},
+		{"hello_world.2018", true},
+		{"café", true},
+		{"日本語", true},
+		{"", false},
+		{"hello world", false},
+		{"tab\there", false},
+		{"new\nline", false},
+		{"null\x00byte", false},
+		{"a#b", false},
+		{"a:b", false},
+		{"a$b", false},
+		{"a@b", false},
+		{"a/b", false},
+		{"a?b", false},
+		{"a=b&c", false},
+		{"bad\xffutf8", false},
+	}
+	for _, test := range tests {
+		if valid := IsValid(test.name); valid != test.valid {
+			t.Errorf("IsValid(%q): expected %t, got %t", test.name, test.valid, valid)
+		}
+	}
+}
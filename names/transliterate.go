@@ -0,0 +1,43 @@
+package names
+
+import (
+	"unicode"
+)
+
+// latinLetters are the Latin letters that don't decompose into a base letter and accents
+var latinLetters = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i", 'ħ': "h", 'ŋ': "ng",
+	'ĸ': "k", 'ŧ': "t", 'ſ': "s", 'ƒ': "f", 'ǝ': "e", 'ə': "e",
+}
+
+// cyrillicLetters follows the common Russian romanization, with the Ukrainian, Belarusian and Serbian letters added
+var cyrillicLetters = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j", 'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz",
+	'ѕ': "dz", 'ѓ': "gj", 'ќ': "kj",
+}
+
+// greekLetters follows the ISO 843 romanization, simplified
+var greekLetters = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l",
+	'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f",
+	'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// transliterate returns the ASCII spelling of a letter that is not ASCII. Accented letters are expected to be
+// decomposed already. ok is false for characters there is no spelling for, including everything but letters. Some
+// letters, like the Cyrillic soft sign, are spelled with nothing.
+func transliterate(r rune) (latin string, ok bool) {
+	if !unicode.IsLetter(r) {
+		return "", false
+	}
+	r = unicode.ToLower(r)
+	for _, letters := range []map[rune]string{latinLetters, cyrillicLetters, greekLetters} {
+		if latin, ok := letters[r]; ok {
+			return latin, true
+		}
+	}
+	return "", false
+}
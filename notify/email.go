@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// Email sends notifications through an SMTP server. Only errors are sent unless IncludeInfo is set, so the inboxes
+// don't get flooded.
+type Email struct {
+	Host        string
+	Port        int
+	Username    string // no authentication is done if empty
+	Password    string
+	From        string
+	To          []string
+	Source      string // shows up in the subject, usually the hostname
+	IncludeInfo bool
+}
+
+func (e *Email) Notify(level Level, message string) error {
+	if level == LevelInfo && !e.IncludeInfo {
+		return nil
+	}
+	if len(e.To) == 0 {
+		return errors.Err("email: no recipients")
+	}
+
+	subject := "[" + string(level) + "]"
+	if e.Source != "" {
+		subject += " " + e.Source
+	}
+	firstLine := strings.SplitN(message, "\n", 2)[0]
+	if short := truncate(firstLine, 80); short != firstLine {
+		firstLine = short + "..."
+	}
+	subject += ": " + firstLine
+
+	body := "From: " + e.From + "\r\n" +
+		"To: " + strings.Join(e.To, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + message + "\r\n"
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+	err := smtp.SendMail(net.JoinHostPort(e.Host, strconv.Itoa(e.Port)), auth, e.From, e.To, []byte(body))
+	return errors.Prefix("email", err)
+}
@@ -0,0 +1,175 @@
+// Package notify sends alerts about long running processes to wherever their operators want them: Slack, Discord,
+// email or any HTTP endpoint.
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Level is how important a notification is
+type Level string
+
+const (
+	LevelInfo  Level = "info"
+	LevelError Level = "error"
+)
+
+// Notifier delivers notifications to a single destination
+type Notifier interface {
+	Notify(level Level, message string) error
+}
+
+// ThreadNotifier is a Notifier that can attach details to a notification, like the replies of a Slack thread, so they
+// don't clutter the channel
+type ThreadNotifier interface {
+	Notifier
+	NotifyThread(level Level, message string, details []string) error
+}
+
+// Multi sends notifications to several notifiers
+type Multi []Notifier
+
+// Notify sends the notification to every notifier, even if some of them fail. The errors are returned together.
+func (m Multi) Notify(level Level, message string) error {
+	var failures []string
+	for _, n := range m {
+		err := n.Notify(level, message)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d notifier(s) failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// NotifyThread sends the notification and its details to every notifier. The notifiers that can't attach details get
+// them appended to the message, one per line.
+func (m Multi) NotifyThread(level Level, message string, details []string) error {
+	var failures []string
+	for _, n := range m {
+		var err error
+		if t, ok := n.(ThreadNotifier); ok {
+			err = t.NotifyThread(level, message, details)
+		} else {
+			err = n.Notify(level, withDetails(message, details))
+		}
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d notifier(s) failed: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+var (
+	defaultMux sync.RWMutex
+	defaults   Multi
+)
+
+// Register adds a notifier to the ones used by Info and Error
+func Register(n Notifier) {
+	defaultMux.Lock()
+	defer defaultMux.Unlock()
+	defaults = append(defaults, n)
+}
+
+// Reset removes all the registered notifiers
+func Reset() {
+	defaultMux.Lock()
+	defer defaultMux.Unlock()
+	defaults = nil
+}
+
+// Registered returns how many notifiers are registered
+func Registered() int {
+	defaultMux.RLock()
+	defer defaultMux.RUnlock()
+	return len(defaults)
+}
+
+// Info sends an info message to the registered notifiers
+func Info(format string, a ...interface{}) error {
+	return send(LevelInfo, format, a...)
+}
+
+// Error sends an error message to the registered notifiers
+func Error(format string, a ...interface{}) error {
+	return send(LevelError, format, a...)
+}
+
+// Thread sends a message to the registered notifiers, with details attached to it where the notifier allows it
+func Thread(level Level, message string, details []string) error {
+	defaultMux.RLock()
+	notifiers := defaults
+	defaultMux.RUnlock()
+
+	err := notifiers.NotifyThread(level, message, details)
+	if err != nil {
+		log.Errorln("error sending notification: " + err.Error())
+	}
+	return err
+}
+
+func send(level Level, format string, a ...interface{}) error {
+	message := format
+	if len(a) > 0 {
+		message = fmt.Sprintf(format, a...)
+	}
+
+	defaultMux.RLock()
+	notifiers := defaults
+	defaultMux.RUnlock()
+
+	err := notifiers.Notify(level, message)
+	if err != nil {
+		log.Errorln("error sending notification: " + err.Error())
+	}
+	return err
+}
+
+// withDetails appends the details to the message, one per line
+func withDetails(message string, details []string) string {
+	if len(details) == 0 {
+		return message
+	}
+	return message + "\n" + strings.Join(details, "\n")
+}
+
+// chunkLines joins the lines into as few messages of at most limit characters as possible. Lines longer than that are
+// truncated.
+func chunkLines(lines []string, limit int) []string {
+	var chunks []string
+	current := ""
+	for _, line := range lines {
+		line = truncate(line, limit)
+		if current != "" && len([]rune(current))+1+len([]rune(line)) > limit {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		if current != "" {
+			current += "\n"
+		}
+		current += line
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
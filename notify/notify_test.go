@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recorder struct {
+	levels   []Level
+	messages []string
+	err      error
+}
+
+func (r *recorder) Notify(level Level, message string) error {
+	r.levels = append(r.levels, level)
+	r.messages = append(r.messages, message)
+	return r.err
+}
+
+func TestInfoAndError(t *testing.T) {
+	defer Reset()
+	r := &recorder{}
+	Register(r)
+
+	if err := Info("hello %s", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Error("disk at %d%%", 100); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(r.messages))
+	}
+	if r.levels[0] != LevelInfo || r.messages[0] != "hello world" {
+		t.Errorf("unexpected first notification: %s %q", r.levels[0], r.messages[0])
+	}
+	if r.levels[1] != LevelError || r.messages[1] != "disk at 100%" {
+		t.Errorf("unexpected second notification: %s %q", r.levels[1], r.messages[1])
+	}
+}
+
+func TestMultiKeepsGoingOnError(t *testing.T) {
+	failing := &recorder{err: errors.New("boom")}
+	working := &recorder{}
+	err := Multi{failing, working}.Notify(LevelError, "msg")
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected the failure to be reported, got %v", err)
+	}
+	if len(working.messages) != 1 {
+		t.Error("a failing notifier kept the others from being notified")
+	}
+}
+
+type threadRecorder struct {
+	recorder
+	details [][]string
+}
+
+func (r *threadRecorder) NotifyThread(level Level, message string, details []string) error {
+	r.details = append(r.details, details)
+	return r.Notify(level, message)
+}
+
+func TestThread(t *testing.T) {
+	defer Reset()
+	plain := &recorder{}
+	threaded := &threadRecorder{}
+	Register(plain)
+	Register(threaded)
+
+	if err := Thread(LevelError, "2 videos failed", []string{"video a: boom", "video b: bang"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(plain.messages) != 1 || plain.messages[0] != "2 videos failed\nvideo a: boom\nvideo b: bang" {
+		t.Errorf("expected the details to be appended to the message, got %q", plain.messages)
+	}
+	if len(threaded.messages) != 1 || threaded.messages[0] != "2 videos failed" {
+		t.Errorf("expected the message alone, got %q", threaded.messages)
+	}
+	if len(threaded.details) != 1 || len(threaded.details[0]) != 2 {
+		t.Errorf("expected the details to be attached, got %q", threaded.details)
+	}
+}
+
+func TestNothingRegistered(t *testing.T) {
+	Reset()
+	if Registered() != 0 {
+		t.Fatal("expected no notifiers after Reset")
+	}
+	if err := Info("nobody listens"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var body map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %s", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer ts.Close()
+
+	w := &Webhook{URL: ts.URL, Source: "host1"}
+	if err := w.Notify(LevelError, "it broke"); err != nil {
+		t.Fatal(err)
+	}
+	if body["level"] != "error" || body["message"] != "it broke" || body["source"] != "host1" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if _, ok := body["time"]; !ok {
+		t.Error("time missing from body")
+	}
+}
+
+func TestWebhookDetails(t *testing.T) {
+	var body struct {
+		Message string   `json:"message"`
+		Details []string `json:"details"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer ts.Close()
+
+	w := &Webhook{URL: ts.URL}
+	if err := w.NotifyThread(LevelInfo, "done", []string{"one", "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if body.Message != "done" || len(body.Details) != 2 || body.Details[1] != "two" {
+		t.Errorf("unexpected body %+v", body)
+	}
+}
+
+func TestWebhookBadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	w := &Webhook{URL: ts.URL}
+	if err := w.Notify(LevelInfo, "msg"); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	var body struct {
+		Content  string `json:"content"`
+		Username string `json:"username"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	d := &Discord{WebhookURL: ts.URL, Username: "host1"}
+	if err := d.Notify(LevelInfo, strings.Repeat("a", 3000)); err != nil {
+		t.Fatal(err)
+	}
+	if body.Username != "host1" {
+		t.Errorf("expected username host1, got %s", body.Username)
+	}
+	if n := len([]rune(body.Content)); n != discordMessageLimit {
+		t.Errorf("expected message to be truncated to %d characters, got %d", discordMessageLimit, n)
+	}
+}
+
+func TestEmailSkipsInfo(t *testing.T) {
+	e := &Email{Host: "invalid.invalid", Port: 25, From: "a@b.c", To: []string{"d@e.f"}}
+	if err := e.Notify(LevelInfo, "msg"); err != nil {
+		t.Errorf("info messages should be skipped without sending anything, got %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if s := truncate("héllo", 2); s != "hé" {
+		t.Errorf("expected hé, got %s", s)
+	}
+	if s := truncate("hi", 5); s != "hi" {
+		t.Errorf("expected hi, got %s", s)
+	}
+}
+
+func TestChunkLines(t *testing.T) {
+	chunks := chunkLines([]string{"aaa", "bbb", "ccc", strings.Repeat("d", 12)}, 8)
+	expected := []string{"aaa\nbbb", "ccc", "dddddddd"}
+	if len(chunks) != len(expected) {
+		t.Fatalf("expected %q, got %q", expected, chunks)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Errorf("chunk %d: expected %q, got %q", i, expected[i], chunks[i])
+		}
+	}
+	if chunks := chunkLines(nil, 8); len(chunks) != 0 {
+		t.Errorf("expected no chunks, got %q", chunks)
+	}
+}
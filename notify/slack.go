@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/nlopes/slack"
+)
+
+// Slack posts notifications to a Slack channel
+type Slack struct {
+	client   *slack.Client
+	channel  string
+	username string
+}
+
+// NewSlack returns a notifier posting to channel as username
+func NewSlack(token, channel, username string) *Slack {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	return &Slack{client: slack.New(token), channel: channel, username: username}
+}
+
+// slackMessageLimit is how long a Slack message can be before it gets cut
+const slackMessageLimit = 4000
+
+func (s *Slack) Notify(level Level, message string) error {
+	return s.NotifyThread(level, message, nil)
+}
+
+// NotifyThread posts the message, then the details as replies in its thread, grouped into as few replies as possible
+func (s *Slack) NotifyThread(level Level, message string, details []string) error {
+	prefix := ":information_source: "
+	if level == LevelError {
+		prefix = ":sos: "
+	}
+	_, timestamp, err := s.client.PostMessage(s.channel, prefix+message, slack.PostMessageParameters{Username: s.username})
+	if err != nil {
+		return errors.Prefix("slack", err)
+	}
+	for _, reply := range chunkLines(details, slackMessageLimit) {
+		_, _, err = s.client.PostMessage(s.channel, reply, slack.PostMessageParameters{Username: s.username, ThreadTimestamp: timestamp})
+		if err != nil {
+			return errors.Prefix("slack", err)
+		}
+	}
+	return nil
+}
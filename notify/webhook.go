@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+func postJSON(url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Err(err)
+	}
+	res, err := httpClient.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Err("non 2xx status code received: %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Webhook POSTs notifications as JSON to any URL, with the level, message, details, source and time as fields
+type Webhook struct {
+	URL    string
+	Source string // who the notification comes from, usually the hostname
+}
+
+func (w *Webhook) Notify(level Level, message string) error {
+	return w.NotifyThread(level, message, nil)
+}
+
+// NotifyThread sends the details as a list in the details field
+func (w *Webhook) NotifyThread(level Level, message string, details []string) error {
+	err := postJSON(w.URL, struct {
+		Level   Level     `json:"level"`
+		Message string    `json:"message"`
+		Details []string  `json:"details,omitempty"`
+		Source  string    `json:"source,omitempty"`
+		Time    time.Time `json:"time"`
+	}{level, message, details, w.Source, time.Now().UTC()})
+	return errors.Prefix("webhook", err)
+}
+
+// Discord posts notifications to a Discord channel through a webhook
+type Discord struct {
+	WebhookURL string
+	Username   string
+}
+
+// discordMessageLimit is the maximum length of a Discord message
+const discordMessageLimit = 2000
+
+func (d *Discord) Notify(level Level, message string) error {
+	prefix := "ℹ️ "
+	if level == LevelError {
+		prefix = "🆘 "
+	}
+	content := truncate(prefix+message, discordMessageLimit)
+	err := postJSON(d.WebhookURL, struct {
+		Content  string `json:"content"`
+		Username string `json:"username,omitempty"`
+	}{content, d.Username})
+	return errors.Prefix("discord", err)
+}
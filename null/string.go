@@ -115,3 +115,34 @@ func (s String) Value() (driver.Value, error) {
 	}
 	return s.String, nil
 }
+
+// StringFlag adapts a String for use as a command line flag. It implements flag.Value (and pflag.Value, used by cobra).
+// A flag that is never given leaves the String null, while a flag given an empty value makes it a valid empty string.
+// String can't implement flag.Value itself because a String() method would clash with its String field.
+type StringFlag struct {
+	s *String
+}
+
+// NewStringFlag creates a flag that stores its value in s
+func NewStringFlag(s *String) *StringFlag {
+	return &StringFlag{s: s}
+}
+
+// String implements flag.Value.
+func (f *StringFlag) String() string {
+	if f.s == nil || !f.s.Valid {
+		return ""
+	}
+	return f.s.String
+}
+
+// Set implements flag.Value.
+func (f *StringFlag) Set(v string) error {
+	f.s.SetValid(v)
+	return nil
+}
+
+// Type implements pflag.Value.
+func (f *StringFlag) Type() string {
+	return "string"
+}
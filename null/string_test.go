@@ -2,6 +2,7 @@ package null
 
 import (
 	"encoding/json"
+	"flag"
 	"testing"
 )
 
@@ -178,6 +179,49 @@ func TestStringScan(t *testing.T) {
 	assertNullStr(t, null, "scanned null")
 }
 
+func TestStringFlag(t *testing.T) {
+	parse := func(args ...string) String {
+		var str String
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Var(NewStringFlag(&str), "str", "usage")
+		err := fs.Parse(args)
+		maybePanic(err)
+		return str
+	}
+
+	str := parse("-str", "test")
+	assertStr(t, str, "flag string")
+
+	unset := parse()
+	assertNullStr(t, unset, "unset flag")
+
+	blank := parse("-str=")
+	if !blank.Valid {
+		t.Error("flag set to an empty value", "is invalid, but should be valid")
+	}
+	if blank.String != "" {
+		t.Errorf("bad blank flag string: %s ≠ %s\n", blank.String, "")
+	}
+
+	var null String
+	f := NewStringFlag(&null)
+	if f.String() != "" {
+		t.Errorf("bad null flag string: %s ≠ %s\n", f.String(), "")
+	}
+	f.Set("test")
+	if f.String() != "test" {
+		t.Errorf("bad flag string: %s ≠ %s\n", f.String(), "test")
+	}
+	if f.Type() != "string" {
+		t.Errorf("bad flag type: %s ≠ %s\n", f.Type(), "string")
+	}
+
+	var zero StringFlag
+	if zero.String() != "" {
+		t.Error("zero value flag should print as an empty string")
+	}
+}
+
 func maybePanic(err error) {
 	if err != nil {
 		panic(err)
@@ -78,6 +78,24 @@ func TestMarshalTime(t *testing.T) {
 	assertJSONEquals(t, data, string(nullJSON), "null json marshal")
 }
 
+func TestMarshalTimeText(t *testing.T) {
+	ti := TimeFrom(timeValue)
+	data, err := ti.MarshalText()
+	maybePanic(err)
+	assertJSONEquals(t, data, timeString, "non-empty text marshal")
+
+	// invalid values should be encoded as an empty string, like the other types, so they unmarshal back to null
+	null := NewTime(time.Time{}, false)
+	data, err = null.MarshalText()
+	maybePanic(err)
+	assertJSONEquals(t, data, "", "null text marshal")
+
+	var unmarshal Time
+	err = unmarshal.UnmarshalText(data)
+	maybePanic(err)
+	assertNullTime(t, unmarshal, "null text round trip")
+}
+
 func TestTimeFrom(t *testing.T) {
 	ti := TimeFrom(timeValue)
 	assertTime(t, ti, "TimeFrom() time.Time")
@@ -0,0 +1,168 @@
+// Package retry retries operations depending on what kind of failure they run into: transient failures are retried
+// with an exponential backoff, permanent ones are given up on right away and fatal ones should stop everything.
+package retry
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+)
+
+// Class tells how a failure should be handled
+type Class int
+
+const (
+	Transient Class = iota // might go away by itself, retry after a while
+	Permanent              // will happen again, don't bother retrying
+	Fatal                  // the whole process should stop
+)
+
+// ParseClass returns the class with the given name, see Class.String
+func ParseClass(name string) (Class, error) {
+	for _, c := range []Class{Transient, Permanent, Fatal} {
+		if strings.EqualFold(name, c.String()) {
+			return c, nil
+		}
+	}
+	return Transient, errors.Err("unknown failure class %q, expected transient, permanent or fatal", name)
+}
+
+func (c Class) String() string {
+	switch c {
+	case Transient:
+		return "transient"
+	case Permanent:
+		return "permanent"
+	case Fatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
+// Rule classifies the errors whose message contains any of the substrings or matches any of the patterns, and the
+// errors of its category if it has one. If Code is set too, only the errors of the category with that code match.
+type Rule struct {
+	Class      Class
+	Reason     string // short description of the failure, e.g. "quota exceeded"
+	Substrings []string
+	Patterns   []*regexp.Regexp
+	Category   errors.Category
+	Code       string
+}
+
+// matches returns true if the rule applies to err
+func (r Rule) matches(err error, msg string) bool {
+	if r.Category != errors.Uncategorized && errors.CategoryOf(err) == r.Category && (r.Code == "" || errors.CodeOf(err) == r.Code) {
+		return true
+	}
+	for _, s := range r.Substrings {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	for _, p := range r.Patterns {
+		if p.MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classifier classifies errors by the first rule that matches them. Errors no rule matches are Transient.
+type Classifier []Rule
+
+// Classify returns the class of err and the reason of the rule that matched it
+func (c Classifier) Classify(err error) (Class, string) {
+	if err == nil {
+		return Transient, ""
+	}
+	msg := err.Error()
+	for _, r := range c {
+		if r.matches(err, msg) {
+			return r.Class, r.Reason
+		}
+	}
+	return Transient, ""
+}
+
+// Error is returned by Do when it gives up. Its message is the message of the last error.
+type Error struct {
+	Err      error
+	Class    Class
+	Reason   string
+	Attempts int
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Cause returns the last error returned by the operation
+func (e *Error) Cause() error { return e.Err }
+
+// Policy describes how an operation is retried
+type Policy struct {
+	Attempts   int          // how many times the operation is tried at most. Less than 1 counts as 1
+	Backoff    util.Backoff // how long to wait between attempts
+	Classifier Classifier
+
+	// OnRetry, if set, is called after a transient failure, before waiting for the next attempt. It can be used to
+	// fix whatever went wrong. Returning an error stops the retries.
+	OnRetry func(attempt int, err error, reason string) error
+}
+
+// Do calls fn until it succeeds, fails with an error that is not transient, runs out of attempts or stop is closed.
+// When it gives up, the returned error is an *Error describing the last failure, or wrapping util.ErrWaitCancelled if
+// stop was closed before fn was ever called. stop may be nil.
+func (p Policy) Do(stop <-chan struct{}, fn func() error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	select {
+	case <-stop:
+		cancel()
+	default:
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	attempt := 0
+	err := util.Retry(ctx, p.Backoff, attempts, func() error {
+		attempt++
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		class, reason := p.Classifier.Classify(err)
+		failure := &Error{Err: err, Class: class, Reason: reason, Attempts: attempt}
+		if class != Transient {
+			return util.Abort(failure)
+		}
+
+		if p.OnRetry != nil && attempt < attempts {
+			retryErr := p.OnRetry(attempt, err, reason)
+			if retryErr != nil {
+				return util.Abort(&Error{Err: retryErr, Class: Fatal, Reason: "retry preparation failed", Attempts: attempt})
+			}
+		}
+		return failure
+	})
+	if err == nil {
+		return nil
+	}
+	if failure, ok := err.(*Error); ok {
+		return failure
+	}
+	// stop was closed before fn was ever called
+	return &Error{Err: errors.Err(util.ErrWaitCancelled), Class: Transient}
+}
@@ -0,0 +1,269 @@
+package retry
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	lbryerrors "github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+)
+
+var testClassifier = Classifier{
+	{Class: Fatal, Reason: "out of space", Substrings: []string{"no space left on device"}},
+	{Class: Permanent, Reason: "video unavailable", Substrings: []string{"video is unavailable", "not available in your country"}},
+	{Class: Transient, Reason: "quota exceeded", Substrings: []string{"quotaExceeded"}},
+}
+
+var fastBackoff = util.Backoff{Base: time.Millisecond, Max: 5 * time.Millisecond}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err    string
+		class  Class
+		reason string
+	}{
+		{"write: no space left on device", Fatal, "out of space"},
+		{"this video is unavailable", Permanent, "video unavailable"},
+		{"uploader has not made this video not available in your country", Permanent, "video unavailable"},
+		{"googleapi: Error 403: quotaExceeded", Transient, "quota exceeded"},
+		{"something nobody has seen before", Transient, ""},
+	}
+	for _, test := range tests {
+		class, reason := testClassifier.Classify(errors.New(test.err))
+		if class != test.class || reason != test.reason {
+			t.Errorf("%q: expected %s (%s), got %s (%s)", test.err, test.class, test.reason, class, reason)
+		}
+	}
+}
+
+func TestClassifyFirstRuleWins(t *testing.T) {
+	c := Classifier{
+		{Class: Permanent, Reason: "first", Substrings: []string{"boom"}},
+		{Class: Fatal, Reason: "second", Substrings: []string{"boom"}},
+	}
+	if class, reason := c.Classify(errors.New("boom")); class != Permanent || reason != "first" {
+		t.Errorf("expected the first rule to win, got %s (%s)", class, reason)
+	}
+}
+
+func TestClassifyByCategory(t *testing.T) {
+	c := Classifier{
+		{Class: Fatal, Reason: "daemon unreachable", Category: lbryerrors.Network, Code: "unreachable"},
+		{Class: Transient, Reason: "network", Category: lbryerrors.Network},
+		{Class: Permanent, Reason: "substring", Substrings: []string{"boom"}},
+	}
+	tests := []struct {
+		err    error
+		class  Class
+		reason string
+	}{
+		{lbryerrors.Categorize(errors.New("connection reset"), lbryerrors.Network, "unreachable"), Fatal, "daemon unreachable"},
+		{lbryerrors.Prefix("publishing", lbryerrors.Categorize(errors.New("i/o timeout"), lbryerrors.Network, "timeout")), Transient, "network"},
+		{lbryerrors.Categorize(errors.New("boom"), lbryerrors.Wallet, ""), Permanent, "substring"},
+		{errors.New("connection reset"), Transient, ""},
+	}
+	for _, test := range tests {
+		class, reason := c.Classify(test.err)
+		if class != test.class || reason != test.reason {
+			t.Errorf("%q: expected %s (%s), got %s (%s)", test.err, test.class, test.reason, class, reason)
+		}
+	}
+}
+
+func TestClassifyByPattern(t *testing.T) {
+	c := Classifier{
+		{Class: Permanent, Reason: "members only", Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)join this channel to get access`)}},
+		{Class: Fatal, Reason: "daemon crashed", Patterns: []*regexp.Regexp{regexp.MustCompile(`^daemon exited with code [1-9]`)}},
+	}
+	tests := []struct {
+		err    string
+		class  Class
+		reason string
+	}{
+		{"Join this channel to get access to members-only content", Permanent, "members only"},
+		{"daemon exited with code 137", Fatal, "daemon crashed"},
+		{"daemon exited with code 0", Transient, ""},
+		{"the daemon exited with code 1", Transient, ""},
+	}
+	for _, test := range tests {
+		class, reason := c.Classify(errors.New(test.err))
+		if class != test.class || reason != test.reason {
+			t.Errorf("%q: expected %s (%s), got %s (%s)", test.err, test.class, test.reason, class, reason)
+		}
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	for _, c := range []Class{Transient, Permanent, Fatal} {
+		parsed, err := ParseClass(c.String())
+		if err != nil || parsed != c {
+			t.Errorf("expected %s to parse, got %s (%v)", c, parsed, err)
+		}
+	}
+	if c, err := ParseClass("Fatal"); err != nil || c != Fatal {
+		t.Errorf("expected the class name to be case insensitive, got %s (%v)", c, err)
+	}
+	if _, err := ParseClass("retry"); err == nil {
+		t.Error("expected an unknown class to be rejected")
+	}
+}
+
+func TestDoSucceeds(t *testing.T) {
+	calls := 0
+	err := Policy{Attempts: 3, Backoff: fastBackoff}.Do(nil, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestDoRetriesTransientUntilAttemptsRunOut(t *testing.T) {
+	calls := 0
+	err := Policy{Attempts: 4, Backoff: fastBackoff, Classifier: testClassifier}.Do(nil, func() error {
+		calls++
+		return errors.New("quotaExceeded")
+	})
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected a *retry.Error, got %T", err)
+	}
+	if e.Class != Transient || e.Reason != "quota exceeded" || e.Attempts != 4 {
+		t.Errorf("unexpected error %+v", e)
+	}
+	if e.Error() != "quotaExceeded" {
+		t.Errorf("the message of the last error should be kept, got %q", e.Error())
+	}
+}
+
+func TestDoGivesUpOnPermanentAndFatal(t *testing.T) {
+	for _, msg := range []string{"this video is unavailable", "no space left on device"} {
+		calls := 0
+		err := Policy{Attempts: 5, Backoff: fastBackoff, Classifier: testClassifier}.Do(nil, func() error {
+			calls++
+			return errors.New(msg)
+		})
+		if calls != 1 {
+			t.Errorf("%q: expected 1 call, got %d", msg, calls)
+		}
+		if e := err.(*Error); e.Class == Transient || e.Attempts != 1 {
+			t.Errorf("%q: unexpected error %+v", msg, e)
+		}
+	}
+}
+
+func TestDoAtLeastOneAttempt(t *testing.T) {
+	calls := 0
+	Policy{}.Do(nil, func() error {
+		calls++
+		return errors.New("fail")
+	})
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestDoOnRetry(t *testing.T) {
+	var attempts []int
+	var reasons []string
+	calls := 0
+	err := Policy{
+		Attempts:   3,
+		Backoff:    fastBackoff,
+		Classifier: testClassifier,
+		OnRetry: func(attempt int, err error, reason string) error {
+			attempts = append(attempts, attempt)
+			reasons = append(reasons, reason)
+			return nil
+		},
+	}.Do(nil, func() error {
+		calls++
+		return errors.New("quotaExceeded")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("OnRetry should be called between attempts, got %v", attempts)
+	}
+	if reasons[0] != "quota exceeded" {
+		t.Errorf("expected the reason to be passed along, got %q", reasons[0])
+	}
+}
+
+func TestDoOnRetryAborts(t *testing.T) {
+	calls := 0
+	err := Policy{
+		Attempts: 5,
+		Backoff:  fastBackoff,
+		OnRetry:  func(int, error, string) error { return errors.New("refill failed") },
+	}.Do(nil, func() error {
+		calls++
+		return errors.New("fail")
+	})
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	if e := err.(*Error); e.Class != Fatal || e.Error() != "refill failed" {
+		t.Errorf("unexpected error %+v", e)
+	}
+}
+
+func TestDoStop(t *testing.T) {
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- Policy{Attempts: 5, Backoff: util.Backoff{Base: time.Hour}}.Do(stop, func() error {
+			return errors.New("fail")
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	select {
+	case err := <-done:
+		if e := err.(*Error); e.Attempts != 1 {
+			t.Errorf("expected to give up after 1 attempt, got %d", e.Attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do did not return after stop was closed")
+	}
+}
+
+func TestDoAlreadyStopped(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+	calls := 0
+	err := Policy{Attempts: 5, Backoff: fastBackoff}.Do(stop, func() error {
+		calls++
+		return nil
+	})
+	if calls != 0 {
+		t.Errorf("expected no calls, got %d", calls)
+	}
+	if !lbryerrors.Is(err, util.ErrWaitCancelled) {
+		t.Errorf("expected ErrWaitCancelled, got %v", err)
+	}
+}
+
+func TestDoBacksOff(t *testing.T) {
+	start := time.Now()
+	Policy{Attempts: 4, Backoff: util.Backoff{Base: 10 * time.Millisecond}}.Do(nil, func() error {
+		return errors.New("fail")
+	})
+	// 10 + 20 + 40
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("expected to wait at least 70ms between attempts, took %s", elapsed)
+	}
+}
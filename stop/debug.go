@@ -0,0 +1,192 @@
+package stop
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugInfo keeps track of the goroutines of a group in debug mode
+type debugInfo struct {
+	name string
+
+	mu       sync.Mutex
+	nextID   int
+	running  []*tracked // in the order they were added
+	children []*Group
+}
+
+// tracked is a goroutine that was added to a group and is not done yet
+type tracked struct {
+	id      int
+	started time.Time
+	stack   []byte
+}
+
+// NewDebug creates a group that remembers where each of its goroutines was added, so the ones that never finish can be
+// found with DumpRunning. Children of a debug group are debug groups too. It's slower than a normal group, use it to
+// diagnose hangs.
+func NewDebug(name string, parent ...*Group) *Group {
+	s := New(parent...)
+	s.dbg = &debugInfo{name: name}
+	if len(parent) > 0 && parent[0] != nil && parent[0].dbg != nil {
+		parent[0].dbg.addChild(s)
+	}
+	return s
+}
+
+// NamedChild returns a new instance that will be stopped when s is stopped. If s is in debug mode, the child shows up
+// under the given name in DumpRunning.
+func (s *Group) NamedChild(name string) *Group {
+	if s.dbg == nil {
+		return New(s)
+	}
+	return NewDebug(s.dbg.name+"/"+name, s)
+}
+
+// Add adds delta to the count of running goroutines, like sync.WaitGroup.Add. In debug mode, it also records the
+// stack of the caller. Negative deltas are matched with the oldest recorded calls, so the stacks are only exact for
+// goroutines started with Go or GoErr.
+func (s *Group) Add(delta int) {
+	if s.dbg != nil {
+		if delta > 0 {
+			stack := debug.Stack()
+			for i := 0; i < delta; i++ {
+				s.dbg.track(stack)
+			}
+		} else {
+			s.dbg.untrackOldest(-delta)
+		}
+	}
+	s.WaitGroup.Add(delta)
+}
+
+// Done marks a goroutine as finished, like sync.WaitGroup.Done
+func (s *Group) Done() {
+	s.Add(-1)
+}
+
+// Go runs fn in a new goroutine tracked by the group
+func (s *Group) Go(fn func()) {
+	id := s.goStarted()
+	go func() {
+		defer s.goFinished(id)
+		fn()
+	}()
+}
+
+func (s *Group) goStarted() int {
+	id := -1
+	if s.dbg != nil {
+		id = s.dbg.track(debug.Stack())
+	}
+	s.WaitGroup.Add(1)
+	return id
+}
+
+func (s *Group) goFinished(id int) {
+	if s.dbg != nil {
+		s.dbg.untrack(id)
+	}
+	s.WaitGroup.Done()
+}
+
+// DumpRunning describes the goroutines of the group and its children that are still running, with the stack they
+// were added from. It only has something to say in debug mode.
+func (s *Group) DumpRunning() string {
+	if s.dbg == nil {
+		return "stop group is not in debug mode, create it with NewDebug to track running goroutines\n"
+	}
+	buf := &bytes.Buffer{}
+	s.dbg.dump(buf, 0)
+	return buf.String()
+}
+
+func (d *debugInfo) track(stack []byte) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nextID++
+	d.running = append(d.running, &tracked{id: d.nextID, started: time.Now(), stack: stack})
+	return d.nextID
+}
+
+func (d *debugInfo) untrack(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, t := range d.running {
+		if t.id == id {
+			d.running = append(d.running[:i], d.running[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *debugInfo) untrackOldest(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if n > len(d.running) {
+		n = len(d.running)
+	}
+	d.running = d.running[n:]
+}
+
+func (d *debugInfo) addChild(child *Group) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// forget the children that are done for good so long running groups don't accumulate them
+	kept := d.children[:0]
+	for _, c := range d.children {
+		if !c.isFinished() {
+			kept = append(kept, c)
+		}
+	}
+	d.children = append(kept, child)
+}
+
+func (d *debugInfo) runningCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.running)
+}
+
+// isFinished is true once the group was stopped and all its goroutines returned
+func (s *Group) isFinished() bool {
+	select {
+	case <-s.Ch():
+	default:
+		return false
+	}
+	return s.dbg.runningCount() == 0 && len(s.dbg.childrenSnapshot()) == 0
+}
+
+func (d *debugInfo) childrenSnapshot() []*Group {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	children := make([]*Group, 0, len(d.children))
+	for _, c := range d.children {
+		if !c.isFinished() {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+func (d *debugInfo) dump(buf *bytes.Buffer, depth int) {
+	indent := strings.Repeat("  ", depth)
+	d.mu.Lock()
+	fmt.Fprintf(buf, "%sgroup %q: %d running\n", indent, d.name, len(d.running))
+	for _, t := range d.running {
+		fmt.Fprintf(buf, "%s  #%d running for %s, added at:\n", indent, t.id, time.Since(t.started).Truncate(time.Millisecond))
+		for _, line := range strings.Split(strings.TrimSpace(string(t.stack)), "\n") {
+			fmt.Fprintf(buf, "%s    %s\n", indent, line)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, c := range d.childrenSnapshot() {
+		c.dbg.dump(buf, depth+1)
+	}
+}
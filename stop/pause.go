@@ -0,0 +1,66 @@
+package stop
+
+import "errors"
+
+// ErrStopped is returned by WaitIfPaused when the group is stopped while it waits
+var ErrStopped = errors.New("group stopped")
+
+// Pause holds back the goroutines of the group and of its children at their next call to WaitIfPaused, until Resume
+// is called. Goroutines that don't call WaitIfPaused are not affected. It returns false if the group was already paused.
+func (s *Group) Pause() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.resumed != nil {
+		return false
+	}
+	s.resumed = make(chan struct{})
+	return true
+}
+
+// Resume lets the goroutines held back by Pause carry on, unless a parent of the group is paused too. It returns false
+// if the group was not paused.
+func (s *Group) Resume() bool {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.resumed == nil {
+		return false
+	}
+	close(s.resumed)
+	s.resumed = nil
+	return true
+}
+
+// IsPaused returns true while the group or one of its parents is paused
+func (s *Group) IsPaused() bool {
+	return s.pausedCh() != nil
+}
+
+// WaitIfPaused blocks while the group or one of its parents is paused. It returns ErrStopped if the group is stopped
+// in the meantime.
+func (s *Group) WaitIfPaused() error {
+	for {
+		resumed := s.pausedCh()
+		if resumed == nil {
+			return nil
+		}
+		select {
+		case <-resumed:
+		case <-s.ctx.Done():
+			return ErrStopped
+		}
+	}
+}
+
+// pausedCh returns the channel closed when the closest paused group among s and its parents is resumed, or nil if
+// none of them is paused
+func (s *Group) pausedCh() chan struct{} {
+	for g := s; g != nil; g = g.parent {
+		g.pauseMu.Lock()
+		resumed := g.resumed
+		g.pauseMu.Unlock()
+		if resumed != nil {
+			return resumed
+		}
+	}
+	return nil
+}
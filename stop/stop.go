@@ -2,9 +2,14 @@ package stop
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 )
 
+// ErrWaitTimeout is returned when goroutines didn't finish in time
+var ErrWaitTimeout = errors.New("timed out waiting for goroutines to finish")
+
 // Chan is a receive-only channel
 type Chan <-chan struct{}
 
@@ -13,6 +18,21 @@ type Group struct {
 	sync.WaitGroup
 	ctx    context.Context
 	cancel context.CancelFunc
+	parent *Group // nil for groups created from a context
+
+	errMu sync.Mutex
+	err   error
+
+	dbg *debugInfo // only set in debug mode
+
+	hooksMu      sync.Mutex
+	hooks        []func()
+	hooksRan     bool
+	hooksWatched bool
+	hooksOnce    sync.Once
+
+	pauseMu sync.Mutex
+	resumed chan struct{} // nil when not paused, closed on resume
 }
 type Stopper = Group
 
@@ -22,19 +42,73 @@ func New(parent ...*Group) *Group {
 	ctx := context.Background()
 	if len(parent) > 0 && parent[0] != nil {
 		ctx = parent[0].ctx
+		s.parent = parent[0]
 	}
 	s.ctx, s.cancel = context.WithCancel(ctx)
 	return s
 }
 
+// NewFromContext returns a new instance that is stopped when ctx is cancelled or expires
+func NewFromContext(ctx context.Context) *Group {
+	s := &Group{}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	return s
+}
+
+// Context returns a context that is cancelled when the group is stopped, to pass to code that takes a context
+func (s *Group) Context() context.Context {
+	return s.ctx
+}
+
 // Ch returns a channel that will be closed when Stop is called.
 func (s *Group) Ch() Chan {
 	return s.ctx.Done()
 }
 
-// Stop signals any listening processes to stop. After the first call, Stop() does nothing.
+// Stop signals any listening processes to stop and runs the OnStop hooks before returning. After the first call,
+// Stop() does nothing.
 func (s *Group) Stop() {
 	s.cancel()
+	s.runHooks()
+}
+
+// OnStop registers fn to be called when the group is stopped, either by Stop or because its parent was stopped. Hooks
+// run once, in the reverse order of their registration like deferred calls, and possibly while goroutines of the
+// group are still returning. If the group is already stopped, fn is called right away.
+func (s *Group) OnStop(fn func()) {
+	s.hooksMu.Lock()
+	if s.hooksRan {
+		s.hooksMu.Unlock()
+		fn()
+		return
+	}
+	s.hooks = append(s.hooks, fn)
+	watch := !s.hooksWatched
+	s.hooksWatched = true
+	s.hooksMu.Unlock()
+
+	if watch {
+		// the parent can stop the group without calling its Stop
+		go func() {
+			<-s.ctx.Done()
+			s.runHooks()
+		}()
+	}
+}
+
+// runHooks runs the OnStop hooks. Concurrent callers wait until the hooks are done.
+func (s *Group) runHooks() {
+	s.hooksOnce.Do(func() {
+		s.hooksMu.Lock()
+		hooks := s.hooks
+		s.hooks = nil
+		s.hooksRan = true
+		s.hooksMu.Unlock()
+
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hooks[i]()
+		}
+	})
 }
 
 // StopAndWait is a convenience method to close the channel and wait for goroutines to return.
@@ -43,7 +117,69 @@ func (s *Group) StopAndWait() {
 	s.Wait()
 }
 
-// Child returns a new instance that will be stopped when s is stopped.
+// WaitTimeout waits for goroutines to return, but gives up after d and returns ErrWaitTimeout. The goroutines are not
+// affected by the timeout, use DumpRunning on a debug group to find out which ones are stuck.
+func (s *Group) WaitTimeout(d time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-t.C:
+		return ErrWaitTimeout
+	}
+}
+
+// StopAndWaitTimeout is a convenience method to close the channel and wait at most d for goroutines to return.
+func (s *Group) StopAndWaitTimeout(d time.Duration) error {
+	s.Stop()
+	return s.WaitTimeout(d)
+}
+
+// Child returns a new instance that will be stopped when s is stopped. Children of a debug group are debug groups.
 func (s *Group) Child() *Group {
+	if s.dbg != nil {
+		return s.NamedChild("child")
+	}
 	return New(s)
 }
+
+// GoErr runs fn in a new goroutine tracked by the group. If fn returns an error and it's the first error returned by
+// any goroutine of the group, it is recorded and the group is stopped. The error can then be read with Err.
+func (s *Group) GoErr(fn func() error) {
+	id := s.goStarted()
+	go func() {
+		defer s.goFinished(id)
+		if err := fn(); err != nil {
+			s.errMu.Lock()
+			first := s.err == nil
+			if first {
+				s.err = err
+			}
+			s.errMu.Unlock()
+			// not under errMu, the OnStop hooks run in Stop and may read Err
+			if first {
+				s.Stop()
+			}
+		}
+	}()
+}
+
+// Err returns the first error returned by a goroutine started with GoErr so far, or nil if there was none
+func (s *Group) Err() error {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
+	return s.err
+}
+
+// WaitErr waits for all goroutines to return and returns the first error returned by a goroutine started with GoErr
+func (s *Group) WaitErr() error {
+	s.Wait()
+	return s.Err()
+}
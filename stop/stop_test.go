@@ -0,0 +1,614 @@
+package stop
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testTimeout = time.Second
+
+// worker is a goroutine that blocks until its group is stopped and records that it exited.
+type worker struct {
+	exited int32
+}
+
+func (w *worker) run(s *Group) {
+	s.Add(1)
+	go func() {
+		defer s.Done()
+		<-s.Ch()
+		time.Sleep(10 * time.Millisecond) // give StopAndWait a chance to return early if it doesn't wait
+		atomic.StoreInt32(&w.exited, 1)
+	}()
+}
+
+func (w *worker) hasExited() bool {
+	return atomic.LoadInt32(&w.exited) == 1
+}
+
+func isClosed(c Chan) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func waitClosed(t *testing.T, c Chan, msg string) {
+	select {
+	case <-c:
+	case <-time.After(testTimeout):
+		t.Fatal(msg)
+	}
+}
+
+func TestStopClosesCh(t *testing.T) {
+	s := New()
+	if isClosed(s.Ch()) {
+		t.Fatal("channel closed before Stop was called")
+	}
+	s.Stop()
+	waitClosed(t, s.Ch(), "channel not closed after Stop")
+}
+
+func TestDoubleStop(t *testing.T) {
+	s := New()
+	s.Stop()
+	s.Stop()
+	if !isClosed(s.Ch()) {
+		t.Error("channel should stay closed after second Stop")
+	}
+}
+
+func TestStopAndWait(t *testing.T) {
+	s := New()
+	workers := make([]*worker, 5)
+	for i := range workers {
+		workers[i] = &worker{}
+		workers[i].run(s)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.StopAndWait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("StopAndWait did not return")
+	}
+
+	for i, w := range workers {
+		if !w.hasExited() {
+			t.Errorf("worker %d still running after StopAndWait returned", i)
+		}
+	}
+}
+
+func TestStopAndWaitNoGoroutines(t *testing.T) {
+	s := New()
+	s.StopAndWait()
+	s.StopAndWait()
+}
+
+func TestChildStopsWithParent(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	grandchild := child.Child()
+
+	parent.Stop()
+	waitClosed(t, child.Ch(), "child not stopped when parent stopped")
+	waitClosed(t, grandchild.Ch(), "grandchild not stopped when parent stopped")
+}
+
+func TestNewWithParent(t *testing.T) {
+	parent := New()
+	child := New(parent)
+
+	parent.Stop()
+	waitClosed(t, child.Ch(), "child created with New(parent) not stopped when parent stopped")
+}
+
+func TestNewWithNilParent(t *testing.T) {
+	s := New(nil)
+	if isClosed(s.Ch()) {
+		t.Fatal("group with nil parent should not start out stopped")
+	}
+	s.Stop()
+	waitClosed(t, s.Ch(), "channel not closed after Stop")
+}
+
+func TestChildStopDoesNotStopParent(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	sibling := parent.Child()
+
+	child.Stop()
+	waitClosed(t, child.Ch(), "child not stopped")
+	if isClosed(parent.Ch()) {
+		t.Error("stopping a child should not stop its parent")
+	}
+	if isClosed(sibling.Ch()) {
+		t.Error("stopping a child should not stop its siblings")
+	}
+}
+
+func TestChildCreatedAfterStop(t *testing.T) {
+	parent := New()
+	parent.Stop()
+	child := parent.Child()
+	waitClosed(t, child.Ch(), "child of a stopped parent should start out stopped")
+}
+
+func TestContextCancellation(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+
+	if parent.ctx.Err() != nil || child.ctx.Err() != nil {
+		t.Fatal("contexts cancelled before Stop was called")
+	}
+
+	parent.Stop()
+	waitClosed(t, child.Ch(), "child not stopped")
+	if parent.ctx.Err() == nil {
+		t.Error("parent context not cancelled after Stop")
+	}
+	if child.ctx.Err() == nil {
+		t.Error("child context not cancelled after parent Stop")
+	}
+}
+
+func TestParentWaitIndependentOfChild(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	w := &worker{}
+	w.run(child)
+
+	parent.StopAndWait() // parent has no goroutines of its own, so this must not block on the child's
+	child.Wait()
+	if !w.hasExited() {
+		t.Error("child worker did not exit after parent stopped")
+	}
+}
+
+func TestGoErrNoError(t *testing.T) {
+	s := New()
+	for i := 0; i < 5; i++ {
+		s.GoErr(func() error { return nil })
+	}
+	if err := s.WaitErr(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if isClosed(s.Ch()) {
+		t.Error("group stopped although no goroutine failed")
+	}
+}
+
+func TestGoErrStopsSiblings(t *testing.T) {
+	s := New()
+	boom := errors.New("boom")
+	var siblingStopped int32
+	s.GoErr(func() error {
+		<-s.Ch()
+		atomic.StoreInt32(&siblingStopped, 1)
+		return nil
+	})
+	s.GoErr(func() error { return boom })
+
+	done := make(chan error)
+	go func() { done <- s.WaitErr() }()
+	select {
+	case err := <-done:
+		if err != boom {
+			t.Errorf("expected %v, got %v", boom, err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("siblings were not stopped by the failing goroutine")
+	}
+	if atomic.LoadInt32(&siblingStopped) != 1 {
+		t.Error("sibling did not see the stop")
+	}
+}
+
+func TestGoErrKeepsFirstError(t *testing.T) {
+	s := New()
+	first := errors.New("first")
+	s.GoErr(func() error { return first })
+	waitClosed(t, s.Ch(), "group not stopped after an error")
+	for i := 0; i < 10; i++ {
+		s.GoErr(func() error { return errors.New("later") })
+	}
+	if err := s.WaitErr(); err != first {
+		t.Errorf("expected the first error, got %v", err)
+	}
+}
+
+func TestGoErrConcurrentErrors(t *testing.T) {
+	s := New()
+	errs := map[error]bool{}
+	for i := 0; i < 50; i++ {
+		err := errors.New("fail")
+		errs[err] = true
+		s.GoErr(func() error { return err })
+	}
+	if err := s.WaitErr(); !errs[err] {
+		t.Errorf("expected one of the returned errors, got %v", err)
+	}
+}
+
+func TestGoErrDoesNotStopParent(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	child.GoErr(func() error { return errors.New("fail") })
+	child.WaitErr()
+	if isClosed(parent.Ch()) {
+		t.Error("an error in the child stopped the parent")
+	}
+	if parent.Err() != nil {
+		t.Error("an error in the child was recorded on the parent")
+	}
+}
+
+func TestErrBeforeGoErr(t *testing.T) {
+	if err := New().Err(); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestGoWaits(t *testing.T) {
+	s := New()
+	var ran int32
+	for i := 0; i < 5; i++ {
+		s.Go(func() {
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+	s.Wait()
+	if n := atomic.LoadInt32(&ran); n != 5 {
+		t.Errorf("expected 5 goroutines to finish before Wait returned, got %d", n)
+	}
+}
+
+func TestDumpRunningNotDebug(t *testing.T) {
+	if !strings.Contains(New().DumpRunning(), "not in debug mode") {
+		t.Error("expected a hint that the group is not in debug mode")
+	}
+}
+
+func stuckInHere(s *Group, release chan struct{}) {
+	s.Go(func() { <-release })
+}
+
+func TestDumpRunning(t *testing.T) {
+	s := NewDebug("main")
+	release := make(chan struct{})
+	stuckInHere(s, release)
+	s.Go(func() {})
+
+	time.Sleep(10 * time.Millisecond)
+	dump := s.DumpRunning()
+	if !strings.Contains(dump, `group "main": 1 running`) {
+		t.Errorf("expected one running goroutine, got:\n%s", dump)
+	}
+	if !strings.Contains(dump, "stuckInHere") {
+		t.Errorf("expected the stack of the stuck goroutine, got:\n%s", dump)
+	}
+
+	close(release)
+	s.Wait()
+	if dump := s.DumpRunning(); !strings.Contains(dump, `group "main": 0 running`) {
+		t.Errorf("expected nothing running, got:\n%s", dump)
+	}
+}
+
+func TestDumpRunningAddDone(t *testing.T) {
+	s := NewDebug("main")
+	s.Add(2)
+	if dump := s.DumpRunning(); !strings.Contains(dump, "2 running") {
+		t.Errorf("expected 2 running, got:\n%s", dump)
+	}
+	s.Done()
+	s.Done()
+	if dump := s.DumpRunning(); !strings.Contains(dump, "0 running") {
+		t.Errorf("expected 0 running, got:\n%s", dump)
+	}
+	s.Wait()
+}
+
+func TestDumpRunningChildren(t *testing.T) {
+	s := NewDebug("main")
+	child := s.NamedChild("worker")
+	release := make(chan struct{})
+	child.Go(func() { <-release })
+
+	dump := s.DumpRunning()
+	if !strings.Contains(dump, `group "main/worker": 1 running`) {
+		t.Errorf("expected the child in the dump, got:\n%s", dump)
+	}
+
+	close(release)
+	s.StopAndWait()
+	child.Wait()
+	if dump := s.DumpRunning(); strings.Contains(dump, "main/worker") {
+		t.Errorf("finished child should not be in the dump, got:\n%s", dump)
+	}
+}
+
+func TestNamedChildNotDebug(t *testing.T) {
+	s := New()
+	child := s.NamedChild("worker")
+	s.Stop()
+	waitClosed(t, child.Ch(), "child not stopped with parent")
+	if child.dbg != nil {
+		t.Error("child of a normal group should not be in debug mode")
+	}
+}
+
+func TestWaitTimeoutReturnsWhenDone(t *testing.T) {
+	s := New()
+	s.Go(func() { time.Sleep(10 * time.Millisecond) })
+	if err := s.WaitTimeout(testTimeout); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestWaitTimeoutNoGoroutines(t *testing.T) {
+	if err := New().WaitTimeout(time.Millisecond); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestWaitTimeoutExpires(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	s.Go(func() { <-release })
+	defer close(release)
+
+	start := time.Now()
+	if err := s.WaitTimeout(20 * time.Millisecond); err != ErrWaitTimeout {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > testTimeout {
+		t.Errorf("WaitTimeout took %s", elapsed)
+	}
+}
+
+func TestStopAndWaitTimeout(t *testing.T) {
+	s := New()
+	w := &worker{}
+	w.run(s)
+	if err := s.StopAndWaitTimeout(testTimeout); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !w.hasExited() {
+		t.Error("StopAndWaitTimeout returned before the worker exited")
+	}
+}
+
+func TestStopAndWaitTimeoutStuck(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	s.Go(func() { <-release }) // ignores the stop signal
+	defer close(release)
+
+	if err := s.StopAndWaitTimeout(20 * time.Millisecond); err != ErrWaitTimeout {
+		t.Errorf("expected ErrWaitTimeout, got %v", err)
+	}
+	if !isClosed(s.Ch()) {
+		t.Error("group was not stopped")
+	}
+}
+
+func TestOnStopReverseOrder(t *testing.T) {
+	s := New()
+	var order []int
+	for i := 0; i < 3; i++ {
+		n := i
+		s.OnStop(func() { order = append(order, n) })
+	}
+	if len(order) != 0 {
+		t.Fatal("hooks ran before Stop")
+	}
+	s.Stop()
+	if len(order) != 3 || order[0] != 2 || order[1] != 1 || order[2] != 0 {
+		t.Errorf("expected hooks to run in reverse order when Stop returns, got %v", order)
+	}
+}
+
+func TestOnStopRunsOnce(t *testing.T) {
+	s := New()
+	var calls int32
+	s.OnStop(func() { atomic.AddInt32(&calls, 1) })
+	s.Stop()
+	s.Stop()
+	s.StopAndWait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected the hook to run once, ran %d times", n)
+	}
+}
+
+func TestOnStopAfterStop(t *testing.T) {
+	s := New()
+	s.Stop()
+	ran := false
+	s.OnStop(func() { ran = true })
+	if !ran {
+		t.Error("hook registered after Stop did not run right away")
+	}
+}
+
+func TestOnStopParentStopped(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	ran := make(chan struct{})
+	child.OnStop(func() { close(ran) })
+	parent.Stop()
+	select {
+	case <-ran:
+	case <-time.After(testTimeout):
+		t.Fatal("hook did not run when the parent was stopped")
+	}
+}
+
+func TestOnStopConcurrentStop(t *testing.T) {
+	s := New()
+	var calls int32
+	s.OnStop(func() {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&calls, 1)
+	})
+	done := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		go func() {
+			s.Stop()
+			// every Stop returns after the hooks are done
+			if atomic.LoadInt32(&calls) != 1 {
+				t.Error("Stop returned before the hooks ran")
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		<-done
+	}
+}
+
+func TestOnStopReadsErr(t *testing.T) {
+	s := New()
+	boom := errors.New("boom")
+	hookErr := make(chan error, 1)
+	s.OnStop(func() { hookErr <- s.Err() })
+	s.GoErr(func() error { return boom })
+	select {
+	case err := <-hookErr:
+		if err != boom {
+			t.Errorf("expected the hook to see %v, got %v", boom, err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("a hook reading Err deadlocked the failing goroutine")
+	}
+	s.Wait()
+}
+
+func TestContextCancelledByStop(t *testing.T) {
+	s := New()
+	ctx := s.Context()
+	if ctx.Err() != nil {
+		t.Fatal("context cancelled before Stop was called")
+	}
+	s.Stop()
+	waitClosed(t, ctx.Done(), "context not cancelled by Stop")
+}
+
+func TestNewFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewFromContext(ctx)
+	child := s.Child()
+
+	cancel()
+	waitClosed(t, s.Ch(), "group not stopped when its context was cancelled")
+	waitClosed(t, child.Ch(), "child not stopped when the parent context was cancelled")
+}
+
+func TestNewFromContextStopDoesNotCancelContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewFromContext(ctx)
+	s.Stop()
+	waitClosed(t, s.Context().Done(), "group context not cancelled by Stop")
+	if ctx.Err() != nil {
+		t.Error("Stop cancelled the context the group was created from")
+	}
+}
+
+func TestWaitIfPausedNotPaused(t *testing.T) {
+	s := New()
+	if s.IsPaused() {
+		t.Fatal("new group is paused")
+	}
+	if err := s.WaitIfPaused(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	s := New()
+	if !s.Pause() {
+		t.Fatal("Pause returned false on a running group")
+	}
+	if s.Pause() {
+		t.Error("Pause returned true on a paused group")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := s.WaitIfPaused(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}()
+	select {
+	case <-done:
+		t.Fatal("WaitIfPaused returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !s.Resume() {
+		t.Fatal("Resume returned false on a paused group")
+	}
+	waitClosed(t, done, "WaitIfPaused did not return after Resume")
+	if s.Resume() {
+		t.Error("Resume returned true on a running group")
+	}
+}
+
+func TestParentPausesChild(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	parent.Pause()
+	if !child.IsPaused() {
+		t.Error("child not paused with its parent")
+	}
+	child.Pause()
+	parent.Resume()
+	if !child.IsPaused() {
+		t.Error("child resumed with its parent, but it was paused itself")
+	}
+	child.Resume()
+	if child.IsPaused() || parent.IsPaused() {
+		t.Error("groups still paused after Resume")
+	}
+}
+
+func TestChildPauseDoesNotPauseParent(t *testing.T) {
+	parent := New()
+	child := parent.Child()
+	child.Pause()
+	if parent.IsPaused() {
+		t.Error("child paused its parent")
+	}
+}
+
+func TestWaitIfPausedStopped(t *testing.T) {
+	s := New()
+	s.Pause()
+	errs := make(chan error, 1)
+	go func() { errs <- s.WaitIfPaused() }()
+	s.Stop()
+	select {
+	case err := <-errs:
+		if err != ErrStopped {
+			t.Errorf("expected ErrStopped, got %v", err)
+		}
+	case <-time.After(testTimeout):
+		t.Error("WaitIfPaused did not return after Stop")
+	}
+}
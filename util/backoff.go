@@ -0,0 +1,129 @@
+package util
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// Backoff computes exponentially growing delays between retries. The zero value is not useful, set at least Base.
+//
+// The nth call to Next returns Base * Factor^n, capped at Max. If Jitter is set, each delay is randomly shortened by
+// up to that fraction of itself, so a Jitter of 0.2 returns delays between 80% and 100% of the computed value. Max is
+// never exceeded.
+type Backoff struct {
+	Base   time.Duration // delay before the first retry
+	Factor float64       // how much the delay grows after each retry. defaults to 2 if less than 1
+	Max    time.Duration // upper bound for the delay. zero means no bound
+	Jitter float64       // fraction of the delay to randomize, between 0 and 1
+
+	attempt int
+}
+
+// Next returns the delay to wait before the next retry and advances the backoff
+func (b *Backoff) Next() time.Duration {
+	factor := b.Factor
+	if factor < 1 {
+		factor = 2
+	}
+
+	d := float64(b.Base) * math.Pow(factor, float64(b.attempt))
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	b.attempt++
+
+	if b.Jitter > 0 {
+		jitter := math.Min(b.Jitter, 1)
+		d -= d * jitter * rand.Float64()
+	}
+
+	if d >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// Reset makes the next call to Next return the base delay again
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// abortError makes Retry give up on the error it wraps
+type abortError struct {
+	err error
+}
+
+func (e abortError) Error() string { return e.err.Error() }
+
+// Abort wraps err so that Retry returns it right away instead of retrying
+func Abort(err error) error {
+	return abortError{err: err}
+}
+
+// Retry calls fn until it succeeds, it has been called attempts times, it fails with an error wrapped by Abort, or
+// ctx is done, waiting b.Next() between calls. If attempts is less than 1, fn is retried until it succeeds or ctx is
+// done. On failure, the error from the last call to fn is returned, unwrapped, or ctx.Err() if ctx was done before fn
+// was ever called. b is copied, so the caller's backoff is not advanced.
+func Retry(ctx context.Context, b Backoff, attempts int, fn func() error) error {
+	b.Reset()
+	var err error
+	for i := 0; attempts < 1 || i < attempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				return ctxErr
+			}
+			return err
+		}
+
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if a, ok := err.(abortError); ok {
+			return a.err
+		}
+		if attempts > 0 && i == attempts-1 {
+			break
+		}
+
+		t := time.NewTimer(b.Next())
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+	return err
+}
+
+// ErrPollTimeout is returned by Poll when the condition still doesn't hold once the timeout is over
+var ErrPollTimeout = errors.Base("timed out waiting for the condition")
+
+var errNotYet = errors.Base("condition not met yet")
+
+// Poll calls cond right away and then every interval until it returns true or an error, or timeout is over. It
+// returns the error from cond, or ErrPollTimeout.
+func Poll(interval, timeout time.Duration, cond func() (bool, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := Retry(ctx, Backoff{Base: interval, Factor: 1}, 0, func() error {
+		ok, err := cond()
+		if err != nil {
+			return Abort(err)
+		}
+		if !ok {
+			return errNotYet
+		}
+		return nil
+	})
+	if err == errNotYet || err == context.DeadlineExceeded {
+		return errors.Err(ErrPollTimeout)
+	}
+	return err
+}
@@ -0,0 +1,298 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	lbryerrors "github.com/lbryio/lbry.go/errors"
+)
+
+func TestBackoffGrowth(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond, Factor: 2}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		1600 * time.Millisecond,
+	}
+	for i, e := range expected {
+		if d := b.Next(); d != e {
+			t.Errorf("attempt %d: expected %s, got %s", i, e, d)
+		}
+	}
+}
+
+func TestBackoffCustomFactor(t *testing.T) {
+	b := Backoff{Base: time.Second, Factor: 3}
+	expected := []time.Duration{1 * time.Second, 3 * time.Second, 9 * time.Second, 27 * time.Second}
+	for i, e := range expected {
+		if d := b.Next(); d != e {
+			t.Errorf("attempt %d: expected %s, got %s", i, e, d)
+		}
+	}
+}
+
+func TestBackoffDefaultFactor(t *testing.T) {
+	for _, factor := range []float64{0, 0.5, -1} {
+		b := Backoff{Base: time.Second, Factor: factor}
+		b.Next()
+		if d := b.Next(); d != 2*time.Second {
+			t.Errorf("factor %v: expected default factor of 2, got second delay of %s", factor, d)
+		}
+	}
+}
+
+func TestBackoffCap(t *testing.T) {
+	b := Backoff{Base: time.Second, Factor: 2, Max: 5 * time.Second}
+	expected := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, e := range expected {
+		if d := b.Next(); d != e {
+			t.Errorf("attempt %d: expected %s, got %s", i, e, d)
+		}
+	}
+}
+
+func TestBackoffNoOverflow(t *testing.T) {
+	b := Backoff{Base: time.Second, Factor: 10}
+	prev := time.Duration(0)
+	for i := 0; i < 100; i++ {
+		d := b.Next()
+		if d < prev {
+			t.Fatalf("attempt %d: delay went from %s down to %s", i, prev, d)
+		}
+		prev = d
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := Backoff{Base: time.Second, Factor: 2}
+	b.Next()
+	b.Next()
+	b.Reset()
+	if d := b.Next(); d != time.Second {
+		t.Errorf("expected base delay after reset, got %s", d)
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	b := Backoff{Base: time.Second, Factor: 2, Max: 8 * time.Second, Jitter: 0.25}
+	for run := 0; run < 200; run++ {
+		b.Reset()
+		for i, full := range []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+			d := b.Next()
+			min := full - full/4
+			if d < min || d > full {
+				t.Fatalf("attempt %d: expected delay between %s and %s, got %s", i, min, full, d)
+			}
+		}
+	}
+}
+
+func TestBackoffJitterVaries(t *testing.T) {
+	b := Backoff{Base: time.Second, Jitter: 0.5}
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		b.Reset()
+		seen[b.Next()] = true
+	}
+	if len(seen) < 2 {
+		t.Error("jittered delays never changed")
+	}
+}
+
+func TestBackoffJitterClamped(t *testing.T) {
+	b := Backoff{Base: time.Second, Jitter: 5}
+	for i := 0; i < 100; i++ {
+		b.Reset()
+		if d := b.Next(); d < 0 || d > time.Second {
+			t.Fatalf("expected delay between 0 and 1s, got %s", d)
+		}
+	}
+}
+
+func TestRetrySucceedsFirstTry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), Backoff{Base: time.Millisecond}, 3, func() error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryEventuallySucceeds(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), Backoff{Base: time.Millisecond}, 5, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryReturnsLastError(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), Backoff{Base: time.Millisecond}, 4, func() error {
+		calls++
+		return errors.New("attempt " + string(rune('0'+calls)))
+	})
+	if err == nil || err.Error() != "attempt 4" {
+		t.Errorf("expected last error, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+}
+
+func TestRetryUnlimitedAttempts(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), Backoff{Base: time.Microsecond}, 0, func() error {
+		calls++
+		if calls < 10 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 10 {
+		t.Errorf("expected 10 calls, got %d", calls)
+	}
+}
+
+func TestRetryDoesNotAdvanceCallerBackoff(t *testing.T) {
+	b := Backoff{Base: time.Millisecond}
+	_ = Retry(context.Background(), b, 3, func() error { return errors.New("fail") })
+	if d := b.Next(); d != time.Millisecond {
+		t.Errorf("caller's backoff was advanced, got %s", d)
+	}
+}
+
+func TestRetryContextAlreadyDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := Retry(ctx, Backoff{Base: time.Millisecond}, 3, func() error {
+		calls++
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no calls, got %d", calls)
+	}
+}
+
+func TestRetryContextCancelledWhileWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fnErr := errors.New("fail")
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- Retry(ctx, Backoff{Base: time.Hour}, 3, func() error {
+			calls++
+			return fnErr
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != fnErr {
+			t.Errorf("expected the last error from fn, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 call, got %d", calls)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Retry did not return after context was cancelled")
+	}
+}
+
+func TestRetryContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := Retry(ctx, Backoff{Base: time.Millisecond, Max: 5 * time.Millisecond}, 0, func() error {
+		return errors.New("fail")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Retry ran for %s past a 50ms deadline", elapsed)
+	}
+}
+
+func TestRetryAbort(t *testing.T) {
+	fnErr := errors.New("fail")
+	calls := 0
+	err := Retry(context.Background(), Backoff{Base: time.Millisecond}, 5, func() error {
+		calls++
+		return Abort(fnErr)
+	})
+	if err != fnErr {
+		t.Errorf("expected the unwrapped error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	calls := 0
+	err := Poll(time.Millisecond, time.Second, func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestPollError(t *testing.T) {
+	condErr := errors.New("fail")
+	calls := 0
+	err := Poll(time.Millisecond, time.Second, func() (bool, error) {
+		calls++
+		return false, condErr
+	})
+	if err != condErr {
+		t.Errorf("expected the error from cond, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestPollTimeout(t *testing.T) {
+	start := time.Now()
+	err := Poll(time.Millisecond, 20*time.Millisecond, func() (bool, error) { return false, nil })
+	if !lbryerrors.Is(err, ErrPollTimeout) {
+		t.Errorf("expected ErrPollTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Poll ran for %s past a 20ms timeout", elapsed)
+	}
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// DirStats sums up the files in a directory and its subdirectories
+type DirStats struct {
+	Files  int
+	Bytes  uint64
+	Oldest time.Time // modification time of the oldest file, zero if there are no files
+}
+
+// OldestAge returns how long ago the oldest file was modified, 0 if there are no files
+func (d DirStats) OldestAge() time.Duration {
+	if d.Files == 0 {
+		return 0
+	}
+	return time.Since(d.Oldest)
+}
+
+// GetDirStats walks dir and sums up the regular files in it. Files removed during the walk are skipped.
+func GetDirStats(dir string) (DirStats, error) {
+	var stats DirStats
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path != dir {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		stats.Files++
+		stats.Bytes += uint64(info.Size())
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return DirStats{}, errors.Err(err)
+	}
+	return stats, nil
+}
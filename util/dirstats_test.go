@@ -0,0 +1,56 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetDirStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dirstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stats, err := GetDirStats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 0 || stats.Bytes != 0 || stats.OldestAge() != 0 {
+		t.Errorf("expected an empty dir, got %+v", stats)
+	}
+
+	err = os.Mkdir(filepath.Join(dir, "sub"), 0750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a": 10, "b": 20, "sub/c": 30} {
+		err = ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0640)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	err = os.Chtimes(filepath.Join(dir, "sub/c"), old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = GetDirStats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 3 || stats.Bytes != 60 {
+		t.Errorf("expected 3 files and 60 bytes, got %+v", stats)
+	}
+	if age := stats.OldestAge(); age < time.Hour || age > 2*time.Hour {
+		t.Errorf("expected the oldest file to be an hour old, got %s", age)
+	}
+
+	if _, err = GetDirStats(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing dir")
+	}
+}
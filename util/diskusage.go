@@ -0,0 +1,47 @@
+package util
+
+import "github.com/lbryio/lbry.go/errors"
+
+// ErrDiskUsageUnsupported is returned by GetDiskUsage on platforms it's not implemented for
+var ErrDiskUsageUnsupported = errors.Base("disk usage is not supported on this platform")
+
+// DiskUsage is how much of a disk is used, in bytes
+type DiskUsage struct {
+	Total     uint64
+	Free      uint64 // including the space reserved for the superuser
+	Available uint64 // free space unprivileged users can write to
+
+	// Inodes and FreeInodes count the files the filesystem can hold. They are 0 if it doesn't report them.
+	Inodes     uint64
+	FreeInodes uint64
+}
+
+// Used returns the number of bytes in use
+func (d DiskUsage) Used() uint64 {
+	if d.Free > d.Total {
+		return 0
+	}
+	return d.Total - d.Free
+}
+
+// UsedFraction returns the used fraction of the disk, between 0 and 1
+func (d DiskUsage) UsedFraction() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return float64(d.Used()) / float64(d.Total)
+}
+
+// UsedPercent returns the used percentage of the disk, between 0 and 100
+func (d DiskUsage) UsedPercent() float64 {
+	return d.UsedFraction() * 100
+}
+
+// UsedInodeFraction returns the used fraction of the inodes of the disk, between 0 and 1. A disk that runs out of
+// inodes can't hold more files, however much space it has left.
+func (d DiskUsage) UsedInodeFraction() float64 {
+	if d.Inodes == 0 || d.FreeInodes > d.Inodes {
+		return 0
+	}
+	return float64(d.Inodes-d.FreeInodes) / float64(d.Inodes)
+}
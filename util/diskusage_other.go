@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package util
+
+import "github.com/lbryio/lbry.go/errors"
+
+// GetDiskUsage is not implemented on this platform, it always returns ErrDiskUsageUnsupported
+func GetDiskUsage(path string) (DiskUsage, error) {
+	return DiskUsage{}, errors.Err(ErrDiskUsageUnsupported)
+}
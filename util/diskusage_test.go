@@ -0,0 +1,47 @@
+package util
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDiskUsageFractions(t *testing.T) {
+	d := DiskUsage{Total: 1000, Free: 250, Available: 200}
+	if d.Used() != 750 {
+		t.Errorf("expected 750 bytes used, got %d", d.Used())
+	}
+	if d.UsedFraction() != 0.75 {
+		t.Errorf("expected 0.75 used, got %f", d.UsedFraction())
+	}
+	if d.UsedPercent() != 75 {
+		t.Errorf("expected 75%% used, got %f", d.UsedPercent())
+	}
+
+	var empty DiskUsage
+	if empty.UsedFraction() != 0 {
+		t.Errorf("expected an empty disk to be unused, got %f", empty.UsedFraction())
+	}
+}
+
+func TestDiskUsageInodes(t *testing.T) {
+	d := DiskUsage{Total: 1000, Free: 900, Inodes: 100, FreeInodes: 5}
+	if d.UsedInodeFraction() != 0.95 {
+		t.Errorf("expected 0.95 of the inodes used, got %f", d.UsedInodeFraction())
+	}
+	if (DiskUsage{Total: 1000}).UsedInodeFraction() != 0 {
+		t.Error("expected a disk without inode counts to have none used")
+	}
+}
+
+func TestGetDiskUsage(t *testing.T) {
+	d, err := GetDiskUsage(os.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Total == 0 {
+		t.Error("expected the disk to have a size")
+	}
+	if d.Free > d.Total || d.Available > d.Free || d.FreeInodes > d.Inodes {
+		t.Errorf("inconsistent usage: %+v", d)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package util
+
+import (
+	"syscall"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// GetDiskUsage returns the usage of the disk that holds path. path can be a mount point or any file on the disk.
+func GetDiskUsage(path string) (DiskUsage, error) {
+	var stat syscall.Statfs_t
+	err := syscall.Statfs(path, &stat)
+	if err != nil {
+		return DiskUsage{}, errors.Err(err)
+	}
+	// the types of these fields differ between platforms
+	blockSize := uint64(stat.Bsize)
+	return DiskUsage{
+		Total:      uint64(stat.Blocks) * blockSize,
+		Free:       uint64(stat.Bfree) * blockSize,
+		Available:  uint64(stat.Bavail) * blockSize,
+		Inodes:     uint64(stat.Files),
+		FreeInodes: uint64(stat.Ffree),
+	}, nil
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// GetDiskUsage returns the usage of the disk that holds path. path can be a drive (C:\), a mounted folder or any
+// directory on the disk.
+func GetDiskUsage(path string) (DiskUsage, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskUsage{}, errors.Err(err)
+	}
+	var available, total, free uint64
+	ok, _, err := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)),
+	)
+	if ok == 0 {
+		return DiskUsage{}, errors.Err(err)
+	}
+	return DiskUsage{Total: total, Free: free, Available: available}, nil
+}
@@ -11,6 +11,16 @@ func InSlice(str string, values []string) bool {
 	return false
 }
 
+// InSliceFold returns true if str is in values, ignoring case
+func InSliceFold(str string, values []string) bool {
+	for _, v := range values {
+		if strings.EqualFold(str, v) {
+			return true
+		}
+	}
+	return false
+}
+
 // SubstringInSlice returns true if str is contained within any element of the values slice. False otherwise
 func SubstringInSlice(str string, values []string) bool {
 	for _, v := range values {
@@ -20,3 +30,80 @@ func SubstringInSlice(str string, values []string) bool {
 	}
 	return false
 }
+
+// StringSet is a set of strings
+type StringSet map[string]struct{}
+
+// NewStringSet returns a set holding values
+func NewStringSet(values ...string) StringSet {
+	s := make(StringSet, len(values))
+	for _, v := range values {
+		s.Add(v)
+	}
+	return s
+}
+
+// Add adds str to the set
+func (s StringSet) Add(str string) {
+	s[str] = struct{}{}
+}
+
+// Has returns true if str is in the set
+func (s StringSet) Has(str string) bool {
+	_, ok := s[str]
+	return ok
+}
+
+// Unique returns values without duplicates, in the order they first appear
+func Unique(values []string) []string {
+	seen := make(StringSet, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen.Has(v) {
+			seen.Add(v)
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// Difference returns the values of a that aren't in b, without duplicates, in the order they appear in a
+func Difference(a, b []string) []string {
+	exclude := NewStringSet(b...)
+	var diff []string
+	for _, v := range Unique(a) {
+		if !exclude.Has(v) {
+			diff = append(diff, v)
+		}
+	}
+	return diff
+}
+
+// Intersection returns the values of a that are also in b, without duplicates, in the order they appear in a
+func Intersection(a, b []string) []string {
+	include := NewStringSet(b...)
+	var common []string
+	for _, v := range Unique(a) {
+		if include.Has(v) {
+			common = append(common, v)
+		}
+	}
+	return common
+}
+
+// Chunk splits values into slices of at most size values, e.g. for API calls that take a limited number of IDs. The
+// chunks share the memory of values.
+func Chunk(values []string, size int) [][]string {
+	if size <= 0 {
+		panic("chunk size must be positive")
+	}
+	chunks := make([][]string, 0, (len(values)+size-1)/size)
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+		chunks = append(chunks, values[start:end:end])
+	}
+	return chunks
+}
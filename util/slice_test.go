@@ -0,0 +1,53 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInSliceFold(t *testing.T) {
+	values := []string{".mp4", ".MKV"}
+	for str, expected := range map[string]bool{".mp4": true, ".MP4": true, ".mkv": true, ".avi": false, "": false} {
+		if InSliceFold(str, values) != expected {
+			t.Errorf("%q: expected %t", str, expected)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	got := Unique([]string{"b", "a", "b", "c", "a"})
+	if expected := []string{"b", "a", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestDifferenceAndIntersection(t *testing.T) {
+	a := []string{"v1", "v2", "v3", "v2", "v4"}
+	b := []string{"v4", "v2", "v5"}
+	if got, expected := Difference(a, b), []string{"v1", "v3"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("difference: expected %v, got %v", expected, got)
+	}
+	if got, expected := Intersection(a, b), []string{"v2", "v4"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("intersection: expected %v, got %v", expected, got)
+	}
+	if got := Difference(nil, b); len(got) != 0 {
+		t.Errorf("expected no difference, got %v", got)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	values := []string{"1", "2", "3", "4", "5"}
+	got := Chunk(values, 2)
+	expected := [][]string{{"1", "2"}, {"3", "4"}, {"5"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	// appending to a chunk doesn't overwrite the next one
+	_ = append(got[0], "x")
+	if values[2] != "3" {
+		t.Errorf("appending to a chunk changed the values: %v", values)
+	}
+	if got := Chunk(nil, 50); len(got) != 0 {
+		t.Errorf("expected no chunks, got %v", got)
+	}
+}
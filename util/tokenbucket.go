@@ -0,0 +1,64 @@
+package util
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// ErrWaitCancelled is returned by TokenBucket.Wait when it's cancelled before the tokens become available
+var ErrWaitCancelled = errors.Base("cancelled while waiting for tokens")
+
+// TokenBucket is a rate limiter that is safe to share between goroutines. Tokens are added at a steady rate, up to the
+// capacity of the bucket, and taken out by Wait.
+type TokenBucket struct {
+	mux      sync.Mutex
+	rate     float64 // tokens per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewTokenBucket returns a full bucket that refills at rate tokens per second and holds at most capacity tokens
+func NewTokenBucket(rate, capacity float64) *TokenBucket {
+	return &TokenBucket{rate: rate, capacity: capacity, tokens: capacity, last: time.Now()}
+}
+
+// Capacity returns the maximum number of tokens the bucket holds
+func (b *TokenBucket) Capacity() float64 {
+	return b.capacity
+}
+
+// Wait takes n tokens out of the bucket, blocking until they are available or cancel is closed. Asking for more tokens
+// than the bucket can hold works, but waits as long as it takes to accumulate them.
+func (b *TokenBucket) Wait(n float64, cancel <-chan struct{}) error {
+	b.mux.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	// take the tokens right away, even if that leaves the bucket in debt. whoever comes next waits for the debt too,
+	// which keeps waiters in order.
+	b.tokens -= n
+	deficit := -b.tokens
+	b.mux.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-cancel:
+		b.mux.Lock()
+		b.tokens += n
+		b.mux.Unlock()
+		return errors.Err(ErrWaitCancelled)
+	}
+}
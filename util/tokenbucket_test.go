@@ -0,0 +1,100 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+func TestTokenBucketStartsFull(t *testing.T) {
+	b := NewTokenBucket(1, 5)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := b.Wait(1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("taking the initial tokens should not block, took %s", elapsed)
+	}
+}
+
+func TestTokenBucketRate(t *testing.T) {
+	b := NewTokenBucket(100, 1)
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		if err := b.Wait(1, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first token is free, the other 10 take 10ms each
+	elapsed := time.Since(start)
+	if elapsed < 90*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("expected about 100ms, took %s", elapsed)
+	}
+}
+
+func TestTokenBucketMoreThanCapacity(t *testing.T) {
+	b := NewTokenBucket(100, 1)
+	start := time.Now()
+	if err := b.Wait(6, nil); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if elapsed < 40*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("expected about 50ms, took %s", elapsed)
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := NewTokenBucket(1, 1)
+	if err := b.Wait(1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- b.Wait(1, cancel) }()
+	time.Sleep(10 * time.Millisecond)
+	close(cancel)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrWaitCancelled) {
+			t.Errorf("expected ErrWaitCancelled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after being cancelled")
+	}
+
+	// the cancelled tokens are given back, so the next wait is about a second away, not two
+	b.mux.Lock()
+	tokens := b.tokens
+	b.mux.Unlock()
+	if tokens < -0.1 {
+		t.Errorf("cancelled tokens were not given back, bucket has %f tokens", tokens)
+	}
+}
+
+func TestTokenBucketShared(t *testing.T) {
+	b := NewTokenBucket(200, 1)
+	b.Wait(1, nil)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				b.Wait(1, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	// 20 tokens at 200/s take 100ms no matter how many goroutines share the bucket
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("shared bucket let tokens through too fast: %s", elapsed)
+	}
+}
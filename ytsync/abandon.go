@@ -0,0 +1,226 @@
+package ytsync
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/stop"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+)
+
+const (
+	// abandonedReason is the failure reason abandoned videos are marked with on the API
+	abandonedReason = "claim abandoned"
+	// reclaimFeeAllowance is left in the wallet to pay for sending the rest back to lbrycrd
+	reclaimFeeAllowance = 0.01
+	// abandonConfirmationTimeout is how long to wait for the bids of the abandoned claims to be back in the wallet
+	abandonConfirmationTimeout = 15 * time.Minute
+)
+
+// AbandonReport is what abandoning a channel did
+type AbandonReport struct {
+	ChannelID string            `json:"channel_id"`
+	Abandoned map[string]string `json:"abandoned"` // claim ID by video ID. The channel claim is listed under its name
+	Failed    map[string]string `json:"failed"`    // error by video ID
+	Reclaimed float64           `json:"reclaimed"` // credits sent back to lbrycrd
+}
+
+// Abandon undoes the syncs of a channel. The claims published for it, as recorded in the local state DB, are abandoned
+// along with the channel claim unless keepChannel is set. The credits left in the wallet of the channel are sent back
+// to lbrycrd, its videos are marked as failed on the API and the channel goes back to the pending status.
+func (s SyncManager) Abandon(channelID string, keepChannel bool) (*AbandonReport, error) {
+	if s.StateDir == "" {
+		return nil, errors.Err("the state dir is needed to know what was published")
+	}
+	var err error
+	s.localDB, err = localdb.Open(filepath.Join(s.StateDir, localDBFile))
+	if err != nil {
+		return nil, err
+	}
+	defer s.localDB.Close()
+	s.grp = stop.New(s.StopGroup)
+	defer s.grp.Stop()
+
+	videos, err := s.localDB.Videos(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := &Sync{
+		YoutubeChannelID: channelID,
+		Manager:          &s,
+		LbrycrdString:    s.LbrycrdString,
+		AwsS3ID:          s.AwsS3ID,
+		AwsS3Secret:      s.AwsS3Secret,
+		AwsS3Region:      s.AwsS3Region,
+		AwsS3Bucket:      s.AwsS3Bucket,
+	}
+	return channel.abandon(videos, keepChannel)
+}
+
+func (s *Sync) abandon(videos map[string]localdb.Video, keepChannel bool) (report *AbandonReport, e error) {
+	report = &AbandonReport{
+		ChannelID: s.YoutubeChannelID,
+		Abandoned: make(map[string]string),
+		Failed:    make(map[string]string),
+	}
+	s.stats = newSyncStats()
+	s.walletMux = &sync.Mutex{}
+	s.grp = stop.NewDebug("abandoning channel "+s.YoutubeChannelID, s.Manager.grp)
+
+	err := s.preflightWallet()
+	if err != nil {
+		return report, err
+	}
+
+	// marking the channel as syncing keeps other sync servers away from it
+	_, err = s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusSyncing, nil)
+	if err != nil {
+		return report, err
+	}
+	defer func() {
+		status := StatusPending
+		if e != nil {
+			status = StatusFailed
+		}
+		_, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, status, nil)
+		if err != nil && e == nil {
+			e = err
+		}
+	}()
+
+	err = s.downloadWallet()
+	if err != nil {
+		return report, errors.Prefix("failure in downloading wallet", err)
+	}
+	defer s.stopAndUploadWallet(&e)
+
+	err = s.startDaemon()
+	if err != nil {
+		return report, err
+	}
+
+	expected, err := daemonWallet{daemon: s.daemon}.Balance()
+	if err != nil {
+		return report, err
+	}
+	mine, err := s.daemon.ClaimListMine()
+	if err != nil {
+		return report, err
+	}
+	bids := make(map[string]float64)
+	for _, c := range *mine {
+		bids[c.ClaimID], _ = c.Amount.Float64()
+	}
+
+	for videoID, v := range videos {
+		if v.Status != localdb.VideoStatusPublished {
+			continue
+		}
+		bid, ok := bids[v.ClaimID]
+		if !ok {
+			report.Failed[videoID] = "claim " + v.ClaimID + " is not in the wallet"
+			continue
+		}
+		fee, err := s.abandonClaim(v.ClaimID)
+		if err != nil {
+			report.Failed[videoID] = err.Error()
+			continue
+		}
+		expected += bid - fee
+		report.Abandoned[videoID] = v.ClaimID
+
+		err = s.Manager.localDB.SetAbandoned(s.YoutubeChannelID, videoID, v.ClaimID, v.ClaimName)
+		if err != nil {
+			return report, err
+		}
+		err = s.Manager.APIConfig.MarkVideoStatus(s.YoutubeChannelID, videoID, sdk.VideoStatusFailed, "", "", abandonedReason)
+		if err != nil {
+			s.logger().Errorf("could not mark video %s as abandoned on the API: %s", videoID, err.Error())
+		}
+	}
+
+	if !keepChannel {
+		channels, err := s.daemon.ChannelList()
+		if err != nil {
+			return report, err
+		}
+		for _, c := range *channels {
+			fee, err := s.abandonClaim(c.ClaimID)
+			if err != nil {
+				report.Failed[c.Name] = err.Error()
+				continue
+			}
+			bid, _ := c.Amount.Float64()
+			expected += bid - fee
+			report.Abandoned[c.Name] = c.ClaimID
+		}
+	}
+
+	report.Reclaimed, err = s.reclaimCredits(expected)
+	if err != nil {
+		return report, err
+	}
+	if len(report.Failed) > 0 {
+		return report, errors.Err("%d claims could not be abandoned", len(report.Failed))
+	}
+	return report, nil
+}
+
+// abandonClaim abandons a claim of the wallet and returns the fee paid for it
+func (s *Sync) abandonClaim(claimID string) (float64, error) {
+	s.logger().Debugf("abandoning claim %s", claimID)
+	response, err := s.daemon.ClaimAbandon(claimID)
+	if err != nil {
+		return 0, err
+	}
+	fee, _ := response.Fee.Float64()
+	s.stats.spend(fee)
+	return fee, nil
+}
+
+// reclaimCredits waits until the wallet holds the expected balance, or for abandonConfirmationTimeout at most, and
+// sends everything it holds but a fee allowance back to lbrycrd. It returns the credits sent.
+func (s *Sync) reclaimCredits(expected float64) (float64, error) {
+	wallet := daemonWallet{daemon: s.daemon}
+	deadline := time.Now().Add(abandonConfirmationTimeout)
+	balance, err := wallet.Balance()
+	for err == nil && balance < expected && time.Now().Before(deadline) {
+		select {
+		case <-s.grp.Ch():
+			return 0, errors.Err(util.ErrWaitCancelled)
+		case <-time.After(30 * time.Second):
+		}
+		balance, err = wallet.Balance()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if balance < expected {
+		s.logger().Warnf("only %.2f of the expected %.2f LBC are in the wallet after %s, reclaiming what's there", balance, expected, abandonConfirmationTimeout)
+	}
+
+	amount := balance - reclaimFeeAllowance
+	if amount <= 0 {
+		return 0, nil
+	}
+	lbrycrdd, err := s.lbrycrdClient()
+	if err != nil {
+		return 0, err
+	}
+	defer lbrycrdd.Shutdown()
+	address, err := lbrycrdd.GetNewAddress("")
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+	_, err = s.daemon.WalletSend(amount, address.EncodeAddress())
+	if err != nil {
+		return 0, err
+	}
+	s.logger().Infof("sent %.2f LBC back to lbrycrd", amount)
+	return amount, nil
+}
@@ -0,0 +1,81 @@
+package ytsync
+
+import (
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+
+	"github.com/shopspring/decimal"
+)
+
+// ClaimAmounts are the credits that go into the claims of a channel. The manager sets the defaults, and the API can
+// override them per channel. Amounts that are 0 fall back to the defaults of ytsync.
+type ClaimAmounts struct {
+	ChannelBid   float64 // bid of the channel claim
+	VideoBid     float64 // bid of each video claim
+	VideoSupport float64 // support added to each video claim once it's published, none if 0
+	VideoFee     float64 // price of each video in LBC, free if 0
+}
+
+// forChannel returns the amounts for a channel, with the ones the API set for it replacing the defaults
+func (a ClaimAmounts) forChannel(c sdk.YoutubeChannel) ClaimAmounts {
+	if c.ChannelBid > 0 {
+		a.ChannelBid = c.ChannelBid
+	}
+	if c.VideoBid > 0 {
+		a.VideoBid = c.VideoBid
+	}
+	if c.VideoSupport > 0 {
+		a.VideoSupport = c.VideoSupport
+	}
+	if c.VideoFee > 0 {
+		a.VideoFee = c.VideoFee
+	}
+	return a
+}
+
+// channelBid returns the bid of the channel claim
+func (a ClaimAmounts) channelBid() float64 {
+	if a.ChannelBid > 0 {
+		return a.ChannelBid
+	}
+	return channelClaimAmount
+}
+
+// videoBid returns the bid of each video claim
+func (a ClaimAmounts) videoBid() float64 {
+	if a.VideoBid > 0 {
+		return a.VideoBid
+	}
+	return publishAmount
+}
+
+// perVideo returns the credits needed to publish a video, fees of the transactions included
+func (a ClaimAmounts) perVideo() float64 {
+	return a.videoBid() + a.VideoSupport + publishFeeAllowance
+}
+
+// fee returns the fee the videos are published with, nil if they are free
+func (a ClaimAmounts) fee(address string) *jsonrpc.Fee {
+	if a.VideoFee <= 0 {
+		return nil
+	}
+	return &jsonrpc.Fee{
+		Currency: jsonrpc.CurrencyLBC,
+		Amount:   decimal.NewFromFloat(a.VideoFee),
+		Address:  &address,
+	}
+}
+
+// supportClaim adds the support of the video claims to a claim that was just published. It returns the credits spent.
+func (s *Sync) supportClaim(name, claimID string) (float64, error) {
+	amount := s.ClaimAmounts.VideoSupport
+	if amount <= 0 {
+		return 0, nil
+	}
+	response, err := s.daemon.ClaimNewSupport(name, claimID, amount)
+	if err != nil {
+		return 0, err
+	}
+	fee, _ := response.Fee.Float64()
+	return amount + fee, nil
+}
@@ -0,0 +1,170 @@
+// Package apicache keeps the responses of an HTTP API on disk, so that requests made again before their TTL is over
+// are answered from the disk instead of the API. It's used for the YouTube Data API, whose quota is spent by every
+// request whatever the answer.
+package apicache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/boltdb/bolt"
+	log "github.com/sirupsen/logrus"
+)
+
+var responsesBucket = []byte("responses")
+
+// Cache is a response cache in a bolt database. Only one process can have it open at a time.
+type Cache struct {
+	db *bolt.DB
+	// MaxAge is the longest TTL the entries are used for. Older entries are deleted when the cache is opened.
+	MaxAge time.Duration
+}
+
+type entry struct {
+	StoredAt time.Time   `json:"stored_at"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+}
+
+// Open opens the cache at path, creating it if needed, and deletes the entries older than maxAge
+func Open(path string, maxAge time.Duration) (*Cache, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Prefix("could not open response cache "+path, err)
+	}
+	c := &Cache{db: db, MaxAge: maxAge}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(responsesBucket)
+		if err != nil {
+			return err
+		}
+		var expired [][]byte
+		err = b.ForEach(func(k, data []byte) error {
+			var e entry
+			if json.Unmarshal(data, &e) != nil || time.Since(e.StoredAt) > maxAge {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			err = b.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Err(err)
+	}
+	return c, nil
+}
+
+func (c *Cache) Close() error {
+	return errors.Err(c.db.Close())
+}
+
+// TTLFunc returns how long the response to a request may be reused, 0 for not caching it
+type TTLFunc func(*http.Request) time.Duration
+
+// Transport returns a transport answering GET requests from the cache while their response is fresher than their TTL,
+// and sending the others to next. Successful responses are stored. Conditional requests always go to next, since
+// they're about what the API has now. onHit, if set, is called for every request answered from the cache.
+func (c *Cache) Transport(next http.RoundTripper, ttl TTLFunc, onHit func(*http.Request)) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{cache: c, next: next, ttl: ttl, onHit: onHit}
+}
+
+type transport struct {
+	cache *Cache
+	next  http.RoundTripper
+	ttl   TTLFunc
+	onHit func(*http.Request)
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ttl := t.ttl(req)
+	if req.Method != http.MethodGet || ttl <= 0 || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return t.next.RoundTrip(req)
+	}
+	key := []byte(Key(req.URL))
+	if e, ok := t.cache.get(key); ok && time.Since(e.StoredAt) < ttl && time.Since(e.StoredAt) < t.cache.MaxAge {
+		if t.onHit != nil {
+			t.onHit(req)
+		}
+		return e.response(req), nil
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	err = t.cache.put(key, entry{StoredAt: time.Now(), Status: res.StatusCode, Header: res.Header, Body: body})
+	if err != nil {
+		log.Warnf("could not cache the response to %s: %s", req.URL.Path, err.Error())
+	}
+	return res, nil
+}
+
+func (c *Cache) get(key []byte) (entry, bool) {
+	var e entry
+	var ok bool
+	_ = c.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(responsesBucket).Get(key)
+		ok = data != nil && json.Unmarshal(data, &e) == nil
+		return nil
+	})
+	return e, ok
+}
+
+func (c *Cache) put(key []byte, e entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(responsesBucket).Put(key, data)
+	}))
+}
+
+func (e entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(e.Status),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// Key returns the cache key of a request URL: the URL with its query sorted and without the API key, so that the
+// responses are shared by the keys of a project
+func Key(u *url.URL) string {
+	query := u.Query()
+	query.Del("key")
+	return strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path+"?"+query.Encode(), "?")
+}
@@ -0,0 +1,122 @@
+package apicache
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func openTestCache(t *testing.T) (*Cache, func()) {
+	dir, err := ioutil.TempDir("", "apicache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Open(filepath.Join(dir, "cache.db"), time.Hour)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return c, func() {
+		c.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func get(t *testing.T, client *http.Client, u string, header http.Header) (int, string) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(body)
+}
+
+func TestTransport(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(strconv.Itoa(calls)))
+	}))
+	defer server.Close()
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+
+	hits := 0
+	ttl := func(r *http.Request) time.Duration {
+		if r.URL.Path == "/uncached" {
+			return 0
+		}
+		return time.Minute
+	}
+	client := &http.Client{Transport: c.Transport(nil, ttl, func(*http.Request) { hits++ })}
+
+	if _, body := get(t, client, server.URL+"/videos?id=a&key=1", nil); body != "1" {
+		t.Errorf("expected the first response, got %s", body)
+	}
+	// the API key and the order of the query don't matter
+	if _, body := get(t, client, server.URL+"/videos?key=2&id=a", nil); body != "1" {
+		t.Errorf("expected the cached response, got %s", body)
+	}
+	if _, body := get(t, client, server.URL+"/videos?id=b", nil); body != "2" {
+		t.Errorf("expected a new response for another request, got %s", body)
+	}
+	if _, body := get(t, client, server.URL+"/videos?id=a", http.Header{"If-None-Match": {"etag"}}); body != "3" {
+		t.Errorf("expected conditional requests to skip the cache, got %s", body)
+	}
+	get(t, client, server.URL+"/uncached", nil)
+	if _, body := get(t, client, server.URL+"/uncached", nil); body != "5" {
+		t.Errorf("expected requests without a TTL to skip the cache, got %s", body)
+	}
+	get(t, client, server.URL+"/missing", nil)
+	if status, body := get(t, client, server.URL+"/missing", nil); status != http.StatusNotFound || body != "7" {
+		t.Errorf("expected errors not to be cached, got %d %s", status, body)
+	}
+	if hits != 1 {
+		t.Errorf("expected 1 hit, got %d", hits)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	c, cleanup := openTestCache(t)
+	defer cleanup()
+	u, _ := url.Parse("https://www.googleapis.com/youtube/v3/videos?id=a")
+	key := []byte(Key(u))
+	err := c.put(key, entry{StoredAt: time.Now().Add(-2 * time.Hour), Status: http.StatusOK, Body: []byte("old")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	next := roundTripper(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return entry{Status: http.StatusOK, Body: []byte("new")}.response(r), nil
+	})
+	client := &http.Client{Transport: c.Transport(next, func(*http.Request) time.Duration { return 3 * time.Hour }, nil)}
+	if _, body := get(t, client, u.String(), nil); body != "new" || calls != 1 {
+		t.Errorf("expected entries older than MaxAge to be refreshed, got %s", body)
+	}
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
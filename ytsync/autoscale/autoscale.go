@@ -0,0 +1,132 @@
+// Package autoscale scales how many videos are processed at once with how well the daemon keeps up. The calls made to
+// the daemon are observed in windows: when too many of them in a window failed or were slow, the limit is halved, and
+// when none of them were, it goes up by one.
+package autoscale
+
+import (
+	"sync"
+
+	"github.com/lbryio/lbry.go/stop"
+)
+
+const (
+	defaultWindow      = 20
+	defaultMaxBadRatio = 0.2
+)
+
+// Limiter limits how many jobs run at once, between 1 and a maximum
+type Limiter struct {
+	// Window is how many calls are observed before the limit is adjusted. Defaults to 20.
+	Window int
+	// MaxBadRatio is the fraction of bad calls in a window above which the limit is halved. Defaults to 0.2.
+	MaxBadRatio float64
+	// OnChange is called with the new limit when it's adjusted, if set
+	OnChange func(limit int)
+
+	mux     sync.Mutex
+	limit   int
+	max     int
+	running int
+	calls   int
+	bad     int
+	changed chan struct{} // closed when a job is released or the limit changes
+}
+
+// New returns a limiter that starts at initial jobs at once and never goes above max
+func New(initial, max int) *Limiter {
+	if max < 1 {
+		max = 1
+	}
+	if initial < 1 {
+		initial = 1
+	} else if initial > max {
+		initial = max
+	}
+	return &Limiter{limit: initial, max: max, changed: make(chan struct{})}
+}
+
+// Limit returns how many jobs may run at once
+func (l *Limiter) Limit() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.limit
+}
+
+// Acquire waits until a job may run. It returns false if stopCh was closed first, in which case Release must not be
+// called.
+func (l *Limiter) Acquire(stopCh stop.Chan) bool {
+	for {
+		l.mux.Lock()
+		if l.running < l.limit {
+			l.running++
+			l.mux.Unlock()
+			return true
+		}
+		changed := l.changed
+		l.mux.Unlock()
+
+		select {
+		case <-changed:
+		case <-stopCh:
+			return false
+		}
+	}
+}
+
+// Release ends a job started with Acquire
+func (l *Limiter) Release() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.running--
+	l.notify()
+}
+
+// Observe records a call made by the jobs. bad means it failed or took too long, in a way that shows the daemon is
+// struggling.
+func (l *Limiter) Observe(bad bool) {
+	l.mux.Lock()
+	l.calls++
+	if bad {
+		l.bad++
+	}
+	window := l.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+	if l.calls < window {
+		l.mux.Unlock()
+		return
+	}
+
+	maxBadRatio := l.MaxBadRatio
+	if maxBadRatio <= 0 {
+		maxBadRatio = defaultMaxBadRatio
+	}
+	limit := l.limit
+	switch {
+	case float64(l.bad)/float64(l.calls) > maxBadRatio:
+		limit = l.limit / 2
+		if limit < 1 {
+			limit = 1
+		}
+	case l.bad == 0 && l.limit < l.max:
+		limit = l.limit + 1
+	}
+	l.calls, l.bad = 0, 0
+	changed := limit != l.limit
+	if changed {
+		l.limit = limit
+		l.notify()
+	}
+	l.mux.Unlock()
+
+	if changed && l.OnChange != nil {
+		l.OnChange(limit)
+	}
+}
+
+// notify wakes up the jobs waiting in Acquire. mux must be held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
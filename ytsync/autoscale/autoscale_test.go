@@ -0,0 +1,88 @@
+package autoscale
+
+import (
+	"testing"
+	"time"
+)
+
+// observe records a window of calls, bad of which are bad
+func observe(l *Limiter, bad int) {
+	for i := 0; i < l.Window; i++ {
+		l.Observe(i < bad)
+	}
+}
+
+func TestLimiter_Scale(t *testing.T) {
+	var changes []int
+	l := New(1, 4)
+	l.Window = 10
+	l.OnChange = func(limit int) { changes = append(changes, limit) }
+
+	for i := 0; i < 5; i++ {
+		observe(l, 0)
+	}
+	if l.Limit() != 4 {
+		t.Fatalf("expected the limit to grow to the maximum, got %d", l.Limit())
+	}
+	observe(l, 1) // 10% bad, within MaxBadRatio
+	if l.Limit() != 4 {
+		t.Errorf("expected the limit to hold, got %d", l.Limit())
+	}
+	observe(l, 5)
+	if l.Limit() != 2 {
+		t.Errorf("expected the limit to be halved, got %d", l.Limit())
+	}
+	observe(l, 10)
+	observe(l, 10)
+	if l.Limit() != 1 {
+		t.Errorf("expected the limit to stay at 1, got %d", l.Limit())
+	}
+
+	expected := []int{2, 3, 4, 2, 1}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Fatalf("expected changes %v, got %v", expected, changes)
+		}
+	}
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	l := New(1, 2)
+	l.Window = 1
+	if !l.Acquire(nil) {
+		t.Fatal("expected the first job to start")
+	}
+
+	started := make(chan bool)
+	go func() { started <- l.Acquire(nil) }()
+	select {
+	case <-started:
+		t.Fatal("a second job started above the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.Observe(false) // raises the limit to 2
+	select {
+	case ok := <-started:
+		if !ok {
+			t.Fatal("expected the second job to start")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the second job didn't start once the limit was raised")
+	}
+
+	stopCh := make(chan struct{})
+	go func() { started <- l.Acquire(stopCh) }()
+	close(stopCh)
+	if <-started {
+		t.Error("a job started after the stop")
+	}
+
+	l.Release()
+	if !l.Acquire(nil) {
+		t.Error("expected a job to start after a release")
+	}
+}
@@ -0,0 +1,180 @@
+package ytsync
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/walletbackup"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// walletOwnerFile is written next to default_wallet when a sync starts. It holds the ID of the channel the wallet
+// belongs to, so a wallet left behind by a sync that couldn't stop its daemon can be put back where it belongs.
+const walletOwnerFile = "ytsync_owner"
+
+// errWalletNotOnS3 is returned by fetchWalletOf for channels that were never synced
+var errWalletNotOnS3 = errors.Base("wallet not on S3")
+
+// markWalletOwner records that the wallet in the wallet dir belongs to the channel being synced
+func (s *Sync) markWalletOwner() error {
+	walletDir, _ := s.walletPaths()
+	err := os.MkdirAll(walletDir, 0700)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(ioutil.WriteFile(filepath.Join(walletDir, walletOwnerFile), []byte(s.YoutubeChannelID), 0600))
+}
+
+// clearWalletOwner removes the record markWalletOwner made, once the wallet is back on S3
+func (s *Sync) clearWalletOwner() {
+	walletDir, _ := s.walletPaths()
+	_ = os.Remove(filepath.Join(walletDir, walletOwnerFile))
+}
+
+// backupWallet saves an encrypted snapshot of the wallet of the channel, as it is before the sync. Failing to do so
+// doesn't stop the sync.
+func (s *Sync) backupWallet() {
+	if s.Manager.WalletBackups == nil {
+		return
+	}
+	_, defaultWallet := s.walletPaths()
+	wallet, err := ioutil.ReadFile(defaultWallet)
+	if err == nil {
+		err = s.saveWalletBackup(s.YoutubeChannelID, wallet)
+	}
+	if err != nil {
+		s.notifyError("could not back up the wallet of %s: %s", s.YoutubeChannelID, err.Error())
+	}
+}
+
+// saveWalletBackup saves an encrypted snapshot of a wallet of the channel
+func (s *Sync) saveWalletBackup(channelID string, wallet []byte) error {
+	backup, err := s.Manager.WalletBackups.Save(channelID, wallet)
+	if err != nil {
+		return err
+	}
+	s.logger().Infof("backed up the wallet of %s as %s", channelID, backup.Name)
+	return nil
+}
+
+// recoverLeftoverWallet puts a wallet a previous sync left behind back on S3, as the wallet of the channel it belongs
+// to. That happens when the daemon couldn't be stopped, or the wallet couldn't be uploaded. The daemon must not be
+// running anymore.
+func (s *Sync) recoverLeftoverWallet() error {
+	walletDir, defaultWallet := s.walletPaths()
+	owner, err := ioutil.ReadFile(filepath.Join(walletDir, walletOwnerFile))
+	channelID := strings.TrimSpace(string(owner))
+	if err != nil || channelID == "" {
+		return errors.Err("the channel it belongs to is unknown")
+	}
+	pid, err := s.daemonSlot.pid()
+	if err != nil {
+		return err
+	}
+	if pid != -1 {
+		return errors.Err("the daemon using it is still running")
+	}
+
+	wallet, err := ioutil.ReadFile(defaultWallet)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if s.Manager.WalletBackups != nil {
+		err = s.saveWalletBackup(channelID, wallet)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.uploadWalletOf(channelID, bytes.NewReader(wallet))
+	if err != nil {
+		return err
+	}
+	err = os.Remove(defaultWallet)
+	if err != nil {
+		return errors.Err(err)
+	}
+	s.clearWalletOwner()
+	SendInfoToSlack("Put the wallet of %s left behind by a previous sync back on S3", channelID)
+	return nil
+}
+
+// fetchWalletOf downloads the wallet of the channel from S3. It returns errWalletNotOnS3 if there is none.
+func (s *Sync) fetchWalletOf(channelID string) ([]byte, error) {
+	creds := credentials.NewStaticCredentials(s.AwsS3ID, s.AwsS3Secret, "")
+	s3Session, err := session.NewSession(&aws.Config{Region: aws.String(s.AwsS3Region), Credentials: creds})
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	buf := aws.NewWriteAtBuffer(nil)
+	_, err = s3manager.NewDownloader(s3Session).Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.AwsS3Bucket),
+		Key:    walletKey(channelID),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, errors.Err(errWalletNotOnS3)
+		}
+		return nil, errors.Err(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// walletChannel returns a Sync that can move the wallet of the channel around, outside of a sync
+func (s *SyncManager) walletChannel(channelID string) *Sync {
+	return &Sync{
+		YoutubeChannelID: channelID,
+		Manager:          s,
+		AwsS3ID:          s.AwsS3ID,
+		AwsS3Secret:      s.AwsS3Secret,
+		AwsS3Region:      s.AwsS3Region,
+		AwsS3Bucket:      s.AwsS3Bucket,
+	}
+}
+
+// WalletBackupsOf returns the backups of the wallet of a channel, oldest first
+func (s SyncManager) WalletBackupsOf(channelID string) ([]walletbackup.Backup, error) {
+	if s.WalletBackups == nil {
+		return nil, errors.Err("wallet backups are not configured")
+	}
+	return s.WalletBackups.List(channelID)
+}
+
+// RestoreWallet replaces the wallet of a channel on S3 with one of its backups, the latest one if name is empty. The
+// wallet it replaces is backed up first, so the restore can be undone. The channel must not be syncing.
+func (s SyncManager) RestoreWallet(channelID, name string) (*walletbackup.Backup, error) {
+	if s.WalletBackups == nil {
+		return nil, errors.Err("wallet backups are not configured")
+	}
+	backup, wallet, err := s.WalletBackups.Fetch(channelID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := s.walletChannel(channelID)
+	current, err := channel.fetchWalletOf(channelID)
+	if err != nil && !errors.Is(err, errWalletNotOnS3) {
+		return nil, err
+	}
+	if err == nil {
+		err = channel.saveWalletBackup(channelID, current)
+		if err != nil {
+			return nil, errors.Prefix("could not back up the current wallet", err)
+		}
+	}
+
+	err = channel.uploadWalletOf(channelID, bytes.NewReader(wallet))
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return &backup, nil
+}
@@ -0,0 +1,177 @@
+package ytsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// channelBranding is how the youtube channel presents itself, as it goes into the metadata of the LBRY channel claim
+type channelBranding struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	AvatarURL   string `json:"avatar_url"` // on youtube, it's hosted with the thumbnails before it goes into the claim
+	BannerURL   string `json:"banner_url"`
+}
+
+// fingerprint identifies the branding, to tell whether it changed since it was put in the claim
+func (b channelBranding) fingerprint() string {
+	data, _ := json.Marshal(b)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fetchBranding gets the title, description, avatar and banner of the channel from youtube. The links of the about
+// page aren't available through the API.
+func (s *Sync) fetchBranding() (*channelBranding, error) {
+	service, err := s.youtubeService()
+	if err != nil {
+		return nil, err
+	}
+	err = s.useQuota(listCost)
+	if err != nil {
+		return nil, err
+	}
+	response, err := service.Channels.List("snippet,brandingSettings").Id(s.YoutubeChannelID).Do()
+	if err != nil {
+		return nil, errors.Prefix("error getting the channel", s.youtubeQuota().Observe(err))
+	}
+	if len(response.Items) < 1 {
+		return nil, errors.Err("youtube channel not found")
+	}
+
+	channel := response.Items[0]
+	b := &channelBranding{}
+	if channel.Snippet != nil {
+		b.Title = channel.Snippet.Title
+		b.Description = channel.Snippet.Description
+		if t := channel.Snippet.Thumbnails; t != nil {
+			for _, avatar := range []*youtube.Thumbnail{t.High, t.Medium, t.Default} {
+				if avatar != nil && avatar.Url != "" {
+					b.AvatarURL = avatar.Url
+					break
+				}
+			}
+		}
+	}
+	if channel.BrandingSettings != nil && channel.BrandingSettings.Image != nil {
+		b.BannerURL = channel.BrandingSettings.Image.BannerExternalUrl
+	}
+	return b, nil
+}
+
+// channelOptions hosts the images of the branding and returns the metadata of the channel claim. The website of the
+// channel is its youtube page.
+func (s *Sync) channelOptions(b *channelBranding) (jsonrpc.ChannelOptions, error) {
+	website := "https://www.youtube.com/channel/" + s.YoutubeChannelID
+	options := jsonrpc.ChannelOptions{Title: &b.Title, Description: &b.Description, WebsiteURL: &website}
+	params := s.syncParams()
+	if b.AvatarURL != "" {
+		avatar, err := sources.HostImage(b.AvatarURL, brandingKey(s.YoutubeChannelID, "avatar", b.AvatarURL), params)
+		if err != nil {
+			return options, errors.Prefix("could not host the avatar", err)
+		}
+		options.ThumbnailURL = &avatar
+	}
+	if b.BannerURL != "" {
+		banner, err := sources.HostImage(b.BannerURL, brandingKey(s.YoutubeChannelID, "banner", b.BannerURL), params)
+		if err != nil {
+			return options, errors.Prefix("could not host the banner", err)
+		}
+		options.CoverURL = &banner
+	}
+	return options, nil
+}
+
+// brandingKey returns where an image of the channel is hosted. It changes with the image on youtube, so apps caching
+// the old one pick the new one up.
+func brandingKey(channelID, image, sourceURL string) string {
+	sum := sha256.Sum256([]byte(sourceURL))
+	return "channels/" + channelID + "/" + image + "-" + hex.EncodeToString(sum[:6])
+}
+
+// newChannelBranding returns the metadata a new channel claim is created with, and the fingerprint of its branding.
+// Without SyncBranding, or if the branding can't be fetched, the channel is created without metadata.
+func (s *Sync) newChannelBranding() (jsonrpc.ChannelOptions, string) {
+	if !s.Manager.SyncBranding {
+		return jsonrpc.ChannelOptions{}, ""
+	}
+	b, err := s.fetchBranding()
+	if err == nil {
+		var options jsonrpc.ChannelOptions
+		options, err = s.channelOptions(b)
+		if err == nil {
+			return options, b.fingerprint()
+		}
+	}
+	s.notifyError("could not get the branding of %s, creating its channel without it: %s", s.YoutubeChannelID, err.Error())
+	return jsonrpc.ChannelOptions{}, ""
+}
+
+// updateBranding puts the branding of the youtube channel in the channel claim, if it changed since it last was.
+// Failing to do so doesn't stop the sync.
+func (s *Sync) updateBranding() {
+	if !s.Manager.SyncBranding {
+		return
+	}
+	b, err := s.fetchBranding()
+	if err != nil {
+		s.notifyError("could not get the branding of %s: %s", s.YoutubeChannelID, err.Error())
+		return
+	}
+	fingerprint := b.fingerprint()
+	if fingerprint == s.lastBranding() {
+		return
+	}
+	options, err := s.channelOptions(b)
+	if err == nil {
+		if s.Manager.ChannelAddress {
+			// the claims of the channel are on the address of the channel claim, it stays there
+			options.ClaimAddress = &s.claimAddress
+		}
+		var response *jsonrpc.ChannelUpdateResponse
+		response, err = s.daemon.ChannelUpdate(s.lbryChannelID, options)
+		if err == nil {
+			fee, _ := response.Fee.Float64()
+			s.stats.spend(fee)
+			s.recordSpend(localdb.Spend{Kind: localdb.SpendChannel, ClaimID: s.lbryChannelID, Fee: fee})
+		}
+	}
+	if err != nil {
+		s.notifyError("could not update the branding of channel %s: %s", s.lbryChannelID, err.Error())
+		return
+	}
+	s.recordBranding(fingerprint)
+	s.logger().Infof("updated the branding of channel %s from youtube", s.lbryChannelID)
+}
+
+// lastBranding returns the fingerprint of the branding last put in the channel claim. Without the local state DB,
+// it's only known if it was put there since the process started.
+func (s *Sync) lastBranding() string {
+	if s.Manager.localDB != nil {
+		state, _, err := s.Manager.localDB.Channel(s.YoutubeChannelID)
+		if err == nil {
+			return state.Branding
+		}
+	}
+	return s.branding
+}
+
+// recordBranding records the fingerprint of the branding that was put in the channel claim
+func (s *Sync) recordBranding(fingerprint string) {
+	s.branding = fingerprint
+	if s.Manager.localDB == nil {
+		return
+	}
+	err := s.Manager.localDB.SetBranding(s.YoutubeChannelID, fingerprint)
+	if err != nil {
+		s.logger().Warnf("could not record the branding of %s on the local db: %s", s.YoutubeChannelID, err.Error())
+	}
+}
@@ -0,0 +1,91 @@
+package ytsync
+
+import (
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/walletbackup"
+)
+
+// exportCertificate saves the certificate of the channel claim with the wallet backups, so that the channel can be
+// synced by another sync server even without its wallet. It does nothing without wallet backups, and failing to do so
+// doesn't stop the sync.
+func (s *Sync) exportCertificate() {
+	if s.Manager.WalletBackups == nil || s.lbryChannelID == "" {
+		return
+	}
+	err := saveCertificate(s.daemon, s.Manager.WalletBackups, s.YoutubeChannelID, s.lbryChannelID)
+	if err != nil {
+		s.notifyError("could not export the certificate of channel claim %s: %s", s.lbryChannelID, err.Error())
+	}
+}
+
+// importCertificate adds the saved certificate of the channel claim to the wallet if the wallet can't sign for the
+// claim, e.g. because the channel moved from another sync server. A missing certificate isn't an error here, the
+// ownership of the claim is checked afterwards.
+func (s *Sync) importCertificate() error {
+	if s.Manager.WalletBackups == nil || s.LbryChannelClaimID == "" {
+		return nil
+	}
+	canSign, err := walletCanSign(s.daemon, s.LbryChannelClaimID)
+	if err != nil || canSign {
+		return err
+	}
+	err = loadCertificate(s.daemon, s.Manager.WalletBackups, s.YoutubeChannelID)
+	if errors.Is(err, walletbackup.ErrNoCertificate) {
+		return nil
+	} else if err != nil {
+		return errors.Prefix("could not import the certificate of channel claim "+s.LbryChannelClaimID, err)
+	}
+	s.logger().Infof("imported the certificate of channel claim %s into the wallet", s.LbryChannelClaimID)
+	return nil
+}
+
+// walletCanSign returns true if the wallet of the daemon can publish to the channel claim
+func walletCanSign(daemon *jsonrpc.Client, claimID string) (bool, error) {
+	channels, err := daemon.ChannelList()
+	if err != nil {
+		return false, err
+	} else if channels == nil {
+		return false, errors.Err("no channel response")
+	}
+	for _, channel := range *channels {
+		if channel.ClaimID == claimID {
+			return channel.CanSign, nil
+		}
+	}
+	return false, nil
+}
+
+func saveCertificate(daemon *jsonrpc.Client, store *walletbackup.Store, channelID, claimID string) error {
+	certificate, err := daemon.ChannelExport(claimID)
+	if err != nil {
+		return err
+	}
+	return store.SaveCertificate(channelID, []byte(*certificate))
+}
+
+func loadCertificate(daemon *jsonrpc.Client, store *walletbackup.Store, channelID string) error {
+	certificate, err := store.FetchCertificate(channelID)
+	if err != nil {
+		return err
+	}
+	return daemon.ChannelImport(string(certificate))
+}
+
+// ExportCertificate exports the certificate of a channel claim from the wallet of the daemon at daemonURL and saves it
+// for the youtube channel, encrypted, with the wallet backups
+func (s SyncManager) ExportCertificate(daemonURL, channelID, claimID string) error {
+	if s.WalletBackups == nil {
+		return errors.Err("wallet backups are not configured")
+	}
+	return saveCertificate(jsonrpc.NewClient(daemonURL), s.WalletBackups, channelID, claimID)
+}
+
+// ImportCertificate imports the certificate saved for the youtube channel into the wallet of the daemon at daemonURL,
+// so that it can publish to the channel claim
+func (s SyncManager) ImportCertificate(daemonURL, channelID string) error {
+	if s.WalletBackups == nil {
+		return errors.Err("wallet backups are not configured")
+	}
+	return loadCertificate(jsonrpc.NewClient(daemonURL), s.WalletBackups, channelID)
+}
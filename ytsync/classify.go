@@ -0,0 +1,161 @@
+package ytsync
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/retry"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+	log "github.com/sirupsen/logrus"
+)
+
+// reasons of the transient failures that need fixing before the video is retried
+const (
+	reasonMempoolConflict   = "mempool conflict"
+	reasonInsufficientFunds = "insufficient funds"
+)
+
+// reasons of the permanent failures the triage report tells apart, see triage.go
+const (
+	reasonCopyright = "copyright blocked"
+	reasonTooBig    = "too big"
+	reasonInvalid   = "invalid video"
+)
+
+// reasonSyncStopping is the reason of the failures caused by the sync stopping, which are not the fault of the video
+const reasonSyncStopping = "sync stopping"
+
+// videoErrors classifies the errors returned while processing a video. Anything not listed is retried. Errors are
+// matched by category where their source categorizes them, and by message otherwise.
+var videoErrors = retry.Classifier{
+	{Class: retry.Fatal, Reason: "daemon unreachable", Substrings: []string{
+		":5279: read: connection reset by peer",
+	}},
+	{Class: retry.Fatal, Reason: "out of disk space", Substrings: []string{
+		"no space left on device",
+		"more than 90% of the space has been used.",
+	}},
+	{Class: retry.Fatal, Reason: "spending cap", Substrings: []string{
+		errSpendingCap.Error(),
+	}},
+	{Class: retry.Fatal, Reason: "wallet broken", Substrings: []string{
+		"NotEnoughFunds",
+		"Cannot publish using channel",
+		"cannot concatenate 'str' and 'NoneType' objects",
+	}},
+	{Class: retry.Permanent, Reason: reasonSyncStopping, Substrings: []string{
+		util.ErrWaitCancelled.Error(),
+	}},
+	{Class: retry.Permanent, Reason: reasonCopyright, Substrings: []string{
+		" reason: 'This video contains content from",
+		"blocked it on copyright grounds",
+	}},
+	{Class: retry.Permanent, Reason: "video unavailable", Substrings: []string{
+		"non 200 status code received",
+		"This video is unavailable",
+		"This video is private",
+		"download error: AccessDenied: Access Denied",
+		"Playback on other websites has been disabled by the video owner",
+		"Error extracting sts from embedded url response",
+	}},
+	// restricted videos are skipped, the next syncs try them again in case cookies or a way around the region lock
+	// were set up since, see SetupYoutubeAccess
+	{Class: retry.Permanent, Reason: "video restricted", Substrings: []string{
+		"Sign in to confirm your age",
+		"This video may be inappropriate for some users",
+		"uploader has not made this video available in your country",
+		"This video is not available in your country",
+		"blocked it in your country",
+	}},
+	{Class: retry.Permanent, Reason: "cannot publish", Substrings: []string{
+		"dont know which claim to update",
+		"Error in daemon: Cannot publish empty file",
+		sources.ErrNameTaken.Error(),
+	}},
+	{Class: retry.Permanent, Reason: reasonTooBig, Substrings: []string{
+		"the video is too big to sync, skipping for now",
+	}},
+	{Class: retry.Permanent, Reason: "duplicate content", Substrings: []string{
+		sources.ErrDuplicate.Error(),
+	}},
+	{Class: retry.Permanent, Reason: reasonInvalid, Substrings: []string{
+		sources.ErrInvalidVideo.Error(),
+	}},
+	// the publish may have gone through, retrying it could create a duplicate claim
+	{Class: retry.Permanent, Reason: "publish timeout", Substrings: []string{
+		"Client.Timeout exceeded while awaiting headers)",
+	}},
+	{Class: retry.Transient, Reason: reasonMempoolConflict, Category: errors.Blockchain, Code: jsonrpc.ErrorCodeMempoolConflict, Substrings: []string{
+		"txn-mempool-conflict",
+		"too-long-mempool-chain",
+	}},
+	{Class: retry.Transient, Reason: reasonInsufficientFunds, Category: errors.Wallet, Code: jsonrpc.ErrorCodeInsufficientFunds, Substrings: []string{
+		"failed: Not enough funds",
+		"Error in daemon: Insufficient funds, please deposit additional LBC",
+	}},
+	{Class: retry.Transient, Reason: "quota exceeded", Category: errors.YouTubeAPI, Code: codeQuotaExceeded, Substrings: []string{
+		"quotaExceeded",
+		"dailyLimitExceeded",
+		"rateLimitExceeded",
+		"HTTP Error 429",
+	}},
+	// the broken download is deleted, so it's downloaded again
+	{Class: retry.Transient, Reason: "corrupted download", Substrings: []string{
+		"download verification failed",
+	}},
+	{Class: retry.Transient, Reason: "daemon timeout", Category: errors.Network, Code: jsonrpc.ErrorCodeTimeout, Substrings: []string{
+		"i/o timeout",
+		"context deadline exceeded",
+		":5279: connect: connection refused",
+	}},
+}
+
+// channelErrors are the errors of a channel sync that stop every other sync, along with wallet errors, see IsWalletError
+var channelErrors = retry.Classifier{
+	{Class: retry.Fatal, Reason: "wallet exists", Substrings: []string{
+		"default_wallet already exists",
+	}},
+	{Class: retry.Fatal, Reason: "wallet broken", Substrings: []string{
+		"NotEnoughFunds",
+	}},
+	{Class: retry.Fatal, Reason: "out of disk space", Substrings: []string{
+		"no space left on device",
+	}},
+}
+
+// classifier returns the configured ErrorRules followed by the built-in rules. The configured rules come first, so they
+// can change how the errors the built-in rules know about are handled.
+func (s *SyncManager) classifier(builtIn retry.Classifier) retry.Classifier {
+	if s == nil || s.ErrorRules == nil {
+		return builtIn
+	}
+	return append(s.ErrorRules.Classifier(), builtIn...)
+}
+
+// videoRetryPolicy returns how a failed video is retried: up to MaxTries times with an exponential backoff, fixing
+// the wallet first when that's what went wrong. With StopOnError, videos are never retried.
+func (s *Sync) videoRetryPolicy() retry.Policy {
+	attempts := s.MaxTries
+	if s.StopOnError {
+		attempts = 1
+	}
+	return retry.Policy{
+		Attempts:   attempts,
+		Backoff:    util.Backoff{Base: 10 * time.Second, Max: 5 * time.Minute, Jitter: 0.2},
+		Classifier: s.Manager.classifier(videoErrors),
+		OnRetry: func(attempt int, err error, reason string) error {
+			switch reason {
+			case reasonMempoolConflict:
+				log.Println("waiting for a block before retrying")
+				return errors.Prefix("something went wrong while waiting for a block", s.waitForNewBlock())
+			case reasonInsufficientFunds:
+				log.Println("refilling addresses before retrying")
+				return errors.Prefix("failed to setup the wallet for a refill", s.walletSetup())
+			}
+			log.Println("Retrying")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,248 @@
+package ytsync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/retry"
+	"github.com/lbryio/lbry.go/stop"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+const (
+	confirmationPollInterval   = 30 * time.Second
+	defaultConfirmationTimeout = time.Hour
+
+	reasonUnconfirmed = "publish never confirmed"
+)
+
+// pendingClaim is a published video whose claim doesn't have enough confirmations yet
+type pendingClaim struct {
+	video     video
+	summary   *sources.SyncSummary
+	started   time.Time // when the video started syncing
+	published time.Time // when the claim was sent, the confirmation timeout runs from there
+	publishes int       // how many times the video was published during this sync
+}
+
+// confirmationMonitor keeps track of the claims published during a sync until they are confirmed, see
+// SyncManager.Confirmations. The videos are only marked as published once their claim is confirmed. The ones whose
+// claim doesn't confirm in time are published again, up to MaxTries times.
+type confirmationMonitor struct {
+	grp         *stop.Group
+	mux         sync.Mutex
+	pending     map[string]*pendingClaim // by txid
+	publishes   map[string]int           // by video ID
+	republishes int                      // videos being published again by the monitor
+	closed      bool                     // the workers are done, no other video is going to be published
+}
+
+// startConfirmationMonitor starts tracking the claims published by the sync, if it has to wait for confirmations
+func (s *Sync) startConfirmationMonitor() {
+	s.confirmations = nil
+	if s.Manager.Confirmations <= 0 {
+		return
+	}
+	s.confirmations = &confirmationMonitor{
+		grp:       s.grp.Child(),
+		pending:   make(map[string]*pendingClaim),
+		publishes: make(map[string]int),
+	}
+	s.confirmations.grp.GoErr(s.monitorConfirmations)
+}
+
+// awaitConfirmation hands a published video over to the confirmation monitor
+func (s *Sync) awaitConfirmation(v video, summary *sources.SyncSummary, started time.Time) {
+	m := s.confirmations
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.publishes[v.ID()]++
+	m.pending[summary.Txid] = &pendingClaim{
+		video:     v,
+		summary:   summary,
+		started:   started,
+		published: time.Now(),
+		publishes: m.publishes[v.ID()],
+	}
+	s.videoLogger(v.ID(), 0).Printf("waiting for %d confirmations of %s", s.Manager.Confirmations, summary.Txid)
+}
+
+// waitForConfirmations waits until every claim of the sync is either confirmed or failed. The claims still pending
+// when the sync is stopped are left alone: they are on the local DB, so the next sync picks them up.
+func (s *Sync) waitForConfirmations() error {
+	m := s.confirmations
+	if m == nil {
+		return nil
+	}
+	m.mux.Lock()
+	m.closed = true
+	pending := len(m.pending)
+	settled := pending == 0 && m.republishes == 0
+	m.mux.Unlock()
+	if settled {
+		m.grp.Stop()
+	} else {
+		s.logger().Printf("waiting for the confirmation of %d claims", pending)
+	}
+	return m.grp.WaitErr()
+}
+
+// monitorConfirmations checks the confirmations of the pending claims until they are all settled and the workers are
+// done
+func (s *Sync) monitorConfirmations() error {
+	m := s.confirmations
+	ticker := time.NewTicker(confirmationPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.grp.Ch():
+			return nil
+		case <-ticker.C:
+		}
+
+		err := s.checkConfirmations()
+		if err != nil {
+			return err
+		}
+
+		m.mux.Lock()
+		done := m.closed && len(m.pending) == 0 && m.republishes == 0
+		m.mux.Unlock()
+		if done {
+			return nil
+		}
+	}
+}
+
+// checkConfirmations marks the videos whose claim is confirmed as published, and publishes the ones that timed out
+// again. It returns an error if the sync has to stop.
+func (s *Sync) checkConfirmations() error {
+	m := s.confirmations
+	m.mux.Lock()
+	pending := len(m.pending)
+	m.mux.Unlock()
+	if pending == 0 {
+		return nil
+	}
+
+	depths := make(map[string]int)
+	claims, err := s.daemon.ClaimListMine()
+	if err != nil {
+		s.logger().Warnf("could not check the confirmations of the published claims: %s", err.Error())
+	} else {
+		for _, c := range *claims {
+			depths[c.Txid] = c.Depth
+		}
+	}
+
+	timeout := s.Manager.ConfirmationTimeout
+	if timeout <= 0 {
+		timeout = defaultConfirmationTimeout
+	}
+	var confirmed, expired []*pendingClaim
+	m.mux.Lock()
+	for txid, p := range m.pending {
+		if depth, ok := depths[txid]; ok && depth >= s.Manager.Confirmations {
+			confirmed = append(confirmed, p)
+			delete(m.pending, txid)
+		} else if err == nil && time.Since(p.published) > timeout {
+			expired = append(expired, p)
+			delete(m.pending, txid)
+		}
+	}
+	m.mux.Unlock()
+
+	for _, p := range confirmed {
+		err := s.markPublished(p.video, p.summary, p.started)
+		if err != nil {
+			s.notifyVideoError("Failed to mark video %s as published: %s", p.video.ID(), err.Error())
+		}
+	}
+	for _, p := range expired {
+		err := s.republish(p, timeout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// republish publishes a video whose claim didn't confirm in time again, unless it was already published MaxTries times
+func (s *Sync) republish(p *pendingClaim, timeout time.Duration) error {
+	vlog := s.videoLogger(p.video.ID(), p.publishes)
+	vlog.Warnf("the claim of %s (%s) wasn't confirmed after %s", p.video.ID(), p.summary.Txid, timeout.String())
+	// the transaction may still make it into a block, in which case the video would be published twice
+	_, err := s.daemon.ClaimAbandon(p.summary.ClaimID)
+	if err != nil {
+		vlog.Warnf("could not abandon the unconfirmed claim %s: %s", p.summary.ClaimID, err.Error())
+	}
+
+	failure := &retry.Error{
+		Err:      errors.Err("%s: %s was not confirmed after %s", reasonUnconfirmed, p.summary.Txid, timeout.String()),
+		Class:    retry.Transient,
+		Reason:   reasonUnconfirmed,
+		Attempts: p.publishes,
+	}
+	if p.publishes >= s.MaxTries || s.StopOnError {
+		stopErr := s.handleVideoFailure(p.video, p.started, failure)
+		if stopErr != nil {
+			s.grp.Stop()
+		}
+		return stopErr
+	}
+
+	if s.Manager.localDB != nil {
+		// the video isn't published as far as the next syncs are concerned, until it's published again
+		err = s.Manager.localDB.SetFailed(s.YoutubeChannelID, p.video.ID(), failure.Error())
+		if err != nil {
+			s.notifyVideoError("Failed to mark video on the local db: %s", err.Error())
+		}
+	}
+	m := s.confirmations
+	m.mux.Lock()
+	m.republishes++
+	m.mux.Unlock()
+	m.grp.GoErr(func() error {
+		defer func() {
+			m.mux.Lock()
+			m.republishes--
+			m.mux.Unlock()
+		}()
+		attempt := 0
+		err := s.videoRetryPolicy().Do(m.grp.Ch(), func() error {
+			attempt++
+			err := s.processVideo(p.video, attempt)
+			if err != nil && !errors.Is(err, util.ErrWaitCancelled) {
+				s.videoLogger(p.video.ID(), attempt).Errorln("error publishing the video again: " + err.Error())
+			}
+			return err
+		})
+		if err == nil || errors.Is(err, util.ErrWaitCancelled) {
+			return nil
+		}
+		stopErr := s.handleVideoFailure(p.video, p.started, err.(*retry.Error))
+		if stopErr != nil {
+			s.grp.Stop()
+		}
+		return stopErr
+	})
+	return nil
+}
+
+// markPublished records a video as published, once its claim is confirmed if the sync waits for confirmations
+func (s *Sync) markPublished(v video, summary *sources.SyncSummary, started time.Time) error {
+	err := s.Manager.APIConfig.MarkVideoStatus(s.YoutubeChannelID, v.ID(), sdk.VideoStatusPublished, summary.ClaimID, summary.ClaimName, "")
+	if err != nil {
+		return err
+	}
+	s.AppendSyncedVideo(v.ID(), true, "")
+	s.stats.publish(v.ID(), summary.Amount+summary.Fee)
+	s.reportProgress(v.ID(), ProgressConfirmed, started, nil)
+	if s.Manager.DeleteBlobs {
+		s.cleanupBlobs(summary.ClaimID)
+	}
+	return nil
+}
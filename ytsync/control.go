@@ -0,0 +1,49 @@
+package ytsync
+
+import (
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+)
+
+// forcedFailureReason is the failure reason of the videos failed through the status server. They are never retried.
+const forcedFailureReason = "failed by an operator"
+
+// Pause stops the workers of the channel from starting on new videos. The videos they are processing are finished.
+// It returns false if the channel was already paused.
+func (s *Sync) Pause() bool {
+	if s.grp == nil {
+		return false
+	}
+	return s.grp.Pause()
+}
+
+// Resume lets the workers of a paused channel carry on. It returns false if the channel was not paused.
+func (s *Sync) Resume() bool {
+	if s.grp == nil {
+		return false
+	}
+	return s.grp.Resume()
+}
+
+// IsPaused returns true while the channel, or the whole node, is paused
+func (s *Sync) IsPaused() bool {
+	return s.grp != nil && s.grp.IsPaused()
+}
+
+// syncedVideo returns what the API reported about the video when the sync started, along with the changes made since
+func (s *Sync) syncedVideo(videoID string) (sdk.SyncedVideo, bool) {
+	s.syncedVideosMux.Lock()
+	defer s.syncedVideosMux.Unlock()
+	sv, ok := s.syncedVideos[videoID]
+	return sv, ok
+}
+
+// forceFail makes the sync skip the video from now on. A video that is being processed finishes its current attempt.
+// If the sync didn't get the synced videos from the API yet, they will include the video once it's marked there.
+func (s *Sync) forceFail(videoID, reason string) {
+	s.syncedVideosMux.Lock()
+	defer s.syncedVideosMux.Unlock()
+	if s.syncedVideos == nil {
+		return
+	}
+	s.syncedVideos[videoID] = sdk.SyncedVideo{VideoID: videoID, FailureReason: reason}
+}
@@ -5,13 +5,26 @@ import (
 
 	"github.com/lbryio/lbry.go/errors"
 
-	"google.golang.org/api/googleapi/transport"
 	"google.golang.org/api/youtube/v3"
 )
 
+// CountVideos returns the number of videos in the channel, or in the playlist if one is set. If the API quota is used up and the RSS fallback is
+// enabled, it returns the number of videos in the channel feed, since those are the only ones that will be synced.
 func (s *Sync) CountVideos() (uint64, error) {
+	count, err := s.countYoutubeAPIVideos()
+	if err != nil && isQuotaError(err) && s.Manager != nil && s.Manager.QuotaFallbackRSS {
+		videos, err := s.fetchRSSVideos()
+		if err != nil {
+			return 0, err
+		}
+		return uint64(len(videos)), nil
+	}
+	return count, err
+}
+
+func (s *Sync) countYoutubeAPIVideos() (uint64, error) {
 	client := &http.Client{
-		Transport: &transport.APIKey{Key: s.YoutubeAPIKey},
+		Transport: s.youtubeTransport(),
 	}
 
 	service, err := youtube.New(client)
@@ -19,9 +32,24 @@ func (s *Sync) CountVideos() (uint64, error) {
 		return 0, errors.Prefix("error creating YouTube service", err)
 	}
 
+	err = s.useQuota(listCost)
+	if err != nil {
+		return 0, err
+	}
+	if s.YoutubePlaylistID != "" {
+		response, err := service.Playlists.List("contentDetails").Id(s.YoutubePlaylistID).Do()
+		if err != nil {
+			return 0, errors.Prefix("error getting playlists", s.youtubeQuota().Observe(err))
+		}
+		if len(response.Items) < 1 {
+			return 0, errors.Err("youtube playlist not found")
+		}
+		return uint64(response.Items[0].ContentDetails.ItemCount), nil
+	}
+
 	response, err := service.Channels.List("statistics").Id(s.YoutubeChannelID).Do()
 	if err != nil {
-		return 0, errors.Prefix("error getting channels", err)
+		return 0, errors.Prefix("error getting channels", s.youtubeQuota().Observe(err))
 	}
 
 	if len(response.Items) < 1 {
@@ -0,0 +1,211 @@
+// Package credits keeps the wallet a sync publishes from funded. It checks the balance before credits are spent,
+// requests refills from a source of credits when the balance is too low and waits for them to show up in the wallet.
+package credits
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Wallet is the wallet credits are spent from
+type Wallet interface {
+	// Balance returns the credits the wallet can spend
+	Balance() (float64, error)
+	// UnusedAddress returns an address of the wallet credits can be sent to
+	UnusedAddress() (string, error)
+}
+
+// Source is where credits come from when the wallet runs low
+type Source interface {
+	// Send sends amount credits to address and returns the ID of the transaction
+	Send(address string, amount float64) (string, error)
+}
+
+// SourceFunc lets a function be used as a Source
+type SourceFunc func(address string, amount float64) (string, error)
+
+func (f SourceFunc) Send(address string, amount float64) (string, error) { return f(address, amount) }
+
+const (
+	defaultPollInterval   = 5 * time.Second
+	defaultConfirmTimeout = 5 * time.Minute
+	minRefill             = 1.0 // no reason to bother adding less than 1 credit
+)
+
+// ErrNotConfirmed is returned when a refill didn't show up in the wallet in time
+var ErrNotConfirmed = errors.Base("refill not seen by the wallet in time")
+
+// Manager keeps a wallet funded
+type Manager struct {
+	Wallet Wallet
+	Source Source
+
+	// Threshold is the balance below which the wallet is refilled before credits are spent
+	Threshold float64
+	// RefillAmount is the least a refill adds. Refills add more if that's what it takes to reach the needed balance.
+	RefillAmount float64
+	// PollInterval is how often the balance is checked while waiting for a refill. Defaults to 5 seconds.
+	PollInterval time.Duration
+	// ConfirmTimeout is how long to wait for a refill to show up in the wallet. Defaults to 5 minutes.
+	ConfirmTimeout time.Duration
+	// OnBalance, if set, is called with every balance read from the wallet
+	OnBalance func(balance float64)
+
+	mux sync.Mutex
+}
+
+// Balance returns the current balance of the wallet
+func (m *Manager) Balance() (float64, error) {
+	balance, err := m.Wallet.Balance()
+	if err != nil {
+		return 0, err
+	}
+	if m.OnBalance != nil {
+		m.OnBalance(balance)
+	}
+	return balance, nil
+}
+
+// BeforeSpending makes sure the wallet holds at least amount credits, and at least Threshold credits, refilling it if
+// needed. It's meant to be called before every publish. It returns how many credits were added.
+func (m *Manager) BeforeSpending(amount float64) (float64, error) {
+	needed := amount
+	if m.Threshold > needed {
+		needed = m.Threshold
+	}
+	return m.Ensure(needed)
+}
+
+// Ensure makes sure the wallet holds at least needed credits. If it doesn't, the difference is requested from the
+// source, or RefillAmount if that's more. It returns how many credits were added.
+func (m *Manager) Ensure(needed float64) (float64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	balance, err := m.Balance()
+	if err != nil {
+		return 0, err
+	}
+	if balance >= needed {
+		return 0, nil
+	}
+
+	amount := needed - balance
+	if amount < m.RefillAmount {
+		amount = m.RefillAmount
+	}
+	return amount, m.add(balance, amount)
+}
+
+// Add sends amount credits to the wallet and waits until it sees them
+func (m *Manager) Add(amount float64) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	balance, err := m.Balance()
+	if err != nil {
+		return err
+	}
+	return m.add(balance, amount)
+}
+
+func (m *Manager) add(balance, amount float64) error {
+	if m.Source == nil {
+		return errors.Err("the wallet needs %.2f more credits and no refill source is set", amount)
+	}
+	if amount < minRefill {
+		amount = minRefill
+	}
+
+	address, err := m.Wallet.UnusedAddress()
+	if err != nil {
+		return err
+	}
+	log.Printf("Adding %f credits", amount)
+	txid, err := m.Source.Send(address, amount)
+	if err != nil {
+		return errors.Prefix("refill failed", err)
+	}
+	log.Printf("Refill sent in %s, waiting for the wallet to see it", txid)
+	return m.waitForBalance(balance + amount)
+}
+
+// waitForBalance waits until the wallet holds at least target credits
+func (m *Manager) waitForBalance(target float64) error {
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	timeout := m.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+
+	// a fraction of a credit may have been spent on fees in the meantime
+	const tolerance = 0.01
+	err := util.Poll(interval, timeout, func() (bool, error) {
+		balance, err := m.Balance()
+		if err != nil {
+			return false, err
+		}
+		return balance >= target-tolerance, nil
+	})
+	if errors.Is(err, util.ErrPollTimeout) {
+		return errors.Err(ErrNotConfirmed)
+	}
+	return err
+}
+
+// Faucet is a Source that requests credits from an HTTP API. It POSTs {"address": ..., "amount": ...} to URL and
+// expects {"txid": ...} back.
+type Faucet struct {
+	URL   string
+	Token string // sent as a bearer token, if set
+
+	Client *http.Client // defaults to a client with a 30 second timeout
+}
+
+func (f *Faucet) Send(address string, amount float64) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{"address": address, "amount": amount})
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, f.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.Token)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return "", errors.Err("faucet answered with status code %d", res.StatusCode)
+	}
+
+	var response struct {
+		TxID string `json:"txid"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&response)
+	if err != nil {
+		return "", errors.Prefix("invalid faucet response", err)
+	}
+	return response.TxID, nil
+}
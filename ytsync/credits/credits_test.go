@@ -0,0 +1,225 @@
+package credits
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// fakeWallet sees the credits sent to it after a number of balance checks
+type fakeWallet struct {
+	mux        sync.Mutex
+	balance    float64
+	pending    float64
+	delay      int // how many balance checks before pending credits show up
+	checks     int
+	failedRead error
+}
+
+func (w *fakeWallet) Balance() (float64, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	if w.failedRead != nil {
+		return 0, w.failedRead
+	}
+	if w.pending > 0 {
+		w.checks++
+		if w.checks > w.delay {
+			w.balance += w.pending
+			w.pending = 0
+			w.checks = 0
+		}
+	}
+	return w.balance, nil
+}
+
+func (w *fakeWallet) UnusedAddress() (string, error) { return "bAddress", nil }
+
+type fakeSource struct {
+	wallet *fakeWallet
+	sent   []float64
+	err    error
+}
+
+func (s *fakeSource) Send(address string, amount float64) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.sent = append(s.sent, amount)
+	s.wallet.mux.Lock()
+	s.wallet.pending += amount
+	s.wallet.mux.Unlock()
+	return "txid", nil
+}
+
+func newTestManager(balance float64, delay int) (*Manager, *fakeWallet, *fakeSource) {
+	w := &fakeWallet{balance: balance, delay: delay}
+	s := &fakeSource{wallet: w}
+	m := &Manager{Wallet: w, Source: s, PollInterval: time.Millisecond, ConfirmTimeout: time.Second}
+	return m, w, s
+}
+
+func TestEnsureEnoughCredits(t *testing.T) {
+	m, _, s := newTestManager(10, 0)
+	added, err := m.Ensure(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 0 || len(s.sent) != 0 {
+		t.Errorf("nothing should have been sent, sent %v", s.sent)
+	}
+}
+
+func TestEnsureRefillsDifference(t *testing.T) {
+	m, w, s := newTestManager(2, 3)
+	added, err := m.Ensure(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 8 || len(s.sent) != 1 || s.sent[0] != 8 {
+		t.Errorf("expected 8 credits to be sent, sent %v", s.sent)
+	}
+	if w.balance != 10 {
+		t.Errorf("expected the refill to be in the wallet, balance is %v", w.balance)
+	}
+}
+
+func TestEnsureRefillAmount(t *testing.T) {
+	m, _, s := newTestManager(9, 0)
+	m.RefillAmount = 20
+	added, err := m.Ensure(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 20 || s.sent[0] != 20 {
+		t.Errorf("expected a refill of at least RefillAmount, sent %v", s.sent)
+	}
+}
+
+func TestMinimumRefill(t *testing.T) {
+	m, _, s := newTestManager(9.9, 0)
+	if _, err := m.Ensure(10); err != nil {
+		t.Fatal(err)
+	}
+	if s.sent[0] != minRefill {
+		t.Errorf("expected a refill of %v, sent %v", minRefill, s.sent)
+	}
+}
+
+func TestBeforeSpendingThreshold(t *testing.T) {
+	m, _, s := newTestManager(3, 0)
+	m.Threshold = 5
+	added, err := m.BeforeSpending(0.11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 || s.sent[0] != 2 {
+		t.Errorf("expected the wallet to be topped up to the threshold, sent %v", s.sent)
+	}
+
+	added, err = m.BeforeSpending(0.11)
+	if err != nil || added != 0 {
+		t.Errorf("expected no refill above the threshold, added %v (%v)", added, err)
+	}
+}
+
+func TestRefillNotConfirmed(t *testing.T) {
+	m, _, _ := newTestManager(0, 1000)
+	m.ConfirmTimeout = 20 * time.Millisecond
+	_, err := m.Ensure(5)
+	if !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed, got %v", err)
+	}
+}
+
+func TestNoSource(t *testing.T) {
+	m, _, _ := newTestManager(0, 0)
+	m.Source = nil
+	if _, err := m.Ensure(5); err == nil {
+		t.Error("expected an error without a refill source")
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	m, _, s := newTestManager(0, 0)
+	s.err = errors.Base("insufficient funds")
+	if _, err := m.Ensure(5); err == nil {
+		t.Error("expected the source error")
+	}
+}
+
+func TestOnBalance(t *testing.T) {
+	m, _, _ := newTestManager(0, 1)
+	var seen []float64
+	m.OnBalance = func(b float64) { seen = append(seen, b) }
+	if _, err := m.Ensure(5); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) < 2 || seen[0] != 0 || seen[len(seen)-1] != 5 {
+		t.Errorf("expected every balance read to be reported, got %v", seen)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	m, w, s := newTestManager(1, 1)
+	if err := m.Add(3); err != nil {
+		t.Fatal(err)
+	}
+	if s.sent[0] != 3 || w.balance != 4 {
+		t.Errorf("expected 3 credits to be added, sent %v, balance %v", s.sent, w.balance)
+	}
+}
+
+func TestConcurrentEnsureRefillsOnce(t *testing.T) {
+	m, _, s := newTestManager(0, 2)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Ensure(5); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(s.sent) != 1 {
+		t.Errorf("expected a single refill, got %v", s.sent)
+	}
+}
+
+func TestFaucet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Address string  `json:"address"`
+			Amount  float64 `json:"amount"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Address != "bAddress" || req.Amount != 2.5 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"txid": "abc"}`))
+	}))
+	defer server.Close()
+
+	txid, err := (&Faucet{URL: server.URL, Token: "secret"}).Send("bAddress", 2.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txid != "abc" {
+		t.Errorf("expected txid abc, got %s", txid)
+	}
+
+	if _, err := (&Faucet{URL: server.URL}).Send("bAddress", 2.5); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
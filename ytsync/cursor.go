@@ -0,0 +1,85 @@
+package ytsync
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+)
+
+const cursorFile = "queue_cursor"
+
+// queueCursor remembers the last channel synced from the jobs queue so that the next cycle carries on from there
+// instead of starting at the top of the queue again, which would starve the channels further down.
+// A cursor without a state dir does nothing.
+type queueCursor struct {
+	path string
+}
+
+func newQueueCursor(stateDir string) *queueCursor {
+	if stateDir == "" {
+		return &queueCursor{}
+	}
+	return &queueCursor{path: filepath.Join(stateDir, cursorFile)}
+}
+
+// Load returns the ID of the last channel that was picked up, or an empty string if there is none
+func (c *queueCursor) Load() (string, error) {
+	if c.path == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Err(err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Save moves the cursor to the given channel
+func (c *queueCursor) Save(channelID string) error {
+	if c.path == "" {
+		return nil
+	}
+	tmp := c.path + ".tmp"
+	err := ioutil.WriteFile(tmp, []byte(channelID), 0644)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(os.Rename(tmp, c.path))
+}
+
+// Reset moves the cursor back to the top of the queue
+func (c *queueCursor) Reset() error {
+	if c.path == "" {
+		return nil
+	}
+	err := os.Remove(c.path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Err(err)
+	}
+	return nil
+}
+
+// channelsAfter returns the channels whose ID sorts after the given one, in the order they were fetched. The queue is
+// ordered by channel ID, so that's where the previous cycle stopped even if that channel left the queue since, as the
+// ones that were synced do. If no channel sorts after it, all channels are returned.
+func channelsAfter(channels []sdk.YoutubeChannel, channelID string) []sdk.YoutubeChannel {
+	if channelID == "" {
+		return channels
+	}
+	var after []sdk.YoutubeChannel
+	for _, c := range channels {
+		if c.ChannelId > channelID {
+			after = append(after, c)
+		}
+	}
+	if len(after) == 0 {
+		return channels
+	}
+	return after
+}
@@ -0,0 +1,76 @@
+package ytsync
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+)
+
+func channelIDs(channels []sdk.YoutubeChannel) string {
+	ids := make([]string, len(channels))
+	for i, c := range channels {
+		ids[i] = c.ChannelId
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestChannelsAfter(t *testing.T) {
+	channels := []sdk.YoutubeChannel{{ChannelId: "UCa"}, {ChannelId: "UCc"}, {ChannelId: "UCe"}}
+	tests := []struct {
+		cursor   string
+		expected string
+	}{
+		{"", "UCa,UCc,UCe"},
+		{"UCa", "UCc,UCe"},
+		{"UCc", "UCe"},
+		{"UCe", "UCa,UCc,UCe"},
+		// the channel of the cursor was synced and left the queue
+		{"UCb", "UCc,UCe"},
+		{"UCd", "UCe"},
+		{"UCf", "UCa,UCc,UCe"},
+	}
+	for _, test := range tests {
+		if after := channelIDs(channelsAfter(channels, test.cursor)); after != test.expected {
+			t.Errorf("after %q: expected %s, got %s", test.cursor, test.expected, after)
+		}
+	}
+}
+
+func TestQueueCursor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := newQueueCursor(dir)
+
+	if id, err := c.Load(); err != nil || id != "" {
+		t.Fatalf("expected no cursor, got %q (%v)", id, err)
+	}
+	if err := c.Save("UCc"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := newQueueCursor(dir).Load(); err != nil || id != "UCc" {
+		t.Errorf("expected the saved cursor, got %q (%v)", id, err)
+	}
+	if err := c.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := c.Load(); err != nil || id != "" {
+		t.Errorf("expected the cursor to be reset, got %q (%v)", id, err)
+	}
+	if err := c.Reset(); err != nil {
+		t.Errorf("expected resetting twice to work, got %v", err)
+	}
+
+	noState := newQueueCursor("")
+	if err := noState.Save("UCc"); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := noState.Load(); id != "" {
+		t.Errorf("expected a cursor without a state dir to do nothing, got %q", id)
+	}
+}
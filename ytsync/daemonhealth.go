@@ -0,0 +1,49 @@
+package ytsync
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/autoscale"
+)
+
+// slowDaemonCall is how long a call to the daemon may take before it's a sign the daemon is struggling
+const slowDaemonCall = 10 * time.Second
+
+// transferMethods upload or download a file, they take as long as the file is big. Only their failures count.
+var transferMethods = util.NewStringSet("publish", "get", "blob_get")
+
+// startAutoscaling scales the videos processed at once between 1 and MaxConcurrentVideos with how well the daemon
+// answers, starting at ConcurrentVideos. It returns how many workers to start.
+func (s *Sync) startAutoscaling() int {
+	if s.Manager == nil || s.Manager.MaxConcurrentVideos <= s.ConcurrentVideos {
+		return s.ConcurrentVideos
+	}
+	s.concurrency = autoscale.New(s.ConcurrentVideos, s.Manager.MaxConcurrentVideos)
+	s.concurrency.OnChange = func(limit int) {
+		s.logger().Infof("processing %d videos at once", limit)
+	}
+	s.daemon.SetObserver(s.observeDaemonCall)
+	return s.Manager.MaxConcurrentVideos
+}
+
+// observeDaemonCall counts the calls that time out, can't reach the daemon or answer slowly against it
+func (s *Sync) observeDaemonCall(method string, took time.Duration, err error) {
+	bad := errors.CategoryOf(err) == errors.Network || (took > slowDaemonCall && !transferMethods.Has(method))
+	s.concurrency.Observe(bad)
+}
+
+// acquireVideoSlot waits until the worker may process a video. It returns false if the sync stopped meanwhile.
+func (s *Sync) acquireVideoSlot() bool {
+	if s.concurrency == nil {
+		return true
+	}
+	return s.concurrency.Acquire(s.grp.Ch())
+}
+
+func (s *Sync) releaseVideoSlot() {
+	if s.concurrency != nil {
+		s.concurrency.Release()
+	}
+}
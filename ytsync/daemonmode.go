@@ -0,0 +1,139 @@
+package ytsync
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/api"
+	"github.com/lbryio/lbry.go/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPollInterval is how long the manager waits before asking the API for channels again when there was nothing to
+// sync
+const defaultPollInterval = 5 * time.Minute
+
+// serviceHealth keeps track of the polling loop so it can be reported on the health endpoint
+type serviceHealth struct {
+	mux       sync.RWMutex
+	startedAt time.Time
+	lastPoll  time.Time
+	lastError string
+	stopping  bool
+}
+
+type healthStatus struct {
+	Status          string     `json:"status"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastPoll        *time.Time `json:"last_poll,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	RunningChannels int        `json:"running_channels"`
+}
+
+func newServiceHealth() *serviceHealth {
+	return &serviceHealth{startedAt: time.Now()}
+}
+
+// polled records the outcome of a request for channels to the API
+func (h *serviceHealth) polled(err error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.lastPoll = time.Now()
+	h.lastError = ""
+	if err != nil {
+		h.lastError = err.Error()
+	}
+}
+
+func (h *serviceHealth) setStopping() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.stopping = true
+}
+
+// healthHandler answers 200 while the manager is running and 503 once it's shutting down
+func (s SyncManager) healthHandler(r *http.Request) api.Response {
+	s.health.mux.RLock()
+	status := healthStatus{
+		Status:          "ok",
+		StartedAt:       s.health.startedAt,
+		LastError:       s.health.lastError,
+		RunningChannels: len(s.running.list()),
+	}
+	if !s.health.lastPoll.IsZero() {
+		lastPoll := s.health.lastPoll
+		status.LastPoll = &lastPoll
+	}
+	stopping := s.health.stopping
+	s.health.mux.RUnlock()
+
+	if stopping {
+		return api.Response{Error: errors.Err(api.StatusError{Status: http.StatusServiceUnavailable, Err: errors.Base("shutting down")})}
+	}
+	return api.Response{Data: status}
+}
+
+// pollInterval returns how long to wait between polls of the API. With an Interval, that's how long to wait before
+// going over the channels again.
+func (s SyncManager) pollInterval() time.Duration {
+	if s.Interval > 0 {
+		return s.Interval
+	}
+	if s.PollInterval > 0 {
+		return s.PollInterval
+	}
+	return defaultPollInterval
+}
+
+// waitForNextPoll sleeps for the poll interval, or until a poll is requested through the status server. It returns false
+// if the manager was stopped in the meantime.
+func (s SyncManager) waitForNextPoll() bool {
+	t := time.NewTimer(s.pollInterval())
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.pollNow:
+		log.Infoln("polling the API now, as requested through the status server")
+		return true
+	case <-s.grp.Ch():
+		return false
+	}
+}
+
+// isStopping returns true once the manager was asked to stop
+func (s SyncManager) isStopping() bool {
+	select {
+	case <-s.grp.Ch():
+		return true
+	default:
+		return false
+	}
+}
+
+// watchShutdown reports the shutdown of the manager once StopGroup is stopped. The manager and the channel syncs stop
+// with it on their own, after their current publishes.
+func (s SyncManager) watchShutdown() (stopWatching func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-s.StopGroup.Ch():
+			SendInfoToSlack("Shutting down after the current publishes")
+			s.health.setStopping()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// pollLater reports an error that happened while looking for channels to sync and waits for the next poll. It returns
+// false if the manager was stopped in the meantime.
+func (s SyncManager) pollLater(err error) bool {
+	s.health.polled(err)
+	SendErrorToSlack("%s. Trying again in %s", err.Error(), s.pollInterval())
+	return s.waitForNextPoll()
+}
@@ -0,0 +1,101 @@
+package ytsync
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+
+	"github.com/mitchellh/go-ps"
+)
+
+const (
+	// daemonStartTimeout is how long a daemon has to get its wallet ready before it's considered unavailable
+	daemonStartTimeout = 15 * time.Minute
+	// daemonCooldown is how long a daemon that was unavailable is left alone before it's tried again
+	daemonCooldown = 10 * time.Minute
+)
+
+// errDaemonUnavailable is returned when the daemon of a slot can't be started
+var errDaemonUnavailable = errors.New(errors.Daemon, "unavailable", "the daemon is unavailable")
+
+// daemonSlot identifies the lbrynet daemon a channel is synced with. Slot 0 is the regular lbrynet.service daemon,
+// which keeps its wallet in $HOME and listens on the default port. Every other slot n is expected to be an instance of
+// the lbrynet@.service template unit that runs with HOME set to $HOME/slots/n and listens on the default port + n.
+// Only one channel can use a slot at a time since the wallet of the channel is swapped in and out of it.
+type daemonSlot int
+
+func (d daemonSlot) unit() string {
+	if d == 0 {
+		return "lbrynet.service"
+	}
+	return "lbrynet@" + strconv.Itoa(int(d)) + ".service"
+}
+
+// home returns the home directory of the daemon
+func (d daemonSlot) home() string {
+	if d == 0 {
+		return os.Getenv("HOME")
+	}
+	return os.Getenv("HOME") + "/slots/" + strconv.Itoa(int(d))
+}
+
+// address returns the address of the daemon's API. An empty string means the default one.
+func (d daemonSlot) address() string {
+	if d == 0 {
+		return ""
+	}
+	return "http://localhost:" + strconv.Itoa(jsonrpc.DefaultPort+int(d))
+}
+
+// daemonAddress returns the address of the API of the daemon of the slot, taken from DaemonURLs if it's set
+func (s SyncManager) daemonAddress(slot daemonSlot) string {
+	if int(slot) < len(s.DaemonURLs) {
+		return s.DaemonURLs[slot]
+	}
+	return slot.address()
+}
+
+// daemonSlots returns how many daemons channels can be synced with at the same time
+func (s SyncManager) daemonSlots() int {
+	if len(s.DaemonURLs) > 0 {
+		return len(s.DaemonURLs)
+	}
+	if s.ConcurrentChannels < 1 {
+		return 1
+	}
+	return s.ConcurrentChannels
+}
+
+// pid returns the process ID of the running daemon, or -1 if it isn't running
+func (d daemonSlot) pid() (int, error) {
+	if d == 0 {
+		processes, err := ps.Processes()
+		if err != nil {
+			return -1, err
+		}
+		for _, p := range processes {
+			if p.Executable() == "lbrynet-daemon" {
+				return p.Pid(), nil
+			}
+		}
+		return -1, nil
+	}
+
+	out, err := exec.Command("/bin/systemctl", "show", "--property=MainPID", d.unit()).Output()
+	if err != nil {
+		return -1, errors.Err(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(string(out)), "MainPID="))
+	if err != nil {
+		return -1, errors.Err(err)
+	}
+	if pid == 0 {
+		return -1, nil
+	}
+	return pid, nil
+}
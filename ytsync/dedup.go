@@ -0,0 +1,43 @@
+package ytsync
+
+import (
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// localDeduplicator finds the files that were published before in the content index of the local state DB. The index
+// covers every channel synced on this server.
+type localDeduplicator struct {
+	db *localdb.DB
+}
+
+func (d localDeduplicator) Original(contentHash string) (sources.Original, bool, error) {
+	c, ok, err := d.db.ContentByHash(contentHash)
+	return sources.Original{ClaimID: c.ClaimID, ClaimName: c.ClaimName}, ok, err
+}
+
+// deduplicator returns what finds the duplicates among the videos of the channel, or nil if there is no local state DB
+// to keep the content index in
+func (s *Sync) deduplicator() sources.Deduplicator {
+	if s.Manager.localDB == nil {
+		return nil
+	}
+	return localDeduplicator{db: s.Manager.localDB}
+}
+
+// indexContent adds a published video to the content index, so it's recognized if another channel uploads it too.
+// Reposts are not indexed, the original claim is.
+func (s *Sync) indexContent(videoID string, summary *sources.SyncSummary) {
+	if s.Manager.localDB == nil || summary.ContentHash == "" || summary.RepostOf != "" {
+		return
+	}
+	err := s.Manager.localDB.SetContent(summary.ContentHash, localdb.Content{
+		ChannelID: s.YoutubeChannelID,
+		VideoID:   videoID,
+		ClaimID:   summary.ClaimID,
+		ClaimName: summary.ClaimName,
+	})
+	if err != nil {
+		s.notifyVideoError("Failed to index the content of video %s on the local db: %s", videoID, err.Error())
+	}
+}
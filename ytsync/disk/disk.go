@@ -0,0 +1,213 @@
+// Package disk keeps a sync from filling up the disk it downloads to. Space is reserved for each video before it's
+// downloaded, and downloads wait while the disk is fuller than a watermark instead of failing.
+package disk
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultHighWatermark = 0.9
+	defaultPollInterval  = time.Minute
+)
+
+// Usage is how much of a disk is used
+type Usage struct {
+	Total      uint64 // bytes
+	Free       uint64 // bytes
+	Inodes     uint64 // 0 if the filesystem doesn't report them
+	FreeInodes uint64
+}
+
+// Used returns the used fraction of the disk, between 0 and 1
+func (u Usage) Used() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return float64(u.Total-u.Free) / float64(u.Total)
+}
+
+// InodesUsed returns the used fraction of the inodes of the disk, between 0 and 1. Every blob takes one, so a disk
+// full of small blobs can run out of them with plenty of space left.
+func (u Usage) InodesUsed() float64 {
+	return util.DiskUsage{Inodes: u.Inodes, FreeInodes: u.FreeInodes}.UsedInodeFraction()
+}
+
+// GetUsage returns the usage of the disk that holds path
+func GetUsage(path string) (Usage, error) {
+	usage, err := util.GetDiskUsage(path)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Total: usage.Total, Free: usage.Free, Inodes: usage.Inodes, FreeInodes: usage.FreeInodes}, nil
+}
+
+// Manager hands out space on the disk holding Dir
+type Manager struct {
+	Dir string
+
+	// HighWatermark is the used fraction of the disk, reservations included, above which no more space is handed out.
+	// Defaults to 0.9. At 1 or more, space is always handed out.
+	HighWatermark float64
+	// LowWatermark is the used fraction the disk has to go back under before space is handed out again, once the high
+	// watermark was crossed. Defaults to the high watermark.
+	LowWatermark float64
+	// PollInterval is how often the disk is checked while waiting for space. Defaults to a minute.
+	PollInterval time.Duration
+	// OnUsage, if set, is called with the used fraction of the disk every time it's checked
+	OnUsage func(used float64)
+	// OnPause, if set, is called when reservations start waiting for space, and with 0 once they stop waiting
+	OnPause func(used float64)
+
+	mux          sync.Mutex
+	reservations map[string]*Reservation
+	reserved     uint64
+	paused       bool
+	usage        func(path string) (Usage, error)
+}
+
+// Reservation is space set aside for a video until it's released
+type Reservation struct {
+	m     *Manager
+	id    string
+	size  uint64
+	freed bool
+}
+
+// Release hands the space back. It's safe to call more than once, and on a nil Reservation.
+func (r *Reservation) Release() {
+	if r == nil {
+		return
+	}
+	r.m.mux.Lock()
+	defer r.m.mux.Unlock()
+	if r.freed {
+		return
+	}
+	r.freed = true
+	r.m.reserved -= r.size
+	delete(r.m.reservations, r.id)
+}
+
+// Reserved returns the bytes set aside by reservations that were not released yet
+func (m *Manager) Reserved() uint64 {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.reserved
+}
+
+// Reserve sets size bytes aside for the video with the given id, waiting until the disk has room for them. If the
+// video already holds a reservation, that one is returned. It returns util.ErrWaitCancelled if stop is closed while
+// waiting.
+func (m *Manager) Reserve(id string, size uint64, stop <-chan struct{}) (*Reservation, error) {
+	for {
+		r, used, err := m.tryReserve(id, size)
+		if err != nil || r != nil {
+			return r, err
+		}
+		log.Warnf("disk holding %s is %.1f%% used (reservations included), waiting for space to download %s", m.Dir, used*100, id)
+
+		t := time.NewTimer(m.pollInterval())
+		select {
+		case <-t.C:
+		case <-stop:
+			t.Stop()
+			return nil, errors.Err(util.ErrWaitCancelled)
+		}
+	}
+}
+
+// Wait waits until the disk is under the watermark. It returns util.ErrWaitCancelled if stop is closed first.
+func (m *Manager) Wait(stop <-chan struct{}) error {
+	r, err := m.Reserve("", 0, stop)
+	r.Release()
+	return err
+}
+
+// tryReserve reserves the space if there is room for it. It returns a nil reservation and the used fraction of the
+// disk, reservations included, if there isn't. A disk running out of inodes is as full as its used fraction of them.
+func (m *Manager) tryReserve(id string, size uint64) (*Reservation, float64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if r, ok := m.reservations[id]; ok && id != "" {
+		return r, 0, nil
+	}
+
+	getUsage := m.usage
+	if getUsage == nil {
+		getUsage = GetUsage
+	}
+	usage, err := getUsage(m.Dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	if m.OnUsage != nil {
+		m.OnUsage(usage.Used())
+	}
+
+	used := 1.0
+	if usage.Total > 0 {
+		used = float64(usage.Total-usage.Free+m.reserved+size) / float64(usage.Total)
+	}
+	if inodes := usage.InodesUsed(); inodes > used {
+		used = inodes
+	}
+	limit := m.highWatermark()
+	if m.paused {
+		limit = m.lowWatermark()
+	}
+	if used > limit && limit < 1 {
+		if !m.paused {
+			m.paused = true
+			if m.OnPause != nil {
+				m.OnPause(used)
+			}
+		}
+		return nil, used, nil
+	}
+	if m.paused {
+		m.paused = false
+		if m.OnPause != nil {
+			m.OnPause(0)
+		}
+		log.Infof("disk holding %s is back under the watermark, resuming downloads", m.Dir)
+	}
+
+	r := &Reservation{m: m, id: id, size: size}
+	if id != "" {
+		if m.reservations == nil {
+			m.reservations = make(map[string]*Reservation)
+		}
+		m.reservations[id] = r
+	}
+	m.reserved += size
+	return r, used, nil
+}
+
+func (m *Manager) highWatermark() float64 {
+	if m.HighWatermark <= 0 {
+		return defaultHighWatermark
+	}
+	return m.HighWatermark
+}
+
+func (m *Manager) lowWatermark() float64 {
+	if m.LowWatermark <= 0 || m.LowWatermark > m.highWatermark() {
+		return m.highWatermark()
+	}
+	return m.LowWatermark
+}
+
+func (m *Manager) pollInterval() time.Duration {
+	if m.PollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return m.PollInterval
+}
@@ -0,0 +1,164 @@
+package disk
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+)
+
+// fakeDisk is a 1000 byte disk, with 100 inodes
+type fakeDisk struct {
+	mux        sync.Mutex
+	used       uint64
+	usedInodes uint64
+}
+
+func (d *fakeDisk) setUsed(used uint64) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.used = used
+}
+
+func (d *fakeDisk) usage(string) (Usage, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return Usage{Total: 1000, Free: 1000 - d.used, Inodes: 100, FreeInodes: 100 - d.usedInodes}, nil
+}
+
+func newTestManager(d *fakeDisk) *Manager {
+	return &Manager{
+		HighWatermark: 0.9,
+		LowWatermark:  0.8,
+		PollInterval:  time.Millisecond,
+		usage:         d.usage,
+	}
+}
+
+func TestReserveCountsReservations(t *testing.T) {
+	d := &fakeDisk{used: 500}
+	m := newTestManager(d)
+
+	a, err := m.Reserve("a", 300, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Reserved() != 300 {
+		t.Errorf("expected 300 bytes reserved, got %d", m.Reserved())
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	_, err = m.Reserve("b", 200, stop)
+	if !errors.Is(err, util.ErrWaitCancelled) {
+		t.Fatalf("expected the reservation to wait for space, got %v", err)
+	}
+
+	a.Release()
+	a.Release()
+	if m.Reserved() != 0 {
+		t.Errorf("expected nothing reserved, got %d", m.Reserved())
+	}
+}
+
+func TestReserveWaitsForInodes(t *testing.T) {
+	m := newTestManager(&fakeDisk{used: 100, usedInodes: 95})
+
+	stop := make(chan struct{})
+	close(stop)
+	_, err := m.Reserve("a", 10, stop)
+	if !errors.Is(err, util.ErrWaitCancelled) {
+		t.Fatalf("expected the reservation to wait for inodes, got %v", err)
+	}
+
+	m = newTestManager(&fakeDisk{used: 100, usedInodes: 50})
+	r, err := m.Reserve("a", 10, stop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Release()
+}
+
+func TestReserveSameVideoTwice(t *testing.T) {
+	m := newTestManager(&fakeDisk{used: 500})
+
+	a, err := m.Reserve("a", 300, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := m.Reserve("a", 300, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != again {
+		t.Error("expected the existing reservation to be returned")
+	}
+	if m.Reserved() != 300 {
+		t.Errorf("expected 300 bytes reserved, got %d", m.Reserved())
+	}
+}
+
+func TestReservePausesUntilLowWatermark(t *testing.T) {
+	d := &fakeDisk{used: 950}
+	m := newTestManager(d)
+	pauses := make(chan float64, 2)
+	m.OnPause = func(used float64) { pauses <- used }
+
+	done := make(chan error)
+	go func() {
+		r, err := m.Reserve("a", 10, nil)
+		r.Release()
+		done <- err
+	}()
+
+	select {
+	case used := <-pauses:
+		if used < 0.9 {
+			t.Errorf("expected to pause above the high watermark, paused at %f", used)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reservation did not pause")
+	}
+
+	// under the high watermark, but not under the low one
+	d.setUsed(850)
+	select {
+	case err := <-done:
+		t.Fatalf("expected the reservation to wait for the low watermark, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	d.setUsed(700)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reservation did not resume")
+	}
+
+	if used := <-pauses; used != 0 {
+		t.Errorf("expected the resume to be reported, got %f", used)
+	}
+}
+
+func TestWait(t *testing.T) {
+	d := &fakeDisk{used: 100}
+	m := newTestManager(d)
+	var used float64
+	m.OnUsage = func(u float64) { used = u }
+
+	err := m.Wait(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used != 0.1 {
+		t.Errorf("expected usage to be reported, got %f", used)
+	}
+	if m.Reserved() != 0 {
+		t.Errorf("expected nothing reserved, got %d", m.Reserved())
+	}
+}
@@ -0,0 +1,157 @@
+package ytsync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/disk"
+	"github.com/lbryio/lbry.go/ytsync/reflector"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// videoReservation is the space set aside for each video while it's synced: the download and the blobs made out of
+	// it, for a large video
+	videoReservation = 2 * 1024 * 1024 * 1024
+	// diskHighWatermark is the used fraction of the disk above which downloads wait for space
+	diskHighWatermark = 0.9
+	// diskLowWatermark is the used fraction of the disk downloads resume below
+	diskLowWatermark = 0.85
+)
+
+// newDiskManager returns the manager of the disk holding the blobs. With SkipSpaceCheck it never waits for space.
+func (s SyncManager) newDiskManager() *disk.Manager {
+	m := &disk.Manager{
+		Dir:           s.BlobsDir,
+		HighWatermark: diskHighWatermark,
+		LowWatermark:  diskLowWatermark,
+		OnUsage:       func(used float64) { diskUsage.Set(used) },
+		OnPause: func(used float64) {
+			if usage, err := disk.GetUsage(s.BlobsDir); used > 0 && err == nil && usage.InodesUsed() >= used {
+				SendErrorToSlack("the disk holding %s has %.1f%% of its inodes used, downloads are paused until it's under %.0f%%", s.BlobsDir, used*100, diskLowWatermark*100)
+			} else if used > 0 {
+				SendErrorToSlack("the disk holding %s is %.1f%% used, downloads are paused until it's under %.0f%%", s.BlobsDir, used*100, diskLowWatermark*100)
+			} else {
+				SendInfoToSlack("the disk holding %s has space again, downloads resumed", s.BlobsDir)
+			}
+		},
+	}
+	if s.SkipSpaceCheck {
+		m.HighWatermark = 1
+		m.LowWatermark = 1
+	}
+	return m
+}
+
+// reserveSpace waits until there is room on the disk for the video and sets it aside
+func (s *Sync) reserveSpace(v video) (*disk.Reservation, error) {
+	return s.Manager.disk.Reserve(v.ID(), videoReservation, s.grp.Ch())
+}
+
+// cleanupBlobs deletes the blobs of a published stream from the daemon once the reflector holds them all. Without a
+// Reflector to check with, they are deleted right away. The streams the reflector doesn't hold yet are checked again at
+// the end of the sync, see cleanupUnreflectedBlobs.
+func (s *Sync) cleanupBlobs(claimID string) {
+	if s.Manager.Reflector == "" {
+		s.deleteBlobs(claimID)
+		return
+	}
+	reflected, err := s.isReflected(claimID)
+	if err != nil {
+		s.logger().Warnf("could not check whether the reflector holds the blobs of claim %s: %s", claimID, err.Error())
+	}
+	if reflected {
+		s.deleteBlobs(claimID)
+		return
+	}
+	s.blobsMux.Lock()
+	s.unreflected = append(s.unreflected, claimID)
+	s.blobsMux.Unlock()
+}
+
+// cleanupUnreflectedBlobs deletes the blobs of the streams cleanupBlobs had to keep, if the reflector holds them by
+// now. The others are kept, they stay with the daemon.
+func (s *Sync) cleanupUnreflectedBlobs() {
+	s.blobsMux.Lock()
+	claimIDs := s.unreflected
+	s.unreflected = nil
+	s.blobsMux.Unlock()
+
+	kept := 0
+	for _, claimID := range claimIDs {
+		reflected, err := s.isReflected(claimID)
+		if err != nil {
+			s.logger().Warnf("could not check whether the reflector holds the blobs of claim %s: %s", claimID, err.Error())
+		}
+		if !reflected {
+			kept++
+			continue
+		}
+		s.deleteBlobs(claimID)
+	}
+	if kept > 0 {
+		s.logger().Warnf("%s doesn't hold the blobs of %d streams of %s, they are kept", s.Manager.Reflector, kept, s.YoutubeChannelID)
+	}
+}
+
+// isReflected returns true if the reflector holds every blob of the stream of the claim. The sd blob is read from
+// BlobsDir for its size.
+func (s *Sync) isReflected(claimID string) (bool, error) {
+	files, err := s.daemon.FileList(jsonrpc.FileListOptions{ClaimID: &claimID})
+	if err != nil {
+		return false, err
+	} else if files == nil || len(*files) == 0 {
+		return false, errors.Err("the daemon has no stream for claim %s", claimID)
+	}
+	sdHash := (*files)[0].SdHash
+	sdBlob, err := os.Stat(filepath.Join(s.Manager.BlobsDir, sdHash))
+	if err != nil {
+		return false, errors.Err(err)
+	}
+	return reflector.Client{Address: s.Manager.Reflector}.HasStream(sdHash, sdBlob.Size())
+}
+
+// deleteBlobs removes the published stream from the daemon, along with its blobs. The blobs must have been reflected
+// already.
+func (s *Sync) deleteBlobs(claimID string) {
+	_, err := s.daemon.FileDelete(claimID)
+	if err != nil {
+		s.logger().Warnf("could not delete the blobs of claim %s: %s", claimID, err.Error())
+	}
+}
+
+// recordBlobStats updates the metrics of the blobs directory, and warns if the disk is running out of inodes: publishes
+// fail with "no space left on device" then, however much space is left
+func (s SyncManager) recordBlobStats() {
+	stats, err := GetBlobStats(s.BlobsDir)
+	if err != nil {
+		log.Warnf("could not get the statistics of %s: %s", s.BlobsDir, err.Error())
+		return
+	}
+	inodeUsage.Set(stats.InodesUsed)
+	blobCount.Set(float64(stats.Blobs))
+	blobBytes.Set(float64(stats.Bytes))
+	oldestBlobAge.Set(stats.OldestBlob.Seconds())
+	if stats.InodesUsed > diskHighWatermark {
+		SendErrorToSlack("the disk holding %s has %.1f%% of its inodes used, with %d blobs in it (the oldest is %s old). Publishes will fail once they run out", s.BlobsDir, stats.InodesUsed*100, stats.Blobs, stats.OldestBlob.Round(time.Hour))
+	}
+}
+
+// explainDiskFull tells running out of inodes apart from running out of space, which both fail with "no space left on
+// device"
+func (s SyncManager) explainDiskFull(err error) error {
+	if !strings.Contains(err.Error(), "no space left on device") {
+		return err
+	}
+	usage, usageErr := disk.GetUsage(s.BlobsDir)
+	if usageErr != nil || usage.InodesUsed() < usage.Used() {
+		return err
+	}
+	return errors.Prefix(fmt.Sprintf("the disk holding %s ran out of inodes (%.1f%% used), not of space (%.1f%% used)", s.BlobsDir, usage.InodesUsed()*100, usage.Used()*100), err)
+}
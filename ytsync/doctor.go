@@ -0,0 +1,203 @@
+package ytsync
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/disk"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// The outcomes of the checks of Doctor
+const (
+	CheckPass = "pass"
+	CheckFail = "fail"
+	CheckSkip = "skip" // the check doesn't apply to how the server is set up
+)
+
+// DoctorCheck is the outcome of one of the checks of Doctor
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DoctorReport is what Doctor found about the sync server. OK is false if any check failed.
+type DoctorReport struct {
+	Host      string        `json:"host"`
+	OK        bool          `json:"ok"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []DoctorCheck `json:"checks"`
+}
+
+func (r *DoctorReport) add(name, status, detail string, args ...interface{}) {
+	if len(args) > 0 {
+		detail = fmt.Sprintf(detail, args...)
+	}
+	r.Checks = append(r.Checks, DoctorCheck{Name: name, Status: status, Detail: detail})
+	if status == CheckFail {
+		r.OK = false
+	}
+}
+
+// Doctor checks that the server has what it takes to sync channels: daemons that answer with unlocked wallets, credits
+// for the refills, disk space for the downloads, a current yt-dlp, a valid YouTube API key and the API. Nothing is
+// changed on the server.
+func (s SyncManager) Doctor() *DoctorReport {
+	report := &DoctorReport{Host: s.HostName, OK: true, CheckedAt: time.Now().UTC()}
+	for slot := 0; slot < s.daemonSlots(); slot++ {
+		s.checkDaemon(report, daemonSlot(slot))
+	}
+	s.checkBalance(report)
+	s.checkDisk(report)
+	s.checkYtDlp(report)
+	s.checkYoutubeAPI(report)
+	s.checkAPI(report)
+	return report
+}
+
+// checkDaemon checks that the daemon of the slot answers and its wallet is unlocked. A daemon that isn't running is
+// fine, syncs start it.
+func (s SyncManager) checkDaemon(report *DoctorReport, slot daemonSlot) {
+	name := "daemon " + strconv.Itoa(int(slot))
+	address := s.daemonAddress(slot)
+	if address == "" {
+		address = "http://localhost:" + strconv.Itoa(jsonrpc.DefaultPort)
+	}
+	daemon := jsonrpc.NewClient(address)
+	daemon.SetRPCTimeout(30 * time.Second)
+	status, err := daemon.Status()
+	if err != nil {
+		if pid, pidErr := slot.pid(); pidErr == nil && pid == -1 && len(s.DaemonURLs) == 0 {
+			report.add(name, CheckSkip, "not running, syncs start %s", slot.unit())
+			return
+		}
+		report.add(name, CheckFail, "unreachable at %s: %s", address, err.Error())
+		return
+	}
+	if status.Wallet.IsLocked {
+		report.add(name, CheckFail, "the wallet is locked")
+		return
+	}
+	report.add(name, CheckPass, "reachable at %s, %d blocks behind", address, status.Wallet.BlocksBehind)
+}
+
+// checkBalance checks that lbrycrd has the credits the wallets are refilled with, as preflightWallet does
+func (s SyncManager) checkBalance(report *DoctorReport) {
+	const name = "wallet balance"
+	required := s.MinimumBalance + float64(s.Refill)
+	if s.CreditSource != nil || required <= 0 {
+		report.add(name, CheckSkip, "the wallets aren't refilled from lbrycrd")
+		return
+	}
+	lbrycrdd, err := (&Sync{LbrycrdString: s.LbrycrdString}).lbrycrdClient()
+	if err != nil {
+		report.add(name, CheckFail, "lbrycrd is unreachable: %s", err.Error())
+		return
+	}
+	defer lbrycrdd.Shutdown()
+	balance, err := lbrycrdd.GetBalance("")
+	if err != nil {
+		report.add(name, CheckFail, "could not get the lbrycrd balance: %s", err.Error())
+		return
+	}
+	if balance.ToBTC() < required {
+		report.add(name, CheckFail, "lbrycrd has %.2f LBC, at least %.2f LBC are needed", balance.ToBTC(), required)
+		return
+	}
+	report.add(name, CheckPass, "lbrycrd has %.2f LBC", balance.ToBTC())
+}
+
+// checkDisk checks that the disk holding the blobs has room for a video under the watermark downloads wait at, and
+// inodes left for its blobs
+func (s SyncManager) checkDisk(report *DoctorReport) {
+	const name = "disk space"
+	usage, err := disk.GetUsage(s.BlobsDir)
+	if err != nil {
+		report.add(name, CheckFail, "could not get the usage of the disk holding %s: %s", s.BlobsDir, err.Error())
+		return
+	}
+	detail := fmt.Sprintf("the disk holding %s is %.1f%% used, %d GB free, %.1f%% of its inodes used", s.BlobsDir, usage.Used()*100, usage.Free>>30, usage.InodesUsed()*100)
+	if s.SkipSpaceCheck {
+		report.add(name, CheckSkip, detail)
+		return
+	}
+	if usage.InodesUsed() > diskHighWatermark {
+		report.add(name, CheckFail, "%s, publishes would fail once they run out", detail)
+		return
+	}
+	if usage.Total == 0 || float64(usage.Total-usage.Free+videoReservation)/float64(usage.Total) > diskHighWatermark {
+		report.add(name, CheckFail, "%s, downloads would wait for space", detail)
+		return
+	}
+	report.add(name, CheckPass, detail)
+}
+
+// checkYtDlp checks that the yt-dlp used when the built-in extractor fails is the latest release
+func (s SyncManager) checkYtDlp(report *DoctorReport) {
+	const name = "yt-dlp"
+	if s.YtDlp == nil {
+		if path, err := exec.LookPath("yt-dlp"); err == nil {
+			report.add(name, CheckSkip, "%s is in the PATH, but not used without --yt-dlp-dir", path)
+		} else {
+			report.add(name, CheckSkip, "not used without --yt-dlp-dir")
+		}
+		return
+	}
+	status, err := s.YtDlp.Check()
+	if status.Binary == "" {
+		report.add(name, CheckFail, "not installed in %s nor in the PATH, it's installed on the first download that needs it", s.YtDlp.Dir)
+		return
+	}
+	if err != nil {
+		report.add(name, CheckFail, "%s is %s, could not look up the latest release: %s", status.Binary, status.Version, err.Error())
+		return
+	}
+	if !status.Current() {
+		report.add(name, CheckFail, "%s is %s, the latest release is %s", status.Binary, status.Version, status.Latest)
+		return
+	}
+	report.add(name, CheckPass, "%s is the latest release, %s", status.Binary, status.Version)
+}
+
+// checkYoutubeAPI checks that the YouTube API accepts the key, with the cheapest request there is
+func (s SyncManager) checkYoutubeAPI(report *DoctorReport) {
+	const name = "youtube api"
+	if s.YoutubeAPIKey == "" {
+		report.add(name, CheckFail, "no API key, set YOUTUBE_API_KEY")
+		return
+	}
+	client := &http.Client{Transport: (&Sync{YoutubeAPIKey: s.YoutubeAPIKey}).youtubeTransport(), Timeout: 30 * time.Second}
+	service, err := youtube.New(client)
+	if err != nil {
+		report.add(name, CheckFail, "could not create the YouTube service: %s", err.Error())
+		return
+	}
+	_, err = service.I18nRegions.List("snippet").Do()
+	if err != nil {
+		report.add(name, CheckFail, "the API key was refused: %s", err.Error())
+		return
+	}
+	report.add(name, CheckPass, "the API key is valid")
+}
+
+// checkAPI checks that the API (or its database) answers, with a request for the channels queued in the last second
+func (s SyncManager) checkAPI(report *DoctorReport) {
+	const name = "api"
+	if s.APIConfig == nil {
+		report.add(name, CheckFail, "no API configured")
+		return
+	}
+	now := time.Now().Unix()
+	_, err := s.APIConfig.FetchChannels("", now-1, now, StatusQueued)
+	if err != nil {
+		report.add(name, CheckFail, "unreachable: %s", err.Error())
+		return
+	}
+	report.add(name, CheckPass, "reachable")
+}
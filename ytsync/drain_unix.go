@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package ytsync
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyDrain relays SIGUSR1 to c. SIGUSR1 asks the manager to finish the channels it already picked up and then exit.
+func notifyDrain(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
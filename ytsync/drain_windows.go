@@ -0,0 +1,6 @@
+package ytsync
+
+import "os"
+
+// notifyDrain is a no-op on windows, which has no SIGUSR1.
+func notifyDrain(c chan<- os.Signal) {}
@@ -0,0 +1,77 @@
+package ytsync
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// plannedVideo is a video that can tell what syncing it would do
+type plannedVideo interface {
+	video
+	Plan(params sources.SyncParams, taken map[string]bool) sources.VideoPlan
+}
+
+// dryRun prints what a sync of the channel would publish and roughly how much it would cost. Nothing is downloaded or
+// published and neither the wallets, the daemon nor the sync API are touched.
+func (s *Sync) dryRun() error {
+	if s.LbryChannelName == "@UCBerkeley" {
+		return errors.Err("dry runs are not supported for %s", s.LbryChannelName)
+	}
+
+	videos, err := s.fetchYoutubeVideos()
+	if err != nil {
+		return err
+	}
+
+	var local map[string]localdb.Video
+	if s.Manager.localDB != nil {
+		local, err = s.Manager.localDB.Videos(s.YoutubeChannelID)
+		if err != nil {
+			return err
+		}
+	}
+
+	params := s.syncParams()
+	taken := make(map[string]bool)
+	var plans []sources.VideoPlan
+	skipped := 0
+	for _, v := range videos {
+		if v.PlaylistPosition() > s.Manager.VideosLimit || local[v.ID()].Status == localdb.VideoStatusPublished {
+			skipped++
+			continue
+		}
+		pv, ok := v.(plannedVideo)
+		if !ok {
+			s.logger().Warnf("%s can't be planned, skipping it", v.ID())
+			skipped++
+			continue
+		}
+		plans = append(plans, pv.Plan(params, taken))
+	}
+
+	generated := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Dry run for %s (%s)\n\n", s.LbryChannelName, s.YoutubeChannelID)
+	fmt.Fprintln(w, "POSITION\tVIDEO\tCLAIM NAME\tTHUMBNAIL\tAMOUNT\tTITLE")
+	for _, p := range plans {
+		thumbnail := p.Thumbnail
+		if p.GenerateThumbnail {
+			thumbnail = "generated from the video"
+			generated++
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%.2f\t%s\n", p.PlaylistPosition, p.VideoID, p.ClaimName, thumbnail, p.Amount, p.Title)
+	}
+	w.Flush()
+
+	// same estimate walletSetup uses to decide how many credits the channel needs
+	cost := float64(len(plans))*s.ClaimAmounts.perVideo() + s.ClaimAmounts.channelBid()
+	fmt.Printf("\n%d videos would be published (%d skipped, %d with generated thumbnails)\n", len(plans), skipped, generated)
+	fmt.Printf("estimated cost: up to %.2f LBC, including fees and the channel claim\n", cost)
+
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package errorrules loads rules classifying the errors of a sync from a file or a URL, and reloads them as they
+// change. New failure modes can then be handled without deploying a new build.
+//
+// A ruleset is a JSON list of rules, checked in order:
+//
+//	[
+//	  {"class": "permanent", "reason": "members only", "patterns": ["(?i)join this channel to get access"]},
+//	  {"class": "fatal", "reason": "wallet broken", "substrings": ["NotEnoughFunds"]}
+//	]
+package errorrules
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/retry"
+	"github.com/lbryio/lbry.go/stop"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Rule is a rule as it's written in a ruleset. An error matches it if its message contains any of the substrings or
+// matches any of the patterns, which are regular expressions.
+type Rule struct {
+	Class      string   `json:"class"` // transient, permanent or fatal
+	Reason     string   `json:"reason"`
+	Substrings []string `json:"substrings,omitempty"`
+	Patterns   []string `json:"patterns,omitempty"`
+}
+
+// Parse returns the classifier of a ruleset. Every rule needs a class, a reason and something to match.
+func Parse(data []byte) (retry.Classifier, error) {
+	var rules []Rule
+	err := json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, errors.Prefix("invalid ruleset", err)
+	}
+	classifier := make(retry.Classifier, 0, len(rules))
+	for i, r := range rules {
+		class, err := retry.ParseClass(r.Class)
+		if err != nil {
+			return nil, errors.Prefix("rule "+r.Reason, err)
+		}
+		if r.Reason == "" {
+			return nil, errors.Err("rule %d has no reason", i+1)
+		}
+		if len(r.Substrings) == 0 && len(r.Patterns) == 0 {
+			return nil, errors.Err("rule %s has no substrings nor patterns", r.Reason)
+		}
+		rule := retry.Rule{Class: class, Reason: r.Reason, Substrings: r.Substrings}
+		for _, p := range r.Patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, errors.Prefix("rule "+r.Reason, err)
+			}
+			rule.Patterns = append(rule.Patterns, re)
+		}
+		classifier = append(classifier, rule)
+	}
+	return classifier, nil
+}
+
+// Ruleset is a ruleset loaded from a file or an http(s) URL
+type Ruleset struct {
+	Location string
+	HTTP     *http.Client // for URLs, a client with a 30 second timeout if nil
+
+	mu    sync.RWMutex
+	rules retry.Classifier
+	data  []byte // what the rules were parsed from, to tell whether they changed
+}
+
+// Load loads the ruleset at location. It fails if the ruleset can't be read or is invalid.
+func Load(location string) (*Ruleset, error) {
+	r := &Ruleset{Location: location}
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Classifier returns the rules currently loaded. Appending to it doesn't change them.
+func (r *Ruleset) Classifier() retry.Classifier {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rules[:len(r.rules):len(r.rules)]
+}
+
+// Reload reads the ruleset again and returns true if it changed. An invalid ruleset is rejected, the rules that were
+// loaded are kept.
+func (r *Ruleset) Reload() (bool, error) {
+	data, err := r.read()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.data != nil && bytes.Equal(data, r.data)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return false, errors.Prefix(r.Location, err)
+	}
+	r.mu.Lock()
+	r.rules, r.data = rules, data
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch reloads the ruleset every interval until stopCh is closed. Failed reloads are logged, the rules that were
+// loaded are kept.
+func (r *Ruleset) Watch(stopCh stop.Chan, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+		changed, err := r.Reload()
+		if err != nil {
+			log.Errorf("could not reload the error rules, keeping the ones loaded: %s", err.Error())
+		} else if changed {
+			log.Infof("reloaded %d error rules from %s", len(r.Classifier()), r.Location)
+		}
+	}
+}
+
+func (r *Ruleset) read() ([]byte, error) {
+	if !strings.HasPrefix(r.Location, "http://") && !strings.HasPrefix(r.Location, "https://") {
+		data, err := ioutil.ReadFile(r.Location)
+		return data, errors.Err(err)
+	}
+	client := r.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	res, err := client.Get(r.Location)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Err("GET %s returned status code %d", r.Location, res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	return data, errors.Err(err)
+}
@@ -0,0 +1,131 @@
+package errorrules
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbry.go/retry"
+)
+
+const ruleset = `[
+	{"class": "permanent", "reason": "members only", "patterns": ["(?i)join this channel to get access"]},
+	{"class": "fatal", "reason": "wallet broken", "substrings": ["NotEnoughFunds"]}
+]`
+
+func TestParse(t *testing.T) {
+	c, err := Parse([]byte(ruleset))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		err    string
+		class  retry.Class
+		reason string
+	}{
+		{"Join this channel to get access to members-only content", retry.Permanent, "members only"},
+		{"publish failed: NotEnoughFunds", retry.Fatal, "wallet broken"},
+		{"something else", retry.Transient, ""},
+	}
+	for _, test := range tests {
+		class, reason := c.Classify(errors.New(test.err))
+		if class != test.class || reason != test.reason {
+			t.Errorf("%q: expected %s (%s), got %s (%s)", test.err, test.class, test.reason, class, reason)
+		}
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	for _, invalid := range []string{
+		`{"class": "fatal"}`,
+		`[{"class": "retry", "reason": "x", "substrings": ["x"]}]`,
+		`[{"class": "fatal", "substrings": ["x"]}]`,
+		`[{"class": "fatal", "reason": "x"}]`,
+		`[{"class": "fatal", "reason": "x", "patterns": ["("]}]`,
+	} {
+		if _, err := Parse([]byte(invalid)); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestReloadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "errorrules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+	err = ioutil.WriteFile(path, []byte(ruleset), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Classifier()) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(r.Classifier()))
+	}
+	if changed, err := r.Reload(); err != nil || changed {
+		t.Errorf("expected the rules to be unchanged, got %t (%v)", changed, err)
+	}
+
+	err = ioutil.WriteFile(path, []byte(`[{"class": "transient", "reason": "busy", "substrings": ["busy"]}]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := r.Reload(); err != nil || !changed {
+		t.Fatalf("expected the rules to change, got %t (%v)", changed, err)
+	}
+	if _, reason := r.Classifier().Classify(errors.New("busy")); reason != "busy" {
+		t.Errorf("expected the new rules to be used, got %q", reason)
+	}
+
+	err = ioutil.WriteFile(path, []byte(`[{"class": "fatal"`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reload(); err == nil {
+		t.Error("expected an invalid ruleset to be rejected")
+	}
+	if _, reason := r.Classifier().Classify(errors.New("busy")); reason != "busy" {
+		t.Errorf("expected the rules to be kept, got %q", reason)
+	}
+}
+
+func TestClassifierCantBeChanged(t *testing.T) {
+	r := &Ruleset{}
+	r.rules, _ = Parse([]byte(ruleset))
+	c := append(r.Classifier(), retry.Rule{Class: retry.Fatal, Reason: "extra", Substrings: []string{"x"}})
+	if len(c) != 3 || len(r.Classifier()) != 2 {
+		t.Errorf("expected the loaded rules to stay the same, got %d", len(r.Classifier()))
+	}
+}
+
+func TestLoadURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/rules.json" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write([]byte(ruleset))
+	}))
+	defer server.Close()
+
+	r, err := Load(server.URL + "/rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Classifier()) != 2 {
+		t.Errorf("expected 2 rules, got %d", len(r.Classifier()))
+	}
+	if _, err := Load(server.URL + "/missing.json"); err == nil {
+		t.Error("expected a missing ruleset to fail")
+	}
+}
@@ -0,0 +1,146 @@
+package ytsync
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// estimateConcurrency is how many videos are sized at once
+const estimateConcurrency = 8
+
+// sizedVideo is a video that can tell how big its download is without downloading it
+type sizedVideo interface {
+	video
+	DownloadSize() (int64, error)
+}
+
+// syncEstimate is what syncing the videos a channel is missing is expected to take
+type syncEstimate struct {
+	Videos   int
+	Unsized  int     // videos whose size couldn't be found. They count as the average of the others
+	Download int64   // bytes downloaded from the source
+	Disk     int64   // bytes the sync needs on disk at its peak: the largest videos being processed at once, and their blobs
+	Cost     float64 // credits spent, fees and the channel claim included
+}
+
+func (e syncEstimate) String() string {
+	msg := fmt.Sprintf("%d videos, %.2f GB to download, %.2f GB of disk, %.2f LBC", e.Videos, gigabytes(e.Download), gigabytes(e.Disk), e.Cost)
+	if e.Unsized > 0 {
+		msg += fmt.Sprintf(" (the size of %d videos is unknown)", e.Unsized)
+	}
+	return msg
+}
+
+func gigabytes(bytes int64) float64 {
+	return float64(bytes) / (1 << 30)
+}
+
+// estimating returns true if the channels are estimated before they are synced
+func (s SyncManager) estimating() bool {
+	return s.Estimate || s.MaxChannelDownload > 0 || s.MaxChannelDisk > 0 || s.MaxChannelCost > 0
+}
+
+// estimateSync lists the videos the sync would process and adds up their sizes and what publishing them costs. The
+// sizes are looked up concurrently. It needs the synced videos of the API to tell which videos are missing.
+func (s *Sync) estimateSync() (syncEstimate, error) {
+	source := s.videoSource()
+	videos, err := source.ListVideos()
+	if err != nil {
+		return syncEstimate{}, errors.Prefix("could not list the videos of "+source.Name(), err)
+	}
+	var pending []video
+	for _, v := range s.VideoFilter.apply(videos) {
+		if s.shouldPrefetch(v) {
+			pending = append(pending, v)
+		}
+	}
+
+	sizes := make([]int64, len(pending)) // -1 when unknown
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < estimateConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				sizes[i] = -1
+				v, ok := pending[i].(sizedVideo)
+				if !ok {
+					continue
+				}
+				size, err := v.DownloadSize()
+				if err != nil {
+					s.logger().Debugf("could not get the size of %s: %s", v.ID(), err.Error())
+					continue
+				}
+				sizes[i] = size
+			}
+		}()
+	}
+Feed:
+	for i := range pending {
+		select {
+		case indexes <- i:
+		case <-s.grp.Ch():
+			break Feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	if s.IsInterrupted() {
+		return syncEstimate{}, errors.Err("interrupted while estimating the sync")
+	}
+
+	e := syncEstimate{Videos: len(pending)}
+	var known []int64
+	for _, size := range sizes {
+		if size < 0 {
+			e.Unsized++
+			continue
+		}
+		known = append(known, size)
+		e.Download += size
+	}
+	if len(known) > 0 {
+		e.Download += e.Download / int64(len(known)) * int64(e.Unsized)
+	}
+
+	// the largest videos being processed at the same time, each with its download and its blobs on disk
+	inFlight := s.ConcurrentVideos
+	if s.Pipeline {
+		inFlight += s.downloaders() + s.PipelineBuffer
+	}
+	sort.Slice(known, func(i, j int) bool { return known[i] > known[j] })
+	for i := 0; i < inFlight && i < len(known); i++ {
+		e.Disk += 2 * known[i]
+	}
+
+	e.Cost = float64(e.Videos) * s.ClaimAmounts.perVideo()
+	if s.LbryChannelClaimID == "" {
+		e.Cost += s.ClaimAmounts.channelBid()
+	}
+	return e, nil
+}
+
+// checkBudgets returns an error if the estimate goes over one of the budgets of the manager. The sizes can't be checked
+// when none of the videos could be sized.
+func (s *Sync) checkBudgets(e syncEstimate) error {
+	m := s.Manager
+	if m.MaxChannelCost > 0 && e.Cost > m.MaxChannelCost {
+		return errors.Err("over budget: the sync would cost %.2f LBC, the budget is %.2f LBC", e.Cost, m.MaxChannelCost)
+	}
+	if e.Unsized == e.Videos && e.Videos > 0 && (m.MaxChannelDownload > 0 || m.MaxChannelDisk > 0) {
+		s.logger().Warnf("none of the videos could be sized, the download and disk budgets can't be checked")
+		return nil
+	}
+	if m.MaxChannelDownload > 0 && e.Download > m.MaxChannelDownload {
+		return errors.Err("over budget: the sync would download %.2f GB, the budget is %.2f GB", gigabytes(e.Download), gigabytes(m.MaxChannelDownload))
+	}
+	if m.MaxChannelDisk > 0 && e.Disk > m.MaxChannelDisk {
+		return errors.Err("over budget: the sync would need %.2f GB of disk, the budget is %.2f GB", gigabytes(e.Disk), gigabytes(m.MaxChannelDisk))
+	}
+	return nil
+}
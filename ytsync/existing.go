@@ -0,0 +1,60 @@
+package ytsync
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// existingVideo is a video that can find the claim it's already published under, see
+// sources.YoutubeVideo.SyncExisting
+type existingVideo interface {
+	SyncExisting(*jsonrpc.Client, sources.SyncParams) (*sources.SyncSummary, sources.ExistingState, error)
+}
+
+// syncExisting handles the video if the channel already published it, with UpdateExisting: the claim is left alone if
+// it's up to date and updated otherwise, and the video is recorded as published. It returns false if the video still
+// has to be synced.
+func (s *Sync) syncExisting(v video, alreadyPublished bool, started time.Time, vlog *log.Entry) (bool, error) {
+	if p, ok := v.(*prefetchedVideo); ok {
+		v = p.video
+	}
+	ev, ok := v.(existingVideo)
+	if !ok {
+		return false, nil
+	}
+	params := s.syncParams()
+	params.Log = vlog
+	summary, state, err := ev.SyncExisting(s.daemon, params)
+	if err != nil || state == sources.NotPublished {
+		return false, err
+	}
+
+	if state == sources.PublishedSame {
+		vlog.Printf("%s is already published as %s#%s, unchanged", v.ID(), summary.ClaimName, summary.ClaimID)
+	} else {
+		s.stats.spend(summary.Fee)
+		s.recordSpend(localdb.Spend{Kind: localdb.SpendUpdate, VideoID: v.ID(), ClaimID: summary.ClaimID, Txid: summary.Txid, Fee: summary.Fee})
+	}
+	s.stats.skip()
+	if !alreadyPublished {
+		if s.Manager.localDB != nil {
+			err = s.Manager.localDB.SetPublished(s.YoutubeChannelID, v.ID(), summary.ClaimID, summary.ClaimName, 0)
+			if err != nil {
+				s.notifyVideoError("Failed to mark video %s as published on the local db: %s", v.ID(), err.Error())
+			}
+		}
+		err = s.Manager.APIConfig.MarkVideoStatus(s.YoutubeChannelID, v.ID(), sdk.VideoStatusPublished, summary.ClaimID, summary.ClaimName, "")
+		if err != nil {
+			return false, err
+		}
+		s.AppendSyncedVideo(v.ID(), true, "")
+	}
+	s.reportProgress(v.ID(), ProgressSkipped, started, nil)
+	return true, nil
+}
@@ -0,0 +1,67 @@
+package ytsync
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/lbryio/lbry.go/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// VideoFilter picks the videos of a channel that are synced, so channels with problematic content can still be synced
+// partially. The zero value lets every video through.
+type VideoFilter struct {
+	ExcludeIDs   []string       // videos that are never synced
+	IncludeIDs   []string       // if set, only these videos are synced
+	Repair       bool           // the included videos are synced even if they are over the limit or failed for good
+	IncludeTitle *regexp.Regexp // if set, only videos whose title matches are synced
+	ExcludeTitle *regexp.Regexp // videos whose title matches are never synced
+	After        time.Time      // if set, only videos uploaded at or after this time are synced
+	Before       time.Time      // if set, only videos uploaded before this time are synced
+}
+
+// names returns true if the video was picked by its ID to be repaired. It's synced even if it's older than the limit
+// of videos or failed in a way that is never retried.
+func (f VideoFilter) names(videoID string) bool {
+	return f.Repair && util.InSlice(videoID, f.IncludeIDs)
+}
+
+// allows returns whether the video should be synced, and why not if it shouldn't
+func (f VideoFilter) allows(v video) (bool, string) {
+	if util.InSlice(v.ID(), f.ExcludeIDs) {
+		return false, "excluded"
+	}
+	if len(f.IncludeIDs) > 0 && !util.InSlice(v.ID(), f.IncludeIDs) {
+		return false, "not in the included videos"
+	}
+	if f.ExcludeTitle != nil && f.ExcludeTitle.MatchString(v.Title()) {
+		return false, "title matches " + f.ExcludeTitle.String()
+	}
+	if f.IncludeTitle != nil && !f.IncludeTitle.MatchString(v.Title()) {
+		return false, "title doesn't match " + f.IncludeTitle.String()
+	}
+	if !f.After.IsZero() && v.PublishedAt().Before(f.After) {
+		return false, "uploaded before " + f.After.Format(time.RFC3339)
+	}
+	if !f.Before.IsZero() && !v.PublishedAt().Before(f.Before) {
+		return false, "uploaded at or after " + f.Before.Format(time.RFC3339)
+	}
+	return true, ""
+}
+
+// apply returns the videos the filter allows, in the same order
+func (f VideoFilter) apply(videos []video) []video {
+	filtered := videos[:0]
+	for _, v := range videos {
+		if ok, reason := f.allows(v); !ok {
+			log.Debugf("%s filtered out: %s", v.ID(), reason)
+			continue
+		}
+		filtered = append(filtered, v)
+	}
+	if skipped := len(videos) - len(filtered); skipped > 0 {
+		log.Infof("%d videos filtered out, %d left to sync", skipped, len(filtered))
+	}
+	return filtered
+}
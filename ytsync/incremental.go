@@ -0,0 +1,119 @@
+package ytsync
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/youtube/v3"
+)
+
+// incrementalCutoff returns the upload time of the most recent video of the channel that was published, when the
+// channel is re-checked on an interval. Only the videos uploaded after it need to be fetched then. ok is false if the
+// whole channel has to be fetched.
+func (s *Sync) incrementalCutoff() (time.Time, bool) {
+	if s.Manager == nil || s.Manager.Interval <= 0 || s.Manager.localDB == nil || s.YoutubePlaylistID != "" {
+		return time.Time{}, false
+	}
+	channel, ok, err := s.Manager.localDB.Channel(s.YoutubeChannelID)
+	if err != nil {
+		s.logger().Warnf("could not get the last upload of %s from the local db, fetching all its videos: %s", s.YoutubeChannelID, err.Error())
+		return time.Time{}, false
+	}
+	if !ok || channel.LastUploadAt.IsZero() {
+		return time.Time{}, false
+	}
+	return channel.LastUploadAt, true
+}
+
+// fetchNewUploads returns the videos the channel uploaded after the given time. They come from the activity feed of
+// the channel, which can be filtered by date, unlike the uploads playlist that would have to be walked in full.
+func (s *Sync) fetchNewUploads(service *youtube.Service, after time.Time) ([]video, error) {
+	var videos []video
+	pageToken := ""
+	for {
+		err := s.useQuota(listCost)
+		if err != nil {
+			return nil, err
+		}
+		response, err := service.Activities.List("snippet,contentDetails").
+			ChannelId(s.YoutubeChannelID).
+			PublishedAfter(after.Format(time.RFC3339)).
+			MaxResults(50).
+			PageToken(pageToken).
+			Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting channel activities", s.youtubeQuota().Observe(err))
+		}
+
+		for _, item := range response.Items {
+			if item.Snippet == nil || item.ContentDetails == nil || item.ContentDetails.Upload == nil {
+				continue
+			}
+			// the feed is newest first, like the uploads playlist, so the positions match the ones the videos have there
+			videos = append(videos, sources.NewYoutubeVideo(s.videoDirectory, &youtube.PlaylistItemSnippet{
+				ResourceId:   &youtube.ResourceId{VideoId: item.ContentDetails.Upload.VideoId},
+				Title:        item.Snippet.Title,
+				Description:  item.Snippet.Description,
+				ChannelTitle: item.Snippet.ChannelTitle,
+				PublishedAt:  item.Snippet.PublishedAt,
+				Thumbnails:   item.Snippet.Thumbnails,
+				Position:     int64(len(videos)),
+			}))
+		}
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	s.logger().Infof("%d videos were uploaded to %s since %s", len(videos), s.YoutubeChannelID, after.Format(time.RFC3339))
+	return videos, nil
+}
+
+// uploadsChanged asks for the latest page of the uploads playlist of the channel, with the ETag it had at the end of
+// the last successful sync. It returns false if the page didn't change, i.e. the channel didn't upload anything since,
+// which costs a single request instead of going through its activities. The new ETag is saved once the sync succeeds.
+func (s *Sync) uploadsChanged(service *youtube.Service) (bool, error) {
+	channel, _, err := s.Manager.localDB.Channel(s.YoutubeChannelID)
+	if err != nil {
+		return true, err
+	}
+	playlistID := channel.UploadsPlaylist
+	if playlistID == "" {
+		playlistID, err = s.uploadsPlaylistID(service)
+		if err != nil {
+			return true, err
+		}
+	}
+
+	err = s.useQuota(listCost)
+	if err != nil {
+		return true, err
+	}
+	call := service.PlaylistItems.List("id").PlaylistId(playlistID).MaxResults(1)
+	if channel.UploadsETag != "" && channel.UploadsPlaylist == playlistID {
+		call = call.IfNoneMatch(channel.UploadsETag)
+	}
+	response, err := call.Do()
+	if googleapi.IsNotModified(err) {
+		return false, nil
+	} else if err != nil {
+		return true, errors.Prefix("error getting the latest upload", s.youtubeQuota().Observe(err))
+	}
+	s.uploadsPlaylist, s.uploadsETag = playlistID, response.Etag
+	return true, nil
+}
+
+// saveUploadsETag records the ETag uploadsChanged got, once the uploads it announced were synced
+func (s *Sync) saveUploadsETag() {
+	if s.uploadsETag == "" || s.Manager.localDB == nil {
+		return
+	}
+	err := s.Manager.localDB.SetUploadsETag(s.YoutubeChannelID, s.uploadsPlaylist, s.uploadsETag)
+	if err != nil {
+		s.logger().Warnf("could not save the ETag of the uploads of %s: %s", s.YoutubeChannelID, err.Error())
+	}
+}
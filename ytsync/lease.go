@@ -0,0 +1,123 @@
+package ytsync
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/lock"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LeaseRenewInterval is how often a server syncing a channel tells the API, or the lock service, it's still at it
+const LeaseRenewInterval = time.Minute
+
+// channelLockTTL is how long the lock on a channel outlives the last renewal of its lease. A channel whose server died
+// is free again after that long.
+const channelLockTTL = 3 * LeaseRenewInterval
+
+// errManagedElsewhere is what the API answers when a channel is assigned to another sync server
+const errManagedElsewhere = "this youtube channel is being managed by another server"
+
+// acquireLock takes the lock on the channel in the lock service of the manager, if there is one. It fails with
+// errManagedElsewhere if another server holds it.
+func (s *Sync) acquireLock() (release func(), err error) {
+	if s.Manager.Locks == nil {
+		return func() {}, nil
+	}
+	s.lease, err = s.Manager.Locks.Acquire("channel:"+s.YoutubeChannelID, s.Manager.HostName, channelLockTTL)
+	if errors.Is(err, lock.ErrHeld) {
+		return nil, errors.Prefix(errManagedElsewhere, err)
+	} else if err != nil {
+		return nil, errors.Prefix("could not lock the channel", err)
+	}
+	s.logger().Debugf("locked the channel with fencing token %d", s.lease.Token)
+	return func() {
+		err := s.Manager.Locks.Release(s.lease)
+		if err != nil {
+			s.logger().Warnf("could not release the lock on the channel, it expires in %s: %s", channelLockTTL, err.Error())
+		}
+	}, nil
+}
+
+// checkLease returns an error if the lease on the channel in the lock service was lost, in which case another server
+// may be syncing it and the wallet must not be written to
+func (s *Sync) checkLease() error {
+	if s.lease == nil {
+		return nil
+	}
+	err := s.Manager.Locks.Renew(s.lease)
+	if errors.Is(err, lock.ErrLost) {
+		return errors.Prefix(fmt.Sprintf("the lock on the channel (fencing token %d) was lost", s.lease.Token), err)
+	}
+	return err
+}
+
+// renewLease keeps the lease on the channel until stopRenewing is called. The lease is in the lock service if the
+// manager has one, and in the API otherwise. If the channel was taken over by another server in the meantime, the sync
+// is stopped and the channel is left to that server.
+func (s *Sync) renewLease() (stopRenewing func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(LeaseRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+			var err error
+			if s.lease != nil {
+				err = s.Manager.Locks.Renew(s.lease)
+			} else {
+				err = s.Manager.APIConfig.RenewChannelLease(s.YoutubeChannelID)
+			}
+			if err == nil {
+				continue
+			}
+			if errors.Is(err, lock.ErrLost) || strings.Contains(err.Error(), errManagedElsewhere) {
+				atomic.StoreInt32(&s.leaseLost, 1)
+				s.notifyError("%s (%s) was taken over by another server, stopping its sync", s.LbryChannelName, s.YoutubeChannelID)
+				s.grp.Stop()
+				return
+			}
+			s.logger().Warnf("could not renew the lease on the channel: %s", err.Error())
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// isLeaseLost returns true if another server took the channel over while it was being synced
+func (s *Sync) isLeaseLost() bool {
+	return atomic.LoadInt32(&s.leaseLost) == 1
+}
+
+// isLeaseStale returns true if the server the channel is assigned to didn't renew its lease for StealStaleLocks.
+// Channels whose server never renewed a lease are not considered stale.
+func (s SyncManager) isLeaseStale(channel sdk.YoutubeChannel) bool {
+	if s.Locks != nil || s.StealStaleLocks <= 0 || channel.LeaseRenewedAt == 0 {
+		return false
+	}
+	return time.Since(time.Unix(channel.LeaseRenewedAt, 0)) > s.StealStaleLocks
+}
+
+// staleLeaseAge returns how long ago the lease on the channel was last renewed
+func staleLeaseAge(channel sdk.YoutubeChannel) time.Duration {
+	return time.Since(time.Unix(channel.LeaseRenewedAt, 0)).Round(time.Second)
+}
+
+// stealStaleLock announces that a channel whose server stopped renewing its lease is about to be synced by this one
+func (s SyncManager) stealStaleLock(channel sdk.YoutubeChannel) {
+	log.Warnf("%s is assigned to %s, which last renewed its lease %s ago. Taking it over", channel.ChannelId, channel.SyncServer.String, staleLeaseAge(channel))
+	SendInfoToSlack("Taking over %s (%s) from %s, which last renewed its lease %s ago", channel.DesiredChannelName, channel.ChannelId, channel.SyncServer.String, staleLeaseAge(channel))
+}
@@ -0,0 +1,128 @@
+package ytsync
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// ChannelLimits restrict which videos of a channel are synced. They are set per channel through the API. The zero value
+// doesn't restrict anything.
+type ChannelLimits struct {
+	MaxVideos   int           // only the latest videos are synced, or the first ones of a playlist
+	MaxDuration time.Duration // longer videos are skipped
+	MaxSize     int           // in MB, larger videos fail to download. It replaces --max-size if set.
+	MinViews    uint64        // videos with fewer views are skipped
+}
+
+// channelLimits returns the limits the API set for the channel
+func channelLimits(c sdk.YoutubeChannel) ChannelLimits {
+	return ChannelLimits{
+		MaxVideos:   c.MaxVideos,
+		MaxDuration: time.Duration(c.MaxVideoDuration) * time.Second,
+		MaxSize:     c.MaxVideoSize,
+		MinViews:    c.MinViews,
+	}
+}
+
+// needsDetails returns true if the limits can't be applied without looking up the length and views of the videos
+func (l ChannelLimits) needsDetails() bool {
+	return l.MaxDuration > 0 || l.MinViews > 0
+}
+
+// videoDetails is what youtube reports about a video that the limits depend on
+type videoDetails struct {
+	length time.Duration
+	views  uint64
+}
+
+// applyLimits drops the videos the limits of the channel exclude. The videos must be sorted already.
+func (s *Sync) applyLimits(service *youtube.Service, videos []video) ([]video, error) {
+	l := s.Limits
+	if l.needsDetails() && len(videos) > 0 {
+		ids := make([]string, len(videos))
+		for i, v := range videos {
+			ids[i] = v.ID()
+		}
+		details, err := s.videoDetails(service, ids)
+		if err != nil {
+			return nil, err
+		}
+		limited := videos[:0]
+		for _, v := range videos {
+			d, ok := details[v.ID()]
+			if !ok {
+				// youtube doesn't know about it anymore, the sync will fail it the usual way
+				limited = append(limited, v)
+				continue
+			}
+			if l.MaxDuration > 0 && d.length > l.MaxDuration {
+				s.logger().Debugf("skipping %s: it's %s long, more than %s", v.ID(), d.length, l.MaxDuration)
+				continue
+			}
+			if d.views < l.MinViews {
+				s.logger().Debugf("skipping %s: it has %d views, less than %d", v.ID(), d.views, l.MinViews)
+				continue
+			}
+			limited = append(limited, v)
+		}
+		if skipped := len(videos) - len(limited); skipped > 0 {
+			s.logger().Infof("skipping %d videos that are too long or have too few views", skipped)
+		}
+		videos = limited
+	}
+
+	if l.MaxVideos > 0 && len(videos) > l.MaxVideos {
+		s.logger().Infof("only syncing %d of the %d videos of the channel", l.MaxVideos, len(videos))
+		if s.YoutubePlaylistID != "" {
+			videos = videos[:l.MaxVideos]
+		} else {
+			// channel videos are sorted oldest first
+			videos = videos[len(videos)-l.MaxVideos:]
+		}
+	}
+	return videos, nil
+}
+
+// videoDetails returns the length and view count youtube reports for the videos
+func (s *Sync) videoDetails(service *youtube.Service, ids []string) (map[string]videoDetails, error) {
+	details := make(map[string]videoDetails)
+	for _, chunk := range util.Chunk(ids, maxVideosPerList) {
+		err := s.useQuota(listCost)
+		if err != nil {
+			return nil, err
+		}
+		response, err := service.Videos.List("contentDetails,statistics").Id(strings.Join(chunk, ",")).Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting video details", s.youtubeQuota().Observe(err))
+		}
+
+		for _, item := range response.Items {
+			var d videoDetails
+			if item.ContentDetails != nil {
+				d.length, err = parseISO8601Duration(item.ContentDetails.Duration)
+				if err != nil {
+					s.logger().Warnf("%s: %s", item.Id, err.Error())
+				}
+			}
+			if item.Statistics != nil {
+				d.views = item.Statistics.ViewCount
+			}
+			details[item.Id] = d
+		}
+	}
+	return details, nil
+}
+
+// maxVideoSize returns the size in MB over which downloaded videos fail
+func (s *Sync) maxVideoSize() int {
+	if s.Limits.MaxSize > 0 {
+		return s.Limits.MaxSize
+	}
+	return s.Manager.MaxVideoSize
+}
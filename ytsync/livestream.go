@@ -0,0 +1,75 @@
+package ytsync
+
+import (
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+type livestreamStatus int
+
+const (
+	livestreamVOD     livestreamStatus = iota // the stream is over and its recording can be synced
+	livestreamOngoing                         // the stream is live or hasn't started yet
+)
+
+// livestreamStatuses looks up which of the videos are livestreams. Videos that were never streamed live are not in the
+// returned map.
+func (s *Sync) livestreamStatuses(service *youtube.Service, ids []string) (map[string]livestreamStatus, error) {
+	statuses := make(map[string]livestreamStatus)
+	for _, chunk := range util.Chunk(ids, maxVideosPerList) {
+		err := s.useQuota(listCost)
+		if err != nil {
+			return nil, err
+		}
+		response, err := service.Videos.List("liveStreamingDetails").Id(strings.Join(chunk, ",")).Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting livestream details", s.youtubeQuota().Observe(err))
+		}
+
+		for _, item := range response.Items {
+			if item.LiveStreamingDetails == nil {
+				continue
+			}
+			if item.LiveStreamingDetails.ActualEndTime != "" {
+				statuses[item.Id] = livestreamVOD
+			} else {
+				statuses[item.Id] = livestreamOngoing
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// handleLivestreams drops the livestreams that can't be synced. Ongoing and upcoming ones are never synced, and the
+// recordings of finished ones only if IncludeLivestreamVODs is set, in which case they are marked as such.
+func (s *Sync) handleLivestreams(videos []video, statuses map[string]livestreamStatus) []video {
+	handled := videos[:0]
+	for _, v := range videos {
+		status, isLivestream := statuses[v.ID()]
+		if !isLivestream {
+			handled = append(handled, v)
+			continue
+		}
+		if status == livestreamOngoing {
+			s.logger().Debugf("skipping %s: it's an ongoing or upcoming livestream", v.ID())
+			continue
+		}
+		if !s.IncludeLivestreamVODs {
+			s.logger().Debugf("skipping %s: it's a livestream recording", v.ID())
+			continue
+		}
+		if yv, ok := v.(sources.YoutubeVideo); ok {
+			v = yv.AsLivestreamVOD()
+		}
+		handled = append(handled, v)
+	}
+	if skipped := len(videos) - len(handled); skipped > 0 {
+		s.logger().Infof("skipping %d livestreams of %s", skipped, s.YoutubeChannelID)
+	}
+	return handled
+}
@@ -0,0 +1,141 @@
+package ytsync
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/stop"
+	"github.com/lbryio/lbry.go/ytsync/credits"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// localChannelID identifies the videos of a directory in the local state DB and in the logs, in place of a youtube
+// channel ID
+func localChannelID(dir string) string {
+	hash := sha1.Sum([]byte(dir))
+	return "local-" + hex.EncodeToString(hash[:])[:12]
+}
+
+// SyncLocal publishes the video files of a directory into the lbry channel, see sources.LocalSource. It uses the
+// daemon that's running, with its wallet as it is: nothing goes through S3 or the API, and the wallet is never
+// refilled. The local state DB keeps track of the videos that were published, so running it again only publishes the
+// new ones.
+func (s SyncManager) SyncLocal(dir, lbryChannelName string) (*ChannelSummary, error) {
+	if s.StateDir == "" {
+		return nil, errors.Err("a state dir is needed to keep track of the published videos")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil, errors.Err("%s is not a directory", dir)
+	}
+	err = os.MkdirAll(s.StateDir, 0755)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	s.localDB, err = localdb.Open(filepath.Join(s.StateDir, localDBFile))
+	if err != nil {
+		return nil, err
+	}
+	defer s.localDB.Close()
+	s.grp = stop.New(s.StopGroup)
+	defer s.grp.Stop()
+	s.disk = s.newDiskManager()
+	s.APIConfig = localJobs{db: s.localDB}
+	// the limit only makes sense for youtube channels, every video of the directory is published
+	s.VideosLimit = math.MaxInt32
+	s.CreditSource = credits.SourceFunc(func(address string, amount float64) (string, error) {
+		return "", errors.Err("the wallet needs %.2f more LBC to publish the videos, please send them to %s", amount, address)
+	})
+
+	channel := &Sync{
+		YoutubeChannelID:        localChannelID(dir),
+		LbryChannelName:         lbryChannelName,
+		LbryChannelClaimID:      s.LbryChannelClaimID,
+		StopOnError:             s.StopOnError,
+		MaxTries:                s.MaxTries,
+		ConcurrentVideos:        s.ConcurrentVideos,
+		ChannelConflictResolver: s.ChannelConflictResolver,
+		VideoConflictResolver:   s.VideoConflictResolver,
+		Manager:                 &s,
+		AwsS3ID:                 s.AwsS3ID,
+		AwsS3Secret:             s.AwsS3Secret,
+		GenerateThumbnails:      true,
+		ThumbnailTimestamp:      s.ThumbnailTimestamp,
+		VideoFilter:             s.VideoFilter,
+		ClaimAmounts:            s.ClaimAmounts,
+		daemon:                  jsonrpc.NewClient(s.daemonAddress(0)),
+	}
+	err = channel.localCycle(dir)
+	summary := channel.Summary()
+	return &summary, err
+}
+
+// localCycle publishes the videos of the directory with the daemon of the sync
+func (s *Sync) localCycle(dir string) (e error) {
+	s.stats = newSyncStats()
+	defer channelLogs.close(s.YoutubeChannelID)
+	s.resetState()
+	defer s.reportFailures()
+
+	syncedVideos, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusSyncing, nil)
+	if err != nil {
+		return err
+	}
+	s.syncedVideos = syncedVideos
+
+	err = s.makeVideoDirectory()
+	if err != nil {
+		return err
+	}
+	defer s.removeVideoDirectory(&e)
+	s.Source = sources.LocalSource{Dir: dir, WorkDir: s.videoDirectory, Author: s.LbryChannelName}
+
+	s.credits = s.newCreditsManager()
+	s.logger().Infof("publishing the videos of %s into %s", dir, s.LbryChannelName)
+	return s.doSync()
+}
+
+// localJobs stands in for the API in local syncs. The videos that were already processed come from the local state
+// DB, which is also where their status is recorded.
+type localJobs struct {
+	db *localdb.DB
+}
+
+func (j localJobs) FetchChannels(channelID string, after, before int64, statuses ...string) ([]sdk.YoutubeChannel, error) {
+	return nil, errors.Err("local syncs have no channel queue")
+}
+
+func (j localJobs) SetChannelStatus(channelID string, status string, progress *sdk.SyncProgress) (map[string]sdk.SyncedVideo, error) {
+	videos, err := j.db.Videos(channelID)
+	if err != nil {
+		return nil, err
+	}
+	svs := make(map[string]sdk.SyncedVideo)
+	for videoID, v := range videos {
+		switch v.Status {
+		case localdb.VideoStatusPublished:
+			svs[videoID] = sdk.SyncedVideo{VideoID: videoID, Published: true}
+		case localdb.VideoStatusFailed:
+			svs[videoID] = sdk.SyncedVideo{VideoID: videoID, FailureReason: v.FailureReason}
+		}
+	}
+	return svs, nil
+}
+
+func (j localJobs) MarkVideoStatus(channelID string, videoID string, status string, claimID string, claimName string, failureReason string) error {
+	return nil
+}
+
+func (j localJobs) RenewChannelLease(channelID string) error {
+	return nil
+}
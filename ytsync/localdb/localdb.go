@@ -0,0 +1,370 @@
+package localdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	VideoStatusPending   = "pending" // processing started but didn't finish
+	VideoStatusPublished = "published"
+	VideoStatusFailed    = "failed"
+	VideoStatusAbandoned = "abandoned" // published, then the claim was abandoned
+)
+
+// Kinds of the transactions a channel spends LBC on
+const (
+	SpendChannel = "channel" // creating or updating the channel claim
+	SpendPublish = "publish" // publishing a video
+	SpendUpdate  = "update"  // updating the claim of a video
+	SpendSupport = "support" // supporting the claim of a video
+)
+
+var (
+	videosBucket = []byte("videos")
+	// contentBucket indexes the published videos by the SHA-256 of their file, across channels
+	contentBucket = []byte("content")
+	// channelsBucket holds the state of the channels themselves
+	channelsBucket = []byte("channels")
+	// spendsBucket holds the transactions of each channel, in the order they were sent
+	spendsBucket = []byte("spends")
+)
+
+// DB keeps track of the sync state on the sync server itself, so an interrupted sync can pick up where it left off
+// even if the state never made it to the API
+type DB struct {
+	db *bolt.DB
+}
+
+// Video is the local state of a single video
+type Video struct {
+	Status        string    `json:"status"`
+	ClaimID       string    `json:"claim_id,omitempty"`
+	ClaimName     string    `json:"claim_name,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	Duration      float64   `json:"duration,omitempty"` // of the published file in seconds, 0 if unknown
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Content is a published video, as found by the hash of its file
+type Content struct {
+	ChannelID   string    `json:"channel_id"`
+	VideoID     string    `json:"video_id"`
+	ClaimID     string    `json:"claim_id"`
+	ClaimName   string    `json:"claim_name"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Channel is the local state of a channel
+type Channel struct {
+	LastUploadAt time.Time `json:"last_upload_at"`     // when the most recently uploaded video that was published was uploaded to youtube
+	Branding     string    `json:"branding,omitempty"` // fingerprint of the youtube branding last put in the channel claim
+	// UploadsPlaylist is the ID of the uploads playlist of the channel, and UploadsETag the ETag of its latest page at
+	// the end of the last successful sync. If the page has the same ETag, the channel didn't upload anything since.
+	UploadsPlaylist string `json:"uploads_playlist,omitempty"`
+	UploadsETag     string `json:"uploads_etag,omitempty"`
+	// Spent is the LBC the channel spent over every sync, the sum of the amounts and fees of its Spends
+	Spent float64 `json:"spent,omitempty"`
+}
+
+// Spend is a transaction the channel spent LBC on. The amount of a claim or support still belongs to the wallet, but
+// it can't be spent anymore unless the claim is abandoned.
+type Spend struct {
+	Kind    string    `json:"kind"`
+	VideoID string    `json:"video_id,omitempty"`
+	ClaimID string    `json:"claim_id,omitempty"`
+	Txid    string    `json:"txid,omitempty"`
+	Amount  float64   `json:"amount"`
+	Fee     float64   `json:"fee"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Open opens the database at path, creating it if needed. Only one process can have it open at a time.
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Prefix("could not open local db "+path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{videosBucket, contentBucket, channelsBucket, spendsBucket} {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Err(err)
+	}
+	return &DB{db: db}, nil
+}
+
+func (d *DB) Close() error {
+	return errors.Err(d.db.Close())
+}
+
+// Video returns the state of a video. ok is false if there is no state for it.
+func (d *DB) Video(channelID, videoID string) (v Video, ok bool, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		channel := tx.Bucket(videosBucket).Bucket([]byte(channelID))
+		if channel == nil {
+			return nil
+		}
+		data := channel.Get([]byte(videoID))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &v)
+	})
+	return v, ok, errors.Err(err)
+}
+
+// Videos returns the state of every video of a channel, keyed by video ID
+func (d *DB) Videos(channelID string) (map[string]Video, error) {
+	videos := make(map[string]Video)
+	err := d.db.View(func(tx *bolt.Tx) error {
+		channel := tx.Bucket(videosBucket).Bucket([]byte(channelID))
+		if channel == nil {
+			return nil
+		}
+		return channel.ForEach(func(k, data []byte) error {
+			var v Video
+			err := json.Unmarshal(data, &v)
+			if err != nil {
+				return err
+			}
+			videos[string(k)] = v
+			return nil
+		})
+	})
+	return videos, errors.Err(err)
+}
+
+// SetVideo stores the state of a video. UpdatedAt is set to the current time.
+func (d *DB) SetVideo(channelID, videoID string, v Video) error {
+	v.UpdatedAt = time.Now()
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = d.db.Update(func(tx *bolt.Tx) error {
+		channel, err := tx.Bucket(videosBucket).CreateBucketIfNotExists([]byte(channelID))
+		if err != nil {
+			return err
+		}
+		return channel.Put([]byte(videoID), data)
+	})
+	return errors.Err(err)
+}
+
+// SetPending records that a video is about to be published
+func (d *DB) SetPending(channelID, videoID string) error {
+	return d.SetVideo(channelID, videoID, Video{Status: VideoStatusPending})
+}
+
+// SetPublished records that a video was published under the given claim
+func (d *DB) SetPublished(channelID, videoID, claimID, claimName string, duration time.Duration) error {
+	return d.SetVideo(channelID, videoID, Video{Status: VideoStatusPublished, ClaimID: claimID, ClaimName: claimName, Duration: duration.Seconds()})
+}
+
+// SetFailed records that a video could not be published
+func (d *DB) SetFailed(channelID, videoID, reason string) error {
+	return d.SetVideo(channelID, videoID, Video{Status: VideoStatusFailed, FailureReason: reason})
+}
+
+// SetAbandoned records that the claim a video was published under was abandoned. The claim is dropped from the
+// content index, so the content can be published again.
+func (d *DB) SetAbandoned(channelID, videoID, claimID, claimName string) error {
+	err := d.SetVideo(channelID, videoID, Video{Status: VideoStatusAbandoned, ClaimID: claimID, ClaimName: claimName})
+	if err != nil {
+		return err
+	}
+	return d.RemoveContent(claimID)
+}
+
+// ContentByHash returns the video that was published with a file with the given SHA-256 (hex encoded). ok is false if
+// there is none.
+func (d *DB) ContentByHash(hash string) (c Content, ok bool, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(contentBucket).Get([]byte(hash))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &c)
+	})
+	return c, ok, errors.Err(err)
+}
+
+// SetContent indexes a published video by the SHA-256 (hex encoded) of its file. PublishedAt is set to the current
+// time.
+func (d *DB) SetContent(hash string, c Content) error {
+	c.PublishedAt = time.Now()
+	data, err := json.Marshal(c)
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(contentBucket).Put([]byte(hash), data)
+	})
+	return errors.Err(err)
+}
+
+// RemoveContent drops the videos published under the claim from the content index
+func (d *DB) RemoveContent(claimID string) error {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(contentBucket)
+		var hashes [][]byte
+		err := bucket.ForEach(func(k, data []byte) error {
+			var c Content
+			err := json.Unmarshal(data, &c)
+			if err != nil {
+				return err
+			}
+			if c.ClaimID == claimID {
+				hashes = append(hashes, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, hash := range hashes {
+			err = bucket.Delete(hash)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Err(err)
+}
+
+// Channel returns the state of a channel. ok is false if there is no state for it.
+func (d *DB) Channel(channelID string) (c Channel, ok bool, err error) {
+	err = d.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(channelsBucket).Get([]byte(channelID))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &c)
+	})
+	return c, ok, errors.Err(err)
+}
+
+// SetLastUpload records that a video uploaded to youtube at uploadedAt was published. Older videos don't change the
+// state of the channel.
+func (d *DB) SetLastUpload(channelID string, uploadedAt time.Time) error {
+	return d.updateChannel(channelID, func(c *Channel) bool {
+		if !uploadedAt.After(c.LastUploadAt) {
+			return false
+		}
+		c.LastUploadAt = uploadedAt
+		return true
+	})
+}
+
+// SetBranding records the fingerprint of the branding that was put in the channel claim
+func (d *DB) SetBranding(channelID, fingerprint string) error {
+	return d.updateChannel(channelID, func(c *Channel) bool {
+		c.Branding = fingerprint
+		return true
+	})
+}
+
+// SetUploadsETag records the ETag of the latest page of the uploads playlist of the channel
+func (d *DB) SetUploadsETag(channelID, playlistID, etag string) error {
+	return d.updateChannel(channelID, func(c *Channel) bool {
+		c.UploadsPlaylist = playlistID
+		c.UploadsETag = etag
+		return true
+	})
+}
+
+// AddSpend records a transaction of the channel and adds what it spent to Channel.Spent. SentAt is set to the current
+// time.
+func (d *DB) AddSpend(channelID string, sp Spend) error {
+	sp.SentAt = time.Now()
+	data, err := json.Marshal(sp)
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = d.db.Update(func(tx *bolt.Tx) error {
+		spends, err := tx.Bucket(spendsBucket).CreateBucketIfNotExists([]byte(channelID))
+		if err != nil {
+			return err
+		}
+		seq, err := spends.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		err = spends.Put(key, data)
+		if err != nil {
+			return err
+		}
+		return updateChannel(tx, channelID, func(c *Channel) bool {
+			c.Spent += sp.Amount + sp.Fee
+			return true
+		})
+	})
+	return errors.Err(err)
+}
+
+// Spends returns the transactions of the channel, oldest first
+func (d *DB) Spends(channelID string) ([]Spend, error) {
+	var spends []Spend
+	err := d.db.View(func(tx *bolt.Tx) error {
+		channel := tx.Bucket(spendsBucket).Bucket([]byte(channelID))
+		if channel == nil {
+			return nil
+		}
+		return channel.ForEach(func(k, data []byte) error {
+			var sp Spend
+			err := json.Unmarshal(data, &sp)
+			if err != nil {
+				return err
+			}
+			spends = append(spends, sp)
+			return nil
+		})
+	})
+	return spends, errors.Err(err)
+}
+
+// updateChannel applies change to the state of the channel, and saves it if change returns true
+func (d *DB) updateChannel(channelID string, change func(c *Channel) bool) error {
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		return updateChannel(tx, channelID, change)
+	})
+	return errors.Err(err)
+}
+
+func updateChannel(tx *bolt.Tx, channelID string, change func(c *Channel) bool) error {
+	bucket := tx.Bucket(channelsBucket)
+	var c Channel
+	if data := bucket.Get([]byte(channelID)); data != nil {
+		err := json.Unmarshal(data, &c)
+		if err != nil {
+			return err
+		}
+	}
+	if !change(&c) {
+		return nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(channelID), data)
+}
@@ -0,0 +1,148 @@
+package localdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTemp opens a db in a new temp dir. The dir is removed by the returned cleanup, which closes the db first if it's
+// still open.
+func openTemp(t *testing.T) (*DB, string, func()) {
+	dir, err := ioutil.TempDir("", "localdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "state.db")
+	d, err := Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return d, path, func() {
+		d.db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestVideoRoundTrip(t *testing.T) {
+	d, _, cleanup := openTemp(t)
+	defer cleanup()
+
+	if _, ok, err := d.Video("UCchannel", "pending"); err != nil || ok {
+		t.Fatalf("expected no state for an unknown video, got %t (%v)", ok, err)
+	}
+
+	if err := d.SetPending("UCchannel", "pending"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPublished("UCchannel", "published", "claim1", "my-video", 90*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetFailed("UCchannel", "failed", "this video is unavailable"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPublished("UCother", "elsewhere", "claim2", "other-video", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok, err := d.Video("UCchannel", "pending")
+	if err != nil || !ok {
+		t.Fatalf("expected the pending video, got %t (%v)", ok, err)
+	}
+	if v.Status != VideoStatusPending || v.UpdatedAt.IsZero() {
+		t.Errorf("unexpected pending video %+v", v)
+	}
+
+	v, _, _ = d.Video("UCchannel", "published")
+	if v.Status != VideoStatusPublished || v.ClaimID != "claim1" || v.ClaimName != "my-video" || v.Duration != 90 {
+		t.Errorf("unexpected published video %+v", v)
+	}
+
+	v, _, _ = d.Video("UCchannel", "failed")
+	if v.Status != VideoStatusFailed || v.FailureReason != "this video is unavailable" || v.ClaimID != "" {
+		t.Errorf("unexpected failed video %+v", v)
+	}
+
+	videos, err := d.Videos("UCchannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 3 {
+		t.Fatalf("expected the 3 videos of the channel, got %d", len(videos))
+	}
+	if videos["published"].ClaimID != "claim1" || videos["failed"].Status != VideoStatusFailed {
+		t.Errorf("unexpected videos %+v", videos)
+	}
+	if _, ok := videos["elsewhere"]; ok {
+		t.Error("expected the videos of other channels to be left out")
+	}
+
+	if videos, err := d.Videos("UCunknown"); err != nil || len(videos) != 0 {
+		t.Errorf("expected no videos for an unknown channel, got %d (%v)", len(videos), err)
+	}
+}
+
+func TestSetVideoReplacesState(t *testing.T) {
+	d, _, cleanup := openTemp(t)
+	defer cleanup()
+
+	if err := d.SetFailed("UCchannel", "video", "download error"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetPublished("UCchannel", "video", "claim1", "my-video", 0); err != nil {
+		t.Fatal(err)
+	}
+	v, _, _ := d.Video("UCchannel", "video")
+	if v.Status != VideoStatusPublished || v.FailureReason != "" {
+		t.Errorf("expected the failure to be replaced, got %+v", v)
+	}
+}
+
+func TestReopenKeepsState(t *testing.T) {
+	d, path, cleanup := openTemp(t)
+	defer cleanup()
+
+	if err := d.SetPublished("UCchannel", "video", "claim1", "my-video", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetFailed("UCchannel", "broken", "invalid video"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AddSpend("UCchannel", Spend{Kind: "publish", VideoID: "video", Amount: 0.01, Fee: 0.002}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.db = reopened.db // closed by cleanup
+
+	videos, err := reopened.Videos("UCchannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 2 || videos["video"].ClaimID != "claim1" || videos["video"].Duration != 60 || videos["broken"].FailureReason != "invalid video" {
+		t.Errorf("expected the videos to be kept, got %+v", videos)
+	}
+	spends, err := reopened.Spends("UCchannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spends) != 1 || spends[0].VideoID != "video" {
+		t.Errorf("expected the spend to be kept, got %+v", spends)
+	}
+	c, ok, err := reopened.Channel("UCchannel")
+	if err != nil || !ok {
+		t.Fatalf("expected the channel state to be kept, got %t (%v)", ok, err)
+	}
+	if c.Spent < 0.0119 || c.Spent > 0.0121 {
+		t.Errorf("expected 0.012 LBC spent, got %f", c.Spent)
+	}
+}
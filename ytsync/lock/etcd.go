@@ -0,0 +1,138 @@
+package lock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+const etcdKeyPrefix = "/ytsync/lock/"
+
+// Etcd holds the leases in etcd, as keys attached to etcd leases. The fencing token of a lease is the revision its key
+// was created at.
+type Etcd struct {
+	endpoint string
+	http     *http.Client
+}
+
+// NewEtcd returns a locker using the JSON gateway of etcd at endpoint, e.g. http://localhost:2379
+func NewEtcd(endpoint string) *Etcd {
+	return &Etcd{endpoint: endpoint, http: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// etcd encodes 64 bit integers as strings, and keys and values in base64, which is how encoding/json encodes []byte
+type etcdKV struct {
+	Value          []byte `json:"value"`
+	CreateRevision int64  `json:"create_revision,string"`
+}
+
+// Acquire takes the key for owner, creating it with a new etcd lease if it doesn't exist
+func (e *Etcd) Acquire(key, owner string, ttl time.Duration) (*Lease, error) {
+	var grant struct {
+		ID  string `json:"ID"`
+		TTL string `json:"TTL"`
+	}
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	err := e.post("/v3/lease/grant", map[string]interface{}{"TTL": seconds}, &grant)
+	if err != nil {
+		return nil, err
+	}
+
+	k := []byte(etcdKeyPrefix + key)
+	var txn struct {
+		Header struct {
+			Revision int64 `json:"revision,string"`
+		} `json:"header"`
+		Succeeded bool `json:"succeeded"`
+		Responses []struct {
+			ResponseRange struct {
+				Kvs []etcdKV `json:"kvs"`
+			} `json:"response_range"`
+		} `json:"responses"`
+	}
+	err = e.post("/v3/kv/txn", map[string]interface{}{
+		"compare": []interface{}{map[string]interface{}{"key": k, "target": "CREATE", "result": "EQUAL", "create_revision": "0"}},
+		"success": []interface{}{map[string]interface{}{"request_put": map[string]interface{}{"key": k, "value": []byte(owner), "lease": grant.ID}}},
+		"failure": []interface{}{map[string]interface{}{"request_range": map[string]interface{}{"key": k}}},
+	}, &txn)
+	if err == nil && !txn.Succeeded {
+		holder := "someone else"
+		if len(txn.Responses) > 0 && len(txn.Responses[0].ResponseRange.Kvs) > 0 {
+			holder = string(txn.Responses[0].ResponseRange.Kvs[0].Value)
+		}
+		err = heldBy(key, holder)
+	}
+	if err != nil {
+		_ = e.post("/v3/lease/revoke", map[string]interface{}{"ID": grant.ID}, nil)
+		return nil, err
+	}
+	return &Lease{Key: key, Owner: owner, Token: txn.Header.Revision, TTL: ttl, id: grant.ID}, nil
+}
+
+// Renew refreshes the etcd lease, after making sure the key is still the one created for the lease
+func (e *Etcd) Renew(l *Lease) error {
+	var current struct {
+		Kvs []etcdKV `json:"kvs"`
+	}
+	err := e.post("/v3/kv/range", map[string]interface{}{"key": []byte(etcdKeyPrefix + l.Key)}, &current)
+	if err != nil {
+		return err
+	}
+	if len(current.Kvs) == 0 || current.Kvs[0].CreateRevision != l.Token {
+		return errors.Err(ErrLost)
+	}
+
+	var keepAlive struct {
+		Result struct {
+			TTL string `json:"TTL"`
+		} `json:"result"`
+	}
+	err = e.post("/v3/lease/keepalive", map[string]interface{}{"ID": l.id}, &keepAlive)
+	if err != nil {
+		return err
+	}
+	if ttl, _ := strconv.ParseInt(keepAlive.Result.TTL, 10, 64); ttl <= 0 {
+		return errors.Err(ErrLost)
+	}
+	return nil
+}
+
+// Release revokes the etcd lease, which deletes the key
+func (e *Etcd) Release(l *Lease) error {
+	return e.post("/v3/lease/revoke", map[string]interface{}{"ID": l.id}, nil)
+}
+
+func (e *Etcd) post(path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return errors.Err(err)
+	}
+	res, err := e.http.Post(e.endpoint+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Prefix("etcd error", err)
+	}
+	defer res.Body.Close()
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Prefix("etcd error", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.Err("etcd error: %s returned status code %d: %s", path, res.StatusCode, bytes.TrimSpace(raw))
+	}
+	if response == nil {
+		return nil
+	}
+	err = json.Unmarshal(raw, response)
+	if err != nil {
+		return errors.Prefix("etcd error: could not decode the response of "+path, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package lock coordinates sync servers with leases held in a lock service, Redis or etcd. A lease expires unless it's
+// renewed, so the channel of a server that died is freed after its TTL, and every lease of a key gets a fencing token
+// that is greater than the one of the lease before it, so that writes from a server that lost its lease without
+// noticing can be told apart.
+package lock
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+var (
+	// ErrHeld is returned when acquiring a key another owner holds
+	ErrHeld = errors.Base("lock is held by another owner")
+	// ErrLost is returned when renewing a lease that expired or was taken over
+	ErrLost = errors.Base("lease was lost")
+)
+
+// Lease is the hold of an owner on a key
+type Lease struct {
+	Key   string
+	Owner string
+	// Token is the fencing token of the lease. It's greater than the token of every lease of the key before it.
+	Token int64
+	TTL   time.Duration
+
+	id string // how the lock service knows the lease
+}
+
+// Locker is a lock service
+type Locker interface {
+	// Acquire takes the key for owner for ttl, unless someone else holds it. It returns an error wrapping ErrHeld then,
+	// which names the owner.
+	Acquire(key, owner string, ttl time.Duration) (*Lease, error)
+	// Renew extends the lease by its TTL. It returns ErrLost if the lease isn't held anymore, in which case whatever
+	// it protects must not be written to.
+	Renew(l *Lease) error
+	// Release frees the key for others, if the lease still holds it
+	Release(l *Lease) error
+}
+
+// Open connects to the lock service at address: redis://HOST:PORT[/DB] or etcd://HOST:PORT (etcd-https:// for TLS). etcd
+// is reached through its JSON gateway, which etcd 3.3 and later serve on the client port.
+func Open(address string) (Locker, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if u.Host == "" {
+		return nil, errors.Err("lock service %q has no host", address)
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedis(u.Host, u.Path), nil
+	case "etcd":
+		return NewEtcd("http://" + u.Host), nil
+	case "etcd-https":
+		return NewEtcd("https://" + u.Host), nil
+	}
+	return nil, errors.Err("unknown lock service %q, use redis:// or etcd://", u.Scheme)
+}
+
+// heldBy returns ErrHeld naming the owner of the key
+func heldBy(key, owner string) error {
+	return errors.Prefix(key+" is held by "+owner, ErrHeld)
+}
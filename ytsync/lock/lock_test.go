@@ -0,0 +1,148 @@
+package lock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+func TestOpen(t *testing.T) {
+	for address, ok := range map[string]bool{
+		"redis://localhost:6379":   true,
+		"redis://localhost:6379/2": true,
+		"etcd://localhost:2379":    true,
+		"etcd-https://etcd:2379":   true,
+		"zookeeper://zk:2181":      false,
+		"localhost:6379":           false,
+	} {
+		_, err := Open(address)
+		if (err == nil) != ok {
+			t.Errorf("%s: expected success %t, got %v", address, ok, err)
+		}
+	}
+}
+
+// fakeEtcd implements the parts of the etcd JSON gateway the locker uses
+type fakeEtcd struct {
+	mux      sync.Mutex
+	revision int64
+	leases   map[string]bool
+	keys     map[string]fakeKey
+}
+
+type fakeKey struct {
+	value          []byte
+	createRevision int64
+	lease          string
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	var req struct {
+		ID      string `json:"ID"`
+		Key     []byte `json:"key"`
+		Compare []struct {
+			Key []byte `json:"key"`
+		} `json:"compare"`
+		Success []struct {
+			Put struct {
+				Value []byte `json:"value"`
+				Lease string `json:"lease"`
+			} `json:"request_put"`
+		} `json:"success"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	kvs := func(key string) []map[string]interface{} {
+		k, ok := f.keys[key]
+		if !ok {
+			return nil
+		}
+		return []map[string]interface{}{{"value": k.value, "create_revision": strconv.FormatInt(k.createRevision, 10)}}
+	}
+	var res interface{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.revision++
+		id := strconv.FormatInt(f.revision, 10)
+		f.leases[id] = true
+		res = map[string]string{"ID": id, "TTL": "60"}
+	case "/v3/lease/keepalive":
+		ttl := "0"
+		if f.leases[req.ID] {
+			ttl = "60"
+		}
+		res = map[string]interface{}{"result": map[string]string{"TTL": ttl}}
+	case "/v3/lease/revoke":
+		delete(f.leases, req.ID)
+		for key, k := range f.keys {
+			if k.lease == req.ID {
+				delete(f.keys, key)
+			}
+		}
+		res = map[string]string{}
+	case "/v3/kv/range":
+		res = map[string]interface{}{"kvs": kvs(string(req.Key))}
+	case "/v3/kv/txn":
+		key := string(req.Compare[0].Key)
+		if _, held := f.keys[key]; held {
+			res = map[string]interface{}{"succeeded": false, "responses": []interface{}{map[string]interface{}{"response_range": map[string]interface{}{"kvs": kvs(key)}}}}
+			break
+		}
+		f.revision++
+		f.keys[key] = fakeKey{value: req.Success[0].Put.Value, createRevision: f.revision, lease: req.Success[0].Put.Lease}
+		res = map[string]interface{}{"succeeded": true, "header": map[string]string{"revision": strconv.FormatInt(f.revision, 10)}}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func TestEtcd(t *testing.T) {
+	fake := &fakeEtcd{leases: make(map[string]bool), keys: make(map[string]fakeKey)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	e := NewEtcd(server.URL)
+
+	first, err := e.Acquire("channel:UC1", "server-1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.Acquire("channel:UC1", "server-2", time.Minute)
+	if !errors.Is(err, ErrHeld) {
+		t.Fatalf("expected the key to be held, got %v", err)
+	}
+	if err := e.Renew(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lease expires and another server takes the key
+	fake.mux.Lock()
+	delete(fake.leases, first.id)
+	delete(fake.keys, etcdKeyPrefix+"channel:UC1")
+	fake.mux.Unlock()
+	second, err := e.Acquire("channel:UC1", "server-2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Token <= first.Token {
+		t.Errorf("expected the fencing token to grow, got %d then %d", first.Token, second.Token)
+	}
+	if err := e.Renew(first); !errors.Is(err, ErrLost) {
+		t.Errorf("expected the first lease to be lost, got %v", err)
+	}
+
+	if err := e.Release(second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Acquire("channel:UC1", "server-1", time.Minute); err != nil {
+		t.Errorf("expected the key to be free after its release, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package lock
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const redisKeyPrefix = "ytsync:lock:"
+
+// the value of a held key is OWNER/TOKEN. The fencing token counter of a key is kept in KEY:fence, which doesn't expire.
+var (
+	// KEYS[1] the key, KEYS[2] its fencing counter, ARGV[1] the owner, ARGV[2] the TTL in ms. Returns the token, or the
+	// value of the key if it's held.
+	acquireScript = redis.NewScript(2, `
+local held = redis.call('GET', KEYS[1])
+if held then return held end
+local token = redis.call('INCR', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. '/' .. token, 'PX', ARGV[2])
+return token`)
+	// KEYS[1] the key, ARGV[1] the value of the lease, ARGV[2] the TTL in ms. Returns 1 if the lease is held.
+	renewScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return 0`)
+	// KEYS[1] the key, ARGV[1] the value of the lease
+	releaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`)
+)
+
+// Redis holds the leases in a Redis server, as keys that expire
+type Redis struct {
+	pool *redis.Pool
+}
+
+// NewRedis returns a locker using the Redis server at addr. db is the database to use, e.g. "/2", the first if empty.
+func NewRedis(addr, db string) *Redis {
+	var options []redis.DialOption
+	if n, err := strconv.Atoi(strings.TrimPrefix(db, "/")); err == nil {
+		options = append(options, redis.DialDatabase(n))
+	}
+	return &Redis{pool: &redis.Pool{
+		MaxIdle:     3,
+		IdleTimeout: 5 * time.Minute,
+		Dial:        func() (redis.Conn, error) { return redis.Dial("tcp", addr, options...) },
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if time.Since(t) < time.Minute {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+	}}
+}
+
+// Acquire takes the key for owner
+func (r *Redis) Acquire(key, owner string, ttl time.Duration) (*Lease, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	reply, err := acquireScript.Do(conn, redisKeyPrefix+key, redisKeyPrefix+key+":fence", owner, milliseconds(ttl))
+	if err != nil {
+		return nil, errors.Prefix("redis error", err)
+	}
+	if held, ok := reply.([]byte); ok {
+		return nil, heldBy(key, ownerOf(string(held)))
+	}
+	token, err := redis.Int64(reply, nil)
+	if err != nil {
+		return nil, errors.Prefix("redis error", err)
+	}
+	return &Lease{Key: key, Owner: owner, Token: token, TTL: ttl, id: owner + "/" + strconv.FormatInt(token, 10)}, nil
+}
+
+// Renew extends the lease if the key still holds it
+func (r *Redis) Renew(l *Lease) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	renewed, err := redis.Int(renewScript.Do(conn, redisKeyPrefix+l.Key, l.id, milliseconds(l.TTL)))
+	if err != nil {
+		return errors.Prefix("redis error", err)
+	}
+	if renewed != 1 {
+		return errors.Err(ErrLost)
+	}
+	return nil
+}
+
+// Release deletes the key if it still holds the lease
+func (r *Redis) Release(l *Lease) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := releaseScript.Do(conn, redisKeyPrefix+l.Key, l.id)
+	if err != nil {
+		return errors.Prefix("redis error", err)
+	}
+	return nil
+}
+
+func ownerOf(value string) string {
+	if i := strings.LastIndex(value, "/"); i >= 0 {
+		return value[:i]
+	}
+	return value
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
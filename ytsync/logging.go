@@ -0,0 +1,215 @@
+package ytsync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/notify"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fields every entry logged for a channel or a video is tagged with
+const (
+	logFieldChannel = "channel_id"
+	logFieldVideo   = "video_id"
+	logFieldAttempt = "attempt"
+)
+
+// channelLogs is the hook writing the per channel log files, if SetupLogging was given a directory
+var channelLogs *channelLogHook
+
+// SetupLogging configures the process log. format is text or json. If dir is set, the entries about each channel are
+// also written to dir/CHANNEL_ID.log, in JSON.
+func SetupLogging(format, dir string) error {
+	switch format {
+	case "", "text":
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return errors.Err("unknown log format %q, use text or json", format)
+	}
+	if dir == "" {
+		return nil
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return errors.Err(err)
+	}
+	channelLogs = &channelLogHook{dir: dir, files: make(map[string]*os.File)}
+	log.AddHook(channelLogs)
+	return nil
+}
+
+// channelLogHook appends the entries tagged with a channel ID to the log file of the channel
+type channelLogHook struct {
+	dir       string
+	formatter log.JSONFormatter
+	mux       sync.Mutex
+	files     map[string]*os.File
+}
+
+func (h *channelLogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *channelLogHook) Fire(entry *log.Entry) error {
+	channelID, ok := entry.Data[logFieldChannel].(string)
+	if !ok || channelID == "" {
+		return nil
+	}
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	f, ok := h.files[channelID]
+	if !ok {
+		f, err = os.OpenFile(filepath.Join(h.dir, channelID+".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		h.files[channelID] = f
+	}
+	_, err = f.Write(line)
+	return err
+}
+
+// close closes the log file of the channel. It's opened again if anything else is logged for the channel.
+func (h *channelLogHook) close(channelID string) {
+	if h == nil {
+		return
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if f, ok := h.files[channelID]; ok {
+		f.Close()
+		delete(h.files, channelID)
+	}
+}
+
+// logger returns the log of the channel
+func (s *Sync) logger() *log.Entry {
+	return log.WithField(logFieldChannel, s.YoutubeChannelID)
+}
+
+// videoLogger returns the log of an attempt at syncing a video of the channel. Attempts start at 1.
+func (s *Sync) videoLogger(videoID string, attempt int) *log.Entry {
+	return s.logger().WithFields(log.Fields{logFieldVideo: videoID, logFieldAttempt: attempt})
+}
+
+// notifyError sends an error about the channel to the registered notifiers and to the log of the channel
+func (s *Sync) notifyError(format string, a ...interface{}) error {
+	message := formatMessage(format, a...)
+	s.logger().Errorln(message)
+	return notify.Error(message)
+}
+
+// notifyInfo sends a message about the channel to the registered notifiers and to the log of the channel
+func (s *Sync) notifyInfo(format string, a ...interface{}) error {
+	message := formatMessage(format, a...)
+	s.logger().Infoln(message)
+	return notify.Info(message)
+}
+
+// notifyVideoError sends an error about a single video of the channel to the registered notifiers. With NotifyDigest,
+// it's only logged and held back for the digest sent at the end of the sync.
+func (s *Sync) notifyVideoError(format string, a ...interface{}) error {
+	if !s.Manager.NotifyDigest {
+		return s.notifyError(format, a...)
+	}
+	message := formatMessage(format, a...)
+	s.logger().Errorln(message)
+	s.failuresMux.Lock()
+	defer s.failuresMux.Unlock()
+	s.videoEvents = append(s.videoEvents, message)
+	return nil
+}
+
+// addFailure records that a video failed, to be reported with the others at the end of the sync
+func (s *Sync) addFailure(videoID string, err error) {
+	s.failuresMux.Lock()
+	defer s.failuresMux.Unlock()
+	s.failures.Append(errors.Prefix(categoryTag(err)+"video "+videoID, err))
+}
+
+// reportFailures sends the videos that failed during the sync to the notifiers in a single message, grouped by root
+// cause, see triageReport. Their errors and stack traces only go to the log.
+func (s *Sync) reportFailures() {
+	if s.Manager.NotifyDigest {
+		s.reportDigest()
+		return
+	}
+	s.failuresMux.Lock()
+	defer s.failuresMux.Unlock()
+	err := s.failures.ErrorOrNil()
+	if err == nil {
+		return
+	}
+	s.notifyError("%s", s.triageReport())
+	s.logger().Errorln(err.Error())
+	s.logger().Debugln(errors.FullTrace(err))
+}
+
+// reportDigest sends a single notification summing up the sync of the channel, instead of one per video. The failures
+// and the events held back by notifyVideoError are attached to it, in a thread on Slack. Syncs that did nothing aren't
+// reported.
+func (s *Sync) reportDigest() {
+	summary := s.Summary()
+	s.failuresMux.Lock()
+	defer s.failuresMux.Unlock()
+	if summary.VideosPublished == 0 && s.failures.Len() == 0 && len(s.videoEvents) == 0 {
+		return
+	}
+
+	details := make([]string, 0, s.failures.Len()+len(s.videoEvents))
+	for _, err := range s.failures.Errors {
+		details = append(details, err.Error())
+	}
+	details = append(details, s.videoEvents...)
+	level := notify.LevelInfo
+	if len(details) > 0 {
+		level = notify.LevelError
+	}
+	duration := time.Duration(summary.DurationSeconds * float64(time.Second)).Round(time.Second)
+	message := fmt.Sprintf("Synced %s (%s) in %s: %d published, %d failed, %d skipped, %.3f LBC spent",
+		s.LbryChannelName, s.YoutubeChannelID, duration, summary.VideosPublished, summary.VideosFailed,
+		summary.VideosSkipped, summary.Spent)
+	if len(summary.Failures) > 0 {
+		message += " (" + formatTriageCounts(summary.Failures) + ")"
+	}
+	s.logger().Infoln(message)
+	notify.Thread(level, message, details)
+	if s.failures.Len() > 0 {
+		s.logger().Debugln(errors.FullTrace(s.failures))
+	}
+}
+
+// categoryTag returns the category of err for notifications to start with, e.g. "[daemon] ", so they can be filtered
+// on. It's empty if err has no category.
+func categoryTag(err error) string {
+	c := errors.CategoryOf(err)
+	if c == errors.Uncategorized {
+		return ""
+	}
+	return "[" + c.String() + "] "
+}
+
+func formatMessage(format string, a ...interface{}) string {
+	if len(a) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, a...)
+}
+
+// flagVideo reports a video that broke a validation rule set to flag it, see sources.ValidationRules
+func (s *Sync) flagVideo(videoID, rule, problem string) {
+	videosFlagged.Inc(rule)
+	s.notifyVideoError("%s broke validation rule %s, it's published anyway: %s", videoID, rule, problem)
+}
@@ -1,40 +1,52 @@
 package ytsync
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"net/url"
-	"strconv"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/lbryio/lbry.go/errors"
-	"github.com/lbryio/lbry.go/null"
+	"github.com/lbryio/lbry.go/retry"
+	"github.com/lbryio/lbry.go/stop"
 	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/apicache"
+	"github.com/lbryio/lbry.go/ytsync/credits"
+	"github.com/lbryio/lbry.go/ytsync/disk"
+	"github.com/lbryio/lbry.go/ytsync/errorrules"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/lock"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+	"github.com/lbryio/lbry.go/ytsync/staging"
+	"github.com/lbryio/lbry.go/ytsync/walletbackup"
+	"github.com/lbryio/lbry.go/ytsync/ytdlp"
 	log "github.com/sirupsen/logrus"
 )
 
 type SyncManager struct {
 	StopOnError             bool
 	MaxTries                int
-	TakeOverExistingChannel bool
+	ChannelConflictResolver sources.NameResolver // what to do if the channel name is held by someone else. skip if not set
+	VideoConflictResolver   sources.NameResolver // what to do if the claim name of a video is held by someone else
 	Refill                  int
 	Limit                   int
 	SkipSpaceCheck          bool
 	SyncUpdate              bool
-	SyncStatus              string
+	SyncStatuses            []string
 	SyncFrom                int64
 	SyncUntil               int64
 	ConcurrentJobs          int
 	ConcurrentVideos        int
+	MaxConcurrentVideos     int // if above ConcurrentVideos, the videos processed at once are scaled up to it while the daemon keeps up
+	ConcurrentChannels      int
 	HostName                string
 	YoutubeChannelID        string
+	YoutubePlaylistID       string // only sync this playlist of the channel. Requires YoutubeChannelID
+	LbryChannelClaimID      string
 	YoutubeAPIKey           string
-	ApiURL                  string
-	ApiToken                string
+	APIConfig               sdk.Jobs // the API, or its database with --db-dsn
 	BlobsDir                string
 	VideosLimit             int
 	MaxVideoSize            int
@@ -44,6 +56,89 @@ type SyncManager struct {
 	AwsS3Region             string
 	AwsS3Bucket             string
 	SingleRun               bool
+	ForceTakeover           bool
+	GenerateThumbnails      bool
+	ThumbnailTimestamp      time.Duration
+	MinimumBalance          float64
+	StateDir                string
+	Pipeline                bool
+	PipelineBuffer          int
+	SummaryOutput           string
+	StatusAddr              string
+	VerifyDownloads         bool
+	DryRun                  bool
+	MaxDownloadRate         int64 // bytes per second, 0 for no limit
+	MaxVideosPerHour        int   // 0 for no limit
+	DaemonMode              bool  // keep polling the API for channels until stopped
+	PollInterval            time.Duration
+	Interval                time.Duration // with DaemonMode, go over the channels again this often, fetching only their new uploads
+	MetricsAddr             string
+	VideoFilter             VideoFilter
+	TranscodeProfile        *sources.TranscodeProfile
+	Duplicates              sources.DuplicateAction
+	ClaimAmounts            ClaimAmounts          // defaults for every channel, the API can override them per channel
+	MetadataConfig          *MetadataConfig       // per channel customization of the metadata of the published videos
+	RefillThreshold         float64               // the wallet is refilled before a publish if it holds less credits
+	CreditSource            credits.Source        // where refills come from. lbrycrd if not set
+	YoutubeQuota            int64                 // YouTube API units available per day
+	QuotaFallbackRSS        bool                  // list videos from the channel feed when the API quota is used up
+	IncludeLivestreamVODs   bool                  // sync the recordings of finished livestreams
+	DownloadTimeout         time.Duration         // how long a download may take, 0 for no limit. Livestreams get longer
+	SyncCaptions            bool                  // host the captions of the videos and link them from their description
+	ThumbnailHost           sources.ThumbnailHost // where thumbnails and captions are uploaded to. berk.ninja if not set
+	DeleteBlobs             bool                  // delete the blobs of videos once they're published, and confirmed with Confirmations
+	Reflector               string                // with DeleteBlobs, blobs are only deleted once this reflector (host:port) holds them. Not checked if empty
+	ControlToken            string                // if set, the status server requires it as a bearer token to change anything
+	SlackSigningSecret      string                // if set, the status server answers the Slack slash commands signed with it on /slack
+	SlackChannel            string                // the Slack channel the slash commands are taken in, any if empty
+	StopGroup               *stop.Group           // stopping it shuts the manager down, the channel syncs go back to the queue
+	StealStaleLocks         time.Duration         // take over channels whose server didn't renew its lease for this long. 0 never does
+	DaemonURLs              []string              // API of the daemon of each slot, overrides ConcurrentChannels. See daemonSlot
+	WalletBackups           *walletbackup.Store   // if set, the wallets are backed up there before each sync
+	NotifyDigest            bool                  // one notification per channel summing up its videos, instead of one per event
+	UpdateExisting          bool                  // resolve the claims of the videos first, to skip or update the ones already published
+	SyncBranding            bool                  // put the title, description, avatar and banner of the youtube channels in their channel claims
+	Confirmations           int                   // videos are only marked published once their claim has this many confirmations. 0 doesn't wait
+	ConfirmationTimeout     time.Duration         // publishes that aren't confirmed after this long are sent again. 1h if not set
+	UTXOTarget              int                   // spendable outputs the wallets are split into before syncing. 40 if not set
+	BigChannelVideos        int                   // channels with this many videos don't get every worker while smaller ones wait. 1000 if not set
+	Estimate                bool                  // size the missing videos of each channel and notify what syncing them takes before starting
+	MaxChannelDownload      int64                 // channels whose missing videos add up to more bytes aren't synced. 0 for no limit
+	MaxChannelDisk          int64                 // channels that need more bytes of disk at once aren't synced. 0 for no limit
+	MaxChannelCost          float64               // channels whose sync would cost more credits aren't synced. 0 for no limit
+	MaxChannelSpend         float64               // channels stop publishing once they spent this many credits over every sync. 0 for no limit
+	YtDlp                   *ytdlp.Manager        // downloads the videos the built-in extractor fails on. Not used if not set
+	Locks                   lock.Locker           // if set, servers lock the channels they sync there, instead of relying on their sync_server
+	UpdateMetadata          bool                  // update the claims whose video changed on youtube instead of syncing new videos
+	UpdateMetadataBudget    float64               // LBC the metadata updates of a channel may spend per run. 0 for no limit
+	YoutubeCacheTTL         time.Duration         // with StateDir, YouTube API responses about channels and videos are reused for this long. 0 doesn't cache
+	Staging                 staging.Storage       // where the videos are downloaded to and wait to be published. StateDir/downloads, or a temp dir, if not set
+	RetryFailed             []string              // if set, only the videos that failed for one of these root causes are synced, see FailureCategories
+	ResignClaims            bool                  // Verify re-signs the claims that aren't signed by the channel claim
+	ShortsMaxDuration       time.Duration         // videos up to this long are shorts, published with ShortsTags or skipped. 0 handles them as regular videos
+	ShortsTags              []string              // the tags shorts are published with
+	SkipShorts              bool                  // shorts aren't synced
+	ErrorRules              *errorrules.Ruleset   // checked before the built-in rules classifying errors, see classifier
+	ChannelAddress          bool                  // publish the claims of a channel to the address of its channel claim, instead of a new address per sync
+	DebugGoroutines         bool                  // track where the goroutines of each channel were started, to report the stuck ones on shutdown. Slower
+	// ValidationRules are checked in the downloaded videos before they are published
+	ValidationRules sources.ValidationRules
+
+	runSummary *RunSummary
+	grp        *stop.Group
+	running    *channelRegistry
+	localDB    *localdb.DB
+	health     *serviceHealth
+	disk       *disk.Manager
+	pollNow    chan struct{} // cuts the wait for the next poll short
+
+	youtubeQuota *QuotaTracker
+	youtubeCache *apicache.Cache
+
+	downloadLimiter *util.TokenBucket
+	videoLimiter    *util.TokenBucket
+
+	progressFuncs []ProgressFunc
 }
 
 const (
@@ -53,145 +148,108 @@ const (
 	StatusSynced    = "synced"  // done
 	StatusFailed    = "failed"
 	StatusFinalized = "finalized" // no more changes allowed
+	StatusOverCap   = "over_cap"  // stopped once it spent MaxChannelSpend, until the cap is raised
 )
 
-var SyncStatuses = []string{StatusPending, StatusQueued, StatusSyncing, StatusSynced, StatusFailed, StatusFinalized}
-
-type apiJobsResponse struct {
-	Success bool                `json:"success"`
-	Error   null.String         `json:"error"`
-	Data    []apiYoutubeChannel `json:"data"`
-}
+// localDBFile is the name of the file in the state dir where the state of each video is kept
+const localDBFile = "sync.db"
 
-type apiYoutubeChannel struct {
-	ChannelId          string      `json:"channel_id"`
-	TotalVideos        uint        `json:"total_videos"`
-	DesiredChannelName string      `json:"desired_channel_name"`
-	SyncServer         null.String `json:"sync_server"`
-}
+var SyncStatuses = []string{StatusPending, StatusQueued, StatusSyncing, StatusSynced, StatusFailed, StatusFinalized, StatusOverCap}
 
-func (s SyncManager) fetchChannels(status string) ([]apiYoutubeChannel, error) {
-	endpoint := s.ApiURL + "/yt/jobs"
-	res, _ := http.PostForm(endpoint, url.Values{
-		"auth_token":  {s.ApiToken},
-		"sync_status": {status},
-		"min_videos":  {strconv.Itoa(1)},
-		"after":       {strconv.Itoa(int(s.SyncFrom))},
-		"before":      {strconv.Itoa(int(s.SyncUntil))},
-		//"sync_server": {s.HostName},
-		"channel_id": {s.YoutubeChannelID},
-	})
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	var response apiJobsResponse
-	err := json.Unmarshal(body, &response)
+// fetchChannels returns the channels in any of the given statuses, narrowed down by the channel ID and the time range
+// the manager was configured with
+func (s SyncManager) fetchChannels(statuses ...string) ([]sdk.YoutubeChannel, error) {
+	channels, err := s.APIConfig.FetchChannels(s.YoutubeChannelID, s.SyncFrom, s.SyncUntil, statuses...)
 	if err != nil {
 		return nil, err
 	}
-	if response.Data == nil {
-		return nil, errors.Err(response.Error)
-	}
-	log.Printf("Fetched channels: %d", len(response.Data))
-	return response.Data, nil
-}
-
-type apiChannelStatusResponse struct {
-	Success bool          `json:"success"`
-	Error   null.String   `json:"error"`
-	Data    []syncedVideo `json:"data"`
-}
-
-type syncedVideo struct {
-	VideoID       string `json:"video_id"`
-	Published     bool   `json:"published"`
-	FailureReason string `json:"failure_reason"`
+	log.Printf("Fetched channels: %d", len(channels))
+	return channels, nil
 }
 
-func (s SyncManager) setChannelStatus(channelID string, status string) (map[string]syncedVideo, error) {
-	endpoint := s.ApiURL + "/yt/channel_status"
-
-	res, _ := http.PostForm(endpoint, url.Values{
-		"channel_id":  {channelID},
-		"sync_server": {s.HostName},
-		"auth_token":  {s.ApiToken},
-		"sync_status": {status},
-	})
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	var response apiChannelStatusResponse
-	err := json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+func (s SyncManager) Start() (e error) {
+	s.grp = stop.New(s.StopGroup)
+	defer s.grp.Stop()
+	s.running = newChannelRegistry()
+	s.health = newServiceHealth()
+	s.youtubeQuota = NewQuotaTracker(s.YoutubeQuota)
+	s.disk = s.newDiskManager()
+	s.pollNow = make(chan struct{}, 1)
+	if s.StopGroup != nil {
+		stopWatching := s.watchShutdown()
+		defer stopWatching()
 	}
-	if !response.Error.IsNull() {
-		return nil, errors.Err(response.Error.String)
+	if s.MaxDownloadRate > 0 {
+		// allow bursts of up to a second worth of data
+		s.downloadLimiter = util.NewTokenBucket(float64(s.MaxDownloadRate), float64(s.MaxDownloadRate))
 	}
-	if response.Data != nil {
-		svs := make(map[string]syncedVideo)
-		for _, v := range response.Data {
-			svs[v.VideoID] = v
-		}
-		return svs, nil
+	if s.MaxVideosPerHour > 0 {
+		// no bursts, videos are spread evenly over the hour
+		s.videoLimiter = util.NewTokenBucket(float64(s.MaxVideosPerHour)/3600, 1)
+	}
+	if s.MetricsAddr != "" {
+		server := s.startMetricsServer()
+		defer server.Close()
 	}
-	return nil, errors.Err("invalid API response. Status code: %d", res.StatusCode)
-}
 
-const (
-	VideoStatusPublished = "published"
-	VideoStatusFailed    = "failed"
-)
+	if s.SummaryOutput != "" {
+		runSummary := &RunSummary{Host: s.HostName, StartedAt: time.Now()}
+		s.runSummary = runSummary
+		defer func() {
+			runSummary.Finish(e)
+			err := runSummary.Export(s.SummaryOutput)
+			if err != nil {
+				SendErrorToSlack("failed to export the sync summary to %s: %s", s.SummaryOutput, err.Error())
+			}
+		}()
+	}
 
-func (s SyncManager) MarkVideoStatus(channelID string, videoID string, status string, claimID string, claimName string, failureReason string) error {
-	endpoint := s.ApiURL + "/yt/video_status"
+	drainChan := make(chan os.Signal, 1)
+	notifyDrain(drainChan)
+	defer signal.Stop(drainChan)
+	draining := false
 
-	vals := url.Values{
-		"youtube_channel_id": {channelID},
-		"video_id":           {videoID},
-		"status":             {status},
-		"auth_token":         {s.ApiToken},
-	}
-	if status == VideoStatusPublished {
-		if claimID == "" || claimName == "" {
-			return errors.Err("claimID or claimName missing")
+	if s.StateDir != "" {
+		err := os.MkdirAll(s.StateDir, 0755)
+		if err != nil {
+			return errors.Err(err)
 		}
-		vals.Add("published_at", strconv.FormatInt(time.Now().Unix(), 10))
-		vals.Add("claim_id", claimID)
-		vals.Add("claim_name", claimName)
-	}
-	if failureReason != "" {
-		maxReasonLength := 500
-		if len(failureReason) > maxReasonLength {
-			failureReason = failureReason[:500]
+		s.localDB, err = localdb.Open(filepath.Join(s.StateDir, localDBFile))
+		if err != nil {
+			return err
 		}
-		vals.Add("failure_reason", failureReason)
-	}
-	res, _ := http.PostForm(endpoint, vals)
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	var response struct {
-		Success bool        `json:"success"`
-		Error   null.String `json:"error"`
-		Data    null.String `json:"data"`
+		defer s.localDB.Close()
 	}
-	err := json.Unmarshal(body, &response)
+	closeCache, err := s.openYoutubeCache()
 	if err != nil {
 		return err
 	}
-	if !response.Error.IsNull() {
-		return errors.Err(response.Error.String)
+	defer closeCache()
+	if s.StatusAddr != "" {
+		// started once the local state DB is open, the handlers work on a copy of the manager
+		server := s.startStatusServer()
+		defer server.Close()
 	}
-	if !response.Data.IsNull() && response.Data.String == "ok" {
-		return nil
-	}
-	return errors.Err("invalid API response. Status code: %d", res.StatusCode)
-}
+	cursor := newQueueCursor(s.StateDir)
 
-func (s SyncManager) Start() error {
 	syncCount := 0
 	for {
-		err := s.checkUsedSpace()
+		select {
+		case <-drainChan:
+			draining = true
+		default:
+		}
+		if draining {
+			SendInfoToSlack("Drain requested, not picking up any new channels. Exiting...")
+			break
+		}
+		if s.isStopping() {
+			break
+		}
+
+		err := s.disk.Wait(s.grp.Ch())
 		if err != nil {
-			return err
+			break
 		}
 
 		var syncs []Sync
@@ -199,26 +257,42 @@ func (s SyncManager) Start() error {
 
 		isSingleChannelSync := s.YoutubeChannelID != ""
 		if isSingleChannelSync {
-			channels, err := s.fetchChannels("")
+			channels, err := s.fetchChannels()
+			if err == nil && len(channels) != 1 {
+				err = errors.Err("Expected 1 channel, %d returned", len(channels))
+			}
 			if err != nil {
+				if s.DaemonMode {
+					if s.pollLater(err) {
+						continue
+					}
+					break
+				}
 				return err
 			}
-			if len(channels) != 1 {
-				return errors.Err("Expected 1 channel, %d returned", len(channels))
-			}
+			s.health.polled(nil)
 			lbryChannelName := channels[0].DesiredChannelName
 			if !s.isWorthProcessing(channels[0]) {
+				if s.DaemonMode && s.waitForNextPoll() {
+					continue
+				}
 				break
 			}
+			if s.isManagedElsewhere(channels[0]) && !s.DryRun {
+				s.takeOver(channels[0])
+			}
 			syncs = make([]Sync, 1)
 			syncs[0] = Sync{
 				YoutubeAPIKey:           s.YoutubeAPIKey,
 				YoutubeChannelID:        s.YoutubeChannelID,
+				YoutubePlaylistID:       s.YoutubePlaylistID,
 				LbryChannelName:         lbryChannelName,
+				LbryChannelClaimID:      s.LbryChannelClaimID,
 				StopOnError:             s.StopOnError,
 				MaxTries:                s.MaxTries,
 				ConcurrentVideos:        s.ConcurrentVideos,
-				TakeOverExistingChannel: s.TakeOverExistingChannel,
+				ChannelConflictResolver: s.ChannelConflictResolver,
+				VideoConflictResolver:   s.VideoConflictResolver,
 				Refill:                  s.Refill,
 				Manager:                 &s,
 				LbrycrdString:           s.LbrycrdString,
@@ -226,112 +300,259 @@ func (s SyncManager) Start() error {
 				AwsS3Secret:             s.AwsS3Secret,
 				AwsS3Region:             s.AwsS3Region,
 				AwsS3Bucket:             s.AwsS3Bucket,
+				GenerateThumbnails:      s.GenerateThumbnails,
+				ThumbnailTimestamp:      s.ThumbnailTimestamp,
+				MinimumBalance:          s.MinimumBalance,
+				Pipeline:                s.Pipeline,
+				PipelineBuffer:          s.PipelineBuffer,
+				VerifyDownloads:         s.VerifyDownloads,
+				DryRun:                  s.DryRun,
+				VideoFilter:             s.VideoFilter,
+				Limits:                  channelLimits(channels[0]),
+				Priority:                channels[0].Priority,
+				ClaimAmounts:            s.ClaimAmounts.forChannel(channels[0]),
+				IncludeLivestreamVODs:   s.IncludeLivestreamVODs,
+				SyncCaptions:            s.SyncCaptions,
+				TranscodeProfile:        s.TranscodeProfile,
+				totalVideos:             channels[0].TotalVideos,
 			}
 			shouldInterruptLoop = true
 		} else {
 			var queuesToSync []string
-			if s.SyncStatus != "" {
-				queuesToSync = append(queuesToSync, s.SyncStatus)
+			if len(s.SyncStatuses) > 0 {
+				queuesToSync = s.SyncStatuses
 			} else if s.SyncUpdate {
-				queuesToSync = append(queuesToSync, StatusSyncing, StatusSynced)
+				queuesToSync = []string{StatusSyncing, StatusSynced}
 			} else {
-				queuesToSync = append(queuesToSync, StatusSyncing, StatusQueued)
+				queuesToSync = []string{StatusSyncing, StatusQueued}
 			}
-			for _, q := range queuesToSync {
-				channels, err := s.fetchChannels(q)
-				if err != nil {
-					return err
-				}
-				for _, c := range channels {
-					if !s.isWorthProcessing(c) {
+			channels, err := s.fetchChannels(queuesToSync...)
+			if err != nil {
+				if s.DaemonMode {
+					if s.pollLater(err) {
 						continue
 					}
-					syncs = append(syncs, Sync{
-						YoutubeAPIKey:           s.YoutubeAPIKey,
-						YoutubeChannelID:        c.ChannelId,
-						LbryChannelName:         c.DesiredChannelName,
-						StopOnError:             s.StopOnError,
-						MaxTries:                s.MaxTries,
-						ConcurrentVideos:        s.ConcurrentVideos,
-						TakeOverExistingChannel: s.TakeOverExistingChannel,
-						Refill:                  s.Refill,
-						Manager:                 &s,
-						LbrycrdString:           s.LbrycrdString,
-						AwsS3ID:                 s.AwsS3ID,
-						AwsS3Secret:             s.AwsS3Secret,
-						AwsS3Region:             s.AwsS3Region,
-						AwsS3Bucket:             s.AwsS3Bucket,
-					})
+					break
+				}
+				return err
+			}
+			s.health.polled(nil)
+			lastChannelID, err := cursor.Load()
+			if err != nil {
+				return err
+			}
+			channels = channelsAfter(channels, lastChannelID)
+			for _, c := range channels {
+				if !s.isWorthProcessing(c) {
+					continue
 				}
+				if s.isManagedElsewhere(c) && !s.DryRun {
+					s.takeOver(c)
+				}
+				syncs = append(syncs, Sync{
+					YoutubeAPIKey:           s.YoutubeAPIKey,
+					YoutubeChannelID:        c.ChannelId,
+					LbryChannelName:         c.DesiredChannelName,
+					StopOnError:             s.StopOnError,
+					MaxTries:                s.MaxTries,
+					ConcurrentVideos:        s.ConcurrentVideos,
+					ChannelConflictResolver: s.ChannelConflictResolver,
+					VideoConflictResolver:   s.VideoConflictResolver,
+					Refill:                  s.Refill,
+					Manager:                 &s,
+					LbrycrdString:           s.LbrycrdString,
+					AwsS3ID:                 s.AwsS3ID,
+					AwsS3Secret:             s.AwsS3Secret,
+					AwsS3Region:             s.AwsS3Region,
+					AwsS3Bucket:             s.AwsS3Bucket,
+					GenerateThumbnails:      s.GenerateThumbnails,
+					ThumbnailTimestamp:      s.ThumbnailTimestamp,
+					MinimumBalance:          s.MinimumBalance,
+					Pipeline:                s.Pipeline,
+					PipelineBuffer:          s.PipelineBuffer,
+					VerifyDownloads:         s.VerifyDownloads,
+					DryRun:                  s.DryRun,
+					VideoFilter:             s.VideoFilter,
+					Limits:                  channelLimits(c),
+					Priority:                c.Priority,
+					ClaimAmounts:            s.ClaimAmounts.forChannel(c),
+					IncludeLivestreamVODs:   s.IncludeLivestreamVODs,
+					SyncCaptions:            s.SyncCaptions,
+					TranscodeProfile:        s.TranscodeProfile,
+					totalVideos:             c.TotalVideos,
+				})
 			}
 		}
 		if len(syncs) == 0 {
-			log.Infoln("No channels to sync. Pausing 5 minutes!")
-			time.Sleep(5 * time.Minute)
+			log.Infof("No channels to sync. Pausing %s!", s.pollInterval())
+			if !s.waitForNextPoll() {
+				break
+			}
 		}
-		for i, sync := range syncs {
-			shouldNotCount := false
-			SendInfoToSlack("Syncing %s (%s) to LBRY! (iteration %d/%d - total processed channels: %d)", sync.LbryChannelName, sync.YoutubeChannelID, i+1, len(syncs), syncCount+1)
-			err := sync.FullCycle()
-			if err != nil {
-				fatalErrors := []string{
-					"default_wallet already exists",
-					"WALLET HAS NOT BEEN MOVED TO THE WALLET BACKUP DIR",
-					"NotEnoughFunds",
-					"no space left on device",
-					"failure uploading wallet",
+		results, pool := s.startSyncPool(syncs)
+		finished := make([]bool, len(syncs))
+		cursorAt := 0 // every channel before this index is done
+		report := batchReport{}
+		var fatalErr error
+		for r := range results {
+			func() {
+				// the worker waits for the result to be handled so it doesn't pick up another channel if it shouldn't
+				defer close(r.handled)
+				i, sync, err := r.index, r.sync, r.err
+				shouldNotCount := false
+				if s.runSummary != nil {
+					channelSummary := sync.Summary()
+					if err != nil {
+						channelSummary.Error = err.Error()
+					}
+					s.runSummary.Add(channelSummary)
+				}
+				if fatalErr != nil {
+					return
 				}
-				if util.SubstringInSlice(err.Error(), fatalErrors) {
-					return errors.Prefix("@Nikooo777 this requires manual intervention! Exiting...", err)
+				if err != nil {
+					class, _ := s.classifier(channelErrors).Classify(err)
+					if IsWalletError(err) || class == retry.Fatal {
+						fatalErr = errors.Prefix("@Nikooo777 this requires manual intervention! Exiting...", s.explainDiskFull(err))
+						pool.Stop()
+						for _, running := range s.running.list() {
+							running.Cancel()
+						}
+						return
+					}
+					shouldNotCount = strings.Contains(err.Error(), errManagedElsewhere)
+					if !shouldNotCount {
+						SendInfoToSlack("%sA non fatal error was reported by the sync process. %s\nContinuing...", categoryTag(err), err.Error())
+					}
 				}
-				shouldNotCount = strings.Contains(err.Error(), "this youtube channel is being managed by another server")
+				SendInfoToSlack("Syncing %s (%s) ended: %s. (iteration %d/%d - total processed channels: %d)", sync.LbryChannelName, sync.YoutubeChannelID, sync.outcome(), i+1, len(syncs), syncCount+1)
+				s.recordBlobStats()
 				if !shouldNotCount {
-					SendInfoToSlack("A non fatal error was reported by the sync process. %s\nContinuing...", err.Error())
+					syncCount++
 				}
+				interrupted := sync.stoppedFromOutside() && !sync.IsCancelled()
+				switch {
+				case interrupted:
+					report.interrupted++
+				case shouldNotCount:
+					report.skipped++
+				case err != nil:
+					report.failed++
+				default:
+					report.synced++
+				}
+				if !isSingleChannelSync && !interrupted && !s.DryRun {
+					finished[i] = true
+					for cursorAt < len(syncs) && finished[cursorAt] {
+						cursorAt++
+					}
+					var cursorErr error
+					if cursorAt == len(syncs) {
+						cursorErr = cursor.Reset()
+					} else if cursorAt > 0 {
+						cursorErr = cursor.Save(syncs[cursorAt-1].YoutubeChannelID)
+					}
+					if cursorErr != nil {
+						fatalErr = errors.Prefix("could not update the queue cursor", cursorErr)
+						pool.Stop()
+						return
+					}
+				}
+				if interrupted || (s.Limit != 0 && syncCount >= s.Limit) {
+					shouldInterruptLoop = true
+					pool.Stop()
+				}
+				if !draining {
+					select {
+					case <-drainChan:
+						draining = true
+						SendInfoToSlack("Drain requested, finishing the channels left in this batch before exiting")
+					default:
+					}
+				}
+			}()
+		}
+		if fatalErr != nil {
+			return fatalErr
+		}
+		if len(syncs) > 1 {
+			report.send(len(syncs))
+		}
+		if s.DaemonMode {
+			if s.isStopping() {
+				break
 			}
-			SendInfoToSlack("Syncing %s (%s) reached an end. (iteration %d/%d - total processed channels: %d)", sync.LbryChannelName, sync.YoutubeChannelID, i+1, len(syncs), syncCount+1)
-			if !shouldNotCount {
-				syncCount++
-			}
-			if sync.IsInterrupted() || (s.Limit != 0 && syncCount >= s.Limit) {
-				shouldInterruptLoop = true
+			if (isSingleChannelSync || s.Interval > 0) && !s.waitForNextPoll() {
 				break
 			}
+			continue
 		}
-		if shouldInterruptLoop || s.SingleRun {
+		if shouldInterruptLoop || s.SingleRun || s.DryRun {
 			break
 		}
 	}
 	return nil
 }
 
-func (s SyncManager) isWorthProcessing(channel apiYoutubeChannel) bool {
-	return channel.TotalVideos > 0 && (channel.SyncServer.IsNull() || channel.SyncServer.String == s.HostName)
+func (s SyncManager) isWorthProcessing(channel sdk.YoutubeChannel) bool {
+	if channel.TotalVideos == 0 {
+		return false
+	}
+	return !s.isManagedElsewhere(channel) || s.ForceTakeover || s.isLeaseStale(channel)
 }
 
-func (s SyncManager) checkUsedSpace() error {
-	usedPctile, err := GetUsedSpace(s.BlobsDir)
-	if err != nil {
-		return err
-	}
-	if usedPctile >= 0.90 && !s.SkipSpaceCheck {
-		return errors.Err(fmt.Sprintf("more than 90%% of the space has been used. use --skip-space-check to ignore. Used: %.1f%%", usedPctile*100))
+// isManagedElsewhere returns true if the channel is assigned to a sync server other than this one. With a lock service,
+// the locks tell instead, when the sync starts.
+func (s SyncManager) isManagedElsewhere(channel sdk.YoutubeChannel) bool {
+	return s.Locks == nil && !channel.SyncServer.IsNull() && channel.SyncServer.String != s.HostName
+}
+
+// takeOver announces that a channel assigned to another sync server is about to be synced by this one
+func (s SyncManager) takeOver(channel sdk.YoutubeChannel) {
+	if s.isLeaseStale(channel) {
+		s.stealStaleLock(channel)
+		return
 	}
-	log.Infof("disk usage: %.1f%%", usedPctile*100)
-	return nil
+	log.Warnln("================================================================================")
+	log.Warnf("FORCED TAKEOVER: %s is assigned to %s, syncing it from %s anyway", channel.ChannelId, channel.SyncServer.String, s.HostName)
+	log.Warnln("================================================================================")
+	SendErrorToSlack("Forcing takeover of %s (%s) from %s. Make sure that server is not syncing it anymore!", channel.DesiredChannelName, channel.ChannelId, channel.SyncServer.String)
 }
 
 // GetUsedSpace returns a value between 0 and 1, with 0 being completely empty and 1 being full, for the disk that holds the provided path
 func GetUsedSpace(path string) (float32, error) {
-	var stat syscall.Statfs_t
-	err := syscall.Statfs(path, &stat)
+	usage, err := disk.GetUsage(path)
 	if err != nil {
 		return 0, err
 	}
-	// Available blocks * size per block = available space in bytes
-	all := stat.Blocks * uint64(stat.Bsize)
-	free := stat.Bfree * uint64(stat.Bsize)
-	used := all - free
+	return float32(usage.Used()), nil
+}
 
-	return float32(used) / float32(all), nil
+// BlobStats describes the blobs directory and the disk holding it
+type BlobStats struct {
+	Blobs      int           // blob files in the directory
+	Bytes      uint64        // their total size
+	OldestBlob time.Duration // age of the oldest blob, 0 if there are none
+	DiskUsed   float64       // used fraction of the disk, between 0 and 1
+	InodesUsed float64       // used fraction of the inodes of the disk, 0 if it doesn't report them
+}
+
+// GetBlobStats returns the statistics of the blobs directory at path. All the blob files are looked at, so it takes a
+// while for large directories.
+func GetBlobStats(path string) (BlobStats, error) {
+	usage, err := disk.GetUsage(path)
+	if err != nil {
+		return BlobStats{}, err
+	}
+	dir, err := util.GetDirStats(path)
+	if err != nil {
+		return BlobStats{}, err
+	}
+	return BlobStats{
+		Blobs:      dir.Files,
+		Bytes:      dir.Bytes,
+		OldestBlob: dir.OldestAge(),
+		DiskUsed:   usage.Used(),
+		InodesUsed: usage.InodesUsed(),
+	}, nil
 }
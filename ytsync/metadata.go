@@ -0,0 +1,126 @@
+package ytsync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// MetadataRule changes the metadata of published videos. Unset fields leave the metadata alone.
+type MetadataRule struct {
+	TitlePrefix       *string  `json:"title_prefix"`
+	TitleSuffix       *string  `json:"title_suffix"`
+	DescriptionFooter *string  `json:"description_footer"`
+	Tags              []string `json:"tags"`
+	License           *string  `json:"license"`
+	LicenseURL        *string  `json:"license_url"`
+	Language          *string  `json:"language"`
+	NSFW              *bool    `json:"nsfw"`
+}
+
+// MetadataConfig holds the metadata rules for all channels and for specific ones, by youtube channel ID. The fields
+// set in the rule of a channel override the default rule.
+type MetadataConfig struct {
+	Default  MetadataRule            `json:"default"`
+	Channels map[string]MetadataRule `json:"channels"`
+}
+
+// LoadMetadataConfig reads a metadata config from a JSON file
+func LoadMetadataConfig(path string) (*MetadataConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	config := &MetadataConfig{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, errors.Prefix("invalid metadata config "+path, err)
+	}
+	return config, nil
+}
+
+// ruleFor returns the rule that applies to a channel
+func (c *MetadataConfig) ruleFor(channelID string) MetadataRule {
+	rule := c.Default
+	override, ok := c.Channels[channelID]
+	if !ok {
+		return rule
+	}
+	if override.TitlePrefix != nil {
+		rule.TitlePrefix = override.TitlePrefix
+	}
+	if override.TitleSuffix != nil {
+		rule.TitleSuffix = override.TitleSuffix
+	}
+	if override.DescriptionFooter != nil {
+		rule.DescriptionFooter = override.DescriptionFooter
+	}
+	if override.Tags != nil {
+		rule.Tags = override.Tags
+	}
+	if override.License != nil {
+		rule.License = override.License
+	}
+	if override.LicenseURL != nil {
+		rule.LicenseURL = override.LicenseURL
+	}
+	if override.Language != nil {
+		rule.Language = override.Language
+	}
+	if override.NSFW != nil {
+		rule.NSFW = override.NSFW
+	}
+	return rule
+}
+
+func (r MetadataRule) apply(_ sources.VideoDetails, m *sources.Metadata) {
+	if r.TitlePrefix != nil {
+		m.Title = *r.TitlePrefix + m.Title
+	}
+	if r.TitleSuffix != nil {
+		m.Title += *r.TitleSuffix
+	}
+	if r.DescriptionFooter != nil {
+		m.Description += "\n" + *r.DescriptionFooter
+	}
+	if r.Tags != nil {
+		m.Tags = append(m.Tags, r.Tags...)
+	}
+	if r.License != nil {
+		m.License = *r.License
+	}
+	if r.LicenseURL != nil {
+		m.LicenseURL = *r.LicenseURL
+	}
+	if r.Language != nil {
+		m.Language = *r.Language
+	}
+	if r.NSFW != nil {
+		m.NSFW = *r.NSFW
+	}
+}
+
+// metadataTransform returns the transform applied to the videos of the channel: the rule of the manager's metadata
+// config, if any, then the MetadataTransform of the sync
+func (s *Sync) metadataTransform() sources.MetadataTransform {
+	var transforms []sources.MetadataTransform
+	if s.Manager != nil && s.Manager.MetadataConfig != nil {
+		transforms = append(transforms, s.Manager.MetadataConfig.ruleFor(s.YoutubeChannelID).apply)
+	}
+	if s.MetadataTransform != nil {
+		transforms = append(transforms, s.MetadataTransform)
+	}
+	switch len(transforms) {
+	case 0:
+		return nil
+	case 1:
+		return transforms[0]
+	}
+	return func(details sources.VideoDetails, m *sources.Metadata) {
+		for _, t := range transforms {
+			t(details, m)
+		}
+	}
+}
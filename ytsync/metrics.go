@@ -0,0 +1,57 @@
+package ytsync
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/lbryio/lbry.go/metrics"
+	"github.com/lbryio/lbry.go/retry"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	videosPublished = metrics.NewCounter("ytsync_videos_published_total", "Videos published")
+	videoFailures   = metrics.NewCounter("ytsync_video_failures_total", "Videos given up on, by type of failure", "type")
+	videosFlagged   = metrics.NewCounter("ytsync_videos_flagged_total", "Videos published despite breaking a validation rule, by rule", "rule")
+	lbcSpent        = metrics.NewCounter("ytsync_lbc_spent_total", "LBC spent on claims and their fees")
+	downloadedBytes = metrics.NewCounter("ytsync_downloaded_bytes_total", "Bytes downloaded from youtube. Its rate is the download throughput")
+	diskUsage       = metrics.NewGauge("ytsync_disk_usage_ratio", "Used fraction of the disk holding the blobs, between 0 and 1")
+	inodeUsage      = metrics.NewGauge("ytsync_disk_inode_usage_ratio", "Used fraction of the inodes of the disk holding the blobs, between 0 and 1")
+	blobCount       = metrics.NewGauge("ytsync_blobs", "Blob files in the blobs directory")
+	blobBytes       = metrics.NewGauge("ytsync_blob_bytes", "Total size of the blob files in the blobs directory")
+	oldestBlobAge   = metrics.NewGauge("ytsync_oldest_blob_age_seconds", "Age of the oldest blob file in the blobs directory")
+	channelsRunning = metrics.NewGauge("ytsync_channels_running", "Channels being synced")
+	channelDuration = metrics.NewGauge("ytsync_channel_sync_duration_seconds", "How long the last sync of each channel took", "channel_id")
+)
+
+// recordFailure counts a video that was given up on
+func recordFailure(failure *retry.Error) {
+	failureType := failure.Reason
+	if failureType == "" {
+		failureType = failure.Class.String()
+	}
+	videoFailures.Inc(failureType)
+}
+
+// startMetricsServer serves the metrics in the Prometheus format on /metrics. It returns the server so that it can be
+// shut down.
+func (s SyncManager) startMetricsServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	server := &http.Server{
+		Addr:         s.MetricsAddr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Infof("metrics server listening on %s", s.MetricsAddr)
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			SendErrorToSlack("metrics server stopped: %s", err.Error())
+		}
+	}()
+	return server
+}
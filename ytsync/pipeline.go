@@ -0,0 +1,158 @@
+package ytsync
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/disk"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// prefetchedVideo is a video that went through the download stage of the pipeline. The first Sync only publishes it,
+// retries go through the whole download and publish cycle again.
+type prefetchedVideo struct {
+	video
+	prefetched  bool
+	downloadErr error
+	reservation *disk.Reservation // released by processVideo once the video is published
+	stash       *stashedDownload  // set while the download waits in the staging storage
+}
+
+func (p *prefetchedVideo) Sync(daemon *jsonrpc.Client, params sources.SyncParams) (*sources.SyncSummary, error) {
+	if !p.prefetched {
+		return p.video.Sync(daemon, params)
+	}
+	p.prefetched = false
+	if p.downloadErr != nil {
+		return nil, p.downloadErr
+	}
+	if p.stash != nil {
+		stash := p.stash
+		p.stash = nil
+		err := stash.restore()
+		if err != nil {
+			stash.remove()
+			return nil, errors.Prefix("could not get the download back from the staging storage", err)
+		}
+	}
+	return p.Publish(daemon, params)
+}
+
+// discard removes the download of a video that is not going to be published
+func (p *prefetchedVideo) discard() {
+	if p.prefetched && p.downloadErr == nil {
+		p.Cleanup()
+	}
+	if p.stash != nil {
+		p.stash.remove()
+		p.stash = nil
+	}
+	p.reservation.Release()
+	p.prefetched = false
+}
+
+// startPipeline starts the download stage of the pipeline, so the next videos download while the workers publish. The
+// downloads don't go through the daemon, only the workers do, so the daemon doesn't get more to do at once. The
+// publish queue is closed once every downloader is done.
+func (s *Sync) startPipeline() {
+	s.publishQueue = make(chan video, s.PipelineBuffer)
+	downloaders := &sync.WaitGroup{}
+	for i := 0; i < s.downloaders(); i++ {
+		downloaders.Add(1)
+		s.grp.Add(1)
+		go func() {
+			defer s.grp.Done()
+			defer downloaders.Done()
+			s.startDownloader()
+		}()
+	}
+	s.grp.Add(1)
+	go func() {
+		defer s.grp.Done()
+		downloaders.Wait()
+		close(s.publishQueue)
+	}()
+}
+
+// downloaders returns how many videos the pipeline downloads at once, as many as the workers publish to begin with
+func (s *Sync) downloaders() int {
+	if s.ConcurrentVideos < 1 {
+		return 1
+	}
+	return s.ConcurrentVideos
+}
+
+// startDownloader runs a downloader of the pipeline. It takes videos off the queue, downloads them and hands them over
+// to the workers, which only have to publish them. At most PipelineBuffer downloaded videos are waiting to be
+// published at any time, besides the ones the downloaders hold on to, which keeps the disk usage bounded. A staging
+// storage that keeps files off the disk holds them meanwhile.
+func (s *Sync) startDownloader() {
+	for {
+		var v video
+		var more bool
+
+		select {
+		case v, more = <-s.queue:
+			if !more {
+				return
+			}
+		case <-s.grp.Ch():
+			return
+		}
+
+		if s.shouldPrefetch(v) {
+			p := &prefetchedVideo{video: v, prefetched: true}
+			started := time.Now()
+			p.reservation, p.downloadErr = s.reserveSpace(v)
+			if p.downloadErr == nil {
+				params := s.syncParams()
+				params.Log = s.videoLogger(v.ID(), 1)
+				params.DownloadProgress = s.downloadProgress(v.ID(), started)
+				p.downloadErr = v.Download(params)
+			}
+			if p.downloadErr == nil {
+				s.reportProgress(v.ID(), ProgressDownloaded, started, nil)
+				s.stashDownload(p)
+			}
+			v = p
+		}
+
+		select {
+		case s.publishQueue <- v:
+		case <-s.grp.Ch():
+			if p, ok := v.(*prefetchedVideo); ok {
+				p.discard()
+			}
+			return
+		}
+	}
+}
+
+// drainPublishQueue removes the downloads that never made it to a worker
+func (s *Sync) drainPublishQueue() {
+	if s.publishQueue == nil {
+		return
+	}
+	for v := range s.publishQueue {
+		if p, ok := v.(*prefetchedVideo); ok {
+			p.discard()
+		}
+	}
+}
+
+// shouldPrefetch returns false for videos processVideo is going to skip anyway
+func (s *Sync) shouldPrefetch(v video) bool {
+	s.syncedVideosMux.Lock()
+	sv, ok := s.syncedVideos[v.ID()]
+	s.syncedVideosMux.Unlock()
+	if s.VideoFilter.names(v.ID()) {
+		return !ok || !sv.Published
+	}
+	if ok && (sv.Published || util.SubstringInSlice(sv.FailureReason, neverRetryFailures)) {
+		return false
+	}
+	return v.PlaylistPosition() <= s.Manager.VideosLimit
+}
@@ -0,0 +1,107 @@
+package ytsync
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/stop"
+)
+
+// syncResult is sent by a channel worker once it's done with a channel
+type syncResult struct {
+	index   int
+	sync    *Sync
+	err     error
+	handled chan struct{} // closed once the manager is done with the result
+}
+
+// startSyncPool syncs the given channels, with one worker per daemon slot. The channels are picked by a
+// channelScheduler. Each worker owns its slot for as long as it
+// runs. If the daemon of a slot can't be started, the channel is handed to another worker and the slot is left alone
+// for daemonCooldown. Results are sent on the returned channel in the order the syncs finish, and the channel is closed
+// once all workers are done. Stopping the returned group keeps the pool from picking up any more channels but lets the
+// ones that are already syncing finish.
+func (s *SyncManager) startSyncPool(syncs []Sync) (<-chan syncResult, *stop.Group) {
+	workers := s.daemonSlots()
+	if workers > len(syncs) {
+		workers = len(syncs)
+	}
+
+	pool := stop.New(s.grp)
+	scheduler := newChannelScheduler(syncs, workers, s.BigChannelVideos)
+	remaining := int32(len(syncs))
+	allDone := make(chan struct{})
+	failovers := make([]int, len(syncs)) // only touched by the worker holding the channel
+	results := make(chan syncResult)
+
+	for w := 0; w < workers; w++ {
+		pool.Add(1)
+		go func(slot daemonSlot) {
+			defer pool.Done()
+			for {
+				var i int
+				select {
+				case <-pool.Ch():
+					return
+				case <-allDone:
+					return
+				case <-scheduler.ready:
+					i = scheduler.next()
+				}
+				select {
+				case <-pool.Ch():
+					scheduler.done(i)
+					return
+				default:
+				}
+				sync := &syncs[i]
+				sync.daemonSlot = slot
+				SendInfoToSlack("Syncing %s (%s) to LBRY! (iteration %d/%d, daemon slot %d)", sync.LbryChannelName, sync.YoutubeChannelID, i+1, len(syncs), slot)
+				err := sync.FullCycle()
+				if errors.Is(err, errDaemonUnavailable) && failovers[i] < workers-1 {
+					failovers[i]++
+					scheduler.requeue(i)
+					SendErrorToSlack("Daemon slot %d (%s) is unavailable, %s goes to another daemon. The slot is tried again in %s",
+						slot, s.daemonAddress(slot), sync.YoutubeChannelID, daemonCooldown)
+					select {
+					case <-pool.Ch():
+						return
+					case <-allDone:
+						return
+					case <-time.After(daemonCooldown):
+					}
+					continue
+				}
+				scheduler.done(i)
+				channelDuration.Set(sync.Summary().DurationSeconds, sync.YoutubeChannelID)
+				handled := make(chan struct{})
+				results <- syncResult{index: i, sync: sync, err: err, handled: handled}
+				<-handled
+				if atomic.AddInt32(&remaining, -1) == 0 {
+					close(allDone)
+				}
+			}
+		}(daemonSlot(w))
+	}
+
+	go func() {
+		pool.Wait()
+		close(results)
+	}()
+
+	return results, pool
+}
+
+// batchReport tallies the outcome of a batch of channels so it can be reported in a single message
+type batchReport struct {
+	synced      int
+	failed      int
+	skipped     int
+	interrupted int
+}
+
+func (b batchReport) send(total int) {
+	SendInfoToSlack("Batch done: %d of %d channels synced, %d failed, %d skipped (managed by another server), %d interrupted",
+		b.synced, total, b.failed, b.skipped, b.interrupted)
+}
@@ -0,0 +1,81 @@
+package ytsync
+
+import (
+	"os"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// WalletError is returned when a sync can't start because of the state of one of the wallets involved. It's a problem
+// with the sync server, not with the channel, so there is no point in moving on to the next channel.
+type WalletError struct {
+	Reason string
+}
+
+func (e WalletError) Error() string { return "wallet pre-flight failed: " + e.Reason }
+
+// IsWalletError returns true if err (or the error it wraps) is a WalletError
+func IsWalletError(err error) bool {
+	_, ok := errors.Unwrap(err).(WalletError)
+	return ok
+}
+
+func (s *Sync) walletPaths() (walletDir string, defaultWallet string) {
+	walletDir = s.daemonSlot.home() + "/.lbryum/wallets"
+	if os.Getenv("REGTEST") == "true" {
+		walletDir = s.daemonSlot.home() + "/.lbryum_regtest/wallets"
+	}
+	return walletDir, walletDir + "/default_wallet"
+}
+
+// preflightWallet checks everything that can be checked about the wallets before the channel is locked: no other
+// wallet is in the way, or it can be put back where it belongs, and the lbrycrd wallet refills come from has enough credits to cover MinimumBalance and Refill.
+func (s *Sync) preflightWallet() error {
+	_, defaultWallet := s.walletPaths()
+	if _, err := os.Stat(defaultWallet); !os.IsNotExist(err) {
+		err = s.recoverLeftoverWallet()
+		if err != nil {
+			return errors.Err(WalletError{Reason: "default_wallet already exists and can't be recovered: " + err.Error()})
+		}
+	}
+
+	required := s.MinimumBalance + float64(s.Refill)
+	if required <= 0 {
+		return nil
+	}
+
+	// lbrycrd not answering can pass, it doesn't take an operator like the wallet states below
+	lbrycrdd, err := s.lbrycrdClient()
+	if err != nil {
+		return errors.Err("lbrycrd is unreachable: %s", err.Error())
+	}
+	defer lbrycrdd.Shutdown()
+	balance, err := lbrycrdd.GetBalance("")
+	if err != nil {
+		return errors.Err("could not get the lbrycrd balance: %s", err.Error())
+	}
+	s.logger().Debugf("lbrycrd balance is %.2f LBC", balance.ToBTC())
+	if balance.ToBTC() < required {
+		return errors.Err(WalletError{Reason: errors.Err("NotEnoughFunds: lbrycrd has %.2f LBC, at least %.2f LBC are needed", balance.ToBTC(), required).Error()})
+	}
+	return nil
+}
+
+// preflightDaemonWallet checks the wallet loaded by the daemon before any video work begins
+func (s *Sync) preflightDaemonWallet() error {
+	status, err := s.daemon.Status()
+	if err != nil {
+		return err
+	}
+	if status.Wallet.IsLocked {
+		return errors.Err(WalletError{Reason: "the wallet is locked"})
+	}
+
+	addresses, err := s.daemon.WalletList()
+	if err != nil {
+		return err
+	} else if addresses == nil || len(*addresses) == 0 {
+		return errors.Err(WalletError{Reason: "the default account has no addresses"})
+	}
+	return nil
+}
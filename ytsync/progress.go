@@ -0,0 +1,127 @@
+package ytsync
+
+import (
+	"time"
+)
+
+// ProgressEvent is a step in the sync of a video
+type ProgressEvent string
+
+const (
+	ProgressStarted     ProgressEvent = "started"     // an attempt at syncing the video started
+	ProgressDownloading ProgressEvent = "downloading" // more of the video was downloaded
+	ProgressDownloaded  ProgressEvent = "downloaded"  // the video is on disk, ready to be published
+	ProgressPublished   ProgressEvent = "published"   // the claim was sent to the blockchain
+	ProgressConfirmed   ProgressEvent = "confirmed"   // the sync API recorded the publish, once the claim is confirmed if it has to be
+	ProgressFailed      ProgressEvent = "failed"      // the video was given up on
+	ProgressSkipped     ProgressEvent = "skipped"     // the video doesn't need to be synced
+	ProgressBalance     ProgressEvent = "balance"     // the balance of the wallet was read, VideoID is empty
+)
+
+// VideoProgress describes something that happened to a video during a sync. The counts are the totals for the
+// channel so far.
+type VideoProgress struct {
+	YoutubeChannelID string
+	VideoID          string
+	Event            ProgressEvent
+	Error            error         // set for ProgressFailed
+	Attempt          int           // set for ProgressStarted
+	Downloaded       int64         // bytes, set for ProgressDownloading
+	DownloadSize     int64         // bytes, set for ProgressDownloading. -1 if it's not known
+	Balance          float64       // set for ProgressBalance
+	Elapsed          time.Duration // time since the processing of the video started
+	Time             time.Time
+	Published        int
+	Failed           int
+	Skipped          int
+}
+
+// ProgressFunc receives progress events. It's called from the sync workers, so it must be safe to call concurrently
+// and should return quickly.
+type ProgressFunc func(VideoProgress)
+
+// OnProgress registers f to be called as the videos of the channel are synced. It must be called before FullCycle.
+func (s *Sync) OnProgress(f ProgressFunc) {
+	s.progressFuncs = append(s.progressFuncs, f)
+}
+
+// OnProgress registers f to be called as the videos of every channel the manager syncs are synced. It must be called
+// before Start.
+func (s *SyncManager) OnProgress(f ProgressFunc) {
+	s.progressFuncs = append(s.progressFuncs, f)
+}
+
+// hasProgressListeners returns true if anyone listens to the progress of this sync
+func (s *Sync) hasProgressListeners() bool {
+	return len(s.progressFuncs) > 0 || (s.Manager != nil && len(s.Manager.progressFuncs) > 0)
+}
+
+// reportProgress sends a progress event to everyone listening to this sync or its manager
+func (s *Sync) reportProgress(videoID string, event ProgressEvent, started time.Time, err error) {
+	if !s.hasProgressListeners() {
+		return
+	}
+	s.sendProgress(VideoProgress{VideoID: videoID, Event: event, Error: err, Elapsed: time.Since(started)})
+}
+
+// reportStarted reports that an attempt at syncing a video started
+func (s *Sync) reportStarted(videoID string, attempt int) {
+	if !s.hasProgressListeners() {
+		return
+	}
+	s.sendProgress(VideoProgress{VideoID: videoID, Event: ProgressStarted, Attempt: attempt})
+}
+
+// reportBalance reports the balance of the wallet of the sync
+func (s *Sync) reportBalance(balance float64) {
+	if !s.hasProgressListeners() {
+		return
+	}
+	s.sendProgress(VideoProgress{Event: ProgressBalance, Balance: balance})
+}
+
+// downloadProgress returns the function reporting the download of a video, nil if nobody listens. Progress is
+// reported at every percent, or every MB if the size of the video isn't known.
+func (s *Sync) downloadProgress(videoID string, started time.Time) func(downloaded, size int64) {
+	if !s.hasProgressListeners() {
+		return nil
+	}
+	last := int64(-1)
+	return func(downloaded, size int64) {
+		step := downloaded >> 20
+		if size > 0 {
+			step = downloaded * 100 / size
+		}
+		if step == last {
+			return
+		}
+		last = step
+		s.sendProgress(VideoProgress{
+			VideoID:      videoID,
+			Event:        ProgressDownloading,
+			Downloaded:   downloaded,
+			DownloadSize: size,
+			Elapsed:      time.Since(started),
+		})
+	}
+}
+
+// sendProgress fills in the channel, time and counts of a progress event and sends it to the listeners
+func (s *Sync) sendProgress(p VideoProgress) {
+	p.YoutubeChannelID = s.YoutubeChannelID
+	p.Time = time.Now()
+	if s.stats != nil {
+		s.stats.mux.Lock()
+		p.Published, p.Failed, p.Skipped = s.stats.published, s.stats.failed, s.stats.skipped
+		s.stats.mux.Unlock()
+	}
+
+	for _, f := range s.progressFuncs {
+		f(p)
+	}
+	if s.Manager != nil {
+		for _, f := range s.Manager.progressFuncs {
+			f(p)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package ytsync
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/metrics"
+	"github.com/lbryio/lbry.go/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultYoutubeQuota is the number of units a YouTube Data API project gets per day
+	DefaultYoutubeQuota = 10000
+
+	// listCost is what a list call (channels, playlistItems) costs
+	listCost = 1
+	// maxVideosPerList is how many videos a single videos list call can look up
+	maxVideosPerList = 50
+	// quotaSlowdown is the fraction of the quota after which calls are paced so the rest lasts until the reset
+	quotaSlowdown = 0.9
+	// maxQuotaDelay caps how long a single call waits when calls are paced
+	maxQuotaDelay = 10 * time.Minute
+
+	// codeQuotaExceeded is the error code of ErrQuotaExhausted
+	codeQuotaExceeded = "quota_exceeded"
+)
+
+// ErrQuotaExhausted is returned instead of calling the YouTube API once the daily quota is used up
+var ErrQuotaExhausted = errors.New(errors.YouTubeAPI, codeQuotaExceeded, "youtube API quota exhausted")
+
+var (
+	quotaUsed      = metrics.NewGauge("ytsync_youtube_quota_used", "YouTube API units used since the last quota reset")
+	quotaExhausted = metrics.NewCounter("ytsync_youtube_quota_exhausted_total", "YouTube API calls skipped because the quota was used up")
+)
+
+// quotaLocation is the time zone of the quota resets. The YouTube quota resets at midnight Pacific Time.
+var quotaLocation = loadQuotaLocation()
+
+func loadQuotaLocation() *time.Location {
+	loc, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		return time.FixedZone("PST", -8*60*60)
+	}
+	return loc
+}
+
+// QuotaTracker keeps count of the YouTube API units used by this process since the last daily reset. It's safe for
+// concurrent use, and a nil QuotaTracker doesn't track anything.
+type QuotaTracker struct {
+	Limit int64 // units per day
+
+	mux       sync.Mutex
+	used      int64
+	dayStart  time.Time
+	exhausted bool // youtube said the quota is exceeded, whatever our count is
+	now       func() time.Time
+}
+
+// NewQuotaTracker returns a tracker for a daily quota of limit units
+func NewQuotaTracker(limit int64) *QuotaTracker {
+	if limit <= 0 {
+		limit = DefaultYoutubeQuota
+	}
+	return &QuotaTracker{Limit: limit, now: time.Now}
+}
+
+// resetIfNewDay starts counting from zero once the quota day is over. It must be called with the lock held.
+func (q *QuotaTracker) resetIfNewDay() time.Time {
+	now := q.now()
+	y, m, d := now.In(quotaLocation).Date()
+	dayStart := time.Date(y, m, d, 0, 0, 0, 0, quotaLocation)
+	if !dayStart.Equal(q.dayStart) {
+		q.dayStart = dayStart
+		q.used = 0
+		q.exhausted = false
+		quotaUsed.Set(0)
+	}
+	return now
+}
+
+// Used returns the units used since the last reset
+func (q *QuotaTracker) Used() int64 {
+	if q == nil {
+		return 0
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.resetIfNewDay()
+	return q.used
+}
+
+// Remaining returns the units left until the next reset
+func (q *QuotaTracker) Remaining() int64 {
+	if q == nil {
+		return DefaultYoutubeQuota
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.resetIfNewDay()
+	return q.remaining()
+}
+
+func (q *QuotaTracker) remaining() int64 {
+	if q.exhausted || q.used >= q.Limit {
+		return 0
+	}
+	return q.Limit - q.used
+}
+
+// ResetsAt returns when the quota is reset next
+func (q *QuotaTracker) ResetsAt() time.Time {
+	if q == nil {
+		return time.Time{}
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.resetIfNewDay()
+	return q.dayStart.AddDate(0, 0, 1)
+}
+
+// PredictExhaustion returns when the quota will run out if units keep being used at the rate they have been used at
+// since the last reset. It returns false if the quota lasts until the reset.
+func (q *QuotaTracker) PredictExhaustion() (time.Time, bool) {
+	if q == nil {
+		return time.Time{}, false
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	now := q.resetIfNewDay()
+	if q.remaining() == 0 {
+		return now, true
+	}
+	elapsed := now.Sub(q.dayStart)
+	if q.used == 0 || elapsed <= 0 {
+		return time.Time{}, false
+	}
+	perUnit := elapsed / time.Duration(q.used)
+	at := now.Add(perUnit * time.Duration(q.remaining()))
+	if !at.Before(q.dayStart.AddDate(0, 0, 1)) {
+		return time.Time{}, false
+	}
+	return at, true
+}
+
+// Use waits until a call costing the given units can be made and counts them as used. Near the limit calls are spread
+// out so that the rest of the quota lasts until the reset. It returns ErrQuotaExhausted if there are not enough units
+// left, and util.ErrWaitCancelled if stop is closed while waiting.
+func (q *QuotaTracker) Use(units int64, stop <-chan struct{}) error {
+	if q == nil {
+		return nil
+	}
+	delay, err := q.reserve(units)
+	if err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	log.Warnf("youtube API quota is almost used up (%d of %d units), waiting %s before the next call", q.Used(), q.Limit, delay.Round(time.Second))
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-stop:
+		return errors.Err(util.ErrWaitCancelled)
+	}
+}
+
+// reserve counts the units as used and returns how long to wait before making the call
+func (q *QuotaTracker) reserve(units int64) (time.Duration, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	now := q.resetIfNewDay()
+
+	if q.remaining() < units {
+		quotaExhausted.Inc()
+		return 0, errors.Err(ErrQuotaExhausted)
+	}
+	q.used += units
+	quotaUsed.Set(float64(q.used))
+
+	if float64(q.used) < quotaSlowdown*float64(q.Limit) {
+		return 0, nil
+	}
+	// spread what's left evenly over the rest of the day
+	calls := q.remaining()/units + 1
+	delay := q.dayStart.AddDate(0, 0, 1).Sub(now) / time.Duration(calls)
+	if delay > maxQuotaDelay {
+		delay = maxQuotaDelay
+	}
+	return delay, nil
+}
+
+// Refund gives back units counted as used by a call that didn't reach the API
+func (q *QuotaTracker) Refund(units int64) {
+	if q == nil {
+		return
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.resetIfNewDay()
+	q.used -= units
+	if q.used < 0 {
+		q.used = 0
+	}
+	quotaUsed.Set(float64(q.used))
+}
+
+// Observe marks the quota as used up if err says it's exceeded. It returns err as ErrQuotaExhausted in that case, and
+// unchanged otherwise.
+func (q *QuotaTracker) Observe(err error) error {
+	if err == nil || !isQuotaError(err) {
+		return err
+	}
+	if q != nil {
+		q.mux.Lock()
+		q.resetIfNewDay()
+		q.exhausted = true
+		q.mux.Unlock()
+	}
+	return errors.Err(ErrQuotaExhausted)
+}
+
+// isQuotaError returns true if err is youtube saying the quota is used up
+func isQuotaError(err error) bool {
+	if errors.Is(err, ErrQuotaExhausted) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, ErrQuotaExhausted.Error()) || strings.Contains(msg, "quotaExceeded") || strings.Contains(msg, "dailyLimitExceeded")
+}
+
+// youtubeQuota returns the quota tracker shared by the channels of the manager, if any
+func (s *Sync) youtubeQuota() *QuotaTracker {
+	if s.Manager == nil {
+		return nil
+	}
+	return s.Manager.youtubeQuota
+}
+
+// useQuota waits until an API call costing the given units can be made
+func (s *Sync) useQuota(units int64) error {
+	var stop <-chan struct{}
+	if s.grp != nil {
+		stop = s.grp.Ch()
+	}
+	return s.youtubeQuota().Use(units, stop)
+}
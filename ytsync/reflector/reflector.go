@@ -0,0 +1,84 @@
+// Package reflector asks a reflector whether it holds the blobs of a stream, so that they can be deleted from the daemon
+// that published the stream. It speaks version 1 of the reflector protocol, which the daemon reflects streams with:
+// JSON messages over TCP, a handshake and then a request per sd blob.
+package reflector
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+const (
+	// DefaultAddress is the reflector the daemon reflects its streams to by default
+	DefaultAddress = "reflector.lbry.io:5566"
+
+	protocolVersion = 1
+	defaultTimeout  = 30 * time.Second
+)
+
+type handshake struct {
+	Version int `json:"version"`
+}
+
+type sdBlobRequest struct {
+	SdBlobHash string `json:"sd_blob_hash"`
+	SdBlobSize int64  `json:"sd_blob_size"`
+}
+
+type sdBlobResponse struct {
+	SendSdBlob  bool     `json:"send_sd_blob"`
+	NeededBlobs []string `json:"needed_blobs"`
+}
+
+// Client checks streams against the reflector at Address (host:port)
+type Client struct {
+	Address string
+	Timeout time.Duration // of a whole check. Defaults to 30 seconds
+}
+
+// HasStream returns true if the reflector holds the sd blob of the stream and all the blobs it lists. The size of the
+// sd blob is part of the request. Nothing is uploaded: the connection is closed once the reflector answered.
+func (c Client) HasStream(sdHash string, sdBlobSize int64) (bool, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	conn, err := net.DialTimeout("tcp", c.Address, timeout)
+	if err != nil {
+		return false, errors.Err(err)
+	}
+	defer conn.Close()
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return false, errors.Err(err)
+	}
+
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+	err = encoder.Encode(handshake{Version: protocolVersion})
+	if err != nil {
+		return false, errors.Err(err)
+	}
+	var hs handshake
+	err = decoder.Decode(&hs)
+	if err != nil {
+		return false, errors.Prefix("reflector handshake failed", errors.Err(err))
+	}
+	if hs.Version != protocolVersion {
+		return false, errors.Err("the reflector speaks version %d of the protocol, not %d", hs.Version, protocolVersion)
+	}
+
+	err = encoder.Encode(sdBlobRequest{SdBlobHash: sdHash, SdBlobSize: sdBlobSize})
+	if err != nil {
+		return false, errors.Err(err)
+	}
+	var response sdBlobResponse
+	err = decoder.Decode(&response)
+	if err != nil {
+		return false, errors.Err(err)
+	}
+	return !response.SendSdBlob && len(response.NeededBlobs) == 0, nil
+}
@@ -0,0 +1,76 @@
+package reflector
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeReflector answers the sd blob requests of one connection with the response for their hash
+func fakeReflector(t *testing.T, responses map[string]sdBlobResponse) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer l.Close()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				decoder := json.NewDecoder(conn)
+				encoder := json.NewEncoder(conn)
+				var hs handshake
+				if decoder.Decode(&hs) != nil || encoder.Encode(handshake{Version: protocolVersion}) != nil {
+					return
+				}
+				var request sdBlobRequest
+				if decoder.Decode(&request) != nil {
+					return
+				}
+				response, ok := responses[request.SdBlobHash]
+				if !ok || request.SdBlobSize <= 0 {
+					response = sdBlobResponse{SendSdBlob: true}
+				}
+				encoder.Encode(response)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestHasStream(t *testing.T) {
+	address := fakeReflector(t, map[string]sdBlobResponse{
+		"reflected": {},
+		"partial":   {NeededBlobs: []string{"blob"}},
+	})
+	c := Client{Address: address, Timeout: 5 * time.Second}
+
+	for hash, expected := range map[string]bool{"reflected": true, "partial": false, "unknown": false} {
+		has, err := c.HasStream(hash, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if has != expected {
+			t.Errorf("expected HasStream(%s) to be %t", hash, expected)
+		}
+	}
+}
+
+func TestHasStreamUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	_, err = Client{Address: address, Timeout: time.Second}.HasStream("sd", 100)
+	if err == nil {
+		t.Error("expected an error when the reflector is unreachable")
+	}
+}
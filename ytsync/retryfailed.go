@@ -0,0 +1,80 @@
+package ytsync
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+)
+
+// FailureCategories returns the names of the root causes failed videos are sorted into, which RetryFailed picks from
+func FailureCategories() []string {
+	names := make([]string, len(triageCategories))
+	for i, c := range triageCategories {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// failedVideos returns the reason of each video of the channel that failed for good, according to the API and the
+// local db. The local db has the last word, it knows about the videos published since the API was told.
+func (s *Sync) failedVideos() (map[string]string, error) {
+	failed := make(map[string]string)
+	s.syncedVideosMux.Lock()
+	for id, sv := range s.syncedVideos {
+		if !sv.Published && sv.FailureReason != "" {
+			failed[id] = sv.FailureReason
+		}
+	}
+	s.syncedVideosMux.Unlock()
+
+	if s.Manager.localDB == nil {
+		return failed, nil
+	}
+	local, err := s.Manager.localDB.Videos(s.YoutubeChannelID)
+	if err != nil {
+		return nil, err
+	}
+	for id, v := range local {
+		switch v.Status {
+		case localdb.VideoStatusFailed:
+			failed[id] = v.FailureReason
+		case localdb.VideoStatusPublished, localdb.VideoStatusAbandoned:
+			delete(failed, id)
+		}
+	}
+	return failed, nil
+}
+
+// selectFailedVideos narrows the sync down to the videos that failed for one of the RetryFailed root causes, among the
+// included videos if the filter has some. They're synced even if they are over the limit or failed in a way that is
+// never retried. It returns how many there are.
+func (s *Sync) selectFailedVideos() (int, error) {
+	failed, err := s.failedVideos()
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	counts := make(map[string]int)
+	for id, reason := range failed {
+		category := s.triageMessage(reason)
+		if !util.InSlice(category.Name, s.Manager.RetryFailed) {
+			continue
+		}
+		if len(s.VideoFilter.IncludeIDs) > 0 && !util.InSlice(id, s.VideoFilter.IncludeIDs) {
+			continue
+		}
+		ids = append(ids, id)
+		counts[category.Name]++
+	}
+	sort.Strings(ids)
+	s.VideoFilter.IncludeIDs = ids
+	s.VideoFilter.Repair = true
+	if len(ids) == 0 {
+		s.logger().Infof("no video of %s failed for %s, there is nothing to retry", s.YoutubeChannelID, strings.Join(s.Manager.RetryFailed, ", "))
+		return 0, nil
+	}
+	s.logger().Infof("retrying %d of the %d failed videos of %s: %s", len(ids), len(failed), s.YoutubeChannelID, formatTriageCounts(counts))
+	return len(ids), nil
+}
@@ -0,0 +1,84 @@
+package ytsync
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// rssFeedURL is the feed of the latest videos of a channel or playlist. It doesn't use any API quota but only has the
+// first 15 videos.
+const rssFeedURL = "https://www.youtube.com/feeds/videos.xml"
+
+type rssFeed struct {
+	Author  string     `xml:"author>name"`
+	Entries []rssEntry `xml:"entry"`
+}
+
+type rssEntry struct {
+	VideoID     string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+	Title       string `xml:"title"`
+	Published   string `xml:"published"`
+	Description string `xml:"http://search.yahoo.com/mrss/ group>description"`
+	Thumbnail   struct {
+		URL    string `xml:"url,attr"`
+		Width  int64  `xml:"width,attr"`
+		Height int64  `xml:"height,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ group>thumbnail"`
+}
+
+// fetchRSSVideos returns the latest videos of the channel, or the first ones of the playlist, from its RSS feed
+func (s *Sync) fetchRSSVideos() ([]video, error) {
+	feedURL := rssFeedURL + "?channel_id=" + url.QueryEscape(s.YoutubeChannelID)
+	if s.YoutubePlaylistID != "" {
+		feedURL = rssFeedURL + "?playlist_id=" + url.QueryEscape(s.YoutubePlaylistID)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Get(feedURL)
+	if err != nil {
+		return nil, errors.Prefix("error getting the channel feed", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Err("error getting the channel feed: %s", response.Status)
+	}
+
+	var feed rssFeed
+	err = xml.NewDecoder(response.Body).Decode(&feed)
+	if err != nil {
+		return nil, errors.Prefix("error parsing the channel feed", err)
+	}
+
+	videos := make([]video, 0, len(feed.Entries))
+	for i, entry := range feed.Entries {
+		if entry.VideoID == "" {
+			continue
+		}
+		// the feed is in playlist order, which is newest first for the uploads of a channel
+		snippet := &youtube.PlaylistItemSnippet{
+			Title:        entry.Title,
+			Description:  entry.Description,
+			ChannelTitle: feed.Author,
+			Position:     int64(i),
+			PublishedAt:  entry.Published,
+			ResourceId:   &youtube.ResourceId{VideoId: entry.VideoID},
+		}
+		if entry.Thumbnail.URL != "" {
+			snippet.Thumbnails = &youtube.ThumbnailDetails{
+				High: &youtube.Thumbnail{Url: entry.Thumbnail.URL, Width: entry.Thumbnail.Width, Height: entry.Thumbnail.Height},
+			}
+		}
+		videos = append(videos, sources.NewYoutubeVideo(s.videoDirectory, snippet))
+	}
+	s.logger().Infof("Got info for %d videos from the channel feed", len(videos))
+
+	s.sortVideos(videos)
+	return s.VideoFilter.apply(videos), nil
+}
@@ -0,0 +1,104 @@
+package ytsync
+
+import (
+	"sort"
+	"sync"
+)
+
+// defaultBigChannelVideos is how many videos a channel needs to be a big channel, see channelScheduler
+const defaultBigChannelVideos = 1000
+
+// channelScheduler decides which channel of a batch a worker syncs next. Channels are picked by priority, highest
+// first, and in the order the API returned them otherwise. Big channels take hours to sync, so they only get up to all
+// workers but one as long as there are small channels waiting: a small channel with a high priority doesn't wait for
+// every big channel to finish. Once there are only big channels left, the idle workers take them too.
+type channelScheduler struct {
+	mux        sync.Mutex
+	syncs      []Sync
+	small      []int // indexes of the waiting syncs, in the order they should be picked
+	big        []int
+	isBig      []bool
+	bigRunning int
+	maxBig     int // how many big channels can be synced at once while small channels wait
+
+	// ready holds a token for each waiting channel, so that workers can wait for one along with a stop signal
+	ready chan struct{}
+}
+
+func newChannelScheduler(syncs []Sync, workers, bigChannelVideos int) *channelScheduler {
+	if bigChannelVideos <= 0 {
+		bigChannelVideos = defaultBigChannelVideos
+	}
+	c := &channelScheduler{
+		syncs:  syncs,
+		isBig:  make([]bool, len(syncs)),
+		maxBig: workers - 1,
+		ready:  make(chan struct{}, len(syncs)),
+	}
+	if c.maxBig < 1 {
+		c.maxBig = 1
+	}
+	for i := range syncs {
+		if syncs[i].totalVideos >= uint(bigChannelVideos) {
+			c.isBig[i] = true
+			c.big = append(c.big, i)
+		} else {
+			c.small = append(c.small, i)
+		}
+		c.ready <- struct{}{}
+	}
+	c.sortLane(c.small)
+	c.sortLane(c.big)
+	return c
+}
+
+// sortLane orders a lane by priority, keeping the order of the API for channels with the same priority
+func (c *channelScheduler) sortLane(lane []int) {
+	sort.SliceStable(lane, func(a, b int) bool {
+		return c.syncs[lane[a]].Priority > c.syncs[lane[b]].Priority
+	})
+}
+
+// next returns the index of the channel to sync next. It must only be called after taking a token from ready.
+func (c *channelScheduler) next() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	pickBig := len(c.small) == 0
+	if len(c.small) > 0 && len(c.big) > 0 && c.bigRunning < c.maxBig {
+		// small channels go first on a tie, they are done sooner
+		pickBig = c.syncs[c.big[0]].Priority > c.syncs[c.small[0]].Priority
+	}
+	if pickBig {
+		i := c.big[0]
+		c.big = c.big[1:]
+		c.bigRunning++
+		return i
+	}
+	i := c.small[0]
+	c.small = c.small[1:]
+	return i
+}
+
+// done records that a channel returned by next isn't being synced anymore
+func (c *channelScheduler) done(i int) {
+	if !c.isBig[i] {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.bigRunning--
+}
+
+// requeue puts a channel returned by next back at the head of its lane, for another worker to sync
+func (c *channelScheduler) requeue(i int) {
+	c.done(i)
+	c.mux.Lock()
+	if c.isBig[i] {
+		c.big = append([]int{i}, c.big...)
+	} else {
+		c.small = append([]int{i}, c.small...)
+	}
+	c.mux.Unlock()
+	c.ready <- struct{}{}
+}
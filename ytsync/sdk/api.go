@@ -0,0 +1,254 @@
+package sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/null"
+)
+
+const (
+	VideoStatusPublished = "published"
+	VideoStatusFailed    = "failed"
+
+	maxReasonLength = 500
+)
+
+// Jobs is the queue of channels to sync, and where their progress is recorded. It's the API (APIConfig) or, where the
+// API isn't available, its database (DBConfig).
+type Jobs interface {
+	FetchChannels(channelID string, after, before int64, statuses ...string) ([]YoutubeChannel, error)
+	SetChannelStatus(channelID string, status string, progress *SyncProgress) (map[string]SyncedVideo, error)
+	MarkVideoStatus(channelID string, videoID string, status string, claimID string, claimName string, failureReason string) error
+	RenewChannelLease(channelID string) error
+}
+
+// APIConfig talks to the internal-apis sync endpoints
+type APIConfig struct {
+	ApiURL   string
+	ApiToken string
+	HostName string
+
+	client *Client
+}
+
+// NewAPIConfig returns a client for the API at apiURL. tlsConfig may be nil, in which case the system defaults are used.
+func NewAPIConfig(apiURL, apiToken, hostName string, tlsConfig *tls.Config) *APIConfig {
+	transport := http.DefaultTransport
+	if tlsConfig != nil {
+		transport = &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	return &APIConfig{
+		ApiURL:   apiURL,
+		ApiToken: apiToken,
+		HostName: hostName,
+		client:   NewClient(apiURL, apiToken, transport),
+	}
+}
+
+// SetAuth changes how the requests to the API are authenticated, AuthToken by default
+func (a *APIConfig) SetAuth(mode AuthMode) {
+	a.client.Auth = mode
+}
+
+// TLSConfig builds a TLS config that trusts the certificates in caFile on top of the system roots. If caFile is empty
+// and insecure is false, nil is returned so the defaults are used.
+func TLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	if caFile == "" && !insecure {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Err("no certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+type YoutubeChannel struct {
+	ChannelId          string      `json:"channel_id"`
+	TotalVideos        uint        `json:"total_videos"`
+	DesiredChannelName string      `json:"desired_channel_name"`
+	SyncServer         null.String `json:"sync_server"`
+	LeaseRenewedAt     int64       `json:"lease_renewed_at"` // unix time the sync server last renewed its lease, 0 if never
+	Priority           int         `json:"priority"`         // channels with a higher priority are synced first, 0 by default
+
+	// limits on which videos are synced, 0 for no limit
+	MaxVideos        int    `json:"max_videos"`
+	MaxVideoDuration int    `json:"max_video_duration"` // in seconds
+	MaxVideoSize     int    `json:"max_video_size"`     // in MB
+	MinViews         uint64 `json:"min_views"`
+
+	// credits that go into the claims of the channel, 0 for the defaults of the sync server
+	ChannelBid   float64 `json:"channel_bid"`
+	VideoBid     float64 `json:"video_bid"`
+	VideoSupport float64 `json:"video_support"`
+	VideoFee     float64 `json:"video_fee"` // price of each video in LBC
+}
+
+// FetchChannels returns the channels in any of the given statuses, or in any status if there are none. The API takes
+// one status per request, so each status is fetched in turn. Channels showing up under more than one status are only
+// returned once, where they first show up. channelID, after and before narrow the results down if set.
+func (a *APIConfig) FetchChannels(channelID string, after, before int64, statuses ...string) ([]YoutubeChannel, error) {
+	if len(statuses) == 0 {
+		statuses = []string{""}
+	}
+	seen := make(map[string]bool)
+	var channels []YoutubeChannel
+	for _, status := range statuses {
+		var data []YoutubeChannel
+		err := a.client.Post("/yt/jobs", url.Values{
+			"sync_status": {status},
+			"min_videos":  {strconv.Itoa(1)},
+			"after":       {strconv.Itoa(int(after))},
+			"before":      {strconv.Itoa(int(before))},
+			//"sync_server": {a.HostName},
+			"channel_id": {channelID},
+		}, &data)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			return nil, errors.Err("invalid API response: no channels")
+		}
+		for _, c := range data {
+			if seen[c.ChannelId] {
+				continue
+			}
+			seen[c.ChannelId] = true
+			channels = append(channels, c)
+		}
+	}
+	return channels, nil
+}
+
+// SyncProgress is how far the sync of a channel got, reported along with the status of the channel
+type SyncProgress struct {
+	VideosPublished int
+	VideosFailed    int
+	LastVideoID     string // the last video that was published or failed
+	LBCSpent        float64
+	TotalLBCSpent   float64        // over every sync of the channel, 0 if it's not known
+	Duration        time.Duration  // since the sync started
+	Failures        map[string]int // failed videos by root cause
+}
+
+// values adds the progress to the values of a request to the API
+func (p *SyncProgress) values(vals url.Values) {
+	vals.Set("videos_published", strconv.Itoa(p.VideosPublished))
+	vals.Set("videos_failed", strconv.Itoa(p.VideosFailed))
+	vals.Set("last_video_id", p.LastVideoID)
+	vals.Set("lbc_spent", strconv.FormatFloat(p.LBCSpent, 'f', 8, 64))
+	if p.TotalLBCSpent > 0 {
+		vals.Set("total_lbc_spent", strconv.FormatFloat(p.TotalLBCSpent, 'f', 8, 64))
+	}
+	vals.Set("duration", strconv.FormatInt(int64(p.Duration.Seconds()), 10))
+	if len(p.Failures) > 0 {
+		failures, _ := json.Marshal(p.Failures)
+		vals.Set("failures", string(failures))
+	}
+}
+
+type SyncedVideo struct {
+	VideoID       string `json:"video_id"`
+	Published     bool   `json:"published"`
+	FailureReason string `json:"failure_reason"`
+}
+
+// SetChannelStatus updates the sync status of a channel and returns the videos that were already processed for it.
+// progress is reported along with the status if it's not nil.
+func (a *APIConfig) SetChannelStatus(channelID string, status string, progress *SyncProgress) (map[string]SyncedVideo, error) {
+	var data []SyncedVideo
+	vals := url.Values{
+		"channel_id":  {channelID},
+		"sync_server": {a.HostName},
+		"sync_status": {status},
+	}
+	if progress != nil {
+		progress.values(vals)
+	}
+	err := a.client.Post("/yt/channel_status", vals, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.Err("invalid API response: no synced videos")
+	}
+	svs := make(map[string]SyncedVideo)
+	for _, v := range data {
+		svs[v.VideoID] = v
+	}
+	return svs, nil
+}
+
+// MarkVideoStatus records the outcome of syncing a single video
+func (a *APIConfig) MarkVideoStatus(channelID string, videoID string, status string, claimID string, claimName string, failureReason string) error {
+	vals := url.Values{
+		"youtube_channel_id": {channelID},
+		"video_id":           {videoID},
+		"status":             {status},
+	}
+	if status == VideoStatusPublished {
+		if claimID == "" || claimName == "" {
+			return errors.Err("claimID or claimName missing")
+		}
+		vals.Add("published_at", strconv.FormatInt(time.Now().Unix(), 10))
+		vals.Add("claim_id", claimID)
+		vals.Add("claim_name", claimName)
+	}
+	if failureReason != "" {
+		if len(failureReason) > maxReasonLength {
+			failureReason = failureReason[:maxReasonLength]
+		}
+		vals.Add("failure_reason", failureReason)
+	}
+	var data null.String
+	err := a.client.Post("/yt/video_status", vals, &data)
+	if err != nil {
+		return err
+	}
+	if data.IsNull() || data.String != "ok" {
+		return errors.Err("invalid API response: %q instead of ok", data.String)
+	}
+	return nil
+}
+
+// RenewChannelLease tells the API that this server is still syncing the channel, so that it's not taken over by another
+// one. It fails if the channel is assigned to another server.
+func (a *APIConfig) RenewChannelLease(channelID string) error {
+	var data null.String
+	err := a.client.Post("/yt/channel_heartbeat", url.Values{
+		"channel_id":  {channelID},
+		"sync_server": {a.HostName},
+	}, &data)
+	if err != nil {
+		return err
+	}
+	if data.IsNull() || data.String != "ok" {
+		return errors.Err("invalid API response: %q instead of ok", data.String)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/null"
+	"github.com/lbryio/lbry.go/retry"
+	"github.com/lbryio/lbry.go/util"
+)
+
+// AuthMode is how the requests to the API are authenticated
+type AuthMode string
+
+const (
+	AuthToken  AuthMode = "token"  // the token is sent as the auth_token form value. The default
+	AuthBearer AuthMode = "bearer" // the token is sent in an Authorization: Bearer header
+	AuthHMAC   AuthMode = "hmac"   // the requests are signed with the token, see Client.sign
+)
+
+// ParseAuthMode returns the auth mode named s. An empty string is AuthToken.
+func ParseAuthMode(s string) (AuthMode, error) {
+	switch AuthMode(s) {
+	case "", AuthToken:
+		return AuthToken, nil
+	case AuthBearer, AuthHMAC:
+		return AuthMode(s), nil
+	}
+	return "", errors.Err("unknown API auth mode %q, use token, bearer or hmac", s)
+}
+
+// error codes of the errors returned by Client, in the errors.API category unless noted otherwise
+const (
+	ErrorCodeRejected        = "rejected"         // the API answered with an error
+	ErrorCodeInvalidResponse = "invalid_response" // the response isn't what the API sends
+	ErrorCodeUnavailable     = "unavailable"      // errors.Network: the API couldn't be reached or had a problem of its own
+)
+
+// APIError is an error the API answered with, in the error field of its response or as an error status code
+type APIError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return "API error on " + e.Endpoint + " (status code " + strconv.Itoa(e.StatusCode) + "): " + e.Message
+}
+
+// apiErrors tells which failed requests are retried: the ones that didn't reach the API, or that it couldn't handle
+// at the time
+var apiErrors = retry.Classifier{
+	{Class: retry.Transient, Reason: "api unavailable", Category: errors.Network},
+	{Class: retry.Permanent, Reason: "api error", Category: errors.API},
+}
+
+// Client sends requests to the API. The requests are form POSTs, authenticated according to Auth, and the responses
+// are {"success": ..., "error": ..., "data": ...} envelopes. Requests that can't reach the API, or that it answers
+// with a 5xx or 429 status code, are retried with a backoff. The errors the API answers with are returned as an
+// *APIError, categorized as errors.API, or as errors.Network for the ones that were retried.
+type Client struct {
+	URL   string
+	Token string
+	Auth  AuthMode
+	// HTTP sends the requests. Its timeout applies to each attempt.
+	HTTP *http.Client
+	// Retry is how failed requests are retried. Its classifier is ignored.
+	Retry retry.Policy
+}
+
+// NewClient returns a client for the API at url, with the default timeout and retries
+func NewClient(url, token string, transport http.RoundTripper) *Client {
+	return &Client{
+		URL:   url,
+		Token: token,
+		Auth:  AuthToken,
+		HTTP:  &http.Client{Transport: transport, Timeout: 2 * time.Minute},
+		Retry: retry.Policy{
+			Attempts: 4,
+			Backoff:  util.Backoff{Base: 2 * time.Second, Max: 30 * time.Second, Jitter: 0.2},
+		},
+	}
+}
+
+// Post sends vals to endpoint and decodes the data of the response into data, unless data is nil
+func (c *Client) Post(endpoint string, vals url.Values, data interface{}) error {
+	policy := c.Retry
+	policy.Classifier = apiErrors
+	err := policy.Do(nil, func() error {
+		return c.post(endpoint, vals, data)
+	})
+	if failure, ok := err.(*retry.Error); ok {
+		return failure.Err
+	}
+	return err
+}
+
+// post makes a single attempt at a request
+func (c *Client) post(endpoint string, vals url.Values, data interface{}) error {
+	form := url.Values{}
+	for k, v := range vals {
+		form[k] = v
+	}
+	if c.Auth == "" || c.Auth == AuthToken {
+		form.Set("auth_token", c.Token)
+	}
+	body := form.Encode()
+	req, err := http.NewRequest(http.MethodPost, c.URL+endpoint, bytes.NewBufferString(body))
+	if err != nil {
+		return errors.Err(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	switch c.Auth {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case AuthHMAC:
+		c.sign(req, endpoint, body)
+	}
+
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return errors.Categorize(err, errors.Network, ErrorCodeUnavailable)
+	}
+	defer res.Body.Close()
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Categorize(err, errors.Network, ErrorCodeUnavailable)
+	}
+
+	var response struct {
+		Success bool            `json:"success"`
+		Error   null.String     `json:"error"`
+		Data    json.RawMessage `json:"data"`
+	}
+	decodeErr := json.Unmarshal(raw, &response)
+	switch {
+	case !response.Error.IsNull() && response.Error.String != "":
+		apiErr := &APIError{Endpoint: endpoint, StatusCode: res.StatusCode, Message: response.Error.String}
+		if res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests {
+			return errors.Categorize(apiErr, errors.Network, ErrorCodeUnavailable)
+		}
+		return errors.Categorize(apiErr, errors.API, ErrorCodeRejected)
+	case res.StatusCode >= 500 || res.StatusCode == http.StatusTooManyRequests:
+		apiErr := &APIError{Endpoint: endpoint, StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+		return errors.Categorize(apiErr, errors.Network, ErrorCodeUnavailable)
+	case res.StatusCode >= 400:
+		apiErr := &APIError{Endpoint: endpoint, StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+		return errors.Categorize(apiErr, errors.API, ErrorCodeRejected)
+	case decodeErr != nil:
+		return errors.Categorize(errors.Prefix("could not decode the response of "+endpoint, decodeErr), errors.API, ErrorCodeInvalidResponse)
+	}
+	if data == nil || len(response.Data) == 0 || string(response.Data) == "null" {
+		return nil
+	}
+	err = json.Unmarshal(response.Data, data)
+	if err != nil {
+		return errors.Categorize(errors.Prefix("could not decode the data of "+endpoint, err), errors.API, ErrorCodeInvalidResponse)
+	}
+	return nil
+}
+
+// sign adds an HMAC-SHA256 signature of the request to its headers. X-Signature-Timestamp is the unix time of the
+// request, and X-Signature the hex encoded HMAC, keyed with the token, of "POST\nENDPOINT\nTIMESTAMP\nBODY".
+func (c *Client) sign(req *http.Request, endpoint, body string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(c.Token))
+	mac.Write([]byte(http.MethodPost + "\n" + endpoint + "\n" + timestamp + "\n" + body))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+}
@@ -1,15 +1,17 @@
 package ytsync
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/lbryio/lbry.go/errors"
-	"github.com/lbryio/lbry.go/jsonrpc"
 	"github.com/lbryio/lbry.go/lbrycrd"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+	"github.com/lbryio/lbry.go/ytsync/utxo"
 
 	"github.com/shopspring/decimal"
-	log "github.com/sirupsen/logrus"
 )
 
 func (s *Sync) walletSetup() error {
@@ -28,19 +30,13 @@ func (s *Sync) walletSetup() error {
 		return errors.Err("no response")
 	}
 	balance := decimal.Decimal(*balanceResp)
-	log.Debugf("Starting balance is %s", balance.String())
+	s.logger().Debugf("Starting balance is %s", balance.String())
 
-	var numOnSource int
-	if s.LbryChannelName == "@UCBerkeley" {
-		numOnSource = 10104
-	} else {
-		n, err := s.CountVideos()
-		if err != nil {
-			return err
-		}
-		numOnSource = int(n)
+	numOnSource, err := s.countSourceVideos()
+	if err != nil {
+		return err
 	}
-	log.Debugf("Source channel has %d videos", numOnSource)
+	s.logger().Debugf("Source channel has %d videos", numOnSource)
 	if numOnSource == 0 {
 		return nil
 	}
@@ -48,15 +44,15 @@ func (s *Sync) walletSetup() error {
 	s.syncedVideosMux.Lock()
 	numPublished := len(s.syncedVideos) //should we only count published videos? Credits are allocated even for failed ones...
 	s.syncedVideosMux.Unlock()
-	log.Debugf("We already published %d videos", numPublished)
+	s.logger().Debugf("We already published %d videos", numPublished)
 
 	if numOnSource-numPublished > s.Manager.VideosLimit {
 		numOnSource = s.Manager.VideosLimit
 	}
 
-	minBalance := (float64(numOnSource)-float64(numPublished))*(publishAmount+0.1) + channelClaimAmount
+	minBalance := (float64(numOnSource)-float64(numPublished))*s.ClaimAmounts.perVideo() + s.ClaimAmounts.channelBid()
 	if numPublished > numOnSource && balance.LessThan(decimal.NewFromFloat(1)) {
-		SendErrorToSlack("something is going on as we published more videos than those available on source: %d/%d", numPublished, numOnSource)
+		s.notifyError("something is going on as we published more videos than those available on source: %d/%d", numPublished, numOnSource)
 		minBalance = 1 //since we ended up in this function it means some juice is still needed
 	}
 	amountToAdd, _ := decimal.NewFromFloat(minBalance).Sub(balance).Float64()
@@ -70,21 +66,15 @@ func (s *Sync) walletSetup() error {
 	}
 
 	if amountToAdd > 0 {
-		if amountToAdd < 1 {
-			amountToAdd = 1 // no reason to bother adding less than 1 credit
+		err = s.credits.Add(amountToAdd)
+		if err != nil {
+			return err
 		}
-		s.addCredits(amountToAdd)
 	}
 
-	claimAddress, err := s.daemon.WalletUnusedAddress()
+	s.claimAddress, err = s.pickClaimAddress()
 	if err != nil {
 		return err
-	} else if claimAddress == nil {
-		return errors.Err("could not get unused address")
-	}
-	s.claimAddress = string(*claimAddress)
-	if s.claimAddress == "" {
-		return errors.Err("found blank claim address")
 	}
 
 	err = s.ensureEnoughUTXOs()
@@ -95,58 +85,58 @@ func (s *Sync) walletSetup() error {
 	return nil
 }
 
-func (s *Sync) ensureEnoughUTXOs() error {
-	utxolist, err := s.daemon.UTXOList()
+// pickClaimAddress returns the address the claims are published to. With ChannelAddress, it's the address of the
+// channel claim, so every claim of the channel lives on it. Otherwise it's an unused address of the wallet, a new one
+// for each sync.
+func (s *Sync) pickClaimAddress() (string, error) {
+	if s.Manager.ChannelAddress {
+		return s.channelClaimAddress()
+	}
+	claimAddress, err := s.daemon.WalletUnusedAddress()
 	if err != nil {
-		return err
-	} else if utxolist == nil {
-		return errors.Err("no response")
+		return "", err
+	} else if claimAddress == nil {
+		return "", errors.Err("could not get unused address")
 	}
-
-	target := 40
-	slack := int(float32(0.1) * float32(target))
-	count := 0
-
-	for _, utxo := range *utxolist {
-		if !utxo.IsClaim && !utxo.IsSupport && !utxo.IsUpdate && utxo.Amount.Cmp(decimal.New(0, 0)) == 1 {
-			count++
-		}
+	if *claimAddress == "" {
+		return "", errors.Err("found blank claim address")
 	}
+	return string(*claimAddress), nil
+}
 
-	if count < target-slack {
-		newAddresses := target - count
-
-		balance, err := s.daemon.WalletBalance()
-		if err != nil {
-			return err
-		} else if balance == nil {
-			return errors.Err("no response")
-		}
-
-		log.Println("balance is " + decimal.Decimal(*balance).String())
-
-		amountPerAddress := decimal.Decimal(*balance).Div(decimal.NewFromFloat(float64(target)))
-		log.Infof("Putting %s credits into each of %d new addresses", amountPerAddress.String(), newAddresses)
-		prefillTx, err := s.daemon.WalletPrefillAddresses(newAddresses, amountPerAddress, true)
-		if err != nil {
-			return err
-		} else if prefillTx == nil {
-			return errors.Err("no response")
-		}
-
-		err = s.waitForNewBlock()
-		if err != nil {
-			return err
+// channelClaimAddress returns the address holding the channel claim of the wallet
+func (s *Sync) channelClaimAddress() (string, error) {
+	channels, err := s.daemon.ChannelList()
+	if err != nil {
+		return "", err
+	} else if channels == nil {
+		return "", errors.Err("no channel response")
+	}
+	for _, channel := range *channels {
+		if channel.ClaimID != s.lbryChannelID {
+			continue
 		}
-	} else if !allUTXOsConfirmed(utxolist) {
-		log.Println("Waiting for previous txns to confirm")
-		err := s.waitForNewBlock()
-		if err != nil {
-			return err
+		if channel.Address == "" {
+			return "", errors.Err("channel claim %s has no address", s.lbryChannelID)
 		}
+		return channel.Address, nil
 	}
+	return "", errors.Err("channel claim %s is not in the wallet", s.lbryChannelID)
+}
 
-	return nil
+// ensureEnoughUTXOs splits the credits of the wallet into enough outputs to publish the videos concurrently, and waits
+// for them to be confirmed
+func (s *Sync) ensureEnoughUTXOs() error {
+	target := s.Manager.UTXOTarget
+	if target <= 0 {
+		target = defaultUTXOTarget
+	}
+	m := &utxo.Manager{
+		Wallet:    daemonWallet{daemon: s.daemon},
+		MinAmount: s.ClaimAmounts.perVideo(),
+	}
+	_, err := m.Ensure(target)
+	return err
 }
 
 func (s *Sync) waitForNewBlock() error {
@@ -165,7 +155,7 @@ func (s *Sync) waitForNewBlock() error {
 	currentBlock := status.Wallet.Blocks
 	for i := 0; status.Wallet.Blocks <= currentBlock; i++ {
 		if i%3 == 0 {
-			log.Printf("Waiting for new block (%d)...", currentBlock+1)
+			s.logger().Printf("Waiting for new block (%d)...", currentBlock+1)
 		}
 		time.Sleep(10 * time.Second)
 		status, err = s.daemon.Status()
@@ -177,6 +167,9 @@ func (s *Sync) waitForNewBlock() error {
 }
 
 func (s *Sync) ensureChannelOwnership() error {
+	if s.LbryChannelClaimID != "" {
+		return s.ensureChannelClaimOwnership()
+	}
 	if s.LbryChannelName == "" {
 		return errors.Err("no channel name set")
 	}
@@ -190,7 +183,7 @@ func (s *Sync) ensureChannelOwnership() error {
 
 	isChannelMine := false
 	for _, channel := range *channels {
-		if channel.Name == s.LbryChannelName {
+		if s.isChannelName(channel.Name) {
 			s.lbryChannelID = channel.ClaimID
 			isChannelMine = true
 		} else {
@@ -201,23 +194,11 @@ func (s *Sync) ensureChannelOwnership() error {
 		return nil
 	}
 
-	resolveResp, err := s.daemon.Resolve(s.LbryChannelName)
+	channelName, channelBidAmount, err := s.pickChannelName()
 	if err != nil {
 		return err
 	}
 
-	channel := (*resolveResp)[s.LbryChannelName]
-	channelBidAmount := channelClaimAmount
-
-	channelNotFound := channel.Error != nil && strings.Contains(*(channel.Error), "cannot be resolved")
-	if !channelNotFound {
-		if !s.TakeOverExistingChannel {
-			return errors.Err("Channel exists and we don't own it. Pick another channel.")
-		}
-		log.Println("Channel exists and we don't own it. Outbidding existing claim.")
-		channelBidAmount, _ = channel.Certificate.Amount.Add(decimal.NewFromFloat(channelClaimAmount)).Float64()
-	}
-
 	balanceResp, err := s.daemon.WalletBalance()
 	if err != nil {
 		return err
@@ -227,67 +208,106 @@ func (s *Sync) ensureChannelOwnership() error {
 	balance := decimal.Decimal(*balanceResp)
 
 	if balance.LessThan(decimal.NewFromFloat(channelBidAmount)) {
-		s.addCredits(channelBidAmount + 0.1)
+		err = s.credits.Add(channelBidAmount + 0.1)
+		if err != nil {
+			return err
+		}
 	}
 
-	c, err := s.daemon.ChannelNew(s.LbryChannelName, channelBidAmount)
+	options, branding := s.newChannelBranding()
+	c, err := s.daemon.ChannelNewWithOptions(channelName, channelBidAmount, options)
 	if err != nil {
 		return err
 	}
+	fee, _ := c.Fee.Float64()
+	s.stats.spend(channelBidAmount + fee)
 	s.lbryChannelID = c.ClaimID
+	s.recordSpend(localdb.Spend{Kind: localdb.SpendChannel, ClaimID: c.ClaimID, Amount: channelBidAmount, Fee: fee})
+	if branding != "" {
+		s.recordBranding(branding)
+	}
 	return nil
 }
 
-func allUTXOsConfirmed(utxolist *jsonrpc.UTXOListResponse) bool {
-	if utxolist == nil {
-		return false
+// isChannelName returns true for LbryChannelName and the suffixed names pickChannelName may have claimed instead
+func (s *Sync) isChannelName(name string) bool {
+	if name == s.LbryChannelName {
+		return true
 	}
+	suffix := strings.TrimPrefix(name, s.LbryChannelName+"-")
+	n, err := strconv.Atoi(suffix)
+	return suffix != name && err == nil && n > 1 && n <= maxChannelNameAttempts
+}
 
-	if len(*utxolist) < 1 {
-		return false
-	}
+// maxChannelNameAttempts is how many suffixed channel names are tried before giving up
+const maxChannelNameAttempts = 10
 
-	for _, utxo := range *utxolist {
-		if utxo.Height == 0 {
-			return false
-		}
+// pickChannelName returns the name the channel is claimed under and the bid, asking the ChannelConflictResolver what
+// to do if LbryChannelName is held by someone else
+func (s *Sync) pickChannelName() (string, float64, error) {
+	resolver := s.ChannelConflictResolver
+	if resolver == nil {
+		resolver = sources.SkipTaken{}
 	}
-
-	return true
-}
-
-func (s *Sync) addCredits(amountToAdd float64) error {
-	log.Printf("Adding %f credits", amountToAdd)
-	var lbrycrdd *lbrycrd.Client
-	var err error
-	if s.LbrycrdString == "" {
-		lbrycrdd, err = lbrycrd.NewWithDefaultURL()
-		if err != nil {
-			return err
+	for attempt := 1; attempt <= maxChannelNameAttempts; attempt++ {
+		name := s.LbryChannelName
+		if attempt > 1 {
+			name += "-" + strconv.Itoa(attempt)
 		}
-	} else {
-		lbrycrdd, err = lbrycrd.New(s.LbrycrdString)
+		existing, taken, err := sources.NameHolder(s.daemon, name)
 		if err != nil {
-			return err
+			return "", 0, err
+		}
+		if !taken {
+			return name, s.ClaimAmounts.channelBid(), nil
 		}
+
+		resolution := resolver.ResolveConflict(sources.NameConflict{Name: name, Attempt: attempt, Bid: s.ClaimAmounts.channelBid(), ExistingBid: existing})
+		switch resolution.Action {
+		case sources.NameSkip:
+			return "", 0, errors.Err("Channel exists and we don't own it. Pick another channel.")
+		case sources.NameBid:
+			s.logger().Printf("Channel %s exists and we don't own it. Outbidding existing claim.", name)
+			s.takenOver = true
+			return name, resolution.Bid, nil
+		}
+		s.logger().Printf("Channel %s exists and we don't own it. Trying the next name.", name)
 	}
+	return "", 0, errors.Err("no free channel name after %d attempts", maxChannelNameAttempts)
+}
 
-	addressResp, err := s.daemon.WalletUnusedAddress()
+// ensureChannelClaimOwnership makes sure the channel claim set in LbryChannelClaimID exists in the wallet and that we
+// can sign with it. Nothing is resolved by name, so an ambiguous channel name can't make us publish elsewhere.
+func (s *Sync) ensureChannelClaimOwnership() error {
+	channels, err := s.daemon.ChannelList()
 	if err != nil {
 		return err
-	} else if addressResp == nil {
-		return errors.Err("no response")
+	} else if channels == nil {
+		return errors.Err("no channel response")
 	}
-	address := string(*addressResp)
 
-	_, err = lbrycrdd.SimpleSend(address, amountToAdd)
-	if err != nil {
-		return err
+	for _, channel := range *channels {
+		if channel.ClaimID != s.LbryChannelClaimID {
+			continue
+		}
+		if !channel.CanSign {
+			return errors.Err("channel claim %s is in the wallet but can't be signed with", s.LbryChannelClaimID)
+		}
+		if s.LbryChannelName != "" && channel.Name != s.LbryChannelName {
+			s.logger().Warnf("channel claim %s is named %s, not %s. Publishing to the claim anyway", s.LbryChannelClaimID, channel.Name, s.LbryChannelName)
+		}
+		s.lbryChannelID = channel.ClaimID
+		return nil
 	}
 
-	wait := 15 * time.Second
-	log.Println("Waiting " + wait.String() + " for lbryum to let us know we have the new transaction")
-	time.Sleep(wait)
+	return errors.Err("channel claim %s is not owned by this wallet", s.LbryChannelClaimID)
+}
 
-	return nil
+// lbrycrdClient connects to the lbrycrd instance credits are taken from. The client must be shut down once it's not
+// needed anymore, or its goroutines keep running.
+func (s *Sync) lbrycrdClient() (*lbrycrd.Client, error) {
+	if s.LbrycrdString == "" {
+		return lbrycrd.NewWithDefaultURL()
+	}
+	return lbrycrd.New(s.LbrycrdString)
 }
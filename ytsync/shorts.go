@@ -0,0 +1,40 @@
+package ytsync
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// handleShorts routes the videos that are at most ShortsMaxDuration long, youtube shorts and clips as short, away from
+// the regular videos: they are dropped with SkipShorts, and marked to be published with ShortsTags otherwise. Videos
+// whose length isn't known are regular videos.
+func (s *Sync) handleShorts(videos []video, lengths map[string]time.Duration) []video {
+	handled := videos[:0]
+	shorts := 0
+	for _, v := range videos {
+		length, ok := lengths[v.ID()]
+		if !ok || length <= 0 || length > s.Manager.ShortsMaxDuration {
+			handled = append(handled, v)
+			continue
+		}
+		shorts++
+		if s.Manager.SkipShorts {
+			s.logger().Debugf("skipping %s: it's a short, %s long", v.ID(), length)
+			continue
+		}
+		if yv, ok := v.(sources.YoutubeVideo); ok {
+			v = yv.AsShort()
+		}
+		handled = append(handled, v)
+	}
+	if shorts == 0 {
+		return handled
+	}
+	if s.Manager.SkipShorts {
+		s.logger().Infof("skipping %d shorts of %s", shorts, s.YoutubeChannelID)
+	} else {
+		s.logger().Infof("%d videos of %s are shorts", shorts, s.YoutubeChannelID)
+	}
+	return handled
+}
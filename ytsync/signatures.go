@@ -0,0 +1,142 @@
+package ytsync
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// UnsignedClaim is a claim of the wallet holding a video of the channel that isn't signed by the channel claim the
+// wallet controls. This happens to the claims published while the channel name was being taken over.
+type UnsignedClaim struct {
+	VideoID   string `json:"video_id"`
+	ClaimID   string `json:"claim_id"`
+	ClaimName string `json:"claim_name"`
+	SignedBy  string `json:"signed_by,omitempty"` // the certificate the claim is signed with, empty if it isn't signed
+	Resigned  bool   `json:"resigned"`
+	Error     string `json:"error,omitempty"` // why it couldn't be re-signed
+}
+
+// resigner is a video that can publish its claim again in the channel, see sources.YoutubeVideo.Resign
+type resigner interface {
+	video
+	Resign(*jsonrpc.Client, sources.SyncParams, jsonrpc.Claim) (*sources.SyncSummary, error)
+}
+
+// checkClaimSignatures re-signs the claims of the channel that aren't signed by its channel claim, once the channel
+// name was taken over. Failing to do so doesn't stop the sync, verify reports the claims that are left.
+func (s *Sync) checkClaimSignatures() {
+	_, takeOver := s.ChannelConflictResolver.(sources.TakeOver)
+	if (!takeOver && !s.takenOver) || s.lbryChannelID == "" {
+		return
+	}
+	unsigned, claims, err := s.unsignedClaims(s.lbryChannelID)
+	if err != nil {
+		s.notifyError("could not check the signatures of the claims of %s: %s", s.LbryChannelName, err.Error())
+		return
+	}
+	if len(unsigned) == 0 {
+		s.logger().Infof("the claims of %s are all signed by channel claim %s", s.LbryChannelName, s.lbryChannelID)
+		return
+	}
+	videos, err := s.fetchYoutubeVideos()
+	if err != nil {
+		s.notifyError("could not re-sign the claims of %s: %s", s.LbryChannelName, err.Error())
+		return
+	}
+	resigned := s.resignClaims(unsigned, claims, videos)
+	s.notifyInfo("%s (%s): %d claims were not signed by channel claim %s, %d of them were re-signed", s.LbryChannelName, s.YoutubeChannelID, len(unsigned), s.lbryChannelID, resigned)
+}
+
+// unsignedClaims returns the claims of the wallet holding videos of the channel that aren't signed by the channel
+// claim with the given ID, along with the claims themselves by claim ID
+func (s *Sync) unsignedClaims(channelID string) ([]UnsignedClaim, map[string]jsonrpc.Claim, error) {
+	response, err := s.daemon.ClaimListMine()
+	if err != nil {
+		return nil, nil, err
+	} else if response == nil {
+		return nil, nil, errors.Err("no claim list response")
+	}
+	local, err := s.localVideos()
+	if err != nil {
+		return nil, nil, err
+	}
+	claimsByVideo, _ := matchClaims(*response, local)
+
+	var unsigned []UnsignedClaim
+	claims := make(map[string]jsonrpc.Claim)
+	for videoID, published := range claimsByVideo {
+		for _, c := range published {
+			if c.Value.GetStream() == nil || sources.SignedBy(c, channelID) {
+				continue
+			}
+			unsigned = append(unsigned, UnsignedClaim{
+				VideoID:   videoID,
+				ClaimID:   c.ClaimID,
+				ClaimName: c.Name,
+				SignedBy:  hex.EncodeToString(c.Value.GetPublisherSignature().GetCertificateId()),
+			})
+			claims[c.ClaimID] = c
+		}
+	}
+	sort.Slice(unsigned, func(i, j int) bool { return unsigned[i].VideoID < unsigned[j].VideoID })
+	return unsigned, claims, nil
+}
+
+// resignClaims publishes the unsigned claims again in the channel, with the metadata of their video. A claim whose
+// video isn't among videos anymore is left as it is. It returns how many were re-signed.
+func (s *Sync) resignClaims(unsigned []UnsignedClaim, claims map[string]jsonrpc.Claim, videos []video) int {
+	byID := make(map[string]video)
+	for _, v := range videos {
+		byID[v.ID()] = v
+	}
+	params := s.syncParams()
+	resigned := 0
+	for i := range unsigned {
+		u := &unsigned[i]
+		if s.IsInterrupted() {
+			u.Error = "the sync is stopping"
+			continue
+		}
+		r, ok := byID[u.VideoID].(resigner)
+		if !ok {
+			u.Error = "the video is not on youtube anymore"
+			continue
+		}
+		params.Log = s.videoLogger(u.VideoID, 1)
+		summary, err := r.Resign(s.daemon, params, claims[u.ClaimID])
+		if err != nil {
+			u.Error = err.Error()
+			continue
+		}
+		u.Resigned = true
+		resigned++
+		if s.stats != nil {
+			s.stats.spend(summary.Fee)
+		}
+		s.recordSpend(localdb.Spend{Kind: localdb.SpendUpdate, VideoID: u.VideoID, ClaimID: summary.ClaimID, Txid: summary.Txid, Fee: summary.Fee})
+	}
+	return resigned
+}
+
+// signingChannelID returns the ID of the channel claim named LbryChannelName the wallet can sign with, the most recent
+// one if there are several. It's empty if there is none.
+func (s *Sync) signingChannelID() (string, error) {
+	channels, err := s.daemon.ChannelList()
+	if err != nil {
+		return "", err
+	} else if channels == nil {
+		return "", errors.Err("no channel response")
+	}
+	claimID, height := "", -1
+	for _, channel := range *channels {
+		if channel.Name == s.LbryChannelName && channel.CanSign && channel.Height > height {
+			claimID, height = channel.ClaimID, channel.Height
+		}
+	}
+	return claimID, nil
+}
@@ -0,0 +1,218 @@
+package ytsync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// slackRequestMaxAge is how old a slash command can be, older ones could be replayed
+const slackRequestMaxAge = 5 * time.Minute
+
+const slackCommandUsage = "Usage: `status [channel]`, `pause [channel]`, `resume [channel]` or `skip <video> [channel] [reason]`. " +
+	"A channel is its youtube ID or LBRY name, without one `pause` and `resume` apply to the whole node."
+
+// slackResponse is the answer to a slash command. Slack shows it to everyone in the channel if it's in_channel, and
+// only to whoever sent the command if it's ephemeral.
+type slackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// slackCommandHandler answers the Slack slash commands controlling the node, see runSlackCommand. The requests must
+// be signed with SlackSigningSecret and, if SlackChannel is set, come from that channel.
+func (s SyncManager) slackCommandHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = s.verifySlackSignature(r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the refusal is only shown to whoever asked, and doesn't tell where commands are taken
+	response := slackResponse{ResponseType: "ephemeral", Text: "ytsync doesn't take commands in this channel"}
+	if s.slackChannelAllowed(form.Get("channel_id"), form.Get("channel_name")) {
+		response = slackResponse{ResponseType: "in_channel", Text: s.runSlackCommand(form)}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// verifySlackSignature checks that a request was signed by Slack with SlackSigningSecret, recently
+func (s SyncManager) verifySlackSignature(header http.Header, body []byte) error {
+	timestamp, err := strconv.ParseInt(header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil {
+		return errors.Err("missing or invalid request timestamp")
+	}
+	age := time.Since(time.Unix(timestamp, 0))
+	if age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return errors.Err("the request is too old")
+	}
+	mac := hmac.New(sha256.New, []byte(s.SlackSigningSecret))
+	mac.Write([]byte("v0:" + strconv.FormatInt(timestamp, 10) + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return errors.Err("invalid request signature")
+	}
+	return nil
+}
+
+// slackChannelAllowed returns true if commands can be run from the channel, given by ID and name
+func (s SyncManager) slackChannelAllowed(channelID, channelName string) bool {
+	allowed := strings.TrimPrefix(s.SlackChannel, "#")
+	return allowed == "" || allowed == channelID || allowed == channelName
+}
+
+// runSlackCommand runs a slash command and returns the answer. The commands that change anything go through the same
+// code as the status server.
+func (s SyncManager) runSlackCommand(form url.Values) string {
+	args := strings.Fields(form.Get("text"))
+	if len(args) == 0 {
+		return slackCommandUsage
+	}
+	by := "by @" + form.Get("user_name") + " through Slack"
+
+	switch args[0] {
+	case "status":
+		if len(args) == 1 {
+			return s.slackNodeStatus()
+		}
+		sync, err := s.findRunningChannel(args[1])
+		if err != nil {
+			return err.Error()
+		}
+		return slackChannelStatus(sync)
+	case "pause", "resume":
+		channelID := ""
+		if len(args) > 1 {
+			sync, err := s.findRunningChannel(args[1])
+			if err != nil {
+				return err.Error()
+			}
+			channelID = sync.YoutubeChannelID
+		}
+		if args[0] == "pause" {
+			err := s.pause(channelID, by)
+			if err != nil {
+				return err.Error()
+			}
+			return "ok, paused"
+		}
+		err := s.resume(channelID, by)
+		if err != nil {
+			return err.Error()
+		}
+		return "ok, resumed"
+	case "skip":
+		if len(args) < 2 {
+			return slackCommandUsage
+		}
+		videoID := args[1]
+		sync, reasonArgs, err := s.findVideoChannel(videoID, args[2:])
+		if err != nil {
+			return err.Error()
+		}
+		err = s.failVideo(sync.YoutubeChannelID, videoID, strings.Join(reasonArgs, " "), by)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("ok, %s of %s is failed for good and won't be retried", videoID, sync.LbryChannelName)
+	}
+	return slackCommandUsage
+}
+
+// findRunningChannel returns the sync of the channel with the given youtube ID or LBRY channel name
+func (s SyncManager) findRunningChannel(channel string) (*Sync, error) {
+	for _, sync := range s.running.list() {
+		if sync.YoutubeChannelID == channel || sync.LbryChannelName == channel || strings.TrimPrefix(sync.LbryChannelName, "@") == channel {
+			return sync, nil
+		}
+	}
+	return nil, errors.Err("%s is not being synced", channel)
+}
+
+// findVideoChannel returns the sync a video to skip belongs to, and the rest of the arguments, the reason. The first
+// argument names the channel if it's one being synced. Otherwise the video must be known to one of the channels, or
+// only one channel must be syncing.
+func (s SyncManager) findVideoChannel(videoID string, args []string) (*Sync, []string, error) {
+	if len(args) > 0 {
+		if sync, err := s.findRunningChannel(args[0]); err == nil {
+			return sync, args[1:], nil
+		}
+	}
+	running := s.running.list()
+	for _, sync := range running {
+		if _, ok := sync.syncedVideo(videoID); ok {
+			return sync, args, nil
+		}
+	}
+	if len(running) == 1 {
+		return running[0], args, nil
+	}
+	return nil, nil, errors.Err("could not tell which channel %s belongs to, use `skip %s <channel>`", videoID, videoID)
+}
+
+// slackNodeStatus sums up the channels being synced, one per line
+func (s SyncManager) slackNodeStatus() string {
+	running := s.running.list()
+	if len(running) == 0 {
+		return s.HostName + " is not syncing anything"
+	}
+	lines := []string{fmt.Sprintf("%s is syncing %d channels:", s.HostName, len(running))}
+	if s.grp.IsPaused() {
+		lines[0] = fmt.Sprintf("%s is paused, with %d channels:", s.HostName, len(running))
+	}
+	for _, sync := range running {
+		lines = append(lines, "• "+slackChannelLine(sync))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// slackChannelStatus is the status of one channel, with its failures by root cause
+func slackChannelStatus(sync *Sync) string {
+	summary := sync.Summary()
+	text := slackChannelLine(sync)
+	if len(summary.Failures) > 0 {
+		text += "\nFailures: " + formatTriageCounts(summary.Failures)
+	}
+	if summary.LastVideoID != "" {
+		text += "\nLast video: " + summary.LastVideoID
+	}
+	return text
+}
+
+func slackChannelLine(sync *Sync) string {
+	summary := sync.Summary()
+	line := fmt.Sprintf("%s (%s): %d published, %d failed, %d skipped, %.2f LBC spent in %s", summary.LbryChannelName, summary.YoutubeChannelID,
+		summary.VideosPublished, summary.VideosFailed, summary.VideosSkipped, summary.Spent, (time.Duration(summary.DurationSeconds) * time.Second).String())
+	if sync.IsCancelled() {
+		line += ", cancelled"
+	} else if sync.IsPaused() {
+		line += ", paused"
+	}
+	return line
+}
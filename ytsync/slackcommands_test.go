@@ -0,0 +1,104 @@
+package ytsync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedHeader returns the headers of a slash command sent at sentAt, signed with secret like Slack does
+func signedHeader(secret string, sentAt time.Time, body string) http.Header {
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	s := SyncManager{SlackSigningSecret: "secret"}
+	body := "command=%2Fytsync&text=status&channel_name=ops"
+
+	missingTimestamp := signedHeader("secret", time.Now(), body)
+	missingTimestamp.Del("X-Slack-Request-Timestamp")
+
+	tests := []struct {
+		name   string
+		header http.Header
+		valid  bool
+	}{
+		{"valid signature", signedHeader("secret", time.Now(), body), true},
+		{"wrong signature", signedHeader("other secret", time.Now(), body), false},
+		{"missing timestamp", missingTimestamp, false},
+		{"old timestamp", signedHeader("secret", time.Now().Add(-slackRequestMaxAge-time.Minute), body), false},
+		{"future timestamp", signedHeader("secret", time.Now().Add(slackRequestMaxAge+time.Minute), body), false},
+	}
+	for _, test := range tests {
+		err := s.verifySlackSignature(test.header, []byte(body))
+		if test.valid && err != nil {
+			t.Errorf("%s: expected the request to be accepted, got %s", test.name, err.Error())
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: expected the request to be refused", test.name)
+		}
+	}
+
+	if err := s.verifySlackSignature(signedHeader("secret", time.Now(), body), []byte(body+"&user_name=mallory")); err == nil {
+		t.Error("expected a changed body to be refused")
+	}
+}
+
+func TestSlackChannelAllowed(t *testing.T) {
+	s := SyncManager{SlackChannel: "#ops"}
+	tests := []struct {
+		channelID, channelName string
+		allowed                bool
+	}{
+		{"C123", "ops", true},
+		{"C456", "general", false},
+		{"C456", "", false},
+	}
+	for _, test := range tests {
+		if allowed := s.slackChannelAllowed(test.channelID, test.channelName); allowed != test.allowed {
+			t.Errorf("%s (%s): expected %t, got %t", test.channelName, test.channelID, test.allowed, allowed)
+		}
+	}
+
+	if !(SyncManager{SlackChannel: "C123"}).slackChannelAllowed("C123", "renamed") {
+		t.Error("expected a channel given by ID to be allowed")
+	}
+	if !(SyncManager{}).slackChannelAllowed("C456", "general") {
+		t.Error("expected every channel to be allowed when none is set")
+	}
+}
+
+func TestSlackCommandRefusedChannel(t *testing.T) {
+	s := SyncManager{SlackSigningSecret: "secret", SlackChannel: "#ops"}
+	body := "command=%2Fytsync&text=pause&channel_id=C456&channel_name=general&user_name=someone"
+	req := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(body))
+	req.Header = signedHeader("secret", time.Now(), body)
+	w := httptest.NewRecorder()
+	s.slackCommandHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var response slackResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ResponseType != "ephemeral" {
+		t.Errorf("expected the refusal to be ephemeral, got %s", response.ResponseType)
+	}
+	if strings.Contains(response.Text, "ops") {
+		t.Errorf("expected the refusal not to name the allowed channel, got %q", response.Text)
+	}
+}
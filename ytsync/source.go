@@ -0,0 +1,123 @@
+package ytsync
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// videoSource returns where the videos of the channel come from: Source if it's set, the youtube channel otherwise
+func (s *Sync) videoSource() sources.VideoSource {
+	if s.Source != nil {
+		return s.Source
+	}
+	if s.LbryChannelName == "@UCBerkeley" {
+		return ucbSource{s}
+	}
+	return youtubeSource{s}
+}
+
+// countSourceVideos returns how many videos the source has, to know how many credits the sync needs
+func (s *Sync) countSourceVideos() (int, error) {
+	switch source := s.videoSource().(type) {
+	case youtubeSource:
+		n, err := s.CountVideos()
+		return int(n), err
+	case ucbSource:
+		return 10104, nil
+	default:
+		videos, err := source.ListVideos()
+		return len(videos), err
+	}
+}
+
+// enqueueVideos lists the videos of the source and hands them over to the workers, in order, until the sync is stopped.
+// The video filter applies to every source.
+func (s *Sync) enqueueVideos(source sources.VideoSource) error {
+	videos, err := source.ListVideos()
+	if err != nil {
+		return errors.Prefix("could not list the videos of "+source.Name(), err)
+	}
+	videos = s.VideoFilter.apply(videos)
+	s.logger().Infof("%d videos to sync from %s", len(videos), source.Name())
+
+Enqueue:
+	for _, v := range videos {
+		select {
+		case <-s.grp.Ch():
+			break Enqueue
+		default:
+		}
+
+		select {
+		case s.queue <- v:
+		case <-s.grp.Ch():
+			break Enqueue
+		}
+	}
+
+	return nil
+}
+
+// youtubeSource lists the videos of the youtube channel, or of the playlist, being synced
+type youtubeSource struct {
+	s *Sync
+}
+
+func (y youtubeSource) Name() string {
+	if y.s.YoutubePlaylistID != "" {
+		return "youtube playlist " + y.s.YoutubePlaylistID
+	}
+	return "youtube channel " + y.s.YoutubeChannelID
+}
+
+func (y youtubeSource) ListVideos() ([]sources.Video, error) {
+	return y.s.fetchYoutubeVideos()
+}
+
+// ucbSource lists the UC Berkeley videos of ucb.csv, which are on S3
+type ucbSource struct {
+	s *Sync
+}
+
+func (u ucbSource) Name() string {
+	return "ucb.csv"
+}
+
+func (u ucbSource) ListVideos() ([]sources.Video, error) {
+	var videos []video
+
+	csvFile, err := os.Open("ucb.csv")
+	if err != nil {
+		return nil, err
+	}
+	defer csvFile.Close()
+
+	reader := csv.NewReader(bufio.NewReader(csvFile))
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		data := struct {
+			PublishedAt string `json:"publishedAt"`
+		}{}
+		err = json.Unmarshal([]byte(line[4]), &data)
+		if err != nil {
+			return nil, err
+		}
+
+		videos = append(videos, sources.NewUCBVideo(line[0], line[2], line[1], line[3], data.PublishedAt, u.s.videoDirectory))
+	}
+
+	sort.Sort(byPublishedAt(videos))
+	return videos, nil
+}
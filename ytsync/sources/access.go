@@ -0,0 +1,176 @@
+package sources
+
+import (
+	"bufio"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// YoutubeAccess holds what it takes to fetch the videos youtube restricts. Age-restricted videos need the cookies of a
+// logged in account whose age is verified. Region-locked videos need the requests to come from a region where they
+// are available, through a proxy or by claiming to be forwarded from there.
+type YoutubeAccess struct {
+	// CookiesFile is a cookies.txt file in the Netscape format, as exported from a browser logged in to youtube
+	CookiesFile string
+	// Proxy is the URL of the proxy the requests to youtube go through
+	Proxy string
+	// GeoBypassIPBlock is an IP block (CIDR) of the region to pretend to be in. The requests to youtube claim to be
+	// forwarded from a random address in it.
+	GeoBypassIPBlock string
+}
+
+// youtubeHosts are the hosts the settings of YoutubeAccess apply to
+var youtubeHosts = []string{"youtube.com", "googlevideo.com", "ytimg.com"}
+
+// SetupYoutubeAccess makes the requests to youtube use the access settings. The ytdl package uses http.DefaultClient
+// for everything, so it's the default client that's changed. Requests to other hosts are left alone.
+func SetupYoutubeAccess(a YoutubeAccess) error {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if a.Proxy != "" {
+		proxyURL, err := url.Parse(a.Proxy)
+		if err != nil {
+			return errors.Err("invalid youtube proxy: %s", err.Error())
+		}
+		transport.Proxy = func(r *http.Request) (*url.URL, error) {
+			if isYoutubeHost(r.URL.Hostname()) {
+				return proxyURL, nil
+			}
+			return http.ProxyFromEnvironment(r)
+		}
+	}
+
+	var forwardedFor *net.IPNet
+	if a.GeoBypassIPBlock != "" {
+		_, block, err := net.ParseCIDR(a.GeoBypassIPBlock)
+		if err != nil || block.IP.To4() == nil {
+			return errors.Err("the geo bypass IP block must be an IPv4 CIDR, like 1.2.3.0/24")
+		}
+		forwardedFor = block
+	}
+
+	client := &http.Client{Transport: &youtubeTransport{base: transport, forwardedFor: forwardedFor}}
+	if a.CookiesFile != "" {
+		jar, err := loadCookies(a.CookiesFile)
+		if err != nil {
+			return err
+		}
+		client.Jar = jar
+	}
+	http.DefaultClient = client
+	return nil
+}
+
+func isYoutubeHost(host string) bool {
+	for _, h := range youtubeHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// youtubeTransport adds an X-Forwarded-For header to the requests to youtube, if a geo bypass IP block is set
+type youtubeTransport struct {
+	base         http.RoundTripper
+	forwardedFor *net.IPNet
+}
+
+func (t *youtubeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.forwardedFor == nil || !isYoutubeHost(r.URL.Hostname()) {
+		return t.base.RoundTrip(r)
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = make(http.Header, len(r.Header)+1)
+	for k, v := range r.Header {
+		r2.Header[k] = v
+	}
+	r2.Header.Set("X-Forwarded-For", randomIP(t.forwardedFor).String())
+	return t.base.RoundTrip(r2)
+}
+
+// randomIP returns a random address in the IPv4 block
+func randomIP(block *net.IPNet) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	base, mask := block.IP.To4(), block.Mask
+	random := rand.Uint32()
+	for i := range ip {
+		ip[i] = base[i]&mask[i] | byte(random>>(8*uint(i)))&^mask[i]
+	}
+	return ip
+}
+
+// loadCookies reads a cookies.txt file in the Netscape format into a cookie jar
+func loadCookies(path string) (http.CookieJar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer f.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	byURL := make(map[string][]*http.Cookie)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, errors.Err("%s:%d is not a Netscape cookie line", path, line)
+		}
+		domain, cookiePath, secure := fields[0], fields[2], fields[3] == "TRUE"
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     cookiePath,
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		if strings.HasPrefix(domain, ".") {
+			cookie.Domain = domain
+		}
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		u := scheme + "://" + strings.TrimPrefix(domain, ".") + cookiePath
+		byURL[u] = append(byURL[u], cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Err(err)
+	}
+	for rawURL, cookies := range byURL {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		jar.SetCookies(u, cookies)
+	}
+	return jar, nil
+}
@@ -0,0 +1,179 @@
+package sources
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// autoCaptionSuffix marks the files of captions generated by youtube
+const autoCaptionSuffix = ".auto"
+
+// Caption is a subtitle track of a video, hosted along with the thumbnail
+type Caption struct {
+	Language string
+	Auto     bool // generated by youtube's speech recognition
+	URL      string
+}
+
+// captionTrack is a caption track as listed by youtube
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"`
+}
+
+func (t captionTrack) filename() string {
+	name := t.LanguageCode
+	if t.Kind == "asr" {
+		name += autoCaptionSuffix
+	}
+	return name + ".vtt"
+}
+
+// captionTracks lists the manual and automatic caption tracks youtube has for a video
+func captionTracks(videoID string) ([]captionTrack, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get("https://www.youtube.com/get_video_info?video_id=" + url.QueryEscape(videoID))
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Err("video info request returned status code %d", res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	var playerResponse struct {
+		Captions struct {
+			Renderer struct {
+				Tracks []captionTrack `json:"captionTracks"`
+			} `json:"playerCaptionsTracklistRenderer"`
+		} `json:"captions"`
+	}
+	if values.Get("player_response") == "" {
+		return nil, nil
+	}
+	err = json.Unmarshal([]byte(values.Get("player_response")), &playerResponse)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return playerResponse.Captions.Renderer.Tracks, nil
+}
+
+// downloadCaption saves a caption track in the WebVTT format
+func downloadCaption(track captionTrack, path string) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(track.BaseURL + "&fmt=vtt")
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Err("caption request returned status code %d", res.StatusCode)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	_, err = io.Copy(file, res.Body)
+	file.Close()
+	if err != nil {
+		_ = os.Remove(path)
+		return errors.Err(err)
+	}
+	return nil
+}
+
+func (v YoutubeVideo) captionsDir() string {
+	return v.videoDir() + "/captions"
+}
+
+func captionKey(videoID, filename string) string {
+	return "captions/" + videoID + "/" + filename
+}
+
+// saveCaptions downloads the captions of the video and hosts them along with the thumbnail
+func (v YoutubeVideo) saveCaptions(params SyncParams) error {
+	tracks, err := captionTracks(v.id)
+	if err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(v.captionsDir(), 0750)
+	if err != nil {
+		return errors.Err(err)
+	}
+	for _, track := range tracks {
+		path := v.captionsDir() + "/" + track.filename()
+		err = downloadCaption(track, path)
+		if err == nil {
+			_, err = host(path, captionKey(v.id, track.filename()), "text/vtt", params)
+		}
+		if err != nil {
+			params.logger().Warnf("could not save the %s captions of %s: %s", track.LanguageCode, v.id, err.Error())
+		}
+	}
+	return nil
+}
+
+// captions returns the captions saved by saveCaptions
+func (v YoutubeVideo) captions() []Caption {
+	files, err := ioutil.ReadDir(v.captionsDir())
+	if err != nil {
+		return nil
+	}
+	var captions []Caption
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".vtt" {
+			continue
+		}
+		hosted := hostedURL(v.captionsDir() + "/" + f.Name())
+		if hosted == "" {
+			continue
+		}
+		language := strings.TrimSuffix(f.Name(), ".vtt")
+		auto := strings.HasSuffix(language, autoCaptionSuffix)
+		captions = append(captions, Caption{
+			Language: strings.TrimSuffix(language, autoCaptionSuffix),
+			Auto:     auto,
+			URL:      hosted,
+		})
+	}
+	return captions
+}
+
+// captionsSection lists the captions in a way that can be appended to the description of a claim
+func captionsSection(captions []Caption) string {
+	if len(captions) == 0 {
+		return ""
+	}
+	lines := []string{"Captions:"}
+	for _, c := range captions {
+		label := c.Language
+		if c.Auto {
+			label += " (auto-generated)"
+		}
+		lines = append(lines, label+": "+c.URL)
+	}
+	return "\n\n" + strings.Join(lines, "\n")
+}
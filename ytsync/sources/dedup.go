@@ -0,0 +1,129 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+)
+
+// ErrDuplicate is returned for a video whose file was published before, when duplicates are skipped
+var ErrDuplicate = errors.Base("the same video was already published")
+
+// DuplicateAction is what happens to a video whose file was published before
+type DuplicateAction int
+
+const (
+	DuplicateSkip    DuplicateAction = iota // fail the video with ErrDuplicate
+	DuplicateRepost                         // publish a claim pointing to the stream of the original claim
+	DuplicatePublish                        // publish the video again
+)
+
+// ParseDuplicateAction returns the action with the given name: skip, repost or publish
+func ParseDuplicateAction(action string) (DuplicateAction, error) {
+	switch action {
+	case "skip":
+		return DuplicateSkip, nil
+	case "repost":
+		return DuplicateRepost, nil
+	case "publish":
+		return DuplicatePublish, nil
+	}
+	return DuplicateSkip, errors.Err("unknown duplicate action %q, use skip, repost or publish", action)
+}
+
+// Original is the claim a file was first published under
+type Original struct {
+	ClaimID   string
+	ClaimName string
+}
+
+// Deduplicator remembers which files were published, so the same video uploaded to several channels isn't published
+// more than once
+type Deduplicator interface {
+	// Original returns the claim the file with the given SHA-256 (hex encoded) was published under, if it was
+	Original(contentHash string) (Original, bool, error)
+}
+
+// contentHash returns the SHA-256 of the file at path, hex encoded
+func contentHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findOriginal hashes the file and looks it up. It returns the hash, and the claim the file was published under if it
+// was.
+func findOriginal(path string, params SyncParams) (string, *Original, error) {
+	if params.Dedup == nil {
+		return "", nil, nil
+	}
+	hash, err := contentHash(path)
+	if err != nil {
+		return "", nil, err
+	}
+	original, found, err := params.Dedup.Original(hash)
+	if err != nil || !found || params.Duplicates == DuplicatePublish {
+		return hash, nil, err
+	}
+	return hash, &original, nil
+}
+
+// publishDeduplicated publishes the file, unless it was published before. Then, depending on params.Duplicates, it
+// fails with ErrDuplicate or publishes a claim pointing to the stream of the original claim instead.
+func publishDeduplicated(daemon *jsonrpc.Client, title, filename string, options jsonrpc.PublishOptions, params SyncParams) (*SyncSummary, error) {
+	hash, original, err := findOriginal(filename, params)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		summary, err := publishAndRetryExistingNames(daemon, title, filename, params.Amount, options, params.NameResolver)
+		if err != nil {
+			return nil, err
+		}
+		summary.ContentHash = hash
+		return summary, nil
+	}
+
+	url := "lbry://" + original.ClaimName + "#" + original.ClaimID
+	if params.Duplicates == DuplicateSkip {
+		return nil, errors.Prefix(url, ErrDuplicate)
+	}
+	sd, err := sdHash(daemon, original.ClaimID)
+	if err != nil {
+		return nil, err
+	}
+	params.logger().Infof("%s was already published as %s, publishing a repost of it", title, url)
+	options.Sources = map[string]string{"lbry_sd_hash": sd}
+	summary, err := publishAndRetryExistingNames(daemon, title, "", params.Amount, options, params.NameResolver)
+	if err != nil {
+		return nil, err
+	}
+	summary.RepostOf = original.ClaimID
+	return summary, nil
+}
+
+// sdHash returns the hash of the stream descriptor of a claim, which other claims can point to instead of uploading
+// the same file again
+func sdHash(daemon *jsonrpc.Client, claimID string) (string, error) {
+	claim, err := daemon.ClaimShow(&claimID, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	source := claim.Value.GetStream().GetSource().GetSource()
+	if len(source) == 0 {
+		return "", errors.Err("claim %s has no stream", claimID)
+	}
+	return hex.EncodeToString(source), nil
+}
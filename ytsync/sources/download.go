@@ -0,0 +1,166 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// partialSuffix is added to the name of a download until it's complete
+	partialSuffix = ".part"
+	// infoSuffix is added to the name of a partial download for the file recording its downloadInfo
+	infoSuffix = ".json"
+)
+
+// downloadInfo is what the server said about a download. It's kept next to a partial download, to resume it only if
+// the file didn't change, and used to verify the complete download.
+type downloadInfo struct {
+	Size int64  `json:"size"` // -1 if the server didn't say
+	ETag string `json:"etag,omitempty"`
+}
+
+// downloadResumable downloads url to path. The data goes to partPath until the download is complete, when it's moved
+// to path. If partPath is already there, the download continues where it stopped, as long as the server supports range
+// requests and the file didn't change since. An interrupted download is left in partPath. wrap can wrap the writer the
+// data goes through, it's given how many bytes are already downloaded and the size of the file, -1 if it's not known.
+// The download is cut short when stop is closed. It returns the size and ETag the server sent.
+func downloadResumable(url, path, partPath string, wrap func(w io.Writer, offset, size int64) io.Writer, stop <-chan struct{}) (downloadInfo, error) {
+	info := downloadInfo{Size: -1}
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return info, errors.Err(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		// the server sends the whole file instead if it changed. Weak ETags can't be used for this
+		if previous, err := readDownloadInfo(partPath); err == nil && previous.ETag != "" && !strings.HasPrefix(previous.ETag, "W/") {
+			req.Header.Set("If-Range", previous.ETag)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	defer res.Body.Close()
+
+	info.ETag = res.Header.Get("ETag")
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		log.Debugf("resuming the download of %s at byte %d", path, offset)
+		info.Size = completeSize(res.Header.Get("Content-Range"))
+	case http.StatusOK:
+		if res.ContentLength > 0 {
+			info.Size = res.ContentLength
+		}
+		if offset > 0 {
+			log.Debugf("the server doesn't support resuming downloads, or the file changed, downloading %s from the start", path)
+			err = f.Truncate(0)
+			if err != nil {
+				return info, errors.Err(err)
+			}
+			_, err = f.Seek(0, io.SeekStart)
+			if err != nil {
+				return info, errors.Err(err)
+			}
+			offset = 0
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		info.Size = completeSize(res.Header.Get("Content-Range"))
+		if info.Size != offset {
+			// the partial download doesn't match the file, start over on the next attempt
+			_ = os.Remove(partPath)
+			_ = os.Remove(partPath + infoSuffix)
+			return info, errors.Err("the partial download of %s is bigger than the file", path)
+		}
+		f.Close()
+		return info, finishDownload(path, partPath)
+	default:
+		return info, errors.Err("download failed with status code %d", res.StatusCode)
+	}
+	err = writeDownloadInfo(partPath, info)
+	if err != nil {
+		log.Warnf("could not record the size and ETag of %s: %s", path, err.Error())
+	}
+
+	var out io.Writer = f
+	if wrap != nil {
+		out = wrap(out, offset, info.Size)
+	}
+	_, err = io.Copy(out, res.Body)
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	err = f.Close()
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	return info, finishDownload(path, partPath)
+}
+
+// finishDownload moves a complete download to path
+func finishDownload(path, partPath string) error {
+	_ = os.Remove(partPath + infoSuffix)
+	return errors.Err(os.Rename(partPath, path))
+}
+
+// readDownloadInfo reads what the server said about the partial download in partPath
+func readDownloadInfo(partPath string) (downloadInfo, error) {
+	var info downloadInfo
+	data, err := ioutil.ReadFile(partPath + infoSuffix)
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	return info, errors.Err(json.Unmarshal(data, &info))
+}
+
+// writeDownloadInfo records what the server said about the partial download in partPath
+func writeDownloadInfo(partPath string, info downloadInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(ioutil.WriteFile(partPath+infoSuffix, data, 0644))
+}
+
+// completeSize returns the complete size of the file from a Content-Range header like "bytes */1234", or -1 if it's not
+// known
+func completeSize(contentRange string) int64 {
+	i := strings.LastIndex(contentRange, "/")
+	if i < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
@@ -0,0 +1,176 @@
+package sources
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/names"
+)
+
+// ExistingState is how a video compares with the claim the channel already published it under
+type ExistingState int
+
+const (
+	NotPublished     ExistingState = iota // no claim of the channel holds the video
+	PublishedSame                         // the claim holds the video with its current metadata, nothing was done
+	PublishedUpdated                      // the metadata of the video changed, the claim was updated
+)
+
+// captionsMarker starts the list of captions at the end of the published descriptions, see captionsSection
+const captionsMarker = "\n\nCaptions:"
+
+// SyncExisting looks for a claim of the channel already holding the video, resolving the names the video would be
+// published under until one is free. If there is one and the metadata of the video changed since, the claim is
+// updated, pointing to the same stream. Nothing is downloaded. If the video isn't published yet, NotPublished is
+// returned and the video has to be synced as usual.
+func (v YoutubeVideo) SyncExisting(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, ExistingState, error) {
+	claim, err := v.existingClaim(daemon, params)
+	if err != nil || claim == nil {
+		return nil, NotPublished, err
+	}
+	summary := &SyncSummary{ClaimID: claim.ClaimID, ClaimName: claim.Name}
+
+	m := v.Metadata(params)
+	published := claim.Value.GetStream().GetMetadata()
+	description, _ := splitCaptions(published.GetDescription())
+	if published.GetTitle() == m.Title && description == m.Description && published.GetAuthor() == m.Author &&
+		published.GetLicense() == m.License && published.GetLicenseUrl() == m.LicenseURL && published.GetNsfw() == m.NSFW {
+		return summary, PublishedSame, nil
+	}
+	return v.updateClaim(daemon, params, *claim, m, published.GetThumbnail())
+}
+
+// UpdateMetadata updates the claim the video was published under if its title, description or thumbnail changed on
+// youtube since. Thumbnails are compared by content, and a new one is hosted under a key of its own so that caches
+// don't serve the old one. Only the thumbnail is downloaded.
+func (v YoutubeVideo) UpdateMetadata(daemon *jsonrpc.Client, params SyncParams, claim jsonrpc.Claim) (*SyncSummary, ExistingState, error) {
+	m := v.Metadata(params)
+	published := claim.Value.GetStream().GetMetadata()
+	description, _ := splitCaptions(published.GetDescription())
+	thumbnail, err := v.changedThumbnail(params, published.GetThumbnail())
+	if err != nil {
+		return nil, NotPublished, err
+	}
+	if published.GetTitle() == m.Title && description == m.Description && thumbnail == "" {
+		return &SyncSummary{ClaimID: claim.ClaimID, ClaimName: claim.Name}, PublishedSame, nil
+	}
+	if thumbnail == "" {
+		thumbnail = published.GetThumbnail()
+	}
+	return v.updateClaim(daemon, params, claim, m, thumbnail)
+}
+
+// updateClaim updates the claim with the metadata, pointing to the same stream
+func (v YoutubeVideo) updateClaim(daemon *jsonrpc.Client, params SyncParams, claim jsonrpc.Claim, m Metadata, thumbnail string) (*SyncSummary, ExistingState, error) {
+	sd := claim.Value.GetStream().GetSource().GetSource()
+	if len(sd) == 0 {
+		return nil, NotPublished, errors.Err("claim %s has no stream", claim.ClaimID)
+	}
+	options := m.publishOptions(params, thumbnail)
+	// the captions aren't fetched without a download, the ones that were published are kept
+	_, captions := splitCaptions(claim.Value.GetStream().GetMetadata().GetDescription())
+	*options.Description += captions
+	options.Sources = map[string]string{"lbry_sd_hash": hex.EncodeToString(sd)}
+	bid, _ := claim.Amount.Float64()
+	response, err := daemon.Publish(claim.Name, "", bid, options)
+	if err != nil {
+		return nil, NotPublished, errors.Prefix("could not update claim "+claim.ClaimID, err)
+	}
+	summary := &SyncSummary{ClaimID: response.ClaimID, ClaimName: claim.Name, Txid: response.Txid, Amount: bid}
+	summary.Fee, _ = response.Fee.Float64()
+	params.logger().Infof("updated the metadata of %s in claim %s", v.id, summary.ClaimID)
+	return summary, PublishedUpdated, nil
+}
+
+// Resign publishes the claim again in the channel params.ChannelID, with the metadata of the video and the same stream,
+// so that it's signed by the certificate the wallet holds. It's for the claims published while the channel name was
+// being taken over.
+func (v YoutubeVideo) Resign(daemon *jsonrpc.Client, params SyncParams, claim jsonrpc.Claim) (*SyncSummary, error) {
+	if params.ClaimAddress == "" {
+		params.ClaimAddress = claim.Address
+	}
+	summary, _, err := v.updateClaim(daemon, params, claim, v.Metadata(params), claim.Value.GetStream().GetMetadata().GetThumbnail())
+	return summary, err
+}
+
+// changedThumbnail hosts the thumbnail youtube has for the video if it differs from the published one, and returns its
+// URL. It returns an empty string if the thumbnail didn't change, or if the published one was generated from the video,
+// which can't be compared.
+func (v YoutubeVideo) changedThumbnail(params SyncParams, published string) (string, error) {
+	if v.thumbnailURL == "" || (params.GenerateThumbnails && v.thumbnailWidth < minThumbnailWidth) {
+		return "", nil
+	}
+	dir, err := ioutil.TempDir("", "thumbnail")
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer os.RemoveAll(dir)
+
+	current := filepath.Join(dir, v.id+".jpg")
+	err = downloadThumbnail(v.thumbnailURL, current)
+	if err != nil {
+		return "", errors.Prefix("could not download the youtube thumbnail", err)
+	}
+	hash, err := contentHash(current)
+	if err != nil {
+		return "", err
+	}
+	if published != "" {
+		// a published thumbnail that can't be downloaded anymore is replaced
+		previous := filepath.Join(dir, "published.jpg")
+		if downloadThumbnail(published, previous) == nil {
+			if previousHash, err := contentHash(previous); err == nil && previousHash == hash {
+				return "", nil
+			}
+		}
+	}
+	return host(current, thumbnailKey(v.id)+"-"+hash[:12], "image/jpeg", params)
+}
+
+// splitCaptions separates the list of captions at the end of a published description from the rest of it
+func splitCaptions(description string) (string, string) {
+	if i := strings.Index(description, captionsMarker); i >= 0 {
+		return description[:i], description[i:]
+	}
+	return description, ""
+}
+
+// existingClaim returns the claim of the channel holding the video, or nil if there is none. The names the video would
+// be published under are resolved in order, until one is free.
+func (v YoutubeVideo) existingClaim(daemon *jsonrpc.Client, params SyncParams) (*jsonrpc.Claim, error) {
+	for attempt := 1; ; attempt++ {
+		name := names.Claim(v.title, attempt)
+		response, err := daemon.Resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		item, ok := (*response)[name]
+		if !ok || item.Claim == nil {
+			return nil, nil
+		}
+		if SignedBy(*item.Claim, params.ChannelID) && v.heldBy(*item.Claim) {
+			return item.Claim, nil
+		}
+	}
+}
+
+// heldBy returns true if the claim is the one of the video: its description links to the video, or, if a metadata
+// transform removed the link, it has the title of the video
+func (v YoutubeVideo) heldBy(c jsonrpc.Claim) bool {
+	metadata := c.Value.GetStream().GetMetadata()
+	return strings.Contains(metadata.GetDescription(), "watch?v="+v.id) || metadata.GetTitle() == v.title
+}
+
+// SignedBy returns true if the claim was published in the channel with the given claim ID
+func SignedBy(c jsonrpc.Claim, channelID string) bool {
+	if c.SignatureIsValid != nil && !*c.SignatureIsValid {
+		return false
+	}
+	certificateID := c.Value.GetPublisherSignature().GetCertificateId()
+	return len(certificateID) > 0 && (string(certificateID) == channelID || hex.EncodeToString(certificateID) == channelID)
+}
@@ -0,0 +1,206 @@
+package sources
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// ThumbnailHost stores the thumbnails (and captions) of published videos and serves them publicly
+type ThumbnailHost interface {
+	// Upload stores the file under the given key and returns the URL it's served at
+	Upload(path, key, contentType string) (string, error)
+}
+
+// S3Host stores files in an S3 bucket, or in any storage with an S3 compatible API
+type S3Host struct {
+	Bucket   string
+	Region   string
+	Endpoint string // for S3 compatible storages, empty for AWS
+	ID       string
+	Secret   string
+	URL      string // where the bucket is served from. Keys are appended to it
+}
+
+// DefaultThumbnailHost returns the bucket thumbnails were always hosted in
+func DefaultThumbnailHost(id, secret string) *S3Host {
+	return &S3Host{
+		Bucket: thumbnailBucket,
+		Region: thumbnailRegion,
+		ID:     id,
+		Secret: secret,
+		URL:    "https://" + thumbnailBucket + "/",
+	}
+}
+
+// URLFor returns the URL a file uploaded under the given key is served at
+func (h *S3Host) URLFor(key string) string {
+	return strings.TrimSuffix(h.URL, "/") + "/" + key
+}
+
+func (h *S3Host) Upload(path, key, contentType string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer file.Close()
+
+	config := &aws.Config{
+		Region:      aws.String(h.Region),
+		Credentials: credentials.NewStaticCredentials(h.ID, h.Secret, ""),
+	}
+	if h.Endpoint != "" {
+		config.Endpoint = aws.String(h.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	s, err := session.NewSession(config)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+
+	_, err = s3manager.NewUploader(s).Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(h.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        file,
+	})
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	return h.URLFor(key), nil
+}
+
+var speechNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+// SpeechHost publishes files through a spee.ch instance
+type SpeechHost struct {
+	URL    string // e.g. https://spee.ch
+	Client *http.Client
+}
+
+func (h *SpeechHost) Upload(path, key, contentType string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	err = form.WriteField("name", speechNameRegexp.ReplaceAllString(key, "-"))
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	err = form.Close()
+	if err != nil {
+		return "", errors.Err(err)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	res, err := client.Post(strings.TrimSuffix(h.URL, "/")+"/api/claim/publish", form.FormDataContentType(), body)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer res.Body.Close()
+	contents, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    struct {
+			ServeURL string `json:"serveUrl"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(contents, &response)
+	if err != nil {
+		return "", errors.Err("unexpected spee.ch response (status %d): %s", res.StatusCode, string(contents))
+	}
+	if !response.Success || response.Data.ServeURL == "" {
+		return "", errors.Err("spee.ch publish failed: %s", response.Message)
+	}
+	return response.Data.ServeURL, nil
+}
+
+// saveHostedURL remembers where the file at path was uploaded to, so the URL is known when the video is published
+func saveHostedURL(path, hosted string) error {
+	return errors.Err(ioutil.WriteFile(path+".url", []byte(hosted), 0640))
+}
+
+// hostedURL returns where the file at path was uploaded to, or an empty string if it wasn't
+func hostedURL(path string) string {
+	hosted, err := ioutil.ReadFile(path + ".url")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(hosted))
+}
+
+// host uploads the file to the thumbnail host of the params and remembers its URL
+func host(path, key, contentType string, params SyncParams) (string, error) {
+	hosted, err := params.thumbnailHost().Upload(path, key, contentType)
+	if err != nil {
+		return "", err
+	}
+	return hosted, saveHostedURL(path, hosted)
+}
+
+// ParseThumbnailHost returns the host described by s, which is either the URL of a spee.ch instance or
+// s3://BUCKET?region=REGION&url=PUBLIC_URL[&endpoint=ENDPOINT] for an S3 bucket accessed with the given credentials.
+func ParseThumbnailHost(s, id, secret string) (ThumbnailHost, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &SpeechHost{URL: s}, nil
+	case "s3":
+		query := u.Query()
+		h := &S3Host{
+			Bucket:   u.Host,
+			Region:   query.Get("region"),
+			Endpoint: query.Get("endpoint"),
+			ID:       id,
+			Secret:   secret,
+			URL:      query.Get("url"),
+		}
+		if h.Bucket == "" || h.Region == "" {
+			return nil, errors.Err("an s3 thumbnail host needs a bucket and a region")
+		}
+		if h.URL == "" {
+			h.URL = "https://" + h.Bucket + ".s3." + h.Region + ".amazonaws.com/"
+		}
+		return h, nil
+	}
+	return nil, errors.Err("unsupported thumbnail host %q", s)
+}
@@ -0,0 +1,113 @@
+package sources
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// deadAirWindow is how much of the start of a livestream is searched for dead air
+	deadAirWindow = 15 * time.Minute
+	// minDeadAir is the shortest silence at the start of a livestream that is trimmed
+	minDeadAir = 10 * time.Second
+	// livestreamTimeoutFactor is how many times longer than other videos livestream recordings may take to download
+	livestreamTimeoutFactor = 6
+)
+
+// ErrDownloadTimeout is returned when a download takes longer than allowed
+var ErrDownloadTimeout = errors.Base("download timed out")
+
+var silenceRegexp = regexp.MustCompile(`silence_(start|end): (-?[0-9.]+)`)
+
+// AsLivestreamVOD returns a copy of the video that is handled as the recording of a finished livestream: it gets
+// more time to download and the dead air at its start is trimmed.
+func (v YoutubeVideo) AsLivestreamVOD() YoutubeVideo {
+	v.livestream = true
+	return v
+}
+
+// IsLivestreamVOD returns whether the video is the recording of a livestream
+func (v YoutubeVideo) IsLivestreamVOD() bool {
+	return v.livestream
+}
+
+// downloadTimeout returns how long the download of the video may take, 0 for no limit
+func (v YoutubeVideo) downloadTimeout(params SyncParams) time.Duration {
+	if v.livestream {
+		return params.DownloadTimeout * livestreamTimeoutFactor
+	}
+	return params.DownloadTimeout
+}
+
+// deadlineWriter fails writes to w once the deadline has passed. It's checked as data comes in, so a download that
+// stalls completely is only stopped when it gets data again.
+type deadlineWriter struct {
+	w        io.Writer
+	deadline time.Time
+}
+
+func (d deadlineWriter) Write(p []byte) (int, error) {
+	if time.Now().After(d.deadline) {
+		return 0, errors.Err(ErrDownloadTimeout)
+	}
+	return d.w.Write(p)
+}
+
+// leadingSilence returns how long the audio at the start of the file is silent. ffmpeg is needed for this, if it's not
+// installed no silence and no error are returned.
+func leadingSilence(path string) (time.Duration, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		log.Debugln("ffmpeg not found, not looking for dead air")
+		return 0, nil
+	}
+	window := strconv.Itoa(int(deadAirWindow.Seconds()))
+	out, err := exec.Command("ffmpeg", "-t", window, "-i", path, "-af", "silencedetect=noise=-50dB:d=2", "-f", "null", "-").CombinedOutput()
+	if err != nil {
+		return 0, errors.Err("ffmpeg failed: %s: %s", err.Error(), string(out))
+	}
+
+	matches := silenceRegexp.FindAllStringSubmatch(string(out), 2)
+	if len(matches) < 1 || matches[0][1] != "start" {
+		return 0, nil
+	}
+	start, err := strconv.ParseFloat(matches[0][2], 64)
+	if err != nil || start > 1 {
+		// the video doesn't start silent
+		return 0, nil
+	}
+	if len(matches) < 2 {
+		// silent until the end of the window, not worth guessing where it ends
+		return 0, nil
+	}
+	end, err := strconv.ParseFloat(matches[1][2], 64)
+	if err != nil {
+		return 0, nil
+	}
+	return time.Duration(end * float64(time.Second)), nil
+}
+
+// trimDeadAir removes the silent part at the start of a livestream recording, where the stream is waiting to begin
+func trimDeadAir(path string) error {
+	silence, err := leadingSilence(path)
+	if err != nil || silence < minDeadAir {
+		return err
+	}
+
+	log.Infof("trimming %s of dead air from the start of %s", silence.Round(time.Second), path)
+	trimmedPath := path + ".trimmed.mp4"
+	start := strconv.FormatFloat(silence.Seconds(), 'f', 3, 64)
+	out, err := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-ss", start, "-i", path, "-c", "copy", "-movflags", "+faststart", trimmedPath).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(trimmedPath)
+		return errors.Err("ffmpeg failed: %s: %s", err.Error(), string(out))
+	}
+	return errors.Err(os.Rename(trimmedPath, path))
+}
@@ -0,0 +1,375 @@
+package sources
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/util"
+)
+
+// localVideoExtensions are the extensions of the files LocalSource publishes
+var localVideoExtensions = []string{".mp4", ".m4v", ".mkv", ".webm", ".mov", ".avi"}
+
+// localImageExtensions are the extensions of the thumbnails a local video can have next to it, in order of preference
+var localImageExtensions = []string{".jpg", ".jpeg", ".png"}
+
+// LocalMetadata is what a sidecar JSON file can tell about a local video. Everything is optional.
+type LocalMetadata struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Author      string   `json:"author"`
+	Tags        []string `json:"tags"`
+	Language    string   `json:"language"`
+	License     string   `json:"license"`
+	LicenseURL  string   `json:"license_url"`
+	NSFW        bool     `json:"nsfw"`
+	// PublishedAt orders the videos, in RFC 3339 or as a date (2006-01-02). The time the file was last modified is
+	// used if it's not set.
+	PublishedAt string `json:"published_at"`
+	// Thumbnail is the URL of the thumbnail, or its path relative to the directory
+	Thumbnail string `json:"thumbnail"`
+}
+
+// nfo is the part of a Kodi .nfo file that makes a LocalMetadata. The root element can be movie, episodedetails,
+// musicvideo...
+type nfo struct {
+	Title     string   `xml:"title"`
+	Plot      string   `xml:"plot"`
+	Outline   string   `xml:"outline"`
+	Studio    string   `xml:"studio"`
+	Director  string   `xml:"director"`
+	Tags      []string `xml:"tag"`
+	Genres    []string `xml:"genre"`
+	Premiered string   `xml:"premiered"`
+	Aired     string   `xml:"aired"`
+	Thumb     string   `xml:"thumb"`
+}
+
+func (n nfo) metadata() LocalMetadata {
+	m := LocalMetadata{
+		Title:       n.Title,
+		Description: n.Plot,
+		Author:      n.Studio,
+		Tags:        append(n.Tags, n.Genres...),
+		PublishedAt: n.Premiered,
+		Thumbnail:   strings.TrimSpace(n.Thumb),
+	}
+	if m.Description == "" {
+		m.Description = n.Outline
+	}
+	if m.Author == "" {
+		m.Author = n.Director
+	}
+	if m.PublishedAt == "" {
+		m.PublishedAt = n.Aired
+	}
+	return m
+}
+
+// LocalSource publishes the video files of a directory, for videos that aren't on youtube. The metadata of a video is
+// read from a sidecar file named like it, VIDEO.json (see LocalMetadata) or VIDEO.nfo (the Kodi format). Its thumbnail
+// is the image named like it, VIDEO.jpg or VIDEO.png, if there is no thumbnail in its metadata, and a frame of the
+// video otherwise. The files in the directory are never changed: the videos aren't transcoded.
+type LocalSource struct {
+	Dir string
+	// WorkDir is where the thumbnails are prepared
+	WorkDir string
+	// Author is the author of the videos whose metadata doesn't name one
+	Author string
+}
+
+func (l LocalSource) Name() string {
+	return "directory " + l.Dir
+}
+
+// ListVideos returns the videos of the directory, oldest first. They aren't subject to the videos limit, which only
+// makes sense for youtube channels.
+func (l LocalSource) ListVideos() ([]Video, error) {
+	files, err := ioutil.ReadDir(l.Dir)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	var videos []localVideo
+	for _, f := range files {
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") || !util.InSliceFold(filepath.Ext(f.Name()), localVideoExtensions) {
+			continue
+		}
+		v, err := l.video(f)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, v)
+	}
+
+	sort.SliceStable(videos, func(i, j int) bool {
+		if !videos[i].publishedAt.Equal(videos[j].publishedAt) {
+			return videos[i].publishedAt.Before(videos[j].publishedAt)
+		}
+		return videos[i].id < videos[j].id
+	})
+	list := make([]Video, len(videos))
+	for i, v := range videos {
+		list[i] = v
+	}
+	return list, nil
+}
+
+// video reads the metadata of the video file f
+func (l LocalSource) video(f os.FileInfo) (localVideo, error) {
+	base := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+	v := localVideo{
+		id:      base,
+		path:    filepath.Join(l.Dir, f.Name()),
+		workDir: l.WorkDir,
+	}
+	var err error
+	v.metadata, err = readSidecar(filepath.Join(l.Dir, base))
+	if err != nil {
+		return v, err
+	}
+	if v.metadata.Title == "" {
+		v.metadata.Title = base
+	}
+	if v.metadata.Author == "" {
+		v.metadata.Author = l.Author
+	}
+
+	v.publishedAt = f.ModTime()
+	if v.metadata.PublishedAt != "" {
+		v.publishedAt, err = parseLocalTime(v.metadata.PublishedAt)
+		if err != nil {
+			return v, errors.Prefix("the metadata of "+f.Name()+" is invalid", err)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(v.metadata.Thumbnail, "http://"), strings.HasPrefix(v.metadata.Thumbnail, "https://"):
+		v.thumbnail = v.metadata.Thumbnail
+	case v.metadata.Thumbnail != "":
+		v.thumbnail = filepath.Join(l.Dir, v.metadata.Thumbnail)
+	default:
+		for _, ext := range localImageExtensions {
+			path := filepath.Join(l.Dir, base+ext)
+			if _, err := os.Stat(path); err == nil {
+				v.thumbnail = path
+				break
+			}
+		}
+	}
+	return v, nil
+}
+
+// readSidecar reads the metadata in base.json or base.nfo. The metadata is empty if there is neither.
+func readSidecar(base string) (LocalMetadata, error) {
+	var m LocalMetadata
+	data, err := ioutil.ReadFile(base + ".json")
+	if err == nil {
+		err = json.Unmarshal(data, &m)
+		if err != nil {
+			return m, errors.Prefix("could not parse "+base+".json", err)
+		}
+		return m, nil
+	} else if !os.IsNotExist(err) {
+		return m, errors.Err(err)
+	}
+
+	data, err = ioutil.ReadFile(base + ".nfo")
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return m, errors.Err(err)
+	}
+	var n nfo
+	err = xml.Unmarshal(data, &n)
+	if err != nil {
+		return m, errors.Prefix("could not parse "+base+".nfo", err)
+	}
+	return n.metadata(), nil
+}
+
+// parseLocalTime parses the publication time of a local video, in RFC 3339 or as a date
+func parseLocalTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", s)
+	}
+	if err != nil {
+		return t, errors.Err("%s is not a date (2006-01-02) or a time in RFC 3339", s)
+	}
+	return t, nil
+}
+
+// localVideo is a video file of a LocalSource
+type localVideo struct {
+	id          string
+	path        string
+	workDir     string
+	metadata    LocalMetadata
+	publishedAt time.Time
+	thumbnail   string // a URL or a path, empty if a frame of the video is used
+}
+
+func (v localVideo) ID() string {
+	return v.id
+}
+
+func (v localVideo) PlaylistPosition() int {
+	return 0
+}
+
+func (v localVideo) IDAndNum() string {
+	return v.id + " (" + v.path + ")"
+}
+
+func (v localVideo) PublishedAt() time.Time {
+	return v.publishedAt
+}
+
+func (v localVideo) Title() string {
+	return v.metadata.Title
+}
+
+// Metadata returns what is published along with the video
+func (v localVideo) Metadata(params SyncParams) Metadata {
+	details := VideoDetails{
+		ID:           v.id,
+		Title:        v.metadata.Title,
+		Description:  v.metadata.Description,
+		ChannelTitle: v.metadata.Author,
+		PublishedAt:  v.publishedAt,
+	}
+	m := Metadata{
+		Title:       v.metadata.Title,
+		Description: v.metadata.Description,
+		Author:      v.metadata.Author,
+		Tags:        v.metadata.Tags,
+		Language:    v.metadata.Language,
+		License:     v.metadata.License,
+		LicenseURL:  v.metadata.LicenseURL,
+		NSFW:        v.metadata.NSFW,
+	}
+	if m.Language == "" {
+		m.Language = "en"
+	}
+	if m.License == "" {
+		m.License = "Copyrighted (contact author)"
+	}
+	return params.applyTransform(details, m)
+}
+
+// thumbnailPath is where the thumbnail is prepared before it's hosted
+func (v localVideo) thumbnailPath() string {
+	if strings.ToLower(filepath.Ext(v.thumbnail)) == ".png" && !strings.HasPrefix(v.thumbnail, "http") {
+		return filepath.Join(v.workDir, v.id+".png")
+	}
+	return filepath.Join(v.workDir, v.id+".jpg")
+}
+
+// Download checks that the video can be published and hosts its thumbnail. The video itself is published from the
+// directory.
+func (v localVideo) Download(params SyncParams) error {
+	fi, err := os.Stat(v.path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if fi.Size() > int64(params.MaxVideoSize)*1024*1024 {
+		return errors.Err("the video is too big to sync, skipping for now")
+	}
+	err = validate(v.path, v.id, params)
+	if err != nil {
+		return err
+	}
+
+	thumbnailPath := v.thumbnailPath()
+	switch {
+	case v.thumbnail == "":
+		err = generateThumbnail(v.path, thumbnailPath, params.ThumbnailTimestamp)
+	case strings.HasPrefix(v.thumbnail, "http"):
+		err = downloadThumbnail(v.thumbnail, thumbnailPath)
+	default:
+		err = copyFile(v.thumbnail, thumbnailPath)
+	}
+	if err != nil {
+		v.Cleanup()
+		return errors.Prefix("thumbnail error", err)
+	}
+	// the IDs are only unique within the channel
+	key := thumbnailKey(params.ChannelID + "/" + v.id)
+	contentType := "image/jpeg"
+	if filepath.Ext(thumbnailPath) == ".png" {
+		contentType = "image/png"
+	}
+	_, err = host(thumbnailPath, key, contentType, params)
+	if err != nil {
+		v.Cleanup()
+		return errors.Prefix("thumbnail error", err)
+	}
+	return nil
+}
+
+// Publish publishes the video and removes its thumbnail from the work dir afterwards
+func (v localVideo) Publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	defer v.Cleanup()
+	if params.ChannelID == "" {
+		return nil, errors.Err("a claim_id for the channel wasn't provided")
+	}
+	thumbnail := hostedURL(v.thumbnailPath())
+	if thumbnail == "" {
+		return nil, errors.Err("the thumbnail of %s wasn't hosted", v.id)
+	}
+	options := v.Metadata(params).publishOptions(params, thumbnail)
+	summary, err := publishDeduplicated(daemon, v.metadata.Title, v.path, options, params)
+	if err != nil {
+		return nil, errors.Prefix("publish error", err)
+	}
+	summary.Duration, err = probeDuration(v.path)
+	if err != nil {
+		params.logger().Warnf("could not probe the duration of %s: %s", v.id, err.Error())
+	}
+	return summary, nil
+}
+
+// Cleanup removes the thumbnail from the work dir. The video is left alone.
+func (v localVideo) Cleanup() {
+	_ = os.Remove(v.thumbnailPath())
+	_ = os.Remove(v.thumbnailPath() + ".url")
+}
+
+func (v localVideo) Sync(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	err := v.Download(params)
+	if err != nil {
+		return nil, err
+	}
+	return v.Publish(daemon, params)
+}
+
+// copyFile copies the file at src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return errors.Err(err)
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		return errors.Err(err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package sources
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/jsonrpc"
+)
+
+// Metadata is what gets published along with a video
+type Metadata struct {
+	Title       string
+	Description string
+	Author      string
+	Tags        []string
+	Language    string
+	License     string
+	LicenseURL  string
+	NSFW        bool
+	Captions    []Caption // listed at the end of the description
+}
+
+// VideoDetails is what is known about a video at its source
+type VideoDetails struct {
+	ID           string
+	Title        string
+	Description  string // the full description, the published one is shortened
+	ChannelTitle string
+	PublishedAt  time.Time
+}
+
+// MetadataTransform changes the metadata of a video before it's published. m holds the default mapping of the
+// video details to LBRY metadata.
+type MetadataTransform func(details VideoDetails, m *Metadata)
+
+// applyTransform runs the transform of the params on the metadata, if there is one
+func (p SyncParams) applyTransform(details VideoDetails, m Metadata) Metadata {
+	if p.MetadataTransform != nil {
+		p.MetadataTransform(details, &m)
+	}
+	return m
+}
+
+// publishOptions turns the metadata into the options of a publish
+func (m Metadata) publishOptions(params SyncParams, thumbnail string) jsonrpc.PublishOptions {
+	options := jsonrpc.PublishOptions{
+		Title:         strPtr(m.Title),
+		Author:        strPtr(m.Author),
+		Description:   strPtr(m.Description + captionsSection(m.Captions)),
+		Language:      strPtr(m.Language),
+		ClaimAddress:  &params.ClaimAddress,
+		Thumbnail:     strPtr(thumbnail),
+		License:       strPtr(m.License),
+		ChangeAddress: &params.ClaimAddress,
+		ChannelID:     &params.ChannelID,
+		Tags:          m.Tags,
+		Fee:           params.Fee,
+	}
+	if m.LicenseURL != "" {
+		options.LicenseURL = strPtr(m.LicenseURL)
+	}
+	if m.NSFW {
+		nsfw := true
+		options.NSFW = &nsfw
+	}
+	return options
+}
@@ -0,0 +1,131 @@
+package sources
+
+import (
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+)
+
+// ErrNameTaken is returned when a claim name is held by someone else and the NameResolver said to skip it
+var ErrNameTaken = errors.Base("claim name is taken by someone else")
+
+// NameAction is what to do about a claim name someone else holds
+type NameAction int
+
+const (
+	NameNext NameAction = iota // try the next name: name-2, name-3...
+	NameBid                    // claim the name anyway, bidding NameResolution.Bid
+	NameSkip                   // don't claim anything
+)
+
+// NameConflict describes a claim name that is held by a claim this wallet doesn't own
+type NameConflict struct {
+	Name        string
+	Attempt     int     // 1 for the name made out of the title, 2 for name-2, and so on
+	Bid         float64 // what would be bid for a free name
+	ExistingBid float64 // effective amount of the claim that holds the name
+}
+
+// NameResolution is what a NameResolver decided to do about a conflict
+type NameResolution struct {
+	Action NameAction
+	Bid    float64 // for NameBid
+}
+
+// NameResolver decides what to do when a claim name is already held by someone else
+type NameResolver interface {
+	ResolveConflict(c NameConflict) NameResolution
+}
+
+// AppendSuffix moves on to the next name until one is free
+type AppendSuffix struct{}
+
+func (AppendSuffix) ResolveConflict(c NameConflict) NameResolution {
+	return NameResolution{Action: NameNext}
+}
+
+// BidHigher outbids the claim holding the name, as long as that costs at most MaxBid. Otherwise it moves on to the
+// next name.
+type BidHigher struct {
+	MaxBid float64
+}
+
+func (b BidHigher) ResolveConflict(c NameConflict) NameResolution {
+	bid := c.ExistingBid + c.Bid
+	if bid > b.MaxBid {
+		return NameResolution{Action: NameNext}
+	}
+	return NameResolution{Action: NameBid, Bid: bid}
+}
+
+// SkipTaken doesn't claim names someone else holds
+type SkipTaken struct{}
+
+func (SkipTaken) ResolveConflict(c NameConflict) NameResolution {
+	return NameResolution{Action: NameSkip}
+}
+
+// TakeOver outbids the claim holding the name, whatever it takes
+type TakeOver struct{}
+
+func (TakeOver) ResolveConflict(c NameConflict) NameResolution {
+	return NameResolution{Action: NameBid, Bid: c.ExistingBid + c.Bid}
+}
+
+// ParseNameResolver returns the strategy with the given name: append-suffix, bid-higher (up to maxBid), skip or
+// take-over
+func ParseNameResolver(strategy string, maxBid float64) (NameResolver, error) {
+	switch strategy {
+	case "append-suffix":
+		return AppendSuffix{}, nil
+	case "bid-higher":
+		return BidHigher{MaxBid: maxBid}, nil
+	case "skip":
+		return SkipTaken{}, nil
+	case "take-over":
+		return TakeOver{}, nil
+	}
+	return nil, errors.Err("unknown name conflict strategy %q, use append-suffix, bid-higher, skip or take-over", strategy)
+}
+
+// NameHolder returns the effective amount of the claim that holds name, and false if the name is free
+func NameHolder(daemon *jsonrpc.Client, name string) (float64, bool, error) {
+	response, err := daemon.Resolve(name)
+	if err != nil {
+		return 0, false, err
+	}
+	item, ok := (*response)[name]
+	if !ok || (item.Error != nil && strings.Contains(*item.Error, "cannot be resolved")) {
+		return 0, false, nil
+	}
+	claim := item.Claim
+	if claim == nil {
+		claim = item.Certificate
+	}
+	if claim == nil {
+		return 0, false, nil
+	}
+	amount, _ := claim.EffectiveAmount.Float64()
+	if amount == 0 {
+		amount, _ = claim.Amount.Float64()
+	}
+	return amount, true, nil
+}
+
+// resolveName asks the resolver what to do if name is held by someone else. It returns the bid to claim the name with,
+// or NameNext or NameSkip.
+func resolveName(daemon *jsonrpc.Client, resolver NameResolver, name string, attempt int, bid float64) (NameAction, float64, error) {
+	if resolver == nil {
+		return NameBid, bid, nil
+	}
+	existing, taken, err := NameHolder(daemon, name)
+	if err != nil || !taken {
+		return NameBid, bid, err
+	}
+	resolution := resolver.ResolveConflict(NameConflict{Name: name, Attempt: attempt, Bid: bid, ExistingBid: existing})
+	if resolution.Action == NameBid && resolution.Bid < bid {
+		resolution.Bid = bid
+	}
+	return resolution.Action, resolution.Bid, nil
+}
@@ -0,0 +1,32 @@
+package sources
+
+// VideoPlan describes what syncing a video would do, without doing any of it
+type VideoPlan struct {
+	VideoID           string
+	Title             string
+	PlaylistPosition  int
+	ClaimName         string
+	Thumbnail         string // empty if the URL is only known once the thumbnail is hosted
+	ThumbnailWidth    int64  // width of the best thumbnail youtube has
+	GenerateThumbnail bool   // youtube's thumbnail is too small, a frame of the video would be used instead
+	Amount            float64
+}
+
+// Plan returns what Sync would do with the video. taken holds the claim names already used by the channel, the name
+// picked for this video is added to it.
+func (v YoutubeVideo) Plan(params SyncParams, taken map[string]bool) VideoPlan {
+	var thumbnail string
+	if h, ok := params.thumbnailHost().(*S3Host); ok {
+		thumbnail = h.URLFor(thumbnailKey(v.id))
+	}
+	return VideoPlan{
+		VideoID:           v.id,
+		Title:             v.Metadata(params).Title,
+		PlaylistPosition:  v.PlaylistPosition(),
+		ClaimName:         planClaimName(v.title, taken),
+		Thumbnail:         thumbnail,
+		ThumbnailWidth:    v.thumbnailWidth,
+		GenerateThumbnail: params.GenerateThumbnails && v.thumbnailWidth < minThumbnailWidth,
+		Amount:            params.Amount,
+	}
+}
@@ -1,62 +1,140 @@
 package sources
 
 import (
-	"fmt"
-	"regexp"
-	"strconv"
+	"context"
 	"strings"
 	"sync"
+	"time"
 
-	"crypto/md5"
-	"encoding/hex"
-
+	"github.com/lbryio/lbry.go/errors"
 	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/metrics"
+	"github.com/lbryio/lbry.go/names"
+	"github.com/lbryio/lbry.go/util"
 	log "github.com/sirupsen/logrus"
 )
 
-var titleRegexp = regexp.MustCompile(`[^a-zA-Z0-9]+`)
-
 type SyncSummary struct {
 	ClaimID   string
 	ClaimName string
+	Txid      string  // of the publish transaction, empty if nothing was sent
+	Amount    float64 // the bid
+	Fee       float64
+	Duration  time.Duration // of the published file, 0 if unknown
+	// ContentHash is the SHA-256 of the published file, hex encoded. It's only computed when there is a Deduplicator.
+	ContentHash string
+	// RepostOf is the ID of the claim the published claim points to, if the file was published before
+	RepostOf string
 }
 
-func getClaimNameFromTitle(title string, attempt int) string {
-	suffix := ""
-	if attempt > 1 {
-		suffix = "-" + strconv.Itoa(attempt)
-	}
-	maxLen := 40 - len(suffix)
+// SyncParams holds the settings that control how a single video is synced
+type SyncParams struct {
+	ClaimAddress string
+	Amount       float64
+	ChannelID    string
+	MaxVideoSize int
+	// Fee, if set, is the price of the published videos
+	Fee *jsonrpc.Fee
+	// DownloadTimeout is how long a download may take, 0 for no limit. Livestream recordings get several times longer.
+	DownloadTimeout time.Duration
+	// VerifyDownloads enables checking downloaded videos against the size and duration reported by youtube
+	VerifyDownloads bool
+	// YtDlp, if set, downloads the youtube videos the built-in extractor fails on
+	YtDlp YtDlp
+
+	// GenerateThumbnails enables extracting a frame from the video when no usable thumbnail is available
+	GenerateThumbnails bool
+	// ThumbnailTimestamp is the position in the video of the frame used for generated thumbnails
+	ThumbnailTimestamp time.Duration
+	AwsS3ID            string
+	AwsS3Secret        string
+	// ThumbnailHost is where thumbnails and captions are uploaded to. The default bucket is used if it's not set.
+	ThumbnailHost ThumbnailHost
+	// SyncCaptions enables hosting the captions of the videos and linking them from the description
+	SyncCaptions bool
+	// Transcode, if set, is the profile downloaded videos are transcoded to before they are published
+	Transcode *TranscodeProfile
+	// ShortsTags are the tags the videos handled as shorts are published with, see YoutubeVideo.AsShort
+	ShortsTags []string
+	// Validation are the rules the videos are checked against before they are published. Flagged, if set, is called
+	// with the rules that flag a video.
+	Validation ValidationRules
+	Flagged    func(videoID, rule, problem string)
+
+	// DownloadLimiter, if set, caps the download speed (one token per byte). It's shared by all workers.
+	DownloadLimiter *util.TokenBucket
+	// VideoLimiter, if set, caps how often videos are downloaded (one token per video). It's shared by all workers.
+	VideoLimiter *util.TokenBucket
+	// DownloadCounter, if set, counts the bytes downloaded
+	DownloadCounter *metrics.Counter
+	// DownloadProgress, if set, is called as the video downloads with the bytes downloaded so far and the size of the
+	// video, -1 if it's not known
+	DownloadProgress func(downloaded, size int64)
 
-	chunks := strings.Split(strings.ToLower(strings.Trim(titleRegexp.ReplaceAllString(title, "-"), "-")), "-")
+	// NameResolver, if set, decides what happens when the claim name of a video is held by someone else. If it's not
+	// set, names held by someone else are claimed too.
+	NameResolver NameResolver
 
-	name := chunks[0]
-	if len(name) > maxLen {
-		return name[:maxLen]
+	// Dedup, if set, looks up whether the file of a video was published before. Duplicates decides what happens then.
+	Dedup      Deduplicator
+	Duplicates DuplicateAction
+
+	// MetadataTransform, if set, changes the metadata of the videos before they are published
+	MetadataTransform MetadataTransform
+	// Stop is closed when the sync is stopping, so waits on the limiters can be cut short
+	Stop <-chan struct{}
+	// Log, if set, is where messages about the video go. It tags them with the channel and the video.
+	Log *log.Entry
+}
+
+// logger returns the log of the video
+func (p SyncParams) logger() *log.Entry {
+	if p.Log != nil {
+		return p.Log
 	}
+	return log.NewEntry(log.StandardLogger())
+}
 
-	for _, chunk := range chunks[1:] {
-		tmpName := name + "-" + chunk
-		if len(tmpName) > maxLen {
-			if len(name) < 20 {
-				name = tmpName[:maxLen]
+// stopped returns util.ErrWaitCancelled once the sync is stopping. It's checked between the steps of a download, so a
+// video interrupted halfway is left for the next run instead of failing.
+func (p SyncParams) stopped() error {
+	select {
+	case <-p.Stop:
+		return errors.Err(util.ErrWaitCancelled)
+	default:
+		return nil
+	}
+}
+
+// stopContext returns a context that is cancelled when the sync stops, to kill the tools run on a video
+func (p SyncParams) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if p.Stop != nil {
+		go func() {
+			select {
+			case <-p.Stop:
+				cancel()
+			case <-ctx.Done():
 			}
-			break
-		}
-		name = tmpName
+		}()
 	}
+	return ctx, cancel
+}
 
-	return name + suffix
+// planClaimName returns the name a video with the given title would most likely be published under, given the names
+// that are already taken. The chosen name is added to taken.
+func planClaimName(title string, taken map[string]bool) string {
+	return names.Unique(title, taken)
 }
 
 var publishedNamesMutex sync.RWMutex
 var publishedNames = map[string]bool{}
 
-func publishAndRetryExistingNames(daemon *jsonrpc.Client, title, filename string, amount float64, options jsonrpc.PublishOptions) (*SyncSummary, error) {
+func publishAndRetryExistingNames(daemon *jsonrpc.Client, title, filename string, amount float64, options jsonrpc.PublishOptions, resolver NameResolver) (*SyncSummary, error) {
 	attempt := 0
 	for {
 		attempt++
-		name := getClaimNameFromTitle(title, attempt)
+		name := names.Claim(title, attempt)
 
 		publishedNamesMutex.RLock()
 		_, exists := publishedNames[name]
@@ -65,20 +143,27 @@ func publishAndRetryExistingNames(daemon *jsonrpc.Client, title, filename string
 			log.Printf("name exists, retrying (%d attempts so far)\n", attempt)
 			continue
 		}
-		//if for some reasons the title can't be converted in a valid claim name (too short or not latin) then we use a hash
-		if len(name) < 2 {
-			hasher := md5.New()
-			hasher.Write([]byte(title))
-			name = fmt.Sprintf("%s-%d", hex.EncodeToString(hasher.Sum(nil))[:15], attempt)
+
+		action, bid, err := resolveName(daemon, resolver, name, attempt, amount)
+		if err != nil {
+			return nil, err
+		}
+		if action == NameSkip {
+			return nil, errors.Prefix(name, ErrNameTaken)
+		}
+		if action == NameNext {
+			log.Printf("name %s is taken, retrying (%d attempts so far)\n", name, attempt)
+			continue
 		}
 
-		response, err := daemon.Publish(name, filename, amount, options)
+		response, err := daemon.Publish(name, filename, bid, options)
 		if err == nil || strings.Contains(err.Error(), "failed: Multiple claims (") {
 			publishedNamesMutex.Lock()
 			publishedNames[name] = true
 			publishedNamesMutex.Unlock()
 			if err == nil {
-				return &SyncSummary{ClaimID: response.ClaimID, ClaimName: name}, nil
+				fee, _ := response.Fee.Float64()
+				return &SyncSummary{ClaimID: response.ClaimID, ClaimName: name, Txid: response.Txid, Amount: bid, Fee: fee}, nil
 			} else {
 				log.Printf("name exists, retrying (%d attempts so far)\n", attempt)
 				continue
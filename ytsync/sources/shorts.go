@@ -0,0 +1,12 @@
+package sources
+
+// AsShort returns a copy of the video that is handled as a short: it's published with the ShortsTags of the params
+func (v YoutubeVideo) AsShort() YoutubeVideo {
+	v.short = true
+	return v
+}
+
+// IsShort returns whether the video is handled as a short
+func (v YoutubeVideo) IsShort() bool {
+	return v.short
+}
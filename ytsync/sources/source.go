@@ -0,0 +1,35 @@
+package sources
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/jsonrpc"
+)
+
+// Video is a video to sync, whatever it comes from. It downloads and publishes itself, so the publish pipeline works
+// the same for every source.
+type Video interface {
+	ID() string
+	IDAndNum() string
+	PlaylistPosition() int
+	PublishedAt() time.Time
+	Title() string
+	// Metadata returns what is published along with the video
+	Metadata(params SyncParams) Metadata
+	// Download fetches the video and what's published with it, so that it's ready to be published
+	Download(params SyncParams) error
+	// Publish publishes a downloaded video and removes it from disk afterwards
+	Publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error)
+	// Cleanup removes whatever Download left on disk, ignoring errors
+	Cleanup()
+	// Sync downloads and publishes the video
+	Sync(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error)
+}
+
+// VideoSource is where the videos of a channel come from: a youtube channel or playlist, a CSV of videos on S3...
+type VideoSource interface {
+	// Name identifies the source in the logs
+	Name() string
+	// ListVideos returns the videos to sync, in the order they should be published in
+	ListVideos() ([]Video, error)
+}
@@ -0,0 +1,68 @@
+package sources
+
+import (
+	"io"
+
+	"github.com/lbryio/lbry.go/metrics"
+	"github.com/lbryio/lbry.go/util"
+)
+
+// throttledWriter limits how fast data can be written to w, taking one token from the bucket per byte
+type throttledWriter struct {
+	w      io.Writer
+	bucket *util.TokenBucket
+	stop   <-chan struct{}
+}
+
+func (t throttledWriter) Write(p []byte) (int, error) {
+	maxChunk := int(t.bucket.Capacity())
+	if maxChunk < 1 {
+		maxChunk = 1
+	}
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxChunk {
+			chunk = chunk[:maxChunk]
+		}
+		err := t.bucket.Wait(float64(len(chunk)), t.stop)
+		if err != nil {
+			return written, err
+		}
+		n, err := t.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[len(chunk):]
+	}
+	return written, nil
+}
+
+// countingWriter adds the number of bytes written to w to a counter
+type countingWriter struct {
+	w       io.Writer
+	counter *metrics.Counter
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.counter.Add(float64(n))
+	return n, err
+}
+
+// progressWriter reports how much of a download of size bytes is written to w, counting the written bytes that were
+// already there
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	size    int64
+	report  func(downloaded, size int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.report(p.written, p.size)
+	return n, err
+}
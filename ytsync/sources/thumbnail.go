@@ -0,0 +1,108 @@
+package sources
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+const (
+	thumbnailBucket   = "berk.ninja"
+	thumbnailRegion   = "us-east-2"
+	thumbnailHost     = "https://berk.ninja/thumbnails/"
+	minThumbnailWidth = 480
+)
+
+// bestThumbnail returns the largest thumbnail youtube has for a video, or nil if there are none
+func bestThumbnail(thumbnails *youtube.ThumbnailDetails) *youtube.Thumbnail {
+	if thumbnails == nil {
+		return nil
+	}
+	var best *youtube.Thumbnail
+	for _, t := range []*youtube.Thumbnail{thumbnails.Default, thumbnails.Medium, thumbnails.High, thumbnails.Standard, thumbnails.Maxres} {
+		if t != nil && t.Url != "" && (best == nil || t.Width > best.Width) {
+			best = t
+		}
+	}
+	return best
+}
+
+func thumbnailKey(videoID string) string {
+	return "thumbnails/" + videoID
+}
+
+// thumbnailHost returns where thumbnails are hosted, the default bucket if none was set
+func (p SyncParams) thumbnailHost() ThumbnailHost {
+	if p.ThumbnailHost != nil {
+		return p.ThumbnailHost
+	}
+	return DefaultThumbnailHost(p.AwsS3ID, p.AwsS3Secret)
+}
+
+// downloadThumbnail saves the image at url to path
+func downloadThumbnail(url, path string) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Err("thumbnail request returned status code %d", res.StatusCode)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	_, err = io.Copy(file, res.Body)
+	file.Close()
+	if err != nil {
+		_ = os.Remove(path)
+		return errors.Err(err)
+	}
+	return nil
+}
+
+// HostImage copies the image at imageURL to the thumbnail host of the params, under key, and returns its hosted URL.
+// It's used for the images that aren't thumbnails of videos, like the avatars and banners of the channels.
+func HostImage(imageURL, key string, params SyncParams) (string, error) {
+	file, err := ioutil.TempFile("", "ytsync-image")
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	err = downloadThumbnail(imageURL, file.Name())
+	if err != nil {
+		return "", err
+	}
+	return params.thumbnailHost().Upload(file.Name(), key, "image/jpeg")
+}
+
+// generateThumbnail extracts the frame at the given position of the video into a jpeg
+func generateThumbnail(videoPath, thumbnailPath string, at time.Duration) error {
+	timestamp := fmt.Sprintf("%.3f", at.Seconds())
+	out, err := exec.Command("ffmpeg", "-y", "-loglevel", "error", "-ss", timestamp, "-i", videoPath, "-vframes", "1", "-q:v", "2", thumbnailPath).CombinedOutput()
+	if err != nil {
+		return errors.Err("ffmpeg failed: %s: %s", err.Error(), string(out))
+	}
+	fi, err := os.Stat(thumbnailPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if fi.Size() == 0 {
+		_ = os.Remove(thumbnailPath)
+		return errors.Err("ffmpeg produced an empty thumbnail. is the video shorter than %s?", at.String())
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// TranscodeProfile controls how downloaded videos are transcoded before they are published. Videos that already fit
+// the profile are left untouched, unless their metadata has to be stripped, which doesn't need re-encoding.
+type TranscodeProfile struct {
+	Name            string `json:"name"`
+	MaxHeight       int    `json:"max_height"`        // taller videos are scaled down, 0 for no limit
+	MaxVideoBitrate int    `json:"max_video_bitrate"` // in kbit/s, 0 for no limit
+	MaxAudioBitrate int    `json:"max_audio_bitrate"` // in kbit/s, 0 for no limit
+	H264            bool   `json:"h264"`              // convert to h264 video and aac audio, in mp4
+	StripMetadata   bool   `json:"strip_metadata"`    // drop the metadata of the container and the streams
+}
+
+// TranscodeProfiles are the profiles that can be picked by name
+var TranscodeProfiles = map[string]TranscodeProfile{
+	"compat": {Name: "compat", H264: true, StripMetadata: true},
+	"720p":   {Name: "720p", MaxHeight: 720, MaxVideoBitrate: 2500, MaxAudioBitrate: 128, H264: true, StripMetadata: true},
+	"1080p":  {Name: "1080p", MaxHeight: 1080, MaxVideoBitrate: 5000, MaxAudioBitrate: 192, H264: true, StripMetadata: true},
+}
+
+// LoadTranscodeProfile returns the profile with the given name, or reads it from a JSON file if there's no such profile
+func LoadTranscodeProfile(nameOrPath string) (*TranscodeProfile, error) {
+	if profile, ok := TranscodeProfiles[nameOrPath]; ok {
+		return &profile, nil
+	}
+	data, err := ioutil.ReadFile(nameOrPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Err("%s is neither a transcode profile nor a file. Profiles: compat, 720p, 1080p", nameOrPath)
+		}
+		return nil, errors.Err(err)
+	}
+	profile := &TranscodeProfile{}
+	err = json.Unmarshal(data, profile)
+	if err != nil {
+		return nil, errors.Prefix("could not parse "+nameOrPath, err)
+	}
+	if profile.MaxHeight < 0 || profile.MaxVideoBitrate < 0 || profile.MaxAudioBitrate < 0 {
+		return nil, errors.Err("the limits of transcode profile %s can't be negative", nameOrPath)
+	}
+	if profile.Name == "" {
+		profile.Name = nameOrPath
+	}
+	return profile, nil
+}
+
+// mediaInfo is what transcoding needs to know about a video
+type mediaInfo struct {
+	VideoCodec   string
+	AudioCodec   string
+	Height       int
+	VideoBitrate int // in kbit/s, 0 if unknown
+	AudioBitrate int // in kbit/s, 0 if unknown
+}
+
+// probeMedia returns the codecs, height and bitrates of the first video and audio streams of a media file
+func probeMedia(path string) (mediaInfo, error) {
+	var info mediaInfo
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=codec_type,codec_name,height,bit_rate", "-of", "json", path).Output()
+	if err != nil {
+		return info, errors.Err("ffprobe failed: %s", err.Error())
+	}
+	var probed struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Height    int    `json:"height"`
+			BitRate   string `json:"bit_rate"`
+		} `json:"streams"`
+	}
+	err = json.Unmarshal(out, &probed)
+	if err != nil {
+		return info, errors.Err(err)
+	}
+	for _, stream := range probed.Streams {
+		bitrate, _ := strconv.Atoi(stream.BitRate)
+		switch {
+		case stream.CodecType == "video" && info.VideoCodec == "":
+			info.VideoCodec, info.Height, info.VideoBitrate = stream.CodecName, stream.Height, bitrate/1000
+		case stream.CodecType == "audio" && info.AudioCodec == "":
+			info.AudioCodec, info.AudioBitrate = stream.CodecName, bitrate/1000
+		}
+	}
+	if info.VideoCodec == "" {
+		return info, errors.Err("no video stream found in %s", path)
+	}
+	return info, nil
+}
+
+// needsVideoEncoding returns true if the video stream doesn't fit the profile
+func (p TranscodeProfile) needsVideoEncoding(info mediaInfo) bool {
+	return (p.H264 && info.VideoCodec != "h264") ||
+		(p.MaxHeight > 0 && info.Height > p.MaxHeight) ||
+		(p.MaxVideoBitrate > 0 && info.VideoBitrate > p.MaxVideoBitrate)
+}
+
+// needsAudioEncoding returns true if the audio stream doesn't fit the profile
+func (p TranscodeProfile) needsAudioEncoding(info mediaInfo) bool {
+	if info.AudioCodec == "" {
+		return false
+	}
+	return (p.H264 && info.AudioCodec != "aac") || (p.MaxAudioBitrate > 0 && info.AudioBitrate > p.MaxAudioBitrate)
+}
+
+// ffmpegArgs returns the arguments for ffmpeg to transcode the file at in to out, or nil if it fits the profile already
+func (p TranscodeProfile) ffmpegArgs(in, out string, info mediaInfo) []string {
+	encodeVideo, encodeAudio := p.needsVideoEncoding(info), p.needsAudioEncoding(info)
+	if !encodeVideo && !encodeAudio && !p.StripMetadata {
+		return nil
+	}
+
+	args := []string{"-y", "-loglevel", "error", "-i", in}
+	if p.StripMetadata {
+		args = append(args, "-map_metadata", "-1", "-map_chapters", "-1")
+	}
+	if encodeVideo {
+		args = append(args, "-c:v", "libx264", "-preset", "medium", "-crf", "23", "-pix_fmt", "yuv420p")
+		if p.MaxHeight > 0 && info.Height > p.MaxHeight {
+			args = append(args, "-vf", "scale=-2:"+strconv.Itoa(p.MaxHeight))
+		}
+		if p.MaxVideoBitrate > 0 {
+			rate := strconv.Itoa(p.MaxVideoBitrate)
+			args = append(args, "-maxrate", rate+"k", "-bufsize", strconv.Itoa(2*p.MaxVideoBitrate)+"k")
+		}
+	} else {
+		args = append(args, "-c:v", "copy")
+	}
+	if encodeAudio {
+		args = append(args, "-c:a", "aac")
+		if p.MaxAudioBitrate > 0 {
+			args = append(args, "-b:a", strconv.Itoa(p.MaxAudioBitrate)+"k")
+		}
+	} else {
+		args = append(args, "-c:a", "copy")
+	}
+	return append(args, "-movflags", "+faststart", "-f", "mp4", out)
+}
+
+// transcode rewrites the video at path to fit the profile. It returns false if the video already fit it. ffmpeg and
+// ffprobe are needed for this. ffmpeg is killed if ctx is cancelled.
+func transcode(ctx context.Context, path string, profile TranscodeProfile) (bool, error) {
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return false, errors.Err("%s not found, it's needed for transcoding", tool)
+		}
+	}
+	info, err := probeMedia(path)
+	if err != nil {
+		return false, err
+	}
+	transcodedPath := path + ".transcoded.mp4"
+	args := profile.ffmpegArgs(path, transcodedPath, info)
+	if args == nil {
+		return false, nil
+	}
+
+	out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(transcodedPath)
+		return false, errors.Err("ffmpeg failed: %s: %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return true, errors.Err(os.Rename(transcodedPath, path))
+}
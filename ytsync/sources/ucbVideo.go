@@ -52,6 +52,10 @@ func (v ucbVideo) IDAndNum() string {
 	return v.ID() + " (?)"
 }
 
+func (v ucbVideo) Title() string {
+	return v.title
+}
+
 func (v ucbVideo) PublishedAt() time.Time {
 	return v.publishedAt
 	//r := regexp.MustCompile(`(\d\d\d\d)-(\d\d)-(\d\d)`)
@@ -69,6 +73,11 @@ func (v ucbVideo) getFilename() string {
 	return v.dir + "/" + v.id + ".mp4"
 }
 
+// DownloadedFile returns where Download puts the video
+func (v ucbVideo) DownloadedFile() string {
+	return v.getFilename()
+}
+
 func (v ucbVideo) getClaimName(attempt int) string {
 	reg := regexp.MustCompile(`[^a-zA-Z0-9]+`)
 	suffix := ""
@@ -170,27 +179,36 @@ func (v ucbVideo) saveThumbnail() error {
 	return err
 }
 
-func (v ucbVideo) publish(daemon *jsonrpc.Client, claimAddress string, amount float64, channelID string) (*SyncSummary, error) {
-	options := jsonrpc.PublishOptions{
-		Title:         &v.title,
-		Author:        strPtr("UC Berkeley"),
-		Description:   strPtr(v.getAbbrevDescription()),
-		Language:      strPtr("en"),
-		ClaimAddress:  &claimAddress,
-		Thumbnail:     strPtr("https://berk.ninja/thumbnails/" + v.id),
-		License:       strPtr("see description"),
-		ChannelID:     &channelID,
-		ChangeAddress: &claimAddress,
+// Metadata returns what is published along with the video
+func (v ucbVideo) Metadata(params SyncParams) Metadata {
+	details := VideoDetails{
+		ID:           v.id,
+		Title:        v.title,
+		Description:  v.description,
+		ChannelTitle: v.channel,
+		PublishedAt:  v.publishedAt,
 	}
+	return params.applyTransform(details, Metadata{
+		Title:       v.title,
+		Description: v.getAbbrevDescription(),
+		Author:      "UC Berkeley",
+		Language:    "en",
+		License:     "see description",
+	})
+}
 
-	return publishAndRetryExistingNames(daemon, v.title, v.getFilename(), amount, options)
+func (v ucbVideo) publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	options := v.Metadata(params).publishOptions(params, thumbnailHost+v.id)
+
+	return publishDeduplicated(daemon, v.title, v.getFilename(), options, params)
 }
 
-func (v ucbVideo) Sync(daemon *jsonrpc.Client, claimAddress string, amount float64, channelID string, maxVideoSize int) (*SyncSummary, error) {
+// Download fetches the video from S3. The thumbnails are already hosted.
+func (v ucbVideo) Download(params SyncParams) error {
 	//download and thumbnail can be done in parallel
 	err := v.download()
 	if err != nil {
-		return nil, errors.Prefix("download error", err)
+		return errors.Prefix("download error", err)
 	}
 	log.Debugln("Downloaded " + v.id)
 
@@ -199,11 +217,29 @@ func (v ucbVideo) Sync(daemon *jsonrpc.Client, claimAddress string, amount float
 	//	return errors.WrapPrefix(err, "thumbnail error", 0)
 	//}
 	//log.Debugln("Created thumbnail for " + v.id)
+	return nil
+}
 
-	summary, err := v.publish(daemon, claimAddress, amount, channelID)
+// Publish publishes a downloaded video and removes it from disk afterwards
+func (v ucbVideo) Publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	summary, err := v.publish(daemon, params)
+	v.Cleanup()
 	if err != nil {
 		return nil, errors.Prefix("publish error", err)
 	}
 
 	return summary, nil
 }
+
+// Cleanup removes the downloaded video, ignoring errors
+func (v ucbVideo) Cleanup() {
+	_ = os.Remove(v.getFilename())
+}
+
+func (v ucbVideo) Sync(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	err := v.Download(params)
+	if err != nil {
+		return nil, err
+	}
+	return v.Publish(daemon, params)
+}
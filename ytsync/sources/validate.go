@@ -0,0 +1,202 @@
+package sources
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrInvalidVideo is returned when a video breaks a validation rule that rejects it
+var ErrInvalidVideo = errors.Base("the video failed validation")
+
+// The rules downloaded videos are validated against before they are published
+const (
+	RuleZeroDuration     = "zero_duration"     // the video is shorter than a second, or has no duration at all
+	RuleBrokenAudio      = "broken_audio"      // the audio track has no codec, channels or sample rate, or can't be decoded
+	RuleUnsupportedCodec = "unsupported_codec" // the video or audio codec can't be played by the lbry apps
+)
+
+// ValidationAction is what happens to a video that breaks a rule
+type ValidationAction string
+
+const (
+	ValidationOff    ValidationAction = "off"    // the rule isn't checked
+	ValidationFlag   ValidationAction = "flag"   // the video is published, and reported
+	ValidationReject ValidationAction = "reject" // the video isn't published, it fails with ErrInvalidVideo
+)
+
+// DefaultValidationRules reject the videos that can't be watched, and flag the ones the apps may not play
+var DefaultValidationRules = []string{RuleZeroDuration + "=reject", RuleBrokenAudio + "=reject", RuleUnsupportedCodec + "=flag"}
+
+var validationRules = []string{RuleZeroDuration, RuleBrokenAudio, RuleUnsupportedCodec}
+
+var (
+	supportedVideoCodecs = util.NewStringSet("h264", "hevc", "vp8", "vp9", "av1")
+	supportedAudioCodecs = util.NewStringSet("aac", "mp3", "opus", "vorbis")
+)
+
+// audioDecodeCheck is how much of the audio track is decoded to make sure it isn't broken
+const audioDecodeCheck = time.Minute
+
+// ValidationRules maps the rules to what happens to the videos that break them. Rules not in it are off.
+type ValidationRules map[string]ValidationAction
+
+// ParseValidationRules parses RULE=ACTION pairs, e.g. "broken_audio=flag"
+func ParseValidationRules(specs []string) (ValidationRules, error) {
+	rules := make(ValidationRules)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Err("%q is not RULE=ACTION", spec)
+		}
+		rule, action := strings.TrimSpace(parts[0]), ValidationAction(strings.TrimSpace(parts[1]))
+		if !util.InSlice(rule, validationRules) {
+			return nil, errors.Err("unknown validation rule %q, the rules are %s", rule, strings.Join(validationRules, ", "))
+		}
+		switch action {
+		case ValidationOff, ValidationFlag, ValidationReject:
+		default:
+			return nil, errors.Err("unknown action %q for validation rule %s, use off, flag or reject", action, rule)
+		}
+		rules[rule] = action
+	}
+	return rules, nil
+}
+
+func (r ValidationRules) enabled() bool {
+	for _, action := range r {
+		if action != ValidationOff {
+			return true
+		}
+	}
+	return false
+}
+
+// probedStreams is what validation needs to know about a video
+type probedStreams struct {
+	Streams []struct {
+		CodecType  string `json:"codec_type"`
+		CodecName  string `json:"codec_name"`
+		Channels   int    `json:"channels"`
+		SampleRate string `json:"sample_rate"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func probeStreams(path string) (probedStreams, error) {
+	var probed probedStreams
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=codec_type,codec_name,channels,sample_rate:format=duration", "-of", "json", path).Output()
+	if err != nil {
+		return probed, errors.Err("ffprobe failed: %s", err.Error())
+	}
+	err = json.Unmarshal(out, &probed)
+	return probed, errors.Err(err)
+}
+
+// violations returns the problems of the video, by the rule they break. The audio track is only decoded with
+// decodeAudioTrack, it takes a while.
+func (p probedStreams) violations(path string, decodeAudioTrack bool) map[string]string {
+	problems := make(map[string]string)
+	seconds, _ := strconv.ParseFloat(p.Format.Duration, 64)
+	if seconds < 1 {
+		problems[RuleZeroDuration] = "the video is " + strconv.FormatFloat(seconds, 'f', -1, 64) + " seconds long"
+	}
+
+	var codecs []string
+	hasAudio := false
+	for _, stream := range p.Streams {
+		switch stream.CodecType {
+		case "video":
+			if !supportedVideoCodecs.Has(stream.CodecName) {
+				codecs = append(codecs, "video codec "+stream.CodecName)
+			}
+		case "audio":
+			hasAudio = true
+			sampleRate, _ := strconv.Atoi(stream.SampleRate)
+			if stream.CodecName == "" || stream.Channels <= 0 || sampleRate <= 0 {
+				problems[RuleBrokenAudio] = "the audio track has no codec, channels or sample rate"
+			} else if !supportedAudioCodecs.Has(stream.CodecName) {
+				codecs = append(codecs, "audio codec "+stream.CodecName)
+			}
+		}
+	}
+	if len(codecs) > 0 {
+		problems[RuleUnsupportedCodec] = "unsupported " + strings.Join(codecs, ", ")
+	}
+	if decodeAudioTrack && hasAudio && problems[RuleBrokenAudio] == "" {
+		if problem := decodeAudio(path); problem != "" {
+			problems[RuleBrokenAudio] = problem
+		}
+	}
+	return problems
+}
+
+// decodeAudio decodes the beginning of the audio track and returns what ffmpeg complained about, if anything. Without
+// ffmpeg, nothing is checked.
+func decodeAudio(path string) string {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return ""
+	}
+	cmd := exec.Command("ffmpeg", "-v", "error", "-t", strconv.Itoa(int(audioDecodeCheck.Seconds())), "-i", path, "-map", "0:a:0", "-f", "null", "-")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	problems := strings.TrimSpace(stderr.String())
+	if err == nil && problems == "" {
+		return ""
+	}
+	if problems == "" {
+		problems = err.Error()
+	}
+	return "the audio track can't be decoded: " + strings.SplitN(problems, "\n", 2)[0]
+}
+
+// validate checks the video at path against the rules of params. It fails with ErrInvalidVideo if the video breaks a
+// rule that rejects it, and reports the rules that flag it to params.Flagged. ffprobe is needed for this, if it's not
+// installed nothing is checked.
+func validate(path, videoID string, params SyncParams) error {
+	if !params.Validation.enabled() {
+		return nil
+	}
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		log.Debugln("ffprobe not found, skipping validation")
+		return nil
+	}
+	probed, err := probeStreams(path)
+	if err != nil {
+		return err
+	}
+	checkAudio := params.Validation[RuleBrokenAudio] == ValidationFlag || params.Validation[RuleBrokenAudio] == ValidationReject
+	problems := probed.violations(path, checkAudio)
+
+	var rejected []string
+	for _, rule := range validationRules {
+		problem, broken := problems[rule]
+		if !broken {
+			continue
+		}
+		switch params.Validation[rule] {
+		case ValidationReject:
+			rejected = append(rejected, rule+": "+problem)
+		case ValidationFlag:
+			params.logger().Warnf("%s breaks validation rule %s: %s", videoID, rule, problem)
+			if params.Flagged != nil {
+				params.Flagged(videoID, rule, problem)
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return errors.Prefix(strings.Join(rejected, "; "), ErrInvalidVideo)
+	}
+	return nil
+}
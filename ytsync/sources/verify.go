@@ -0,0 +1,132 @@
+package sources
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// downloadSizeTolerance is how far off (as a fraction of the expected size) a download can be before it's considered broken
+	downloadSizeTolerance = 0.01
+	// durationTolerance is how far off the probed duration of a download can be from the one youtube reports
+	durationTolerance = 5 * time.Second
+)
+
+// remoteContentLength returns the size of the file at the given url, as reported by the server
+func remoteContentLength(url string) (int64, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Head(url)
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return 0, errors.Err("HEAD request returned status code %d", res.StatusCode)
+	}
+	if res.ContentLength < 0 {
+		return 0, errors.Err("no content length reported")
+	}
+	return res.ContentLength, nil
+}
+
+// verifySize makes sure the file at path is within tolerance of the expected size
+func verifySize(path string, expected int64) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	diff := math.Abs(float64(fi.Size() - expected))
+	if diff > float64(expected)*downloadSizeTolerance {
+		return errors.Err("download verification failed: got %d bytes, youtube reported %d", fi.Size(), expected)
+	}
+	return nil
+}
+
+// probeDuration returns the duration of a media file. ffprobe is needed for this, if it's not installed a zero duration
+// and no error are returned.
+func probeDuration(path string) (time.Duration, error) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		log.Debugln("ffprobe not found, skipping duration check")
+		return 0, nil
+	}
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", path).Output()
+	if err != nil {
+		return 0, errors.Err("ffprobe failed: %s", err.Error())
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, errors.Err("could not parse ffprobe duration %q", strings.TrimSpace(string(out)))
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// verifyDuration makes sure the media file at path is about as long as expected
+func verifyDuration(path string, expected time.Duration) error {
+	if expected <= 0 {
+		return nil
+	}
+	duration, err := probeDuration(path)
+	if err != nil || duration == 0 {
+		return err
+	}
+	diff := duration - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > durationTolerance {
+		return errors.Err("download verification failed: video is %s long, youtube reported %s", duration.String(), expected.String())
+	}
+	return nil
+}
+
+// verifyDownloadedFile makes sure a download is complete, with exactly the size the server sent, and that the video
+// isn't corrupted, if ffprobe is installed
+func verifyDownloadedFile(path string, info downloadInfo) error {
+	if info.Size >= 0 {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return errors.Err(err)
+		}
+		if fi.Size() != info.Size {
+			return errors.Err("download verification failed: got %d bytes, the server sent %d", fi.Size(), info.Size)
+		}
+	}
+	return probeIntegrity(path)
+}
+
+// probeIntegrity makes sure ffprobe can read the media file at path without errors and finds a video stream in it. It
+// reads the container and the headers of the streams, not every frame, so it's quick but only catches truncated or
+// mangled files.
+func probeIntegrity(path string) error {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		log.Debugln("ffprobe not found, skipping integrity check")
+		return nil
+	}
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=codec_type", "-of", "csv=p=0", path)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	problems := strings.TrimSpace(stderr.String())
+	if err != nil || problems != "" {
+		if problems == "" {
+			problems = err.Error()
+		}
+		return errors.Err("download verification failed: the video is corrupted: %s", problems)
+	}
+	for _, codecType := range strings.Fields(string(out)) {
+		if codecType == "video" {
+			return nil
+		}
+	}
+	return errors.Err("download verification failed: the video has no video stream")
+}
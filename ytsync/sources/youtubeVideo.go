@@ -1,10 +1,8 @@
 package sources
 
 import (
-	"bytes"
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
+	"io"
+	"net/url"
 	"os"
 	"regexp"
 	"strconv"
@@ -26,12 +24,16 @@ type YoutubeVideo struct {
 	description      string
 	playlistPosition int64
 	publishedAt      time.Time
+	thumbnailWidth   int64
+	thumbnailURL     string
 	dir              string
+	livestream       bool
+	short            bool
 }
 
 func NewYoutubeVideo(directory string, snippet *youtube.PlaylistItemSnippet) YoutubeVideo {
 	publishedAt, _ := time.Parse(time.RFC3339Nano, snippet.PublishedAt) // ignore parse errors
-	return YoutubeVideo{
+	v := YoutubeVideo{
 		id:               snippet.ResourceId.VideoId,
 		title:            snippet.Title,
 		description:      snippet.Description,
@@ -40,6 +42,11 @@ func NewYoutubeVideo(directory string, snippet *youtube.PlaylistItemSnippet) You
 		publishedAt:      publishedAt,
 		dir:              directory,
 	}
+	if thumbnail := bestThumbnail(snippet.Thumbnails); thumbnail != nil {
+		v.thumbnailURL = thumbnail.Url
+		v.thumbnailWidth = thumbnail.Width
+	}
+	return v
 }
 
 func (v YoutubeVideo) ID() string {
@@ -58,6 +65,10 @@ func (v YoutubeVideo) PublishedAt() time.Time {
 	return v.publishedAt
 }
 
+func (v YoutubeVideo) Title() string {
+	return v.title
+}
+
 func (v YoutubeVideo) getFilename() string {
 	maxLen := 30
 	reg := regexp.MustCompile(`[^a-zA-Z0-9]+`)
@@ -85,6 +96,11 @@ func (v YoutubeVideo) getFilename() string {
 	return v.videoDir() + "/" + name + ".mp4"
 }
 
+// DownloadedFile returns where Download puts the video
+func (v YoutubeVideo) DownloadedFile() string {
+	return v.getFilename()
+}
+
 func (v YoutubeVideo) getAbbrevDescription() string {
 	maxLines := 10
 	description := strings.TrimSpace(v.description)
@@ -94,7 +110,7 @@ func (v YoutubeVideo) getAbbrevDescription() string {
 	return strings.Join(strings.Split(description, "\n")[:maxLines], "\n") + "\n..."
 }
 
-func (v YoutubeVideo) download() error {
+func (v YoutubeVideo) download(params SyncParams) error {
 	videoPath := v.getFilename()
 
 	err := os.Mkdir(v.videoDir(), 0750)
@@ -106,25 +122,129 @@ func (v YoutubeVideo) download() error {
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	} else if err == nil {
-		log.Debugln(v.id + " already exists at " + videoPath)
+		params.logger().Debugln(v.id + " already exists at " + videoPath)
 		return nil
 	}
 
 	videoUrl := "https://www.youtube.com/watch?v=" + v.id
-	videoInfo, err := ytdl.GetVideoInfo(videoUrl)
+	videoInfo, format, downloadURL, err := extract(videoUrl)
+	if err != nil {
+		if params.YtDlp == nil {
+			return err
+		}
+		params.logger().Warnf("%s: %s, downloading it with yt-dlp", v.id, err.Error())
+		return v.downloadWithYtDlp(params, videoUrl, videoPath)
+	}
+
+	timeout := v.downloadTimeout(params)
+	started := time.Now()
+	wrap := func(out io.Writer, offset, size int64) io.Writer {
+		if timeout > 0 {
+			out = deadlineWriter{w: out, deadline: started.Add(timeout)}
+		}
+		if params.DownloadLimiter != nil {
+			out = throttledWriter{w: out, bucket: params.DownloadLimiter, stop: params.Stop}
+		}
+		if params.DownloadCounter != nil {
+			out = countingWriter{w: out, counter: params.DownloadCounter}
+		}
+		if params.DownloadProgress != nil {
+			out = &progressWriter{w: out, written: offset, size: size, report: params.DownloadProgress}
+		}
+		return out
+	}
+	// an interrupted download is kept, the next attempt picks it up where it stopped if it gets the same format
+	partPath := videoPath + "." + strconv.Itoa(format.Itag) + partialSuffix
+	info, err := downloadResumable(downloadURL.String(), videoPath, partPath, wrap, params.Stop)
 	if err != nil {
 		return err
 	}
+	if !params.VerifyDownloads {
+		return nil
+	}
 
-	var downloadedFile *os.File
-	downloadedFile, err = os.Create(videoPath)
+	err = verifyDownloadedFile(videoPath, info)
+	if err == nil {
+		err = v.verifyDownload(videoInfo, format)
+	}
 	if err != nil {
+		params.logger().Errorf("%s: %s. Flaky network?", v.id, err.Error())
+		_ = v.delete()
 		return err
 	}
+	return nil
+}
 
-	defer downloadedFile.Close()
+// extract finds the format to download and where to download it from
+func extract(videoURL string) (*ytdl.VideoInfo, ytdl.Format, *url.URL, error) {
+	videoInfo, err := ytdl.GetVideoInfo(videoURL)
+	if err != nil {
+		return nil, ytdl.Format{}, nil, errors.Err(err)
+	}
+	formats := videoInfo.Formats.Best(ytdl.FormatAudioEncodingKey)
+	if len(formats) == 0 {
+		return nil, ytdl.Format{}, nil, errors.Err("no format available")
+	}
+	downloadURL, err := videoInfo.GetDownloadURL(formats[0])
+	if err != nil {
+		return nil, ytdl.Format{}, nil, errors.Err(err)
+	}
+	return videoInfo, formats[0], downloadURL, nil
+}
 
-	return videoInfo.Download(videoInfo.Formats.Best(ytdl.FormatAudioEncodingKey)[0], downloadedFile)
+// DownloadSize returns the size of the format download would pick, without downloading it
+func (v YoutubeVideo) DownloadSize() (int64, error) {
+	_, _, downloadURL, err := extract("https://www.youtube.com/watch?v=" + v.id)
+	if err != nil {
+		return 0, err
+	}
+	return remoteContentLength(downloadURL.String())
+}
+
+// verifyDownload compares the downloaded file against what youtube says it should be
+func (v YoutubeVideo) verifyDownload(videoInfo *ytdl.VideoInfo, format ytdl.Format) error {
+	downloadURL, err := videoInfo.GetDownloadURL(format)
+	if err != nil {
+		return err
+	}
+	expectedSize, err := remoteContentLength(downloadURL.String())
+	if err != nil {
+		log.Warnf("%s: could not get the expected size of the video, skipping size check: %s", v.id, err.Error())
+	} else {
+		err = verifySize(v.getFilename(), expectedSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	return verifyDuration(v.getFilename(), videoInfo.Duration)
+}
+
+// transcode fits the downloaded video to the transcode profile. If that fails, the video is published as downloaded.
+func (v YoutubeVideo) transcode(params SyncParams) {
+	before, err := os.Stat(v.getFilename())
+	if err != nil {
+		params.logger().Warnf("could not transcode %s, publishing it as is: %s", v.id, err.Error())
+		return
+	}
+	ctx, cancel := params.stopContext()
+	defer cancel()
+	transcoded, err := transcode(ctx, v.getFilename(), *params.Transcode)
+	if err != nil && params.stopped() != nil {
+		return
+	}
+	if err != nil {
+		params.logger().Warnf("could not transcode %s, publishing it as is: %s", v.id, err.Error())
+		return
+	}
+	if !transcoded {
+		params.logger().Debugf("%s already fits transcode profile %s", v.id, params.Transcode.Name)
+		return
+	}
+	after, err := os.Stat(v.getFilename())
+	if err == nil {
+		params.logger().Infof("transcoded %s with profile %s: %d bytes down to %d", v.id, params.Transcode.Name, before.Size(), after.Size())
+	}
 }
 
 func (v YoutubeVideo) videoDir() string {
@@ -142,94 +262,158 @@ func (v YoutubeVideo) delete() error {
 	return nil
 }
 
-func (v YoutubeVideo) triggerThumbnailSave() error {
-	client := &http.Client{Timeout: 30 * time.Second}
-
-	params, err := json.Marshal(map[string]string{"videoid": v.id})
-	if err != nil {
-		return err
+// saveThumbnail hosts the best thumbnail youtube has for the video. If youtube doesn't have a usable one and thumbnail
+// generation is enabled, a frame of the downloaded video is hosted instead.
+func (v YoutubeVideo) saveThumbnail(params SyncParams) error {
+	var err error
+	if v.thumbnailURL == "" {
+		err = errors.Err("youtube has no thumbnail for the video")
+	} else {
+		err = downloadThumbnail(v.thumbnailURL, v.thumbnailPath())
+		if err == nil && (v.thumbnailWidth >= minThumbnailWidth || !params.GenerateThumbnails) {
+			_, err = host(v.thumbnailPath(), thumbnailKey(v.id), "image/jpeg", params)
+			return err
+		}
 	}
-
-	request, err := http.NewRequest(http.MethodPut, "https://jgp4g1qoud.execute-api.us-east-1.amazonaws.com/prod/thumbnail", bytes.NewBuffer(params))
-	if err != nil {
+	if !params.GenerateThumbnails {
 		return err
 	}
 
-	response, err := client.Do(request)
 	if err != nil {
-		return err
+		params.logger().Warnf("could not get the youtube thumbnail for %s, generating one: %s", v.id, err.Error())
+	} else {
+		params.logger().Infof("youtube thumbnail for %s is only %dpx wide, generating one", v.id, v.thumbnailWidth)
 	}
-	defer response.Body.Close()
 
-	contents, err := ioutil.ReadAll(response.Body)
+	err = generateThumbnail(v.getFilename(), v.thumbnailPath(), params.ThumbnailTimestamp)
 	if err != nil {
 		return err
 	}
+	_, err = host(v.thumbnailPath(), thumbnailKey(v.id), "image/jpeg", params)
+	return err
+}
+
+func (v YoutubeVideo) thumbnailPath() string {
+	return v.getFilename() + ".jpg"
+}
 
-	var decoded struct {
-		error   int    `json:"error"`
-		url     string `json:"url,omitempty"`
-		message string `json:"message,omitempty"`
+func strPtr(s string) *string { return &s }
+
+func (v YoutubeVideo) publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	if params.ChannelID == "" {
+		return nil, errors.Err("a claim_id for the channel wasn't provided") //TODO: this is probably not needed?
+	}
+	thumbnail := hostedURL(v.thumbnailPath())
+	if thumbnail == "" {
+		return nil, errors.Err("the thumbnail of %s wasn't hosted", v.id)
 	}
-	err = json.Unmarshal(contents, &decoded)
+	options := v.Metadata(params).publishOptions(params, thumbnail)
+	summary, err := publishDeduplicated(daemon, v.title, v.getFilename(), options, params)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	if decoded.error != 0 {
-		return errors.Err("error creating thumbnail: " + decoded.message)
+	summary.Duration, err = probeDuration(v.getFilename())
+	if err != nil {
+		params.logger().Warnf("could not probe the duration of %s: %s", v.id, err.Error())
 	}
-
-	return nil
+	return summary, nil
 }
 
-func strPtr(s string) *string { return &s }
-
-func (v YoutubeVideo) publish(daemon *jsonrpc.Client, claimAddress string, amount float64, channelID string) (*SyncSummary, error) {
-	if channelID == "" {
-		return nil, errors.Err("a claim_id for the channel wasn't provided") //TODO: this is probably not needed?
+// Metadata returns what is published along with the video
+func (v YoutubeVideo) Metadata(params SyncParams) Metadata {
+	details := VideoDetails{
+		ID:           v.id,
+		Title:        v.title,
+		Description:  v.description,
+		ChannelTitle: v.channelTitle,
+		PublishedAt:  v.publishedAt,
 	}
-	options := jsonrpc.PublishOptions{
-		Title:         &v.title,
-		Author:        &v.channelTitle,
-		Description:   strPtr(v.getAbbrevDescription() + "\nhttps://www.youtube.com/watch?v=" + v.id),
-		Language:      strPtr("en"),
-		ClaimAddress:  &claimAddress,
-		Thumbnail:     strPtr("https://berk.ninja/thumbnails/" + v.id),
-		License:       strPtr("Copyrighted (contact author)"),
-		ChangeAddress: &claimAddress,
-		ChannelID:     &channelID,
+	m := Metadata{
+		Title:       v.title,
+		Description: v.getAbbrevDescription() + "\nhttps://www.youtube.com/watch?v=" + v.id,
+		Author:      v.channelTitle,
+		Language:    "en",
+		License:     "Copyrighted (contact author)",
+		Captions:    v.captions(),
 	}
-	return publishAndRetryExistingNames(daemon, v.title, v.getFilename(), amount, options)
+	if v.short {
+		m.Tags = append([]string(nil), params.ShortsTags...)
+	}
+	return params.applyTransform(details, m)
 }
 
-func (v YoutubeVideo) Sync(daemon *jsonrpc.Client, claimAddress string, amount float64, channelID string, maxVideoSize int) (*SyncSummary, error) {
+// Download fetches the video and makes sure it has a thumbnail, so that it's ready to be published
+func (v YoutubeVideo) Download(params SyncParams) error {
+	if params.VideoLimiter != nil {
+		err := params.VideoLimiter.Wait(1, params.Stop)
+		if err != nil {
+			return err
+		}
+	}
+
 	//download and thumbnail can be done in parallel
-	err := v.download()
+	err := v.download(params)
 	if err != nil {
-		return nil, errors.Prefix("download error", err)
+		if stopErr := params.stopped(); stopErr != nil {
+			// the partial download is picked up by the next run
+			return stopErr
+		}
+		return errors.Prefix("download error", err)
 	}
-	log.Debugln("Downloaded " + v.id)
+	params.logger().Debugln("Downloaded " + v.id)
 
 	fi, err := os.Stat(v.getFilename())
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if fi.Size() > int64(maxVideoSize)*1024*1024 {
-		//delete the video and ignore the error
-		_ = v.delete()
-		return nil, errors.Err("the video is too big to sync, skipping for now")
+	if fi.Size() > int64(params.MaxVideoSize)*1024*1024 {
+		v.Cleanup()
+		return errors.Err("the video is too big to sync, skipping for now")
+	}
+
+	if v.livestream {
+		err = trimDeadAir(v.getFilename())
+		if err != nil {
+			params.logger().Warnf("could not trim the dead air of livestream %s, publishing it untrimmed: %s", v.id, err.Error())
+		}
 	}
 
-	err = v.triggerThumbnailSave()
+	if params.Transcode != nil {
+		v.transcode(params)
+	}
+	err = params.stopped()
 	if err != nil {
-		return nil, errors.Prefix("thumbnail error", err)
+		return err
 	}
-	log.Debugln("Created thumbnail for " + v.id)
 
-	summary, err := v.publish(daemon, claimAddress, amount, channelID)
-	//delete the video in all cases (and ignore the error)
-	_ = v.delete()
+	err = validate(v.getFilename(), v.id, params)
+	if err != nil {
+		v.Cleanup()
+		return err
+	}
+
+	err = v.saveThumbnail(params)
+	if err != nil {
+		v.Cleanup()
+		return errors.Prefix("thumbnail error", err)
+	}
+	params.logger().Debugln("Created thumbnail for " + v.id)
+
+	if params.SyncCaptions {
+		err = v.saveCaptions(params)
+		if err != nil {
+			params.logger().Warnf("could not get the captions of %s, publishing without them: %s", v.id, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Publish publishes a downloaded video and removes it from disk afterwards
+func (v YoutubeVideo) Publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	summary, err := v.publish(daemon, params)
+	//delete the video in all cases
+	v.Cleanup()
 	if err != nil {
 		return nil, errors.Prefix("publish error", err)
 	}
@@ -237,6 +421,21 @@ func (v YoutubeVideo) Sync(daemon *jsonrpc.Client, claimAddress string, amount f
 	return summary, nil
 }
 
+// Cleanup removes the directory of the video from disk, with the downloaded video, its thumbnail and captions (if
+// any), ignoring errors
+func (v YoutubeVideo) Cleanup() {
+	_ = v.delete()
+	_ = os.RemoveAll(v.videoDir())
+}
+
+func (v YoutubeVideo) Sync(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	err := v.Download(params)
+	if err != nil {
+		return nil, err
+	}
+	return v.Publish(daemon, params)
+}
+
 // sorting videos
 //type ByPublishedAt []YoutubeVideo
 //
@@ -0,0 +1,118 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// YtDlp provides the yt-dlp binaries youtube videos are downloaded with when the built-in extractor fails
+type YtDlp interface {
+	// Binaries returns the binaries to try, the preferred one first
+	Binaries() ([]string, error)
+	// Update installs a newer release if there is one, and returns true if it did
+	Update() (bool, error)
+}
+
+// ytDlpFormat picks an mp4 with audio, like the built-in extractor does
+const ytDlpFormat = "best[ext=mp4]/bestvideo[ext=mp4]+bestaudio[ext=m4a]/best"
+
+// downloadWithYtDlp downloads the video to videoPath with the binaries of params.YtDlp, one after the other until one
+// succeeds. If they all fail, a newer release is installed if there is one and tried as well: youtube changes break
+// the older ones.
+func (v YoutubeVideo) downloadWithYtDlp(params SyncParams, videoURL, videoPath string) error {
+	binaries, err := params.YtDlp.Binaries()
+	if err != nil {
+		return err
+	}
+	for _, binary := range binaries {
+		err = v.runYtDlp(params, binary, videoURL, videoPath)
+		if err == nil {
+			return v.verifyYtDlpDownload(params, videoPath)
+		}
+		select {
+		case <-params.Stop:
+			return err
+		default:
+		}
+		params.logger().Warnf("%s: %s", v.id, err.Error())
+	}
+
+	updated, updateErr := params.YtDlp.Update()
+	if updateErr != nil {
+		params.logger().Warnf("could not update yt-dlp: %s", updateErr.Error())
+	}
+	if !updated {
+		return err
+	}
+	binaries, updateErr = params.YtDlp.Binaries()
+	if updateErr != nil {
+		return updateErr
+	}
+	err = v.runYtDlp(params, binaries[0], videoURL, videoPath)
+	if err != nil {
+		return err
+	}
+	return v.verifyYtDlpDownload(params, videoPath)
+}
+
+// runYtDlp downloads the video with binary. It's killed when the sync stops or the download timeout passes.
+func (v YoutubeVideo) runYtDlp(params SyncParams, binary, videoURL, videoPath string) error {
+	ctx, cancel := params.stopContext()
+	defer cancel()
+	if timeout := v.downloadTimeout(params); timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+
+	args := []string{"--no-playlist", "--no-progress", "--quiet", "--no-warnings", "--continue",
+		"-f", ytDlpFormat, "--merge-output-format", "mp4", "-o", videoPath}
+	if params.MaxVideoSize > 0 {
+		args = append(args, "--max-filesize", strconv.Itoa(params.MaxVideoSize)+"M")
+	}
+	cmd := exec.CommandContext(ctx, binary, append(args, videoURL)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return errors.Err("yt-dlp was stopped: %s", ctx.Err().Error())
+	}
+	if err != nil {
+		return errors.Err("%s failed: %s", binary, strings.TrimSpace(err.Error()+" "+stderr.String()))
+	}
+	if _, err := os.Stat(videoPath); err != nil {
+		// --max-filesize skips the video without failing
+		return errors.Err("%s did not download the video", binary)
+	}
+	return nil
+}
+
+// verifyYtDlpDownload counts the downloaded bytes and checks the file is playable. yt-dlp checks the size of what it
+// downloads itself.
+func (v YoutubeVideo) verifyYtDlpDownload(params SyncParams, videoPath string) error {
+	fi, err := os.Stat(videoPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if params.DownloadCounter != nil {
+		params.DownloadCounter.Add(float64(fi.Size()))
+	}
+	if params.DownloadProgress != nil {
+		params.DownloadProgress(fi.Size(), fi.Size())
+	}
+	if !params.VerifyDownloads {
+		return nil
+	}
+	err = probeIntegrity(videoPath)
+	if err != nil {
+		params.logger().Errorf("%s: %s", v.id, err.Error())
+		_ = v.delete()
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package ytsync
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+)
+
+// errSpendingCap stops the sync of a channel once it spent MaxChannelSpend
+var errSpendingCap = errors.Base("the channel reached its LBC spending cap")
+
+// recordSpend adds a transaction of the channel to its spending history in the local db. Failing to do so doesn't
+// stop the sync.
+func (s *Sync) recordSpend(sp localdb.Spend) {
+	if s.Manager.localDB == nil || sp.Amount+sp.Fee <= 0 {
+		return
+	}
+	err := s.Manager.localDB.AddSpend(s.YoutubeChannelID, sp)
+	if err != nil {
+		s.notifyError("could not record a %s transaction of %s on the local db: %s", sp.Kind, s.YoutubeChannelID, err.Error())
+	}
+}
+
+// totalSpent returns the LBC the channel spent over every sync. ok is false without a local db.
+func (s *Sync) totalSpent() (spent float64, ok bool) {
+	if s.Manager == nil || s.Manager.localDB == nil {
+		return 0, false
+	}
+	c, _, err := s.Manager.localDB.Channel(s.YoutubeChannelID)
+	if err != nil {
+		s.logger().Warnf("could not get what %s spent from the local db: %s", s.YoutubeChannelID, err.Error())
+		return 0, false
+	}
+	return c.Spent, true
+}
+
+// checkSpendingCap returns errSpendingCap if spending next more LBC would take the channel over MaxChannelSpend.
+// Without a local db, only what was spent during this sync counts.
+func (s *Sync) checkSpendingCap(next float64) error {
+	limit := s.Manager.MaxChannelSpend
+	if limit <= 0 {
+		return nil
+	}
+	spent, ok := s.totalSpent()
+	if !ok {
+		spent = s.Summary().Spent
+	}
+	if spent+next <= limit {
+		return nil
+	}
+	return errors.Prefix(fmt.Sprintf("%.4f LBC spent, %.4f more would go over %.4f", spent, next, limit), errSpendingCap)
+}
+
+// ChannelSpend is what a channel spent over every sync, see SyncManager.Spend
+type ChannelSpend struct {
+	YoutubeChannelID string          `json:"youtube_channel_id"`
+	Spent            float64         `json:"spent"`
+	Transactions     []localdb.Spend `json:"transactions,omitempty"`
+}
+
+// Spend returns what the channel spent according to the local db in the state dir, with its transactions if
+// withTransactions is set
+func (s SyncManager) Spend(channelID string, withTransactions bool) (*ChannelSpend, error) {
+	if s.StateDir == "" {
+		return nil, errors.Err("the spending of the channels is kept in the state dir, there is none")
+	}
+	db, err := localdb.Open(filepath.Join(s.StateDir, localDBFile))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	c, _, err := db.Channel(channelID)
+	if err != nil {
+		return nil, err
+	}
+	spend := &ChannelSpend{YoutubeChannelID: channelID, Spent: c.Spent}
+	if withTransactions {
+		spend.Transactions, err = db.Spends(channelID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spend, nil
+}
@@ -0,0 +1,48 @@
+package ytsync
+
+// stashableVideo is implemented by the videos whose download is a single file, which can wait in the staging storage
+// to be published, see sources.YoutubeVideo.DownloadedFile
+type stashableVideo interface {
+	DownloadedFile() string
+}
+
+// stashedDownload is the download of a prefetched video, moved to the staging storage until a worker publishes it
+type stashedDownload struct {
+	s    *Sync
+	key  string
+	path string
+}
+
+// stashDownload moves the download of a prefetched video to the staging storage, if it keeps files off the disk, and
+// gives its disk reservation back meanwhile. If it can't, the download stays on disk.
+func (s *Sync) stashDownload(p *prefetchedVideo) {
+	v, ok := p.video.(stashableVideo)
+	if s.Manager.Staging == nil || !ok {
+		return
+	}
+	path := v.DownloadedFile()
+	key, err := s.Manager.Staging.Stash(path)
+	if err != nil {
+		s.videoLogger(p.ID(), 1).Warnf("could not stash the download of %s, it stays on disk: %s", p.ID(), err.Error())
+		return
+	}
+	if key == "" {
+		return
+	}
+	p.stash = &stashedDownload{s: s, key: key, path: path}
+	p.reservation.Release()
+	p.reservation = nil
+}
+
+// restore puts the download back where the video expects it. processVideo reserved the space for it already.
+func (d *stashedDownload) restore() error {
+	return d.s.Manager.Staging.Restore(d.key, d.path)
+}
+
+// remove deletes the download from the staging storage
+func (d *stashedDownload) remove() {
+	err := d.s.Manager.Staging.Remove(d.key)
+	if err != nil {
+		d.s.logger().Warnf("could not remove %s from the staging storage: %s", d.key, err.Error())
+	}
+}
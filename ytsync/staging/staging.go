@@ -0,0 +1,203 @@
+// Package staging abstracts where downloaded videos are kept until they are published: a directory on the local disk
+// or on an NFS mount, or an S3 bucket for sync servers whose disks can't hold more than the videos being published.
+package staging
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// Storage is where downloaded videos wait to be published
+type Storage interface {
+	// Dir returns the directory the videos of the channel are downloaded to, creating it if needed. The daemon
+	// publishes from it, so it has to see it under the same path.
+	Dir(channelID string) (string, error)
+	// Stash moves the downloaded file at path out of the download dir while it waits to be published. It returns the
+	// key to restore it with, or an empty key if the file stays where it is.
+	Stash(path string) (string, error)
+	// Restore puts the file stashed under key back at path
+	Restore(key, path string) error
+	// Remove deletes the file stashed under key
+	Remove(key string) error
+}
+
+// Disk keeps the downloads in Root/CHANNEL_ID, on the local disk or on a network filesystem mounted on the sync server
+// and the daemon under the same path. Stashing leaves the files where they are.
+type Disk struct {
+	Root string
+}
+
+func (d Disk) Dir(channelID string) (string, error) {
+	dir := filepath.Join(d.Root, channelID)
+	return dir, errors.Err(os.MkdirAll(dir, 0750))
+}
+
+func (d Disk) Stash(path string) (string, error) { return "", nil }
+
+func (d Disk) Restore(key, path string) error { return nil }
+
+func (d Disk) Remove(key string) error { return nil }
+
+// S3 downloads the videos to Local and stashes them in an S3 bucket, or in any storage with an S3 compatible API, under
+// Prefix/CHANNEL_ID/... until they are published. Only the videos being published are on the local disk then.
+type S3 struct {
+	Local    Disk
+	Bucket   string
+	Prefix   string
+	Region   string
+	Endpoint string // for S3 compatible storages, empty for AWS
+	ID       string
+	Secret   string
+}
+
+func (s *S3) Dir(channelID string) (string, error) {
+	return s.Local.Dir(channelID)
+}
+
+// Stash uploads the file and removes it from the local disk
+func (s *S3) Stash(path string) (string, error) {
+	key, err := s.key(path)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer file.Close()
+
+	sess, err := s.session()
+	if err != nil {
+		return "", err
+	}
+	_, err = s3manager.NewUploader(sess).Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   file,
+	})
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	err = os.Remove(path)
+	if err != nil {
+		_ = s.Remove(key)
+		return "", errors.Err(err)
+	}
+	return key, nil
+}
+
+// Restore downloads the file next to path first, so a failed download never leaves a truncated file behind
+func (s *S3) Restore(key, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0750)
+	if err != nil {
+		return errors.Err(err)
+	}
+	partPath := path + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer os.Remove(partPath)
+	defer file.Close()
+
+	sess, err := s.session()
+	if err != nil {
+		return err
+	}
+	_, err = s3manager.NewDownloader(sess).Download(file, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = file.Close()
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = os.Rename(partPath, path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return s.Remove(key)
+}
+
+func (s *S3) Remove(key string) error {
+	sess, err := s.session()
+	if err != nil {
+		return err
+	}
+	_, err = s3.New(sess).DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return errors.Err(err)
+}
+
+// key returns the S3 key of the file at path, its path under the local root appended to Prefix
+func (s *S3) key(path string) (string, error) {
+	rel, err := filepath.Rel(s.Local.Root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", errors.Err("%s is not in the download dir %s", path, s.Local.Root)
+	}
+	prefix := strings.Trim(s.Prefix, "/")
+	if prefix == "" {
+		return filepath.ToSlash(rel), nil
+	}
+	return prefix + "/" + filepath.ToSlash(rel), nil
+}
+
+func (s *S3) session() (*session.Session, error) {
+	config := &aws.Config{
+		Region:      aws.String(s.Region),
+		Credentials: credentials.NewStaticCredentials(s.ID, s.Secret, ""),
+	}
+	if s.Endpoint != "" {
+		config.Endpoint = aws.String(s.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return sess, nil
+}
+
+// Parse returns the storage described by spec: a directory, or s3://BUCKET[/PREFIX]?region=REGION[&endpoint=ENDPOINT]
+// for a bucket accessed with the given credentials. The videos stashed in a bucket are downloaded to localRoot.
+func Parse(spec, localRoot, id, secret string) (Storage, error) {
+	if !strings.HasPrefix(spec, "s3://") {
+		if spec == "" {
+			return nil, errors.Err("no download dir given")
+		}
+		return Disk{Root: strings.TrimPrefix(spec, "file://")}, nil
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	query := u.Query()
+	s := &S3{
+		Local:    Disk{Root: localRoot},
+		Bucket:   u.Host,
+		Prefix:   strings.Trim(u.Path, "/"),
+		Region:   query.Get("region"),
+		Endpoint: query.Get("endpoint"),
+		ID:       id,
+		Secret:   secret,
+	}
+	if s.Bucket == "" || s.Region == "" {
+		return nil, errors.Err("s3 staging needs a bucket and a region")
+	}
+	return s, nil
+}
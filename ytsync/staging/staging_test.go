@@ -0,0 +1,101 @@
+package staging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDisk(t *testing.T) {
+	root, err := ioutil.TempDir("", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	d := Disk{Root: root}
+	dir, err := d.Dir("UCchannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(root, "UCchannel") {
+		t.Errorf("expected the downloads in %s, got %s", filepath.Join(root, "UCchannel"), dir)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Fatalf("expected %s to be created", dir)
+	}
+
+	path := filepath.Join(dir, "video.mp4")
+	err = ioutil.WriteFile(path, []byte("video"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := d.Stash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		t.Errorf("expected the file to stay on disk, got key %q", key)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Error("expected the file to stay on disk")
+	}
+}
+
+func TestS3Key(t *testing.T) {
+	s := &S3{Local: Disk{Root: "/downloads"}, Prefix: "/staging/"}
+	key, err := s.key("/downloads/UCchannel/abc/video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "staging/UCchannel/abc/video.mp4" {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	s.Prefix = ""
+	key, err = s.key("/downloads/UCchannel/video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "UCchannel/video.mp4" {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	for _, outside := range []string{"/downloads", "/tmp/video.mp4", "/downloads/../video.mp4"} {
+		if _, err := s.key(outside); err == nil {
+			t.Errorf("expected %s to be rejected, it's not in the download dir", outside)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	storage, err := Parse("/mnt/nfs/ytsync", "/tmp", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := storage.(Disk); !ok || d.Root != "/mnt/nfs/ytsync" {
+		t.Errorf("expected a disk storage in /mnt/nfs/ytsync, got %#v", storage)
+	}
+
+	storage, err = Parse("s3://bucket/staging/?region=us-east-2&endpoint=https://minio.local", "/tmp/downloads", "id", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, ok := storage.(*S3)
+	if !ok {
+		t.Fatalf("expected an s3 storage, got %#v", storage)
+	}
+	if s.Bucket != "bucket" || s.Prefix != "staging" || s.Region != "us-east-2" || s.Endpoint != "https://minio.local" {
+		t.Errorf("unexpected s3 storage %#v", s)
+	}
+	if s.Local.Root != "/tmp/downloads" || s.ID != "id" || s.Secret != "secret" {
+		t.Errorf("unexpected s3 storage %#v", s)
+	}
+
+	for _, invalid := range []string{"", "s3://bucket", "s3://?region=us-east-2"} {
+		if _, err := Parse(invalid, "/tmp", "", ""); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package ytsync
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/api"
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// channelRegistry keeps track of the channels that are currently being synced
+type channelRegistry struct {
+	mux   sync.RWMutex
+	syncs map[string]*Sync
+}
+
+func newChannelRegistry() *channelRegistry {
+	return &channelRegistry{syncs: make(map[string]*Sync)}
+}
+
+func (r *channelRegistry) add(s *Sync) {
+	if r == nil {
+		return
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.syncs[s.YoutubeChannelID]; !ok {
+		channelsRunning.Add(1)
+	}
+	r.syncs[s.YoutubeChannelID] = s
+}
+
+func (r *channelRegistry) remove(s *Sync) {
+	if r == nil {
+		return
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.syncs[s.YoutubeChannelID] == s {
+		delete(r.syncs, s.YoutubeChannelID)
+		channelsRunning.Add(-1)
+	}
+}
+
+func (r *channelRegistry) get(channelID string) *Sync {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return r.syncs[channelID]
+}
+
+func (r *channelRegistry) list() []*Sync {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	syncs := make([]*Sync, 0, len(r.syncs))
+	for _, s := range r.syncs {
+		syncs = append(syncs, s)
+	}
+	sort.Slice(syncs, func(i, j int) bool { return syncs[i].YoutubeChannelID < syncs[j].YoutubeChannelID })
+	return syncs
+}
+
+type runningChannel struct {
+	ChannelSummary
+	Cancelled bool `json:"cancelled"`
+	Paused    bool `json:"paused"`
+}
+
+// videoStatus is what this node knows about a video
+type videoStatus struct {
+	ChannelID string           `json:"channel_id"`
+	VideoID   string           `json:"video_id"`
+	Syncing   bool             `json:"syncing"`          // the channel is being synced by this node
+	Synced    *sdk.SyncedVideo `json:"synced,omitempty"` // the state of the video in the running sync
+	Local     *localdb.Video   `json:"local,omitempty"`  // the state of the video in the local state DB
+}
+
+func (s SyncManager) statusHandler(r *http.Request) api.Response {
+	var channels []runningChannel
+	for _, sync := range s.running.list() {
+		channels = append(channels, runningChannel{ChannelSummary: sync.Summary(), Cancelled: sync.IsCancelled(), Paused: sync.IsPaused()})
+	}
+	return api.Response{Data: channels}
+}
+
+// checkControlRequest makes sure a request that changes anything is a POST, carrying the control token if there is one
+func (s SyncManager) checkControlRequest(r *http.Request) error {
+	if r.Method != http.MethodPost {
+		return errors.Err(api.StatusError{Status: http.StatusMethodNotAllowed, Err: errors.Base("POST required")})
+	}
+	if s.ControlToken == "" {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.ControlToken)) != 1 {
+		return errors.Err(api.StatusError{Status: http.StatusUnauthorized, Err: errors.Base("a valid control token is required")})
+	}
+	return nil
+}
+
+// requiredParam returns the value of a request parameter that can't be empty
+func requiredParam(r *http.Request, name string) (string, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return "", errors.Err(api.StatusError{Status: http.StatusBadRequest, Err: errors.Base(name + " is required")})
+	}
+	return value, nil
+}
+
+// runningSync returns the sync of the channel the request is about
+func (s SyncManager) runningSync(r *http.Request) (*Sync, error) {
+	channelID, err := requiredParam(r, "channel_id")
+	if err != nil {
+		return nil, err
+	}
+	return s.runningChannel(channelID)
+}
+
+// runningChannel returns the sync of the channel with the given ID
+func (s SyncManager) runningChannel(channelID string) (*Sync, error) {
+	sync := s.running.get(channelID)
+	if sync == nil {
+		return nil, errors.Err(api.StatusError{Status: http.StatusNotFound, Err: errors.Base("channel " + channelID + " is not being synced")})
+	}
+	return sync, nil
+}
+
+func (s SyncManager) cancelHandler(r *http.Request) api.Response {
+	err := s.checkControlRequest(r)
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	sync, err := s.runningSync(r)
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	sync.Cancel()
+	SendInfoToSlack("Sync of %s (%s) was cancelled through the status server", sync.LbryChannelName, sync.YoutubeChannelID)
+	return api.Response{Data: "ok"}
+}
+
+// pauseHandler pauses the channel given by channel_id, or the whole node without it. The workers of channels that start
+// while the node is paused wait for it to be resumed.
+func (s SyncManager) pauseHandler(r *http.Request) api.Response {
+	err := s.checkControlRequest(r)
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	err = s.pause(r.FormValue("channel_id"), "through the status server")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	return api.Response{Data: "ok"}
+}
+
+// resumeHandler resumes the channel given by channel_id, or the whole node without it
+func (s SyncManager) resumeHandler(r *http.Request) api.Response {
+	err := s.checkControlRequest(r)
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	err = s.resume(r.FormValue("channel_id"), "through the status server")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	return api.Response{Data: "ok"}
+}
+
+// videoFailHandler marks a video as failed for good, see failVideo
+func (s SyncManager) videoFailHandler(r *http.Request) api.Response {
+	err := s.checkControlRequest(r)
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	channelID, err := requiredParam(r, "channel_id")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	videoID, err := requiredParam(r, "video_id")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	err = s.failVideo(channelID, videoID, r.FormValue("reason"), "through the status server")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	return api.Response{Data: "ok"}
+}
+
+// pause pauses the channel, or the whole node if channelID is empty. by says who did it, in the Slack notification.
+func (s SyncManager) pause(channelID, by string) error {
+	if channelID == "" {
+		if !s.grp.Pause() {
+			return errors.Err(api.StatusError{Status: http.StatusConflict, Err: errors.Base("the node is already paused")})
+		}
+		SendInfoToSlack("All the syncs of the node were paused %s", by)
+		return nil
+	}
+	sync, err := s.runningChannel(channelID)
+	if err != nil {
+		return err
+	}
+	if !sync.Pause() {
+		return errors.Err(api.StatusError{Status: http.StatusConflict, Err: errors.Base("channel " + sync.YoutubeChannelID + " is already paused")})
+	}
+	SendInfoToSlack("Sync of %s (%s) was paused %s", sync.LbryChannelName, sync.YoutubeChannelID, by)
+	return nil
+}
+
+// resume resumes the channel, or the whole node if channelID is empty
+func (s SyncManager) resume(channelID, by string) error {
+	if channelID == "" {
+		if !s.grp.Resume() {
+			return errors.Err(api.StatusError{Status: http.StatusConflict, Err: errors.Base("the node is not paused")})
+		}
+		SendInfoToSlack("All the syncs of the node were resumed %s", by)
+		return nil
+	}
+	sync, err := s.runningChannel(channelID)
+	if err != nil {
+		return err
+	}
+	if !sync.Resume() {
+		return errors.Err(api.StatusError{Status: http.StatusConflict, Err: errors.Base("channel " + sync.YoutubeChannelID + " is not paused")})
+	}
+	SendInfoToSlack("Sync of %s (%s) was resumed %s", sync.LbryChannelName, sync.YoutubeChannelID, by)
+	return nil
+}
+
+// failVideo marks a video as failed for good, on the API and in the local state DB, with the optional extra reason. If
+// its channel is being synced, the video is skipped from now on.
+func (s SyncManager) failVideo(channelID, videoID, extra, by string) error {
+	reason := forcedFailureReason
+	if extra != "" {
+		reason += ": " + extra
+	}
+	err := s.APIConfig.MarkVideoStatus(channelID, videoID, sdk.VideoStatusFailed, "", "", reason)
+	if err != nil {
+		return err
+	}
+	if s.localDB != nil {
+		err = s.localDB.SetFailed(channelID, videoID, reason)
+		if err != nil {
+			return err
+		}
+	}
+	if sync := s.running.get(channelID); sync != nil {
+		sync.forceFail(videoID, reason)
+	}
+	SendInfoToSlack("Video %s of %s was failed %s (%s)", videoID, channelID, by, reason)
+	return nil
+}
+
+func (s SyncManager) videoStatusHandler(r *http.Request) api.Response {
+	channelID, err := requiredParam(r, "channel_id")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	videoID, err := requiredParam(r, "video_id")
+	if err != nil {
+		return api.Response{Error: err}
+	}
+
+	status := videoStatus{ChannelID: channelID, VideoID: videoID}
+	if sync := s.running.get(channelID); sync != nil {
+		status.Syncing = true
+		if sv, ok := sync.syncedVideo(videoID); ok {
+			status.Synced = &sv
+		}
+	}
+	if s.localDB != nil {
+		v, ok, err := s.localDB.Video(channelID, videoID)
+		if err != nil {
+			return api.Response{Error: err}
+		}
+		if ok {
+			status.Local = &v
+		}
+	}
+	return api.Response{Data: status}
+}
+
+// pollHandler makes the manager poll the API for channels right away instead of at the end of the poll interval
+func (s SyncManager) pollHandler(r *http.Request) api.Response {
+	err := s.checkControlRequest(r)
+	if err != nil {
+		return api.Response{Error: err}
+	}
+	select {
+	case s.pollNow <- struct{}{}:
+	default: // a poll was already requested
+	}
+	return api.Response{Data: "ok"}
+}
+
+// startStatusServer serves the status of the running channel syncs and of single videos, and lets the channels be
+// cancelled, paused and resumed individually, videos be failed and polls be triggered. It also serves the health of
+// the manager, for service supervisors, and the Slack slash commands if there is a signing secret. It returns the
+// server so that it can be shut down.
+func (s SyncManager) startStatusServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/status", api.Handler(s.statusHandler))
+	mux.Handle("/cancel", api.Handler(s.cancelHandler))
+	mux.Handle("/pause", api.Handler(s.pauseHandler))
+	mux.Handle("/resume", api.Handler(s.resumeHandler))
+	mux.Handle("/video/status", api.Handler(s.videoStatusHandler))
+	mux.Handle("/video/fail", api.Handler(s.videoFailHandler))
+	mux.Handle("/poll", api.Handler(s.pollHandler))
+	mux.Handle("/health", api.Handler(s.healthHandler))
+	if s.SlackSigningSecret != "" {
+		mux.HandleFunc("/slack", s.slackCommandHandler)
+	}
+
+	server := &http.Server{
+		Addr:         s.StatusAddr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Infof("status server listening on %s", s.StatusAddr)
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			SendErrorToSlack("status server stopped: %s", err.Error())
+		}
+	}()
+	return server
+}
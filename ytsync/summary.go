@@ -0,0 +1,170 @@
+package ytsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
+)
+
+// ChannelSummary describes the outcome of syncing a single channel
+type ChannelSummary struct {
+	YoutubeChannelID string  `json:"youtube_channel_id"`
+	LbryChannelName  string  `json:"lbry_channel_name"`
+	VideosPublished  int     `json:"videos_published"`
+	VideosFailed     int     `json:"videos_failed"`
+	VideosSkipped    int     `json:"videos_skipped"`
+	LastVideoID      string  `json:"last_video_id,omitempty"` // the last video that was published or failed
+	Spent            float64 `json:"spent"`
+	TotalSpent       float64 `json:"total_spent,omitempty"` // over every sync, according to the local db
+	DurationSeconds  float64 `json:"duration_seconds"`
+	Error            string  `json:"error,omitempty"`
+	// Failures counts the failed videos by root cause, e.g. "download error", see triage.go
+	Failures map[string]int `json:"failures,omitempty"`
+}
+
+// RunSummary aggregates the channel summaries of a whole run of the sync manager
+type RunSummary struct {
+	Host            string           `json:"host"`
+	StartedAt       time.Time        `json:"started_at"`
+	FinishedAt      time.Time        `json:"finished_at"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	VideosPublished int              `json:"videos_published"`
+	VideosFailed    int              `json:"videos_failed"`
+	VideosSkipped   int              `json:"videos_skipped"`
+	Spent           float64          `json:"spent"`
+	Error           string           `json:"error,omitempty"`
+	Channels        []ChannelSummary `json:"channels"`
+}
+
+// Add adds a channel's results to the run
+func (r *RunSummary) Add(c ChannelSummary) {
+	r.Channels = append(r.Channels, c)
+	r.VideosPublished += c.VideosPublished
+	r.VideosFailed += c.VideosFailed
+	r.VideosSkipped += c.VideosSkipped
+	r.Spent += c.Spent
+}
+
+// Finish marks the run as done. err is the error the run ended with, if any.
+func (r *RunSummary) Finish(err error) {
+	r.FinishedAt = time.Now()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// Export writes the summary as JSON to the given file, or POSTs it if the destination is an http(s) URL
+func (r *RunSummary) Export(destination string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	if !strings.HasPrefix(destination, "http://") && !strings.HasPrefix(destination, "https://") {
+		return errors.Err(ioutil.WriteFile(destination, data, 0644))
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Post(destination, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Err("posting the sync summary failed with status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// syncStats counts what happened to the videos of a channel during a sync
+type syncStats struct {
+	mux         sync.Mutex
+	started     time.Time
+	published   int
+	failed      int
+	skipped     int
+	spent       float64
+	lastVideoID string
+}
+
+func newSyncStats() *syncStats {
+	return &syncStats{started: time.Now()}
+}
+
+func (st *syncStats) publish(videoID string, spent float64) {
+	st.mux.Lock()
+	defer st.mux.Unlock()
+	st.published++
+	st.spent += spent
+	st.lastVideoID = videoID
+	videosPublished.Inc()
+	lbcSpent.Add(spent)
+}
+
+func (st *syncStats) fail(videoID string) {
+	st.mux.Lock()
+	defer st.mux.Unlock()
+	st.failed++
+	st.lastVideoID = videoID
+}
+
+func (st *syncStats) skip() {
+	st.mux.Lock()
+	defer st.mux.Unlock()
+	st.skipped++
+}
+
+func (st *syncStats) spend(amount float64) {
+	st.mux.Lock()
+	defer st.mux.Unlock()
+	st.spent += amount
+	lbcSpent.Add(amount)
+}
+
+// Summary returns what happened during the last FullCycle
+func (s *Sync) Summary() ChannelSummary {
+	summary := ChannelSummary{
+		YoutubeChannelID: s.YoutubeChannelID,
+		LbryChannelName:  s.LbryChannelName,
+	}
+	if s.stats == nil {
+		return summary
+	}
+	summary.TotalSpent, _ = s.totalSpent()
+	s.stats.mux.Lock()
+	defer s.stats.mux.Unlock()
+	summary.VideosPublished = s.stats.published
+	summary.VideosFailed = s.stats.failed
+	summary.VideosSkipped = s.stats.skipped
+	summary.LastVideoID = s.stats.lastVideoID
+	summary.Spent = s.stats.spent
+	summary.DurationSeconds = time.Since(s.stats.started).Seconds()
+	summary.Failures = s.triageCounts()
+	return summary
+}
+
+// syncProgress returns how far the sync got, to report along with the status of the channel. It's nil if FullCycle
+// never ran.
+func (s *Sync) syncProgress() *sdk.SyncProgress {
+	if s.stats == nil {
+		return nil
+	}
+	summary := s.Summary()
+	return &sdk.SyncProgress{
+		VideosPublished: summary.VideosPublished,
+		VideosFailed:    summary.VideosFailed,
+		LastVideoID:     summary.LastVideoID,
+		LBCSpent:        summary.Spent,
+		TotalLBCSpent:   summary.TotalSpent,
+		Duration:        time.Duration(summary.DurationSeconds * float64(time.Second)),
+		Failures:        summary.Failures,
+	}
+}
@@ -0,0 +1,142 @@
+package ytsync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/retry"
+)
+
+// triageListed is how many video IDs the triage report lists per category
+const triageListed = 10
+
+// triageCategory groups the videos that failed for the same root cause, with what can be done about them
+type triageCategory struct {
+	Name   string
+	Action string
+}
+
+// triageCategories in the order of the report
+var (
+	triageFunds          = triageCategory{"insufficient funds", "refill the wallet, the videos are retried by the next sync"}
+	triagePublishTimeout = triageCategory{"publish timeout", "check that the claims weren't published anyway before syncing them again, the daemon may be overloaded"}
+	triageDownload       = triageCategory{"download error", "check the access to youtube (cookies, IP blocks) and the network, the videos are retried by the next sync"}
+	triageTooLong        = triageCategory{"too long", "raise --max-size, or the max_video_size of the channel, to sync them"}
+	triageCopyright      = triageCategory{"copyright blocked", "nothing to do, the rights holders blocked them"}
+	triageUnavailable    = triageCategory{"unavailable", "nothing to do, the videos are private, removed or restricted"}
+	triageInvalid        = triageCategory{"invalid video", "check the videos, or relax the rules they broke with --validate"}
+	triageOther          = triageCategory{"other", "see the log of the channel"}
+	triageCategories     = []triageCategory{triageFunds, triagePublishTimeout, triageDownload, triageTooLong, triageCopyright, triageUnavailable, triageInvalid, triageOther}
+)
+
+// triage returns the root cause of a failure, from the reason videoErrors gave it and its message
+func triage(failure *retry.Error) triageCategory {
+	switch failure.Reason {
+	case reasonInsufficientFunds, "wallet broken":
+		return triageFunds
+	case "publish timeout", "daemon timeout":
+		return triagePublishTimeout
+	case reasonTooBig:
+		return triageTooLong
+	case reasonCopyright:
+		return triageCopyright
+	case "video unavailable", "video restricted":
+		return triageUnavailable
+	case reasonInvalid:
+		return triageInvalid
+	case "corrupted download":
+		return triageDownload
+	}
+	if strings.Contains(failure.Error(), "download error") {
+		return triageDownload
+	}
+	return triageOther
+}
+
+// triageMessage returns the root cause of a failure recorded with the given message, in the local db or the API
+func (s *Sync) triageMessage(msg string) triageCategory {
+	err := errors.Base(msg)
+	class, reason := s.Manager.classifier(videoErrors).Classify(err)
+	return triage(&retry.Error{Err: err, Class: class, Reason: reason})
+}
+
+// addTriage records the root cause of a failed video, for the triage report
+func (s *Sync) addTriage(videoID string, failure *retry.Error) {
+	s.failuresMux.Lock()
+	defer s.failuresMux.Unlock()
+	name := triage(failure).Name
+	s.triage[name] = append(s.triage[name], videoID)
+}
+
+// triageCounts returns how many videos failed for each root cause. It's nil if none did.
+func (s *Sync) triageCounts() map[string]int {
+	if s.failuresMux == nil {
+		return nil
+	}
+	s.failuresMux.Lock()
+	defer s.failuresMux.Unlock()
+	if len(s.triage) == 0 {
+		return nil
+	}
+	counts := make(map[string]int, len(s.triage))
+	for name, videos := range s.triage {
+		counts[name] = len(videos)
+	}
+	return counts
+}
+
+// triageReport lists the failed videos of the channel by root cause, most actionable first, with what to do about
+// each. It's empty if no video failed. failuresMux must be held.
+func (s *Sync) triageReport() string {
+	total := 0
+	for _, videos := range s.triage {
+		total += len(videos)
+	}
+	if total == 0 {
+		return ""
+	}
+	lines := []string{fmt.Sprintf("Videos of %s (%s) that failed during the sync: %d", s.LbryChannelName, s.YoutubeChannelID, total)}
+	for _, c := range triageCategories {
+		videos := s.triage[c.Name]
+		if len(videos) == 0 {
+			continue
+		}
+		listed := strings.Join(videos, ", ")
+		if len(videos) > triageListed {
+			listed = fmt.Sprintf("%s and %d more", strings.Join(videos[:triageListed], ", "), len(videos)-triageListed)
+		}
+		lines = append(lines, fmt.Sprintf("- %s (%d): %s. To do: %s", c.Name, len(videos), listed, c.Action))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTriageCounts sums up triage counts on one line, largest first, e.g. "3 download error, 1 too long"
+func formatTriageCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %s", counts[name], name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// outcome sums up what the last FullCycle did, with the root causes of the failures
+func (s *Sync) outcome() string {
+	summary := s.Summary()
+	outcome := fmt.Sprintf("%d published, %d failed, %d skipped", summary.VideosPublished, summary.VideosFailed, summary.VideosSkipped)
+	if len(summary.Failures) > 0 {
+		outcome += " (" + formatTriageCounts(summary.Failures) + ")"
+	}
+	return outcome
+}
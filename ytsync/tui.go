@@ -0,0 +1,191 @@
+package ytsync
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	tuiRefreshInterval = time.Second
+	tuiRecentVideos    = 5 // finished videos kept on screen
+)
+
+// ProgressTUI shows the videos being synced in the terminal, for interactive runs: a table of the videos in flight
+// with their download progress, status and retries, under the counts and wallet balance of each channel. It's fed
+// with progress events, see SyncManager.OnProgress, and redraws the screen every second while it runs.
+type ProgressTUI struct {
+	out io.Writer
+
+	mux      sync.Mutex
+	videos   map[string]*tuiVideo // in flight, by channel and video ID
+	channels map[string]*tuiChannel
+	recent   []string // the last videos that finished, latest last
+}
+
+type tuiVideo struct {
+	channelID  string
+	videoID    string
+	status     ProgressEvent
+	attempt    int
+	downloaded int64
+	size       int64
+	started    time.Time
+}
+
+type tuiChannel struct {
+	published int
+	failed    int
+	skipped   int
+	balance   float64
+	seen      bool // the balance was read
+}
+
+// NewProgressTUI returns a TUI drawing to out, which should be a terminal
+func NewProgressTUI(out io.Writer) *ProgressTUI {
+	return &ProgressTUI{
+		out:      out,
+		videos:   make(map[string]*tuiVideo),
+		channels: make(map[string]*tuiChannel),
+	}
+}
+
+// Update records a progress event. It's a ProgressFunc.
+func (t *ProgressTUI) Update(p VideoProgress) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	c, ok := t.channels[p.YoutubeChannelID]
+	if !ok {
+		c = &tuiChannel{}
+		t.channels[p.YoutubeChannelID] = c
+	}
+	if p.Event == ProgressBalance {
+		c.balance, c.seen = p.Balance, true
+		return
+	}
+	c.published, c.failed, c.skipped = p.Published, p.Failed, p.Skipped
+
+	key := p.YoutubeChannelID + "/" + p.VideoID
+	v, ok := t.videos[key]
+	if !ok {
+		v = &tuiVideo{channelID: p.YoutubeChannelID, videoID: p.VideoID, attempt: 1, size: -1, started: p.Time.Add(-p.Elapsed)}
+		t.videos[key] = v
+	}
+	switch p.Event {
+	case ProgressStarted:
+		if p.Attempt > 0 {
+			v.attempt = p.Attempt
+		}
+		v.downloaded, v.size = 0, -1
+	case ProgressDownloading:
+		v.downloaded, v.size = p.Downloaded, p.DownloadSize
+	case ProgressConfirmed, ProgressFailed, ProgressSkipped:
+		delete(t.videos, key)
+		line := fmt.Sprintf("%s  %s  %s", p.Time.Format("15:04:05"), p.VideoID, p.Event)
+		if p.Error != nil {
+			line += ": " + p.Error.Error()
+		}
+		t.recent = append(t.recent, line)
+		if len(t.recent) > tuiRecentVideos {
+			t.recent = t.recent[len(t.recent)-tuiRecentVideos:]
+		}
+		return
+	}
+	v.status = p.Event
+}
+
+// Run redraws the screen until stop is closed, and once more then
+func (t *ProgressTUI) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+	for {
+		t.draw()
+		select {
+		case <-stop:
+			t.draw()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw clears the screen and renders the current state
+func (t *ProgressTUI) draw() {
+	_, _ = io.WriteString(t.out, "\x1b[H\x1b[2J"+t.render(time.Now()))
+}
+
+func (t *ProgressTUI) render(now time.Time) string {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tPUBLISHED\tFAILED\tSKIPPED\tBALANCE")
+	channelIDs := make([]string, 0, len(t.channels))
+	for id := range t.channels {
+		channelIDs = append(channelIDs, id)
+	}
+	sort.Strings(channelIDs)
+	for _, id := range channelIDs {
+		c := t.channels[id]
+		balance := "-"
+		if c.seen {
+			balance = fmt.Sprintf("%.4f LBC", c.balance)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\n", id, c.published, c.failed, c.skipped, balance)
+	}
+	w.Flush()
+	buf.WriteString("\n")
+
+	videos := make([]*tuiVideo, 0, len(t.videos))
+	for _, v := range t.videos {
+		videos = append(videos, v)
+	}
+	sort.Slice(videos, func(i, j int) bool { return videos[i].started.Before(videos[j].started) })
+	w = tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VIDEO\tCHANNEL\tSTATUS\tDOWNLOAD\tRETRIES\tELAPSED")
+	for _, v := range videos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", v.videoID, v.channelID, v.statusText(), v.downloadText(), v.attempt-1,
+			now.Sub(v.started).Truncate(time.Second).String())
+	}
+	w.Flush()
+
+	if len(t.recent) > 0 {
+		buf.WriteString("\nRecently finished:\n")
+		for _, line := range t.recent {
+			buf.WriteString(line + "\n")
+		}
+	}
+	return buf.String()
+}
+
+func (v *tuiVideo) statusText() string {
+	switch v.status {
+	case ProgressStarted:
+		return "starting"
+	case ProgressDownloading:
+		return "downloading"
+	case ProgressDownloaded:
+		return "publishing"
+	case ProgressPublished:
+		return "confirming"
+	}
+	return string(v.status)
+}
+
+func (v *tuiVideo) downloadText() string {
+	switch {
+	case v.size > 0:
+		return fmt.Sprintf("%d%%", v.downloaded*100/v.size)
+	case v.downloaded > 0:
+		return fmt.Sprintf("%.1f MB", float64(v.downloaded)/(1<<20))
+	case v.status == ProgressDownloaded || v.status == ProgressPublished:
+		return "100%"
+	}
+	return "-"
+}
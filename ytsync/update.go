@@ -0,0 +1,96 @@
+package ytsync
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/sources"
+)
+
+// metadataUpdater is a video that can update the claim it was published under, see
+// sources.YoutubeVideo.UpdateMetadata
+type metadataUpdater interface {
+	video
+	UpdateMetadata(*jsonrpc.Client, sources.SyncParams, jsonrpc.Claim) (*sources.SyncSummary, sources.ExistingState, error)
+}
+
+// updateMetadata goes over the claims the channel published and updates the ones whose video changed its title,
+// description or thumbnail on youtube since, instead of syncing new videos. The updates are sent in batches of as many
+// as the wallet has spendable outputs, waiting for a block between batches so the change of the previous one can be
+// spent. Updates stop once UpdateMetadataBudget is spent.
+func (s *Sync) updateMetadata() error {
+	if s.LbryChannelName == "" {
+		return errors.Err("the channel has no lbry channel name, its claims can't be listed")
+	}
+	claims, err := s.channelClaims()
+	if err != nil {
+		return err
+	}
+	local, err := s.localVideos()
+	if err != nil {
+		return err
+	}
+	claimsByVideo, _ := matchClaims(claims, local)
+	videos, err := s.fetchYoutubeVideos()
+	if err != nil {
+		return err
+	}
+
+	batchSize := s.Manager.UTXOTarget
+	if batchSize <= 0 {
+		batchSize = defaultUTXOTarget
+	}
+	budget := s.Manager.UpdateMetadataBudget
+	params := s.syncParams()
+	var updated, unchanged, inBatch int
+	var spent float64
+	for _, v := range s.VideoFilter.apply(videos) {
+		if s.IsInterrupted() {
+			break
+		}
+		published := claimsByVideo[v.ID()]
+		updater, ok := v.(metadataUpdater)
+		if !ok || len(published) != 1 {
+			// not published, or published more than once, which verify reports
+			continue
+		}
+		// the fee of the next update is assumed to be the average of the previous ones
+		if budget > 0 && updated > 0 && spent+spent/float64(updated) > budget {
+			s.notifyInfo("%s (%s): stopping the metadata updates, %.4f of the %.4f LBC budget were spent", s.LbryChannelName, s.YoutubeChannelID, spent, budget)
+			break
+		}
+		if inBatch == batchSize {
+			s.logger().Infof("updated a batch of %d claims, waiting for a block", inBatch)
+			err = s.waitForNewBlock()
+			if err != nil {
+				return err
+			}
+			inBatch = 0
+		}
+
+		started := time.Now()
+		params.Log = s.videoLogger(v.ID(), 1)
+		summary, state, err := updater.UpdateMetadata(s.daemon, params, published[0])
+		if err != nil {
+			s.stats.fail(v.ID())
+			s.addFailure(v.ID(), errors.Prefix("could not update the metadata of claim "+published[0].ClaimID, err))
+			s.reportProgress(v.ID(), ProgressFailed, started, err)
+			continue
+		}
+		s.stats.skip()
+		s.reportProgress(v.ID(), ProgressSkipped, started, nil)
+		if state == sources.PublishedSame {
+			unchanged++
+			continue
+		}
+		updated++
+		inBatch++
+		spent += summary.Fee
+		s.stats.spend(summary.Fee)
+		s.recordSpend(localdb.Spend{Kind: localdb.SpendUpdate, VideoID: v.ID(), ClaimID: published[0].ClaimID, Txid: summary.Txid, Fee: summary.Fee})
+	}
+	s.notifyInfo("%s (%s): updated the metadata of %d claims for %.4f LBC, %d were up to date", s.LbryChannelName, s.YoutubeChannelID, updated, spent, unchanged)
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package utxo makes sure a wallet has enough spendable outputs to publish claims concurrently. Every publish spends an
+// output, and its change can't be spent before it's confirmed, so a wallet holding its credits in a few large outputs
+// can only publish a few claims per block. The large outputs are split into many smaller ones before a sync starts.
+package utxo
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UTXO is an unspent output of the wallet
+type UTXO struct {
+	Txid      string
+	Nout      int
+	Amount    float64
+	Confirmed bool
+}
+
+// Wallet is the wallet whose outputs are split
+type Wallet interface {
+	// Spendable returns the unspent outputs the wallet can publish with: not claims, supports or updates
+	Spendable() ([]UTXO, error)
+	// Split sends n outputs of amount credits each to new addresses of the wallet, in a single transaction
+	Split(n int, amount float64) error
+}
+
+const (
+	defaultSlack          = 0.1
+	defaultPollInterval   = 10 * time.Second
+	defaultConfirmTimeout = 30 * time.Minute
+	feeAllowance          = 0.01 // fraction of the balance left out of the split, for the fee of the transaction
+)
+
+// ErrNotConfirmed is returned when the split outputs weren't confirmed in time
+var ErrNotConfirmed = errors.Base("split outputs not confirmed in time")
+
+// Manager splits the outputs of a wallet when there are too few of them
+type Manager struct {
+	Wallet Wallet
+
+	// MinAmount is the least an output must hold to pay for a publish. Smaller outputs aren't counted.
+	MinAmount float64
+	// Slack is the fraction of the target that can be missing before outputs are split. Defaults to 10%.
+	Slack float64
+	// PollInterval is how often the outputs are checked while waiting for them to be confirmed. Defaults to 10 seconds.
+	PollInterval time.Duration
+	// ConfirmTimeout is how long to wait for the outputs to be confirmed. Defaults to 30 minutes.
+	ConfirmTimeout time.Duration
+}
+
+// Count returns how many confirmed outputs holding at least MinAmount the wallet has, along with all of its spendable
+// outputs
+func (m *Manager) Count() (int, []UTXO, error) {
+	utxos, err := m.Wallet.Spendable()
+	if err != nil {
+		return 0, nil, err
+	}
+	count := 0
+	for _, u := range utxos {
+		if u.Confirmed && u.Amount > 0 && u.Amount >= m.MinAmount {
+			count++
+		}
+	}
+	return count, utxos, nil
+}
+
+// Ensure makes sure the wallet has about target usable outputs. If it has too few, its balance is split into target
+// outputs, or as many outputs of MinAmount as it can pay for, and Ensure waits until they are confirmed. It returns how
+// many outputs were created.
+func (m *Manager) Ensure(target int) (int, error) {
+	count, utxos, err := m.Count()
+	if err != nil {
+		return 0, err
+	}
+	slack := m.Slack
+	if slack <= 0 {
+		slack = defaultSlack
+	}
+	if count >= target-int(slack*float64(target)) {
+		if !allConfirmed(utxos) {
+			log.Println("waiting for the previous transactions of the wallet to confirm")
+			return 0, m.waitConfirmed()
+		}
+		return 0, nil
+	}
+
+	balance := 0.0
+	for _, u := range utxos {
+		balance += u.Amount
+	}
+	balance -= balance * feeAllowance
+	n := target
+	amount := balance / float64(target)
+	if m.MinAmount > 0 && amount < m.MinAmount {
+		n = int(balance / m.MinAmount)
+		amount = m.MinAmount
+	}
+	if n <= count {
+		log.Printf("the wallet only has %d usable outputs out of %d, but its balance can't be split further", count, target)
+		return 0, nil
+	}
+
+	log.Printf("the wallet only has %d usable outputs out of %d, splitting %.4f credits into %d outputs of %.4f", count, target, balance, n, amount)
+	err = m.Wallet.Split(n, amount)
+	if err != nil {
+		return 0, errors.Prefix("could not split the wallet outputs", err)
+	}
+	return n, m.waitConfirmed()
+}
+
+// waitConfirmed waits until every spendable output of the wallet is confirmed
+func (m *Manager) waitConfirmed() error {
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	timeout := m.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+
+	err := util.Poll(interval, timeout, func() (bool, error) {
+		utxos, err := m.Wallet.Spendable()
+		if err != nil {
+			return false, err
+		}
+		return allConfirmed(utxos), nil
+	})
+	if errors.Is(err, util.ErrPollTimeout) {
+		return errors.Err(ErrNotConfirmed)
+	}
+	return err
+}
+
+func allConfirmed(utxos []UTXO) bool {
+	for _, u := range utxos {
+		if !u.Confirmed {
+			return false
+		}
+	}
+	return true
+}
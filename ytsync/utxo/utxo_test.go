@@ -0,0 +1,110 @@
+package utxo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// fakeWallet confirms the outputs of a split after a number of checks
+type fakeWallet struct {
+	utxos  []UTXO
+	delay  int // how many checks before new outputs are confirmed
+	checks int
+	splits [][2]float64
+}
+
+func (w *fakeWallet) Spendable() ([]UTXO, error) {
+	w.checks++
+	if w.checks > w.delay {
+		for i := range w.utxos {
+			w.utxos[i].Confirmed = true
+		}
+	}
+	return append([]UTXO(nil), w.utxos...), nil
+}
+
+func (w *fakeWallet) Split(n int, amount float64) error {
+	w.splits = append(w.splits, [2]float64{float64(n), amount})
+	w.utxos = nil
+	for i := 0; i < n; i++ {
+		w.utxos = append(w.utxos, UTXO{Txid: "split", Nout: i, Amount: amount})
+	}
+	w.checks = 0
+	return nil
+}
+
+func newTestManager(delay int, amounts ...float64) (*Manager, *fakeWallet) {
+	w := &fakeWallet{delay: delay, checks: delay}
+	for i, a := range amounts {
+		w.utxos = append(w.utxos, UTXO{Txid: "tx", Nout: i, Amount: a, Confirmed: true})
+	}
+	m := &Manager{Wallet: w, PollInterval: time.Millisecond, ConfirmTimeout: time.Second}
+	return m, w
+}
+
+func TestEnoughOutputs(t *testing.T) {
+	amounts := make([]float64, 37)
+	for i := range amounts {
+		amounts[i] = 1
+	}
+	m, w := newTestManager(0, amounts...)
+	n, err := m.Ensure(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 || len(w.splits) != 0 {
+		t.Errorf("nothing should have been split, split %v", w.splits)
+	}
+}
+
+func TestSplitsLargeOutput(t *testing.T) {
+	m, w := newTestManager(3, 100)
+	n, err := m.Ensure(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 40 || len(w.splits) != 1 {
+		t.Fatalf("expected a split into 40 outputs, split %v", w.splits)
+	}
+	if w.splits[0][1] != 99.0/40 {
+		t.Errorf("expected outputs of %v, got %v", 99.0/40, w.splits[0][1])
+	}
+	if count, _, _ := m.Count(); count != 40 {
+		t.Errorf("expected 40 confirmed outputs, got %d", count)
+	}
+}
+
+func TestSplitLimitedByMinAmount(t *testing.T) {
+	m, w := newTestManager(0, 1)
+	m.MinAmount = 0.1
+	n, err := m.Ensure(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 9 || w.splits[0][1] != 0.1 {
+		t.Errorf("expected 9 outputs of 0.1, split %v", w.splits)
+	}
+}
+
+func TestSmallOutputsDontCount(t *testing.T) {
+	m, _ := newTestManager(0, 0.001, 0.001, 5)
+	m.MinAmount = 0.1
+	count, _, err := m.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 usable output, got %d", count)
+	}
+}
+
+func TestSplitNotConfirmed(t *testing.T) {
+	m, _ := newTestManager(1000, 100)
+	m.ConfirmTimeout = 20 * time.Millisecond
+	_, err := m.Ensure(40)
+	if !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed, got %v", err)
+	}
+}
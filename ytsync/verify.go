@@ -0,0 +1,347 @@
+package ytsync
+
+import (
+	"math"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/stop"
+	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+const (
+	// verifyPageSize is how many claims are listed per call to the daemon
+	verifyPageSize = 50
+	// lengthTolerance is how far off the length of a published video can be from the one youtube reports
+	lengthTolerance = 5 * time.Second
+)
+
+// youtubeLinkPattern finds the youtube link published videos have at the end of their description
+var youtubeLinkPattern = regexp.MustCompile(`youtube\.com/watch\?v=([a-zA-Z0-9_-]{11})`)
+
+// iso8601DurationPattern matches the durations the YouTube API returns, like PT1H2M3S
+var iso8601DurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// VerifyReport compares the videos of a youtube channel with the claims of its lbry channel
+type VerifyReport struct {
+	YoutubeChannelID string              `json:"youtube_channel_id"`
+	LbryChannelName  string              `json:"lbry_channel_name"`
+	Videos           int                 `json:"videos"`     // on youtube
+	Claims           int                 `json:"claims"`     // in the lbry channel
+	Missing          []string            `json:"missing"`    // IDs of the videos that have no claim
+	Duplicated       map[string][]string `json:"duplicated"` // claim IDs by video ID, for videos with more than one claim
+	Mismatched       []Mismatch          `json:"mismatched"`
+	Unknown          []string            `json:"unknown"`   // IDs of the claims that don't belong to any video
+	Unsigned         []UnsignedClaim     `json:"unsigned"`  // not signed by the channel claim, see UnsignedClaim
+	Addresses        map[string]int      `json:"addresses"` // how many claims of the channel each address holds
+}
+
+// Mismatch is a claim that doesn't match the youtube video it was published for
+type Mismatch struct {
+	VideoID string `json:"video_id"`
+	ClaimID string `json:"claim_id"`
+	Field   string `json:"field"` // title, thumbnail or length
+	Youtube string `json:"youtube"`
+	Lbry    string `json:"lbry"`
+}
+
+// Verify compares the videos of a youtube channel with the claims of the lbry channel they were published into. The
+// daemon at the default address resolves the claims, and the claims of its wallet are checked to be signed by the
+// channel claim. Nothing is published or changed, unless ResignClaims is set: the claims that aren't signed by the
+// channel claim are re-signed then.
+func (s SyncManager) Verify(channelID, lbryChannelName string) (*VerifyReport, error) {
+	if s.StateDir != "" {
+		var err error
+		s.localDB, err = localdb.Open(filepath.Join(s.StateDir, localDBFile))
+		if err != nil {
+			return nil, err
+		}
+		defer s.localDB.Close()
+	}
+	closeCache, err := s.openYoutubeCache()
+	if err != nil {
+		return nil, err
+	}
+	defer closeCache()
+	s.grp = stop.New(s.StopGroup)
+	defer s.grp.Stop()
+	s.youtubeQuota = NewQuotaTracker(s.YoutubeQuota)
+
+	channel := &Sync{
+		YoutubeAPIKey:    s.YoutubeAPIKey,
+		YoutubeChannelID: channelID,
+		LbryChannelName:  lbryChannelName,
+		Manager:          &s,
+		VideoFilter:      s.VideoFilter,
+		daemon:           jsonrpc.NewClient(""),
+		grp:              s.grp,
+		verifying:        true,
+	}
+	return channel.verify()
+}
+
+// verify matches the claims of the channel to the videos with the local state DB if there is one, and with the
+// youtube link in their description otherwise
+func (s *Sync) verify() (*VerifyReport, error) {
+	report := &VerifyReport{
+		YoutubeChannelID: s.YoutubeChannelID,
+		LbryChannelName:  s.LbryChannelName,
+		Duplicated:       make(map[string][]string),
+		Addresses:        make(map[string]int),
+	}
+
+	videos, err := s.fetchYoutubeVideos()
+	if err != nil {
+		return nil, err
+	}
+	report.Videos = len(videos)
+	ids := make([]string, len(videos))
+	for i, v := range videos {
+		ids[i] = v.ID()
+	}
+	service, err := s.youtubeService()
+	if err != nil {
+		return nil, err
+	}
+	lengths, err := s.videoLengths(service, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.channelClaims()
+	if err != nil {
+		return nil, err
+	}
+	report.Claims = len(claims)
+	for _, c := range claims {
+		report.Addresses[c.Address]++
+	}
+
+	local, err := s.localVideos()
+	if err != nil {
+		return nil, err
+	}
+	claimsByVideo, unknown := matchClaims(claims, local)
+	report.Unknown = append(report.Unknown, unknown...)
+
+	params := s.syncParams()
+	for _, v := range videos {
+		published := claimsByVideo[v.ID()]
+		if len(published) == 0 {
+			report.Missing = append(report.Missing, v.ID())
+			continue
+		}
+		if len(published) > 1 {
+			for _, c := range published {
+				report.Duplicated[v.ID()] = append(report.Duplicated[v.ID()], c.ClaimID)
+			}
+		}
+
+		title, thumbnail := v.Title(), ""
+		if pv, ok := v.(plannedVideo); ok {
+			plan := pv.Plan(params, make(map[string]bool))
+			title, thumbnail = plan.Title, plan.Thumbnail
+		}
+		for _, c := range published {
+			report.Mismatched = append(report.Mismatched, compareClaim(v.ID(), c, title, thumbnail, lengths[v.ID()], local[v.ID()])...)
+		}
+	}
+	claimed := make([]string, 0, len(claimsByVideo))
+	for videoID := range claimsByVideo {
+		claimed = append(claimed, videoID)
+	}
+	for _, videoID := range util.Difference(claimed, ids) {
+		for _, c := range claimsByVideo[videoID] {
+			report.Unknown = append(report.Unknown, c.ClaimID)
+		}
+	}
+
+	report.Unsigned, err = s.verifySignatures(videos)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unknown)
+	s.logger().Infof("%s: %d videos, %d claims on %d addresses, %d missing, %d duplicated, %d mismatches, %d unsigned", s.YoutubeChannelID, report.Videos, report.Claims, len(report.Addresses), len(report.Missing), len(report.Duplicated), len(report.Mismatched), len(report.Unsigned))
+	return report, nil
+}
+
+// verifySignatures returns the claims of the wallet that aren't signed by the channel claim it controls, re-signing
+// them with ResignClaims
+func (s *Sync) verifySignatures(videos []video) ([]UnsignedClaim, error) {
+	channelID, err := s.signingChannelID()
+	if err != nil {
+		return nil, err
+	}
+	if channelID == "" {
+		s.logger().Warnf("the wallet can't sign for %s, the signatures of the claims are not checked", s.LbryChannelName)
+		return nil, nil
+	}
+	unsigned, claims, err := s.unsignedClaims(channelID)
+	if err != nil || len(unsigned) == 0 || !s.Manager.ResignClaims {
+		return unsigned, err
+	}
+	s.lbryChannelID = channelID
+	resigned := s.resignClaims(unsigned, claims, videos)
+	s.logger().Infof("re-signed %d of the %d claims that were not signed by channel claim %s", resigned, len(unsigned), channelID)
+	return unsigned, nil
+}
+
+// compareClaim returns how the claim differs from what was expected to be published for the video. The length can
+// only be compared if the local state DB recorded it.
+func compareClaim(videoID string, c jsonrpc.Claim, title, thumbnail string, length time.Duration, local localdb.Video) []Mismatch {
+	var mismatches []Mismatch
+	mismatch := func(field, youtube, lbry string) {
+		mismatches = append(mismatches, Mismatch{VideoID: videoID, ClaimID: c.ClaimID, Field: field, Youtube: youtube, Lbry: lbry})
+	}
+
+	metadata := c.Value.GetStream().GetMetadata()
+	if metadata.GetTitle() != title {
+		mismatch("title", title, metadata.GetTitle())
+	}
+
+	published := metadata.GetThumbnail()
+	if published == "" || (thumbnail != "" && published != thumbnail) {
+		mismatch("thumbnail", thumbnail, published)
+	} else if err := checkThumbnail(published); err != nil {
+		mismatch("thumbnail", thumbnail, published+": "+err.Error())
+	}
+
+	if local.ClaimID == c.ClaimID && local.Duration > 0 && length > 0 {
+		publishedLength := time.Duration(local.Duration * float64(time.Second))
+		if time.Duration(math.Abs(float64(publishedLength-length))) > lengthTolerance {
+			mismatch("length", length.String(), publishedLength.String())
+		}
+	}
+	return mismatches
+}
+
+// checkThumbnail makes sure the thumbnail can be fetched
+func checkThumbnail(url string) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Head(url)
+	if err != nil {
+		return errors.Err(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Err("status code %d", res.StatusCode)
+	}
+	return nil
+}
+
+// channelClaims returns all the claims in the lbry channel
+func (s *Sync) channelClaims() ([]jsonrpc.Claim, error) {
+	var claims []jsonrpc.Claim
+	for page := uint64(1); ; page++ {
+		response, err := s.daemon.ClaimListByChannel(s.LbryChannelName, page, verifyPageSize)
+		if err != nil {
+			return nil, err
+		}
+		channel, ok := (*response)[s.LbryChannelName]
+		if !ok {
+			return nil, errors.Err("%s not in the response", s.LbryChannelName)
+		}
+		if channel.Error != "" {
+			return nil, errors.Err(channel.Error)
+		}
+		claims = append(claims, channel.ClaimsInChannel...)
+		if len(channel.ClaimsInChannel) < verifyPageSize {
+			return claims, nil
+		}
+	}
+}
+
+// localVideos returns the videos the local state DB has for the channel, none if there is no local state DB
+func (s *Sync) localVideos() (map[string]localdb.Video, error) {
+	if s.Manager.localDB == nil {
+		return make(map[string]localdb.Video), nil
+	}
+	return s.Manager.localDB.Videos(s.YoutubeChannelID)
+}
+
+// matchClaims matches the claims to the videos they hold, with the claim IDs the local state DB recorded for the
+// published videos, and with the youtube link in their description otherwise. It also returns the IDs of the claims
+// that don't belong to any video.
+func matchClaims(claims []jsonrpc.Claim, local map[string]localdb.Video) (map[string][]jsonrpc.Claim, []string) {
+	videoIDs := make(map[string]string) // by claim ID
+	for videoID, v := range local {
+		if v.Status == localdb.VideoStatusPublished {
+			videoIDs[v.ClaimID] = videoID
+		}
+	}
+	byVideo := make(map[string][]jsonrpc.Claim)
+	var unknown []string
+	for _, c := range claims {
+		videoID, ok := videoIDs[c.ClaimID]
+		if !ok {
+			match := youtubeLinkPattern.FindStringSubmatch(c.Value.GetStream().GetMetadata().GetDescription())
+			if match == nil {
+				unknown = append(unknown, c.ClaimID)
+				continue
+			}
+			videoID = match[1]
+		}
+		byVideo[videoID] = append(byVideo[videoID], c)
+	}
+	return byVideo, unknown
+}
+
+// videoLengths returns the length of the videos youtube reports
+func (s *Sync) videoLengths(service *youtube.Service, ids []string) (map[string]time.Duration, error) {
+	lengths := make(map[string]time.Duration)
+	for _, chunk := range util.Chunk(ids, maxVideosPerList) {
+		err := s.useQuota(listCost)
+		if err != nil {
+			return nil, err
+		}
+		response, err := service.Videos.List("contentDetails").Id(strings.Join(chunk, ",")).Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting video details", s.youtubeQuota().Observe(err))
+		}
+
+		for _, item := range response.Items {
+			if item.ContentDetails == nil {
+				continue
+			}
+			length, err := parseISO8601Duration(item.ContentDetails.Duration)
+			if err != nil {
+				s.logger().Warnf("%s: %s", item.Id, err.Error())
+				continue
+			}
+			lengths[item.Id] = length
+		}
+	}
+	return lengths, nil
+}
+
+// parseISO8601Duration parses durations like PT1H2M3S
+func parseISO8601Duration(duration string) (time.Duration, error) {
+	match := iso8601DurationPattern.FindStringSubmatch(duration)
+	if match == nil {
+		return 0, errors.Err("could not parse duration %q", duration)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if match[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, errors.Err(err)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
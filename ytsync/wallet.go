@@ -0,0 +1,101 @@
+package ytsync
+
+import (
+	"github.com/lbryio/lbry.go/errors"
+	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/metrics"
+	"github.com/lbryio/lbry.go/ytsync/credits"
+	"github.com/lbryio/lbry.go/ytsync/utxo"
+
+	"github.com/shopspring/decimal"
+)
+
+var walletBalance = metrics.NewGauge("ytsync_wallet_balance", "Credits in the wallet of each channel being synced", "channel_id")
+
+// daemonWallet is the wallet of the daemon, seen by the credits manager
+type daemonWallet struct {
+	daemon *jsonrpc.Client
+}
+
+func (w daemonWallet) Balance() (float64, error) {
+	balance, err := w.daemon.WalletBalance()
+	if err != nil {
+		return 0, err
+	} else if balance == nil {
+		return 0, errors.Err("no response")
+	}
+	b, _ := decimal.Decimal(*balance).Float64()
+	return b, nil
+}
+
+func (w daemonWallet) UnusedAddress() (string, error) {
+	address, err := w.daemon.WalletUnusedAddress()
+	if err != nil {
+		return "", err
+	} else if address == nil {
+		return "", errors.Err("no response")
+	}
+	return string(*address), nil
+}
+
+// Spendable returns the outputs that aren't claims, supports or updates
+func (w daemonWallet) Spendable() ([]utxo.UTXO, error) {
+	utxolist, err := w.daemon.UTXOList()
+	if err != nil {
+		return nil, err
+	} else if utxolist == nil {
+		return nil, errors.Err("no response")
+	}
+	var utxos []utxo.UTXO
+	for _, u := range *utxolist {
+		if u.IsClaim || u.IsSupport || u.IsUpdate {
+			continue
+		}
+		amount, _ := u.Amount.Float64()
+		utxos = append(utxos, utxo.UTXO{Txid: u.Txid, Nout: u.Nout, Amount: amount, Confirmed: u.Height > 0})
+	}
+	return utxos, nil
+}
+
+func (w daemonWallet) Split(n int, amount float64) error {
+	tx, err := w.daemon.WalletPrefillAddresses(n, decimal.NewFromFloat(amount), true)
+	if err != nil {
+		return err
+	} else if tx == nil {
+		return errors.Err("no response")
+	}
+	return nil
+}
+
+// lbrycrdSource sends credits from the lbrycrd wallet
+func (s *Sync) lbrycrdSource(address string, amount float64) (string, error) {
+	lbrycrdd, err := s.lbrycrdClient()
+	if err != nil {
+		return "", err
+	}
+	defer lbrycrdd.Shutdown()
+	hash, err := lbrycrdd.SimpleSend(address, amount)
+	if err != nil {
+		return "", errors.Categorize(err, errors.Blockchain, "")
+	}
+	return hash.String(), nil
+}
+
+// newCreditsManager returns the manager keeping the daemon wallet funded. Credits come from the manager's
+// CreditSource, or from lbrycrd if there is none.
+func (s *Sync) newCreditsManager() *credits.Manager {
+	var source credits.Source = credits.SourceFunc(s.lbrycrdSource)
+	if s.Manager.CreditSource != nil {
+		source = s.Manager.CreditSource
+	}
+	channelID := s.YoutubeChannelID
+	return &credits.Manager{
+		Wallet:    daemonWallet{daemon: s.daemon},
+		Source:    source,
+		Threshold: s.Manager.RefillThreshold,
+		OnBalance: func(balance float64) {
+			walletBalance.Set(balance, channelID)
+			s.reportBalance(balance)
+		},
+	}
+}
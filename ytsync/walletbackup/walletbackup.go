@@ -0,0 +1,307 @@
+// Package walletbackup keeps encrypted snapshots of the wallets of the synced channels on S3, so a wallet that gets lost
+// or corrupted can be restored from before the sync that broke it.
+package walletbackup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+const (
+	// KeySize is the length of the encryption key, AES-256
+	KeySize = 32
+	// DefaultPrefix is where the backups go in the bucket when Store.Prefix isn't set
+	DefaultPrefix = "wallet-backups"
+	// nameFormat is the format of the names of the backups, the time they were taken. They sort in time order.
+	nameFormat = "20060102T150405.000000000Z"
+	// certificateName is the name of the exported certificate of the channel claim, next to the backups of its wallet
+	certificateName = "certificate"
+)
+
+var (
+	// ErrNotFound is returned when there is no backup to restore
+	ErrNotFound = errors.Base("no wallet backup found")
+	// ErrNoCertificate is returned when no certificate was saved for the channel
+	ErrNoCertificate = errors.Base("no channel certificate found")
+)
+
+// Backup is a snapshot of the wallet of a channel
+type Backup struct {
+	ChannelID string    `json:"channel_id"`
+	Name      string    `json:"name"`
+	Time      time.Time `json:"time"`
+	Size      int64     `json:"size"` // of the encrypted backup
+}
+
+// Store keeps the backups in an S3 bucket, under Prefix/CHANNEL_ID/NAME. The certificate of the channel claim, if it
+// was saved, is under Prefix/CHANNEL_ID/certificate.
+type Store struct {
+	Key    []byte // encryption key, KeySize bytes. See ParseKey.
+	ID     string
+	Secret string
+	Region string
+	Bucket string
+	Prefix string // DefaultPrefix if empty
+	Keep   int    // how many backups of each channel are kept, the older ones are deleted. 0 keeps them all.
+}
+
+// ParseKey decodes a hex encoded key, like the output of `openssl rand -hex 32`
+func ParseKey(hexKey string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(hexKey))
+	if err != nil {
+		return nil, errors.Err("the wallet backup key is not hex encoded: %s", err.Error())
+	}
+	if len(key) != KeySize {
+		return nil, errors.Err("the wallet backup key must be %d bytes long, it's %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// Encrypt seals the wallet with AES-GCM. The random nonce is prepended to the result.
+func Encrypt(key, wallet []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return gcm.Seal(nonce, nonce, wallet, nil), nil
+}
+
+// Decrypt opens a backup sealed by Encrypt. It fails if the key is wrong or the backup was tampered with.
+func Decrypt(key, backup []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(backup) < gcm.NonceSize() {
+		return nil, errors.Err("the wallet backup is truncated")
+	}
+	nonce, sealed := backup[:gcm.NonceSize()], backup[gcm.NonceSize():]
+	wallet, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.Err("could not decrypt the wallet backup, wrong key or corrupted backup")
+	}
+	return wallet, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.Err("the wallet backup key must be %d bytes long, it's %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return gcm, nil
+}
+
+// backupName returns the name of a backup taken at t
+func backupName(t time.Time) string {
+	return t.UTC().Format(nameFormat)
+}
+
+// parseBackupName returns the time a backup was taken at, from its name
+func parseBackupName(name string) (time.Time, bool) {
+	t, err := time.Parse(nameFormat, name)
+	return t, err == nil
+}
+
+// Save encrypts the wallet of the channel and uploads it, then deletes the backups beyond Keep
+func (s *Store) Save(channelID string, wallet []byte) (Backup, error) {
+	backup := Backup{ChannelID: channelID, Time: time.Now().UTC()}
+	backup.Name = backupName(backup.Time)
+	encrypted, err := Encrypt(s.Key, wallet)
+	if err != nil {
+		return backup, err
+	}
+	backup.Size = int64(len(encrypted))
+
+	sess, err := s.session()
+	if err != nil {
+		return backup, err
+	}
+	_, err = s3manager.NewUploader(sess).Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(channelID, backup.Name)),
+		Body:   bytes.NewReader(encrypted),
+	})
+	if err != nil {
+		return backup, errors.Err(err)
+	}
+	return backup, s.prune(channelID)
+}
+
+// List returns the backups of the channel, oldest first
+func (s *Store) List(channelID string) ([]Backup, error) {
+	sess, err := s.session()
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	prefix := s.key(channelID, "")
+	err = s3.New(sess).ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(object.Key), prefix)
+			t, ok := parseBackupName(name)
+			if !ok {
+				continue
+			}
+			backups = append(backups, Backup{ChannelID: channelID, Name: name, Time: t, Size: aws.Int64Value(object.Size)})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// Fetch downloads and decrypts a backup of the channel. The latest one is fetched if name is empty.
+func (s *Store) Fetch(channelID, name string) (Backup, []byte, error) {
+	backup := Backup{ChannelID: channelID, Name: name}
+	if name == "" {
+		backups, err := s.List(channelID)
+		if err != nil {
+			return backup, nil, err
+		}
+		if len(backups) == 0 {
+			return backup, nil, errors.Prefix(channelID, ErrNotFound)
+		}
+		backup = backups[len(backups)-1]
+	} else if t, ok := parseBackupName(name); ok {
+		backup.Time = t
+	} else {
+		return backup, nil, errors.Err("%q is not the name of a wallet backup", name)
+	}
+
+	sess, err := s.session()
+	if err != nil {
+		return backup, nil, err
+	}
+	buf := aws.NewWriteAtBuffer(nil)
+	_, err = s3manager.NewDownloader(sess).Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(channelID, backup.Name)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return backup, nil, errors.Prefix(channelID+"/"+backup.Name, ErrNotFound)
+		}
+		return backup, nil, errors.Err(err)
+	}
+	backup.Size = int64(len(buf.Bytes()))
+	wallet, err := Decrypt(s.Key, buf.Bytes())
+	return backup, wallet, err
+}
+
+// SaveCertificate encrypts the exported certificate of the channel claim and uploads it, replacing the previous one. A
+// sync server without the wallet of the channel can then publish to it, see FetchCertificate.
+func (s *Store) SaveCertificate(channelID string, certificate []byte) error {
+	encrypted, err := Encrypt(s.Key, certificate)
+	if err != nil {
+		return err
+	}
+	sess, err := s.session()
+	if err != nil {
+		return err
+	}
+	_, err = s3manager.NewUploader(sess).Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(channelID, certificateName)),
+		Body:   bytes.NewReader(encrypted),
+	})
+	return errors.Err(err)
+}
+
+// FetchCertificate downloads and decrypts the certificate saved for the channel. It returns ErrNoCertificate if there
+// is none.
+func (s *Store) FetchCertificate(channelID string) ([]byte, error) {
+	sess, err := s.session()
+	if err != nil {
+		return nil, err
+	}
+	buf := aws.NewWriteAtBuffer(nil)
+	_, err = s3manager.NewDownloader(sess).Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.key(channelID, certificateName)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, errors.Prefix(channelID, ErrNoCertificate)
+		}
+		return nil, errors.Err(err)
+	}
+	return Decrypt(s.Key, buf.Bytes())
+}
+
+// prune deletes the oldest backups of the channel, keeping the last Keep ones
+func (s *Store) prune(channelID string) error {
+	if s.Keep <= 0 {
+		return nil
+	}
+	backups, err := s.List(channelID)
+	if err != nil || len(backups) <= s.Keep {
+		return err
+	}
+	sess, err := s.session()
+	if err != nil {
+		return err
+	}
+	client := s3.New(sess)
+	for _, b := range backups[:len(backups)-s.Keep] {
+		_, err = client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(s.Bucket),
+			Key:    aws.String(s.key(channelID, b.Name)),
+		})
+		if err != nil {
+			return errors.Err(err)
+		}
+	}
+	return nil
+}
+
+// key returns the S3 key of a backup of the channel, or the prefix of all of them if name is empty
+func (s *Store) key(channelID, name string) string {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return strings.Trim(prefix, "/") + "/" + channelID + "/" + name
+}
+
+func (s *Store) session() (*session.Session, error) {
+	creds := credentials.NewStaticCredentials(s.ID, s.Secret, "")
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(s.Region), Credentials: creds})
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return sess, nil
+}
@@ -0,0 +1,107 @@
+package walletbackup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = bytes.Repeat([]byte{7}, KeySize)
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey(strings.Repeat("07", KeySize) + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, testKey) {
+		t.Errorf("expected %x, got %x", testKey, key)
+	}
+
+	for _, invalid := range []string{"", "07", strings.Repeat("07", KeySize+1), strings.Repeat("zz", KeySize)} {
+		if _, err := ParseKey(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	wallet := []byte(`{"accounts": [], "seed": "not a real seed"}`)
+	encrypted, err := Encrypt(testKey, wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, []byte("seed")) {
+		t.Error("expected the wallet to be encrypted")
+	}
+	again, err := Encrypt(testKey, wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(encrypted, again) {
+		t.Error("expected every encryption to use a different nonce")
+	}
+
+	decrypted, err := Decrypt(testKey, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, wallet) {
+		t.Errorf("expected %q, got %q", wallet, decrypted)
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	encrypted, err := Encrypt(testKey, []byte("wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name   string
+		key    []byte
+		backup []byte
+	}{
+		{"wrong key", bytes.Repeat([]byte{8}, KeySize), encrypted},
+		{"short key", testKey[:16], encrypted},
+		{"tampered", testKey, tampered},
+		{"truncated", testKey, encrypted[:5]},
+		{"empty", testKey, nil},
+	}
+	for _, test := range tests {
+		if _, err := Decrypt(test.key, test.backup); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestBackupName(t *testing.T) {
+	at := time.Date(2018, 6, 1, 12, 30, 45, 123456789, time.FixedZone("EDT", -4*3600))
+	name := backupName(at)
+	if name != "20180601T163045.123456789Z" {
+		t.Errorf("unexpected name %s", name)
+	}
+	parsed, ok := parseBackupName(name)
+	if !ok || !parsed.Equal(at) {
+		t.Errorf("expected %s to parse back to %s, got %s", name, at, parsed)
+	}
+	if backupName(at) >= backupName(at.Add(time.Nanosecond)) {
+		t.Error("expected the names to sort in time order")
+	}
+	if _, ok := parseBackupName("default_wallet"); ok {
+		t.Error("expected other files to be ignored")
+	}
+}
+
+func TestKey(t *testing.T) {
+	s := Store{}
+	if k := s.key("UC123", "20180601T163045.123456789Z"); k != "wallet-backups/UC123/20180601T163045.123456789Z" {
+		t.Errorf("unexpected key %s", k)
+	}
+	s.Prefix = "/backups/"
+	if k := s.key("UC123", ""); k != "backups/UC123/" {
+		t.Errorf("unexpected prefix %s", k)
+	}
+}
@@ -0,0 +1,67 @@
+package ytsync
+
+import (
+	"net/http"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/lbryio/lbry.go/metrics"
+	"github.com/lbryio/lbry.go/ytsync/apicache"
+
+	"google.golang.org/api/googleapi/transport"
+)
+
+// youtubeCacheFile is the name of the file in the state dir where the responses of the YouTube API are cached
+const youtubeCacheFile = "youtube-cache.db"
+
+// uploadsCacheTTL caps how long listings of the videos of a channel are reused. They're only reused by dry runs and
+// verify, a sync must see the latest uploads.
+const uploadsCacheTTL = 10 * time.Minute
+
+var youtubeCacheHits = metrics.NewCounter("ytsync_youtube_cache_hits_total", "YouTube API calls answered from the response cache")
+
+// openYoutubeCache opens the response cache in the state dir if there is a state dir and YoutubeCacheTTL is set. The
+// returned function closes it.
+func (s *SyncManager) openYoutubeCache() (closeCache func(), err error) {
+	if s.StateDir == "" || s.YoutubeCacheTTL <= 0 {
+		return func() {}, nil
+	}
+	s.youtubeCache, err = apicache.Open(filepath.Join(s.StateDir, youtubeCacheFile), s.YoutubeCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	return func() { s.youtubeCache.Close() }, nil
+}
+
+// youtubeTransport returns the transport of the YouTube API clients of the channel. With a response cache, the calls
+// it answers don't count against the quota.
+func (s *Sync) youtubeTransport() http.RoundTripper {
+	apiKey := &transport.APIKey{Key: s.YoutubeAPIKey}
+	if s.Manager == nil || s.Manager.youtubeCache == nil {
+		return apiKey
+	}
+	return s.Manager.youtubeCache.Transport(apiKey, s.youtubeCacheTTL, func(*http.Request) {
+		youtubeCacheHits.Inc()
+		s.youtubeQuota().Refund(listCost)
+	})
+}
+
+// youtubeCacheTTL returns how long the response to a YouTube API request may be reused. Channels and videos change
+// rarely. The listings of the uploads of a channel are only reused when nothing is published from them.
+func (s *Sync) youtubeCacheTTL(req *http.Request) time.Duration {
+	ttl := s.Manager.YoutubeCacheTTL
+	switch path.Base(req.URL.Path) {
+	case "channels", "playlists", "videos":
+		return ttl
+	case "playlistItems":
+		if !s.DryRun && !s.verifying {
+			return 0
+		}
+		if ttl > uploadsCacheTTL {
+			return uploadsCacheTTL
+		}
+		return ttl
+	}
+	return 0
+}
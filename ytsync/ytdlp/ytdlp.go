@@ -0,0 +1,287 @@
+// Package ytdlp keeps a yt-dlp binary up to date, for the videos the built-in extractor can't download anymore when
+// youtube changes break it. Releases are checked against the SHA-256 checksums published with them, and the previous
+// release is kept to fall back on in case the latest one is broken.
+package ytdlp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultReleaseURL describes the latest release of yt-dlp
+	DefaultReleaseURL = "https://api.github.com/repos/yt-dlp/yt-dlp/releases/latest"
+
+	defaultUpdateInterval = 24 * time.Hour
+	// forcedUpdateInterval is how often Update looks for a new release after downloads failed
+	forcedUpdateInterval = time.Hour
+	assetName            = "yt-dlp_linux" // standalone binary, no python needed
+	checksumsName        = "SHA2-256SUMS"
+	binaryPrefix         = "yt-dlp-"
+	keepReleases         = 2
+)
+
+// ErrChecksumMismatch is returned when a downloaded release doesn't match its published checksum
+var ErrChecksumMismatch = errors.Base("checksum mismatch")
+
+// Manager downloads yt-dlp releases to Dir, as yt-dlp-TAG, and keeps the latest ones
+type Manager struct {
+	Dir string
+	// ReleaseURL describes the release to install, in the format of the GitHub API. Defaults to DefaultReleaseURL.
+	ReleaseURL string
+	// UpdateInterval is how often Binaries looks for a new release. Defaults to a day.
+	UpdateInterval time.Duration
+	HTTP           *http.Client
+
+	mux     sync.Mutex
+	checked time.Time // when the latest release was last looked up
+	forced  time.Time // when Update last looked it up
+}
+
+type release struct {
+	Tag    string `json:"tag_name"`
+	Assets []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r release) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// Binaries returns the yt-dlp binaries to try, the latest release first, followed by the one in the PATH if there is
+// one. The latest release is installed first if the last check is older than UpdateInterval. Failing to do so isn't an
+// error as long as there is a binary to try.
+func (m *Manager) Binaries() ([]string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	interval := m.UpdateInterval
+	if interval <= 0 {
+		interval = defaultUpdateInterval
+	}
+	var updateErr error
+	if time.Since(m.checked) > interval {
+		_, updateErr = m.update()
+		if updateErr != nil {
+			log.Warnf("could not update yt-dlp: %s", updateErr.Error())
+		}
+	}
+
+	binaries := m.installed()
+	if path, err := exec.LookPath("yt-dlp"); err == nil {
+		binaries = append(binaries, path)
+	}
+	if len(binaries) == 0 {
+		if updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, errors.Err("no yt-dlp binary available")
+	}
+	return binaries, nil
+}
+
+// Update installs the latest release if it isn't installed yet, e.g. because downloads started failing. It only looks
+// for it once an hour. It returns true if a new release was installed.
+func (m *Manager) Update() (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if time.Since(m.forced) < forcedUpdateInterval {
+		return false, nil
+	}
+	m.forced = time.Now()
+	return m.update()
+}
+
+// Status is which yt-dlp would be used, and whether it's the latest release
+type Status struct {
+	Binary  string `json:"binary,omitempty"`  // the binary Binaries returns first, empty if there is none
+	Version string `json:"version,omitempty"` // its release tag
+	Latest  string `json:"latest"`            // the tag of the latest release
+}
+
+// Current returns true if there is a binary and it's the latest release
+func (s Status) Current() bool {
+	return s.Binary != "" && s.Version == s.Latest
+}
+
+// Check looks up the latest release and returns whether the binary that would be used is that one, without installing
+// anything. The version of the yt-dlp in the PATH is asked to it.
+func (m *Manager) Check() (Status, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var status Status
+	if installed := m.installed(); len(installed) > 0 {
+		status.Binary = installed[0]
+		status.Version = strings.TrimPrefix(filepath.Base(installed[0]), binaryPrefix)
+	} else if path, err := exec.LookPath("yt-dlp"); err == nil {
+		status.Binary = path
+		out, err := exec.Command(path, "--version").Output()
+		if err != nil {
+			return status, errors.Prefix("could not get the version of "+path, err)
+		}
+		status.Version = strings.TrimSpace(string(out))
+	}
+	r, err := m.latestRelease()
+	if err != nil {
+		return status, err
+	}
+	status.Latest = r.Tag
+	return status, nil
+}
+
+// update installs the latest release if it isn't installed yet. mux must be held.
+func (m *Manager) update() (bool, error) {
+	m.checked = time.Now()
+	r, err := m.latestRelease()
+	if err != nil {
+		return false, err
+	}
+	path := filepath.Join(m.Dir, binaryPrefix+r.Tag)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	err = m.install(r, path)
+	if err != nil {
+		return false, err
+	}
+	log.Infof("installed yt-dlp %s", r.Tag)
+	m.prune()
+	return true, nil
+}
+
+func (m *Manager) latestRelease() (release, error) {
+	var r release
+	url := m.ReleaseURL
+	if url == "" {
+		url = DefaultReleaseURL
+	}
+	data, err := m.get(url)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(data, &r)
+	if err != nil {
+		return r, errors.Prefix("could not decode the yt-dlp release", err)
+	}
+	if r.Tag == "" || strings.ContainsAny(r.Tag, `/\`) {
+		return r, errors.Err("invalid yt-dlp release tag %q", r.Tag)
+	}
+	return r, nil
+}
+
+// install downloads the binary of the release to path, once it's verified
+func (m *Manager) install(r release, path string) error {
+	binaryURL, checksumsURL := r.assetURL(assetName), r.assetURL(checksumsName)
+	if binaryURL == "" || checksumsURL == "" {
+		return errors.Err("yt-dlp %s has no %s or %s", r.Tag, assetName, checksumsName)
+	}
+	checksums, err := m.get(checksumsURL)
+	if err != nil {
+		return err
+	}
+	binary, err := m.get(binaryURL)
+	if err != nil {
+		return err
+	}
+	err = verifyChecksum(binary, checksums, assetName)
+	if err != nil {
+		return errors.Prefix("yt-dlp "+r.Tag, err)
+	}
+
+	err = os.MkdirAll(m.Dir, 0755)
+	if err != nil {
+		return errors.Err(err)
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, binary, 0755)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(os.Rename(tmp, path))
+}
+
+// installed returns the installed releases, latest first
+func (m *Manager) installed() []string {
+	matches, _ := filepath.Glob(filepath.Join(m.Dir, binaryPrefix+"*"))
+	var binaries []string
+	for _, path := range matches {
+		if !strings.HasSuffix(path, ".tmp") {
+			binaries = append(binaries, path)
+		}
+	}
+	// the tags are dates, YYYY.MM.DD with an optional patch number
+	sort.Sort(sort.Reverse(sort.StringSlice(binaries)))
+	return binaries
+}
+
+// prune deletes the releases beyond the latest keepReleases
+func (m *Manager) prune() {
+	binaries := m.installed()
+	if len(binaries) <= keepReleases {
+		return
+	}
+	for _, path := range binaries[keepReleases:] {
+		err := os.Remove(path)
+		if err != nil {
+			log.Warnf("could not remove %s: %s", path, err.Error())
+		}
+	}
+}
+
+func (m *Manager) get(url string) ([]byte, error) {
+	client := m.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Err("GET %s returned status code %d", url, res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	return data, errors.Err(err)
+}
+
+// verifyChecksum checks data against the checksum of name in a sha256sum style list
+func verifyChecksum(data, checksums []byte, name string) error {
+	sum := sha256.Sum256(data)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return errors.Err(ErrChecksumMismatch)
+		}
+		return nil
+	}
+	return errors.Err("no checksum for %s", name)
+}
@@ -0,0 +1,141 @@
+package ytdlp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbry.go/errors"
+)
+
+// releaseServer serves a release with the given tag, whose binary matches its checksum unless corrupt is set
+func releaseServer(tag *string, corrupt bool) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		binary := "binary " + *tag
+		sum := sha256.Sum256([]byte(binary))
+		switch r.URL.Path {
+		case "/latest":
+			fmt.Fprintf(w, `{"tag_name": %q, "assets": [{"name": %q, "browser_download_url": "%s/binary"}, {"name": %q, "browser_download_url": "%s/sums"}]}`,
+				*tag, assetName, server.URL, checksumsName, server.URL)
+		case "/binary":
+			if corrupt {
+				binary += "!"
+			}
+			fmt.Fprint(w, binary)
+		case "/sums":
+			fmt.Fprintf(w, "0000  yt-dlp.exe\n%s  %s\n", hex.EncodeToString(sum[:]), assetName)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestManager_Update(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytdlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tag := "2024.01.01"
+	server := releaseServer(&tag, false)
+	defer server.Close()
+	m := &Manager{Dir: dir, ReleaseURL: server.URL + "/latest"}
+
+	for _, tag = range []string{"2024.01.01", "2024.02.01", "2024.03.01"} {
+		m.forced = m.forced.AddDate(0, 0, -1)
+		updated, err := m.Update()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated {
+			t.Errorf("release %s was not installed", tag)
+		}
+	}
+	updated, err := m.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated {
+		t.Error("updated again within the hour")
+	}
+
+	binaries := m.installed()
+	expected := []string{filepath.Join(dir, "yt-dlp-2024.03.01"), filepath.Join(dir, "yt-dlp-2024.02.01")}
+	if len(binaries) != len(expected) || binaries[0] != expected[0] || binaries[1] != expected[1] {
+		t.Fatalf("expected %v to be installed, got %v", expected, binaries)
+	}
+	data, err := ioutil.ReadFile(binaries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "binary 2024.03.01" {
+		t.Errorf("unexpected binary %q", data)
+	}
+}
+
+func TestManager_ChecksumMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytdlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tag := "2024.01.01"
+	server := releaseServer(&tag, true)
+	defer server.Close()
+	m := &Manager{Dir: dir, ReleaseURL: server.URL + "/latest"}
+
+	_, err = m.Update()
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if binaries := m.installed(); len(binaries) > 0 {
+		t.Errorf("a corrupted release was installed: %v", binaries)
+	}
+}
+
+func TestManager_Check(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytdlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tag := "2024.01.01"
+	server := releaseServer(&tag, false)
+	defer server.Close()
+	m := &Manager{Dir: dir, ReleaseURL: server.URL + "/latest"}
+	_, err = m.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := m.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Current() || status.Binary != filepath.Join(dir, "yt-dlp-2024.01.01") {
+		t.Errorf("expected the installed release to be current, got %+v", status)
+	}
+
+	tag = "2024.02.01"
+	status, err = m.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Current() || status.Version != "2024.01.01" || status.Latest != "2024.02.01" {
+		t.Errorf("expected the installed release to be outdated, got %+v", status)
+	}
+	if binaries := m.installed(); len(binaries) != 1 {
+		t.Errorf("expected Check not to install anything, got %v", binaries)
+	}
+}
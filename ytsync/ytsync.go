@@ -1,19 +1,16 @@
 package ytsync
 
 import (
-	"bufio"
-	"encoding/csv"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
-	"os/signal"
+	"path/filepath"
 	"sort"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -25,29 +22,41 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/lbryio/lbry.go/errors"
 	"github.com/lbryio/lbry.go/jsonrpc"
+	"github.com/lbryio/lbry.go/notify"
+	"github.com/lbryio/lbry.go/retry"
 	"github.com/lbryio/lbry.go/stop"
 	"github.com/lbryio/lbry.go/util"
+	"github.com/lbryio/lbry.go/ytsync/autoscale"
+	"github.com/lbryio/lbry.go/ytsync/credits"
+	"github.com/lbryio/lbry.go/ytsync/localdb"
+	"github.com/lbryio/lbry.go/ytsync/lock"
 	"github.com/lbryio/lbry.go/ytsync/redisdb"
+	"github.com/lbryio/lbry.go/ytsync/sdk"
 	"github.com/lbryio/lbry.go/ytsync/sources"
-	"github.com/mitchellh/go-ps"
 	log "github.com/sirupsen/logrus"
-	"google.golang.org/api/googleapi/transport"
 	"google.golang.org/api/youtube/v3"
 )
 
 const (
-	channelClaimAmount = 0.01
-	publishAmount      = 0.01
+	channelClaimAmount  = 0.01
+	publishAmount       = 0.01
+	publishFeeAllowance = 0.1 // credits set aside for the fees of each publish
+	defaultUTXOTarget   = 40  // spendable outputs a wallet is split into, see ensureEnoughUTXOs
+
+	shutdownWarningTimeout = 15 * time.Minute // how long an interrupted sync can take to stop before it's reported
 )
 
-type video interface {
-	ID() string
-	IDAndNum() string
-	PlaylistPosition() int
-	PublishedAt() time.Time
-	Sync(*jsonrpc.Client, string, float64, string, int) (*sources.SyncSummary, error)
+// neverRetryFailures are failure reasons for videos that can't ever be published
+var neverRetryFailures = []string{
+	"Error extracting sts from embedded url response",
+	"the video is too big to sync, skipping for now",
+	forcedFailureReason,
+	sources.ErrDuplicate.Error(),
 }
 
+// video is a video of the channel being synced, see sources.VideoSource
+type video = sources.Video
+
 // sorting videos
 type byPublishedAt []video
 
@@ -55,15 +64,26 @@ func (a byPublishedAt) Len() int           { return len(a) }
 func (a byPublishedAt) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a byPublishedAt) Less(i, j int) bool { return a[i].PublishedAt().Before(a[j].PublishedAt()) }
 
+type byPlaylistPosition []video
+
+func (a byPlaylistPosition) Len() int      { return len(a) }
+func (a byPlaylistPosition) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byPlaylistPosition) Less(i, j int) bool {
+	return a[i].PlaylistPosition() < a[j].PlaylistPosition()
+}
+
 // Sync stores the options that control how syncing happens
 type Sync struct {
 	YoutubeAPIKey           string
 	YoutubeChannelID        string
+	YoutubePlaylistID       string // if set, only the videos of this playlist of the channel are synced
 	LbryChannelName         string
+	LbryChannelClaimID      string
 	StopOnError             bool
 	MaxTries                int
 	ConcurrentVideos        int
-	TakeOverExistingChannel bool
+	ChannelConflictResolver sources.NameResolver // what to do if the channel name is held by someone else. skip if not set
+	VideoConflictResolver   sources.NameResolver // what to do if the claim name of a video is held by someone else
 	Refill                  int
 	Manager                 *SyncManager
 	LbrycrdString           string
@@ -71,48 +91,80 @@ type Sync struct {
 	AwsS3Secret             string
 	AwsS3Region             string
 	AwsS3Bucket             string
-
+	GenerateThumbnails      bool
+	ThumbnailTimestamp      time.Duration
+	MinimumBalance          float64
+	Pipeline                bool
+	PipelineBuffer          int
+	VerifyDownloads         bool
+	DryRun                  bool
+	VideoFilter             VideoFilter
+	Limits                  ChannelLimits             // set per channel through the API
+	Priority                int                       // set per channel through the API, higher priorities are synced first
+	ClaimAmounts            ClaimAmounts              // the bids, support and price of the claims
+	IncludeLivestreamVODs   bool                      // sync the recordings of finished livestreams
+	SyncCaptions            bool                      // host the captions of the videos and link them from their description
+	TranscodeProfile        *sources.TranscodeProfile // downloaded videos are transcoded to it before they are published, if set
+	MetadataTransform       sources.MetadataTransform // customizes the metadata of the published videos
+	Source                  sources.VideoSource       // where the videos come from, the youtube channel if not set
+
+	daemonSlot      daemonSlot
 	daemon          *jsonrpc.Client
 	claimAddress    string
 	videoDirectory  string
 	db              *redisdb.DB
-	syncedVideos    map[string]syncedVideo
+	syncedVideos    map[string]sdk.SyncedVideo
 	syncedVideosMux *sync.Mutex
 	grp             *stop.Group
 	lbryChannelID   string
-
-	walletMux *sync.Mutex
-	queue     chan video
+	branding        string // fingerprint of the branding last put in the channel claim, see updateBranding
+	credits         *credits.Manager
+	totalVideos     uint                 // according to the API, to schedule the channel
+	confirmations   *confirmationMonitor // nil if the sync doesn't wait for the claims to be confirmed
+	uploadsPlaylist string               // the uploads playlist of the channel and the ETag of its latest page, see uploadsChanged
+	uploadsETag     string
+
+	stats         *syncStats
+	progressFuncs []ProgressFunc
+	cancelled     int32
+	leaseLost     int32
+	lease         *lock.Lease // on the channel, if the manager has a lock service
+	verifying     bool        // the channel is only compared with youtube, see verify
+	takenOver     bool        // the channel name was outbid from someone else, see checkClaimSignatures
+	walletMux     *sync.Mutex
+	failures      *errors.MultiError // the videos that failed during the sync, reported together at the end
+	failuresMux   *sync.Mutex
+	triage        map[string][]string // IDs of the failed videos by root cause, see triage.go
+	videoEvents   []string            // notifications about single videos held back for the digest, see notifyVideoError
+	concurrency   *autoscale.Limiter  // of the videos processed at once, if it's scaled with the health of the daemon
+	unreflected   []string            // claim IDs whose blobs are kept until the reflector holds them, see cleanupBlobs
+	blobsMux      *sync.Mutex
+	queue         chan video
+	publishQueue  chan video
 }
 
 func (s *Sync) AppendSyncedVideo(videoID string, published bool, failureReason string) {
 	s.syncedVideosMux.Lock()
 	defer s.syncedVideosMux.Unlock()
-	s.syncedVideos[videoID] = syncedVideo{
+	s.syncedVideos[videoID] = sdk.SyncedVideo{
 		VideoID:       videoID,
 		Published:     published,
 		FailureReason: failureReason,
 	}
 }
 
-// SendErrorToSlack Sends an error message to the default channel and to the process log.
+// SendErrorToSlack Sends an error message to the registered notifiers (Slack or otherwise) and to the process log.
 func SendErrorToSlack(format string, a ...interface{}) error {
-	message := format
-	if len(a) > 0 {
-		message = fmt.Sprintf(format, a...)
-	}
+	message := formatMessage(format, a...)
 	log.Errorln(message)
-	return util.SendToSlack(":sos: " + message)
+	return notify.Error(message)
 }
 
-// SendInfoToSlack Sends an info message to the default channel and to the process log.
+// SendInfoToSlack Sends an info message to the registered notifiers (Slack or otherwise) and to the process log.
 func SendInfoToSlack(format string, a ...interface{}) error {
-	message := format
-	if len(a) > 0 {
-		message = fmt.Sprintf(format, a...)
-	}
+	message := formatMessage(format, a...)
 	log.Infoln(message)
-	return util.SendToSlack(":information_source: " + message)
+	return notify.Info(message)
 }
 
 // IsInterrupted can be queried to discover if the sync process was interrupted manually
@@ -125,15 +177,55 @@ func (s *Sync) IsInterrupted() bool {
 	}
 }
 
-func (s *Sync) downloadWallet() error {
-	defaultWalletDir := os.Getenv("HOME") + "/.lbryum/wallets/default_wallet"
-	defaultTempWalletDir := os.Getenv("HOME") + "/.lbryum/wallets/tmp_wallet"
-	key := aws.String("/wallets/" + s.YoutubeChannelID)
+// checkpoint returns util.ErrWaitCancelled if the sync was interrupted. It's checked before each step of a video that
+// takes a while, the video is left pending for the next run then.
+func (s *Sync) checkpoint() error {
+	if s.IsInterrupted() {
+		return errors.Err(util.ErrWaitCancelled)
+	}
+	return nil
+}
+
+// Cancel stops the sync of this channel only. The channel goes back to the queue.
+func (s *Sync) Cancel() {
+	atomic.StoreInt32(&s.cancelled, 1)
+	s.grp.Stop()
+}
+
+// IsCancelled can be queried to discover if the sync of this channel was cancelled by itself
+func (s *Sync) IsCancelled() bool {
+	return atomic.LoadInt32(&s.cancelled) == 1
+}
+
+// stoppedFromOutside returns true if the sync was cancelled or the manager is shutting down, rather than stopped by an
+// error of its own. The channel goes back to the queue then, instead of being marked as failed.
+func (s *Sync) stoppedFromOutside() bool {
+	if !s.IsInterrupted() || s.grp.Err() != nil {
+		return false
+	}
+	if s.IsCancelled() {
+		return true
+	}
+	select {
+	case <-s.Manager.grp.Ch():
+		return true
+	default:
+		return false
+	}
+}
+
+// walletKey returns the S3 key the wallet of a channel is kept under between syncs
+func walletKey(channelID string) *string {
 	if os.Getenv("REGTEST") == "true" {
-		defaultWalletDir = os.Getenv("HOME") + "/.lbryum_regtest/wallets/default_wallet"
-		defaultTempWalletDir = os.Getenv("HOME") + "/.lbryum_regtest/wallets/tmp_wallet"
-		key = aws.String("/regtest/" + s.YoutubeChannelID)
+		return aws.String("/regtest/" + channelID)
 	}
+	return aws.String("/wallets/" + channelID)
+}
+
+func (s *Sync) downloadWallet() error {
+	walletDir, defaultWalletDir := s.walletPaths()
+	defaultTempWalletDir := walletDir + "/tmp_wallet"
+	key := walletKey(s.YoutubeChannelID)
 
 	if _, err := os.Stat(defaultWalletDir); !os.IsNotExist(err) {
 		return errors.Err("default_wallet already exists")
@@ -179,64 +271,112 @@ func (s *Sync) downloadWallet() error {
 }
 
 func (s *Sync) uploadWallet() error {
-	defaultWalletDir := os.Getenv("HOME") + "/.lbryum/wallets/default_wallet"
-	key := aws.String("/wallets/" + s.YoutubeChannelID)
-	if os.Getenv("REGTEST") == "true" {
-		defaultWalletDir = os.Getenv("HOME") + "/.lbryum_regtest/wallets/default_wallet"
-		key = aws.String("/regtest/" + s.YoutubeChannelID)
-	}
-
+	_, defaultWalletDir := s.walletPaths()
 	if _, err := os.Stat(defaultWalletDir); os.IsNotExist(err) {
 		return errors.Err("default_wallet does not exist")
 	}
 
-	creds := credentials.NewStaticCredentials(s.AwsS3ID, s.AwsS3Secret, "")
-	s3Session, err := session.NewSession(&aws.Config{Region: aws.String(s.AwsS3Region), Credentials: creds})
+	// a server that lost the lock on the channel must not overwrite the wallet of the one syncing it now
+	err := s.checkLease()
 	if err != nil {
 		return err
 	}
-
-	uploader := s3manager.NewUploader(s3Session)
-
 	file, err := os.Open(defaultWalletDir)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	err = s.uploadWalletOf(s.YoutubeChannelID, file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	s.clearWalletOwner()
+	return os.Remove(defaultWalletDir)
+}
+
+// uploadWalletOf uploads a wallet as the wallet of the channel, replacing the one on S3
+func (s *Sync) uploadWalletOf(channelID string, wallet io.Reader) error {
+	key := walletKey(channelID)
+	creds := credentials.NewStaticCredentials(s.AwsS3ID, s.AwsS3Secret, "")
+	s3Session, err := session.NewSession(&aws.Config{Region: aws.String(s.AwsS3Region), Credentials: creds})
+	if err != nil {
+		return err
+	}
 
+	uploader := s3manager.NewUploader(s3Session)
 	_, err = uploader.Upload(&s3manager.UploadInput{
 		Bucket: aws.String(s.AwsS3Bucket),
 		Key:    key,
-		Body:   file,
+		Body:   wallet,
 	})
-	if err != nil {
-		return err
-	}
+	return err
+}
 
-	return os.Remove(defaultWalletDir)
+// resetState prepares the state of the sync before it starts
+func (s *Sync) resetState() {
+	s.syncedVideosMux = &sync.Mutex{}
+	s.walletMux = &sync.Mutex{}
+	s.failures = &errors.MultiError{}
+	s.failuresMux = &sync.Mutex{}
+	s.triage = make(map[string][]string)
+	s.unreflected = nil
+	s.blobsMux = &sync.Mutex{}
+	s.videoEvents = nil
+	s.uploadsPlaylist, s.uploadsETag = "", ""
+	s.lease = nil
+	if s.Manager.DebugGoroutines {
+		s.grp = stop.NewDebug("channel "+s.YoutubeChannelID, s.Manager.grp)
+	} else {
+		s.grp = stop.New(s.Manager.grp)
+	}
+	atomic.StoreInt32(&s.cancelled, 0)
+	s.queue = make(chan video)
+	s.publishQueue = nil
 }
 
 func (s *Sync) FullCycle() (e error) {
+	s.stats = newSyncStats()
+	defer channelLogs.close(s.YoutubeChannelID)
 	if os.Getenv("HOME") == "" {
 		return errors.Err("no $HOME env var found")
 	}
 	if s.YoutubeChannelID == "" {
 		return errors.Err("channel ID not provided")
 	}
-	s.syncedVideosMux = &sync.Mutex{}
-	s.walletMux = &sync.Mutex{}
+	s.resetState()
+	defer s.reportFailures()
 	s.db = redisdb.New()
-	s.grp = stop.New()
-	s.queue = make(chan video)
-	interruptChan := make(chan os.Signal, 1)
-	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(interruptChan)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		<-interruptChan
-		log.Println("Got interrupt signal, shutting down (if publishing, will shut down after current publish)")
+		select {
+		case <-s.Manager.grp.Ch():
+		case <-done:
+			return
+		}
+		s.logger().Println("Shutting down (if publishing, will shut down after current publish)")
 		s.grp.Stop()
 	}()
-	syncedVideos, err := s.Manager.setChannelStatus(s.YoutubeChannelID, StatusSyncing)
+
+	s.Manager.running.add(s)
+	defer s.Manager.running.remove(s)
+
+	if s.DryRun {
+		return s.dryRun()
+	}
+
+	err := s.preflightWallet()
+	if err != nil {
+		return err
+	}
+
+	releaseLock, err := s.acquireLock()
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	syncedVideos, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusSyncing, nil)
 	if err != nil {
 		return err
 	}
@@ -245,75 +385,173 @@ func (s *Sync) FullCycle() (e error) {
 	s.syncedVideosMux.Unlock()
 
 	defer s.updateChannelStatus(&e)
+	stopRenewing := s.renewLease()
+	defer stopRenewing()
+
+	if s.Manager.estimating() {
+		estimate, err := s.estimateSync()
+		if err != nil {
+			return err
+		}
+		s.notifyInfo("Syncing %s (%s): %s", s.LbryChannelName, s.YoutubeChannelID, estimate)
+		err = s.checkBudgets(estimate)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = s.downloadWallet()
 	if err != nil && err.Error() != "wallet not on S3" {
 		return errors.Prefix("failure in downloading wallet: ", err)
 	} else if err == nil {
-		log.Println("Continuing previous upload")
+		s.logger().Println("Continuing previous upload")
+		s.backupWallet()
 	} else {
-		log.Println("Starting new wallet")
+		s.logger().Println("Starting new wallet")
+	}
+	err = s.markWalletOwner()
+	if err != nil {
+		return err
 	}
 
 	defer s.stopAndUploadWallet(&e)
 
-	s.videoDirectory, err = ioutil.TempDir("", "ytsync")
+	err = s.makeVideoDirectory()
 	if err != nil {
-		return errors.Wrap(err, 0)
+		return err
 	}
+	defer s.removeVideoDirectory(&e)
 
-	log.Printf("Starting daemon")
-	err = startDaemonViaSystemd()
+	err = s.startDaemon()
 	if err != nil {
 		return err
 	}
+	s.credits = s.newCreditsManager()
 
-	log.Infoln("Waiting for daemon to finish starting...")
-	s.daemon = jsonrpc.NewClient("")
-	s.daemon.SetRPCTimeout(40 * time.Minute)
-
-	err = s.waitForDaemonStart()
+	err = s.preflightDaemonWallet()
 	if err != nil {
 		return err
 	}
 
+	if s.LbryChannelClaimID != "" {
+		// the ownership of the channel claim is checked by walletSetup, first thing in doSync
+		err = s.importCertificate()
+		if err != nil {
+			return err
+		}
+	}
+
 	err = s.doSync()
 	if err != nil {
 		return err
-	} else {
+	} else if !s.IsInterrupted() {
+		s.checkClaimSignatures()
 		// wait for reflection to finish???
 		wait := 15 * time.Second // should bump this up to a few min, but keeping it low for testing
-		log.Println("Waiting " + wait.String() + " to finish reflecting everything")
+		s.logger().Println("Waiting " + wait.String() + " to finish reflecting everything")
 		time.Sleep(wait)
+		s.cleanupUnreflectedBlobs()
 	}
 
 	return nil
 }
 func (s *Sync) updateChannelStatus(e *error) {
+	if s.isLeaseLost() {
+		// the channel belongs to another server now
+		return
+	}
+	if s.stoppedFromOutside() || errors.Is(*e, errDaemonUnavailable) {
+		// cancelled, shutting down or failed over to another daemon, the channel goes back to the queue to be picked up
+		// again
+		_, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusQueued, s.syncProgress())
+		if err != nil {
+			msg := fmt.Sprintf("Failed setting queued state for channel %s.", s.LbryChannelName)
+			err = errors.Prefix(msg, err)
+			if *e != nil {
+				err = errors.Prefix(err.Error(), *e)
+			}
+			*e = err
+		}
+		return
+	}
+	if errors.Is(*e, errSpendingCap) {
+		s.notifyInfo("%s (%s) stopped publishing: %s", s.LbryChannelName, s.YoutubeChannelID, (*e).Error())
+		_, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusOverCap, s.syncProgress())
+		if err != nil {
+			*e = errors.Prefix(fmt.Sprintf("Failed setting %s state for channel %s: %s.", StatusOverCap, s.LbryChannelName, err.Error()), *e)
+		}
+		return
+	}
 	if *e != nil {
 		//conditions for which a channel shouldn't be marked as failed
 		noFailConditions := []string{
-			"this youtube channel is being managed by another server",
+			errManagedElsewhere,
 		}
 		if util.SubstringInSlice((*e).Error(), noFailConditions) {
 			return
 		}
-		_, err := s.Manager.setChannelStatus(s.YoutubeChannelID, StatusFailed)
+		_, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusFailed, s.syncProgress())
 		if err != nil {
 			msg := fmt.Sprintf("Failed setting failed state for channel %s.", s.LbryChannelName)
 			err = errors.Prefix(msg, err)
 			*e = errors.Prefix(err.Error(), *e)
 		}
-	} else if !s.IsInterrupted() {
-		_, err := s.Manager.setChannelStatus(s.YoutubeChannelID, StatusSynced)
+	} else {
+		_, err := s.Manager.APIConfig.SetChannelStatus(s.YoutubeChannelID, StatusSynced, s.syncProgress())
 		if err != nil {
 			*e = err
 		}
 	}
 }
 
-func (s *Sync) waitForDaemonStart() error {
+// makeVideoDirectory creates the directory videos are downloaded to, in the staging storage if there is one. With a
+// staging storage or a state dir, it's the same for every run of the channel, so downloads interrupted by a crash or a
+// shutdown can be resumed by the next run.
+func (s *Sync) makeVideoDirectory() error {
+	if s.Manager.Staging != nil {
+		var err error
+		s.videoDirectory, err = s.Manager.Staging.Dir(s.YoutubeChannelID)
+		return err
+	}
+	if s.Manager.StateDir == "" {
+		var err error
+		s.videoDirectory, err = ioutil.TempDir("", "ytsync")
+		return errors.Err(err)
+	}
+	s.videoDirectory = filepath.Join(s.Manager.StateDir, "downloads", s.YoutubeChannelID)
+	return errors.Err(os.MkdirAll(s.videoDirectory, 0750))
+}
 
+// removeVideoDirectory removes the directory videos are downloaded to, unless the sync was interrupted and there is a
+// staging storage or a state dir, in which case the partial downloads are kept for the next run
+func (s *Sync) removeVideoDirectory(e *error) {
+	if (s.Manager.Staging != nil || s.Manager.StateDir != "") && (s.IsInterrupted() || errors.Is(*e, errDaemonUnavailable)) {
+		s.logger().Infof("keeping the partial downloads in %s", s.videoDirectory)
+		return
+	}
+	_ = os.RemoveAll(s.videoDirectory)
+}
+
+// startDaemon starts the daemon of the slot and waits for it to be ready. If it can't be started, errDaemonUnavailable
+// is returned so that the channel can be synced with another daemon.
+func (s *Sync) startDaemon() error {
+	s.logger().Printf("Starting daemon")
+	err := startDaemonViaSystemd(s.daemonSlot)
+	if err != nil {
+		s.logger().Errorf("could not start %s: %s", s.daemonSlot.unit(), err.Error())
+		return errors.Err(errDaemonUnavailable)
+	}
+
+	s.logger().Infoln("Waiting for daemon to finish starting...")
+	s.daemon = jsonrpc.NewClient(s.Manager.daemonAddress(s.daemonSlot))
+	s.daemon.SetRPCTimeout(40 * time.Minute)
+	return s.waitForDaemonStart()
+}
+
+// waitForDaemonStart waits for the wallet of the daemon to be ready. It returns errDaemonUnavailable if that takes
+// longer than daemonStartTimeout.
+func (s *Sync) waitForDaemonStart() error {
+	deadline := time.Now().Add(daemonStartTimeout)
 	for {
 		select {
 		case <-s.grp.Ch():
@@ -323,38 +561,41 @@ func (s *Sync) waitForDaemonStart() error {
 			if err == nil && s.StartupStatus.Wallet {
 				return nil
 			}
+			if time.Now().After(deadline) {
+				return errors.Err(errDaemonUnavailable)
+			}
 			time.Sleep(5 * time.Second)
 		}
 	}
 }
 func (s *Sync) stopAndUploadWallet(e *error) {
-	log.Printf("Stopping daemon")
-	shutdownErr := stopDaemonViaSystemd()
+	s.logger().Printf("Stopping daemon")
+	shutdownErr := stopDaemonViaSystemd(s.daemonSlot)
 	if shutdownErr != nil {
-		logShutdownError(shutdownErr)
+		s.logShutdownError(shutdownErr)
 	} else {
 		// the cli will return long before the daemon effectively stops. we must observe the processes running
 		// before moving the wallet
 		waitTimeout := 8 * time.Minute
-		processDeathError := waitForDaemonProcess(waitTimeout)
+		processDeathError := waitForDaemonProcess(s.daemonSlot, waitTimeout)
 		if processDeathError != nil {
-			logShutdownError(processDeathError)
+			s.logShutdownError(processDeathError)
 		} else {
 			err := s.uploadWallet()
 			if err != nil {
+				// reported even if the sync went well, or the wallet on S3 is left behind without anyone noticing
 				if *e == nil {
-					e = &err
-					return
+					*e = errors.Prefix("failure uploading wallet", err)
 				} else {
-					*e = errors.Prefix("failure uploading wallet: ", *e)
+					*e = errors.Prefix("failure uploading wallet: "+err.Error(), *e)
 				}
 			}
 		}
 	}
 }
-func logShutdownError(shutdownErr error) {
-	SendErrorToSlack("error shutting down daemon: %v", shutdownErr)
-	SendErrorToSlack("WALLET HAS NOT BEEN MOVED TO THE WALLET BACKUP DIR")
+func (s *Sync) logShutdownError(shutdownErr error) {
+	s.notifyError("error shutting down daemon: %v", shutdownErr)
+	s.notifyError("the wallet of %s is still in the wallet dir, the next sync will put it back on S3 once the daemon is stopped", s.YoutubeChannelID)
 }
 
 func (s *Sync) doSync() error {
@@ -364,148 +605,271 @@ func (s *Sync) doSync() error {
 	if err != nil {
 		return errors.Prefix("Initial wallet setup failed! Manual Intervention is required.", err)
 	}
+	s.exportCertificate()
+	s.updateBranding()
+	if s.Manager.UpdateMetadata {
+		return s.updateMetadata()
+	}
+
+	if len(s.Manager.RetryFailed) > 0 {
+		retrying, err := s.selectFailedVideos()
+		if err != nil {
+			return err
+		}
+		if retrying == 0 {
+			return nil
+		}
+	}
 
 	if s.StopOnError {
-		log.Println("Will stop publishing if an error is detected")
+		s.logger().Println("Will stop publishing if an error is detected")
 	}
 
-	for i := 0; i < s.ConcurrentVideos; i++ {
-		s.grp.Add(1)
-		go func() {
-			defer s.grp.Done()
-			s.startWorker(i)
-		}()
+	if s.Pipeline {
+		s.startPipeline()
 	}
 
-	if s.LbryChannelName == "@UCBerkeley" {
-		err = s.enqueueUCBVideos()
-	} else {
-		err = s.enqueueYoutubeVideos()
+	s.startConfirmationMonitor()
+	workers := s.startAutoscaling()
+	for i := 0; i < workers; i++ {
+		workerNum := i
+		s.grp.GoErr(func() error {
+			return s.startWorker(workerNum)
+		})
 	}
+
+	err = s.enqueueVideos(s.videoSource())
 	close(s.queue)
-	s.grp.Wait()
+	workerErr := s.waitForWorkers()
+	s.drainPublishQueue()
+	confirmErr := s.waitForConfirmations()
+	if err == nil {
+		err = workerErr
+	}
+	if err == nil {
+		err = confirmErr
+	}
+	if err == nil {
+		s.saveUploadsETag()
+	}
 	return err
 }
 
-func (s *Sync) startWorker(workerNum int) {
+// waitForWorkers waits for the goroutines of the sync to return and returns the first error of a worker. If they're
+// still running shutdownWarningTimeout after the sync was stopped, the stuck ones are reported.
+func (s *Sync) waitForWorkers() error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.grp.WaitErr()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-s.grp.Ch():
+	}
+	if s.grp.WaitTimeout(shutdownWarningTimeout) != nil {
+		stuck := "run with --debug-goroutines to find out which goroutines are stuck"
+		if s.Manager.DebugGoroutines {
+			stuck = "stuck goroutines:\n" + s.grp.DumpRunning()
+		}
+		s.notifyError("%s is still shutting down after %s, %s", s.YoutubeChannelID, shutdownWarningTimeout, stuck)
+	}
+	return <-done
+}
+
+// startWorker publishes videos from the queue until it's empty or the sync is stopped. It returns an error if a video
+// failed in a way that should stop the whole sync.
+func (s *Sync) startWorker(workerNum int) error {
 	var v video
 	var more bool
 
+	queue := s.queue
+	if s.publishQueue != nil {
+		queue = s.publishQueue
+	}
+
 	for {
 		select {
 		case <-s.grp.Ch():
-			log.Printf("Stopping worker %d", workerNum)
-			return
+			s.logger().Printf("Stopping worker %d", workerNum)
+			return nil
 		default:
 		}
 
 		select {
-		case v, more = <-s.queue:
+		case v, more = <-queue:
 			if !more {
-				return
+				return nil
 			}
 		case <-s.grp.Ch():
-			log.Printf("Stopping worker %d", workerNum)
-			return
+			s.logger().Printf("Stopping worker %d", workerNum)
+			return nil
+		}
+
+		if s.grp.IsPaused() {
+			s.logger().Printf("Worker %d is waiting for the channel to be resumed", workerNum)
+		}
+		err := s.grp.WaitIfPaused()
+		if err != nil {
+			s.logger().Printf("Stopping worker %d", workerNum)
+			return nil
 		}
 
-		log.Println("================================================================================")
+		if !s.acquireVideoSlot() {
+			s.logger().Printf("Stopping worker %d", workerNum)
+			return nil
+		}
 
-		tryCount := 0
-		for {
-			tryCount++
-			err := s.processVideo(v)
+		s.logger().Println("================================================================================")
 
+		started := time.Now()
+		attempt := 0
+		err = s.videoRetryPolicy().Do(s.grp.Ch(), func() error {
+			attempt++
+			err := s.processVideo(v, attempt)
+			if err != nil && !errors.Is(err, util.ErrWaitCancelled) {
+				s.videoLogger(v.ID(), attempt).Errorln("error processing video: " + err.Error())
+			}
+			return err
+		})
+		s.releaseVideoSlot()
+		if p, ok := v.(*prefetchedVideo); ok {
+			p.discard()
+		}
+		if err != nil {
+			failure := err.(*retry.Error)
+			// a transient failure isn't retried once the sync is stopping, the next run retries it instead
+			if errors.Is(err, util.ErrWaitCancelled) || (failure.Class == retry.Transient && s.IsInterrupted()) {
+				s.videoLogger(v.ID(), attempt).Printf("%s was not processed, the sync is stopping. It's left pending for the next run", v.ID())
+				continue
+			}
+			err = s.handleVideoFailure(v, started, failure)
 			if err != nil {
-				logMsg := fmt.Sprintf("error processing video: " + err.Error())
-				log.Errorln(logMsg)
-				fatalErrors := []string{
-					":5279: read: connection reset by peer",
-					"no space left on device",
-					"NotEnoughFunds",
-					"Cannot publish using channel",
-					"cannot concatenate 'str' and 'NoneType' objects",
-					"more than 90% of the space has been used.",
-				}
-				if util.SubstringInSlice(err.Error(), fatalErrors) || s.StopOnError {
-					s.grp.Stop()
-				} else if s.MaxTries > 1 {
-					errorsNoRetry := []string{
-						"non 200 status code received",
-						" reason: 'This video contains content from",
-						"dont know which claim to update",
-						"uploader has not made this video available in your country",
-						"download error: AccessDenied: Access Denied",
-						"Playback on other websites has been disabled by the video owner",
-						"Error in daemon: Cannot publish empty file",
-						"Error extracting sts from embedded url response",
-						"Client.Timeout exceeded while awaiting headers)",
-						"the video is too big to sync, skipping for now",
-					}
-					if util.SubstringInSlice(err.Error(), errorsNoRetry) {
-						log.Println("This error should not be retried at all")
-					} else if tryCount < s.MaxTries {
-						if strings.Contains(err.Error(), "txn-mempool-conflict") ||
-							strings.Contains(err.Error(), "too-long-mempool-chain") {
-							log.Println("waiting for a block before retrying")
-							err = s.waitForNewBlock()
-							if err != nil {
-								s.grp.Stop()
-								SendErrorToSlack("something went wrong while waiting for a block: %v", err)
-								break
-							}
-						} else if strings.Contains(err.Error(), "failed: Not enough funds") ||
-							strings.Contains(err.Error(), "Error in daemon: Insufficient funds, please deposit additional LBC") {
-							log.Println("refilling addresses before retrying")
-							err = s.walletSetup()
-							if err != nil {
-								s.grp.Stop()
-								SendErrorToSlack("failed to setup the wallet for a refill: %v", err)
-								break
-							}
-						}
-						log.Println("Retrying")
-						continue
-					}
-					SendErrorToSlack("Video failed after %d retries, skipping. Stack: %s", tryCount, logMsg)
-				}
-				s.AppendSyncedVideo(v.ID(), false, err.Error())
-				err = s.Manager.MarkVideoStatus(s.YoutubeChannelID, v.ID(), VideoStatusFailed, "", "", err.Error())
-				if err != nil {
-					SendErrorToSlack("Failed to mark video on the database: %s", err.Error())
-				}
+				return err
 			}
-			break
 		}
 	}
 }
 
-func (s *Sync) enqueueYoutubeVideos() error {
+// handleVideoFailure records a video that failed for good. It returns an error if the sync should stop.
+func (s *Sync) handleVideoFailure(v video, started time.Time, failure *retry.Error) error {
+	vlog := s.videoLogger(v.ID(), failure.Attempts)
+	var stopErr error
+	switch {
+	case failure.Class == retry.Fatal || s.StopOnError:
+		stopErr = errors.Prefix("error processing video "+v.ID(), failure.Err)
+		if failure.Reason != "" {
+			vlog.Printf("Stopping the sync: %s", failure.Reason)
+		}
+	case failure.Class == retry.Permanent:
+		vlog.Printf("This error should not be retried at all (%s)", failure.Reason)
+	case s.MaxTries > 1:
+		vlog.Errorf("Video failed after %d retries, skipping: %s", failure.Attempts, failure.Error())
+	}
+	if failure.Reason != reasonSyncStopping {
+		s.addFailure(v.ID(), failure.Err)
+		s.addTriage(v.ID(), failure)
+	}
+
+	s.stats.fail(v.ID())
+	recordFailure(failure)
+	s.reportProgress(v.ID(), ProgressFailed, started, failure.Err)
+	s.AppendSyncedVideo(v.ID(), false, failure.Error())
+	if s.Manager.localDB != nil {
+		dbErr := s.Manager.localDB.SetFailed(s.YoutubeChannelID, v.ID(), failure.Error())
+		if dbErr != nil {
+			s.notifyVideoError("Failed to mark video on the local db: %s", dbErr.Error())
+		}
+	}
+	err := s.Manager.APIConfig.MarkVideoStatus(s.YoutubeChannelID, v.ID(), sdk.VideoStatusFailed, "", "", failure.Error())
+	if err != nil {
+		s.notifyVideoError("Failed to mark video on the database: %s", err.Error())
+	}
+	return stopErr
+}
+
+// fetchYoutubeVideos returns all the videos of the channel, oldest first, or the videos of the playlist in the order
+// of the playlist. If the API quota is used up and the RSS fallback is enabled, only the latest videos from the feed
+// are returned.
+func (s *Sync) fetchYoutubeVideos() ([]video, error) {
+	videos, err := s.fetchYoutubeAPIVideos()
+	if err != nil && isQuotaError(err) && s.Manager != nil && s.Manager.QuotaFallbackRSS {
+		s.logger().Warnf("%s: youtube API quota exhausted, getting the latest videos from the channel feed instead", s.YoutubeChannelID)
+		return s.fetchRSSVideos()
+	}
+	return videos, err
+}
+
+// youtubeService returns a client of the YouTube API authenticated with YoutubeAPIKey
+func (s *Sync) youtubeService() (*youtube.Service, error) {
 	client := &http.Client{
-		Transport: &transport.APIKey{Key: s.YoutubeAPIKey},
+		Transport: s.youtubeTransport(),
 	}
 
 	service, err := youtube.New(client)
 	if err != nil {
-		return errors.Prefix("error creating YouTube service", err)
+		return nil, errors.Prefix("error creating YouTube service", err)
 	}
+	return service, nil
+}
 
-	response, err := service.Channels.List("contentDetails").Id(s.YoutubeChannelID).Do()
+func (s *Sync) fetchYoutubeAPIVideos() ([]video, error) {
+	service, err := s.youtubeService()
 	if err != nil {
-		return errors.Prefix("error getting channels", err)
+		return nil, err
 	}
 
-	if len(response.Items) < 1 {
-		return errors.Err("youtube channel not found")
+	var videos []video
+	if after, ok := s.incrementalCutoff(); ok {
+		changed, checkErr := s.uploadsChanged(service)
+		if checkErr != nil {
+			s.logger().Warnf("could not tell whether %s uploaded anything since the last sync, fetching its new uploads: %s", s.YoutubeChannelID, checkErr.Error())
+		} else if !changed {
+			s.logger().Infof("%s didn't upload anything since the last sync", s.YoutubeChannelID)
+			return nil, nil
+		}
+		videos, err = s.fetchNewUploads(service, after)
+	} else {
+		videos, err = s.fetchPlaylistVideos(service)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	if response.Items[0].ContentDetails.RelatedPlaylists == nil {
-		return errors.Err("no related playlists")
+	ids := make([]string, len(videos))
+	for i, v := range videos {
+		ids[i] = v.ID()
+	}
+	livestreams, err := s.livestreamStatuses(service, ids)
+	if err != nil {
+		return nil, err
+	}
+	videos = s.handleLivestreams(videos, livestreams)
+	if s.Manager.ShortsMaxDuration > 0 {
+		lengths, err := s.videoLengths(service, ids)
+		if err != nil {
+			return nil, err
+		}
+		videos = s.handleShorts(videos, lengths)
 	}
 
-	playlistID := response.Items[0].ContentDetails.RelatedPlaylists.Uploads
+	if at, ok := s.youtubeQuota().PredictExhaustion(); ok {
+		s.logger().Warnf("at this rate the youtube API quota will be used up at %s, before it's reset at %s", at.Format(time.Kitchen), s.youtubeQuota().ResetsAt().Format(time.Kitchen))
+	}
+
+	s.sortVideos(videos)
+	return s.applyLimits(service, s.VideoFilter.apply(videos))
+}
+
+// fetchPlaylistVideos returns all the videos of the playlist being synced, or of the uploads playlist of the channel
+func (s *Sync) fetchPlaylistVideos(service *youtube.Service) ([]video, error) {
+	playlistID := s.YoutubePlaylistID
 	if playlistID == "" {
-		return errors.Err("no channel playlist")
+		var err error
+		playlistID, err = s.uploadsPlaylistID(service)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var videos []video
@@ -517,13 +881,17 @@ func (s *Sync) enqueueYoutubeVideos() error {
 			MaxResults(50).
 			PageToken(nextPageToken)
 
+		err := s.useQuota(listCost)
+		if err != nil {
+			return nil, err
+		}
 		playlistResponse, err := req.Do()
 		if err != nil {
-			return errors.Prefix("error getting playlist items", err)
+			return nil, errors.Prefix("error getting playlist items", s.youtubeQuota().Observe(err))
 		}
 
 		if len(playlistResponse.Items) < 1 {
-			return errors.Err("playlist items not found")
+			return nil, errors.Err("playlist items not found")
 		}
 
 		for _, item := range playlistResponse.Items {
@@ -532,85 +900,55 @@ func (s *Sync) enqueueYoutubeVideos() error {
 			videos = append(videos, sources.NewYoutubeVideo(s.videoDirectory, item.Snippet))
 		}
 
-		log.Infof("Got info for %d videos from youtube API", len(videos))
+		s.logger().Infof("Got info for %d videos from youtube API", len(videos))
 
 		nextPageToken = playlistResponse.NextPageToken
 		if nextPageToken == "" {
 			break
 		}
 	}
-
-	sort.Sort(byPublishedAt(videos))
-	//or sort.Sort(sort.Reverse(byPlaylistPosition(videos)))
-
-Enqueue:
-	for _, v := range videos {
-		select {
-		case <-s.grp.Ch():
-			break Enqueue
-		default:
-		}
-
-		select {
-		case s.queue <- v:
-		case <-s.grp.Ch():
-			break Enqueue
-		}
-	}
-
-	return nil
+	return videos, nil
 }
 
-func (s *Sync) enqueueUCBVideos() error {
-	var videos []video
-
-	csvFile, err := os.Open("ucb.csv")
+// uploadsPlaylistID returns the ID of the playlist holding all the uploads of the channel
+func (s *Sync) uploadsPlaylistID(service *youtube.Service) (string, error) {
+	err := s.useQuota(listCost)
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	reader := csv.NewReader(bufio.NewReader(csvFile))
-	for {
-		line, err := reader.Read()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-		data := struct {
-			PublishedAt string `json:"publishedAt"`
-		}{}
-		err = json.Unmarshal([]byte(line[4]), &data)
-		if err != nil {
-			return err
-		}
-
-		videos = append(videos, sources.NewUCBVideo(line[0], line[2], line[1], line[3], data.PublishedAt, s.videoDirectory))
+	response, err := service.Channels.List("contentDetails").Id(s.YoutubeChannelID).Do()
+	if err != nil {
+		return "", errors.Prefix("error getting channels", s.youtubeQuota().Observe(err))
 	}
 
-	log.Printf("Publishing %d videos\n", len(videos))
-
-	sort.Sort(byPublishedAt(videos))
+	if len(response.Items) < 1 {
+		return "", errors.Err("youtube channel not found")
+	}
 
-Enqueue:
-	for _, v := range videos {
-		select {
-		case <-s.grp.Ch():
-			break Enqueue
-		default:
-		}
+	if response.Items[0].ContentDetails.RelatedPlaylists == nil {
+		return "", errors.Err("no related playlists")
+	}
 
-		select {
-		case s.queue <- v:
-		case <-s.grp.Ch():
-			break Enqueue
-		}
+	playlistID := response.Items[0].ContentDetails.RelatedPlaylists.Uploads
+	if playlistID == "" {
+		return "", errors.Err("no channel playlist")
 	}
+	return playlistID, nil
+}
 
-	return nil
+// sortVideos puts the videos in the order they should be published in. Channel videos are published oldest first, and
+// playlist videos in the order of the playlist.
+func (s *Sync) sortVideos(videos []video) {
+	if s.YoutubePlaylistID != "" {
+		sort.Stable(byPlaylistPosition(videos))
+		return
+	}
+	sort.Sort(byPublishedAt(videos))
 }
 
-func (s *Sync) processVideo(v video) (err error) {
+// processVideo syncs a video. attempt counts the tries at syncing it, starting at 1.
+func (s *Sync) processVideo(v video, attempt int) (err error) {
+	vlog := s.videoLogger(v.ID(), attempt)
 	defer func() {
 		if p := recover(); p != nil {
 			var ok bool
@@ -622,74 +960,221 @@ func (s *Sync) processVideo(v video) (err error) {
 		}
 	}()
 
-	log.Println("Processing " + v.IDAndNum())
+	vlog.Println("Processing " + v.IDAndNum())
+	s.reportStarted(v.ID(), attempt)
+	started := time.Now()
 	defer func(start time.Time) {
-		log.Println(v.ID() + " took " + time.Since(start).String())
-	}(time.Now())
+		vlog.Println(v.ID() + " took " + time.Since(start).String())
+	}(started)
 
 	s.syncedVideosMux.Lock()
 	sv, ok := s.syncedVideos[v.ID()]
 	s.syncedVideosMux.Unlock()
 	alreadyPublished := ok && sv.Published
 
-	neverRetryFailures := []string{
-		"Error extracting sts from embedded url response",
-		"the video is too big to sync, skipping for now",
-	}
-	if ok && !sv.Published && util.SubstringInSlice(sv.FailureReason, neverRetryFailures) {
-		log.Println(v.ID() + " can't ever be published")
+	if ok && !sv.Published && util.SubstringInSlice(sv.FailureReason, neverRetryFailures) && !s.VideoFilter.names(v.ID()) {
+		vlog.Println(v.ID() + " can't ever be published")
+		s.stats.skip()
+		s.reportProgress(v.ID(), ProgressSkipped, started, nil)
 		return nil
 	}
 
+	if s.Manager.UpdateExisting {
+		handled, err := s.syncExisting(v, alreadyPublished, started, vlog)
+		if err != nil && alreadyPublished {
+			// the video stays published as it is
+			vlog.Warnf("could not check the claim of %s for changes: %s", v.ID(), err.Error())
+		} else if err != nil || handled {
+			return err
+		}
+	}
+
 	//TODO: remove this after a few runs...
-	alreadyPublishedOld, err := s.db.IsPublished(v.ID())
-	if err != nil {
-		return err
+	alreadyPublishedOld := false
+	if s.db != nil { // local syncs have no redis
+		alreadyPublishedOld, err = s.db.IsPublished(v.ID())
+		if err != nil {
+			return err
+		}
 	}
 	//TODO: remove this after a few runs...
 	if alreadyPublishedOld && !alreadyPublished {
 		//seems like something in the migration of blobs didn't go perfectly right so warn about it!
-		SendInfoToSlack("A video that was previously published is on the local database but isn't on the remote db! fix it @Nikooo777! \nchannelID: %s, videoID: %s",
+		s.notifyInfo("A video that was previously published is on the local database but isn't on the remote db! fix it @Nikooo777! \nchannelID: %s, videoID: %s",
 			s.YoutubeChannelID, v.ID())
+		s.stats.skip()
+		s.reportProgress(v.ID(), ProgressSkipped, started, nil)
 		return nil
 	}
 
 	if alreadyPublished {
-		log.Println(v.ID() + " already published")
+		vlog.Println(v.ID() + " already published")
+		s.stats.skip()
+		s.reportProgress(v.ID(), ProgressSkipped, started, nil)
+		return nil
+	}
+
+	publishedLocally, err := s.recoverLocallyPublished(v)
+	if err != nil {
+		return err
+	}
+	if publishedLocally {
+		s.stats.skip()
+		s.reportProgress(v.ID(), ProgressSkipped, started, nil)
 		return nil
 	}
 
-	if v.PlaylistPosition() > s.Manager.VideosLimit {
-		log.Println(v.ID() + " is old: skipping")
+	if v.PlaylistPosition() > s.Manager.VideosLimit && !s.VideoFilter.names(v.ID()) {
+		vlog.Println(v.ID() + " is old: skipping")
+		s.stats.skip()
+		s.reportProgress(v.ID(), ProgressSkipped, started, nil)
 		return nil
 	}
-	err = s.Manager.checkUsedSpace()
+	reservation, err := s.reserveSpace(v)
 	if err != nil {
 		return err
 	}
-	summary, err := v.Sync(s.daemon, s.claimAddress, publishAmount, s.lbryChannelID, s.Manager.MaxVideoSize)
+	defer reservation.Release()
+	err = s.checkSpendingCap(s.ClaimAmounts.perVideo())
 	if err != nil {
 		return err
 	}
-	err = s.Manager.MarkVideoStatus(s.YoutubeChannelID, v.ID(), VideoStatusPublished, summary.ClaimID, summary.ClaimName, "")
+	_, err = s.credits.BeforeSpending(s.ClaimAmounts.perVideo())
 	if err != nil {
 		return err
 	}
+	if s.Manager.localDB != nil {
+		err = s.Manager.localDB.SetPending(s.YoutubeChannelID, v.ID())
+		if err != nil {
+			return err
+		}
+	}
+	summary, err := s.syncVideo(v, started, vlog)
+	if err != nil {
+		return err
+	}
+	s.reportProgress(v.ID(), ProgressPublished, started, nil)
+	s.recordSpend(localdb.Spend{Kind: localdb.SpendPublish, VideoID: v.ID(), ClaimID: summary.ClaimID, Txid: summary.Txid, Amount: summary.Amount, Fee: summary.Fee})
+	supported, err := s.supportClaim(summary.ClaimName, summary.ClaimID)
+	if err != nil {
+		// the claim is published, it's only missing the support
+		s.notifyVideoError("Failed to support the claim of %s: %s", v.ID(), err.Error())
+	}
+	s.stats.spend(supported)
+	s.recordSpend(localdb.Spend{Kind: localdb.SpendSupport, VideoID: v.ID(), ClaimID: summary.ClaimID, Amount: supported})
+	if s.Manager.localDB != nil {
+		err = s.Manager.localDB.SetPublished(s.YoutubeChannelID, v.ID(), summary.ClaimID, summary.ClaimName, summary.Duration)
+		if err != nil {
+			s.notifyVideoError("Failed to mark video %s as published on the local db: %s", v.ID(), err.Error())
+		}
+		err = s.Manager.localDB.SetLastUpload(s.YoutubeChannelID, v.PublishedAt())
+		if err != nil {
+			s.notifyError("Failed to record the last upload of %s on the local db: %s", s.YoutubeChannelID, err.Error())
+		}
+		s.indexContent(v.ID(), summary)
+	}
+	if s.confirmations != nil && summary.Txid != "" {
+		s.awaitConfirmation(v, summary, started)
+		return nil
+	}
+	return s.markPublished(v, summary, started)
+}
+
+// syncVideo downloads and publishes a video. The download is reported separately, unless it already happened in the
+// pipeline. If the sync is interrupted before the publish starts, util.ErrWaitCancelled is returned.
+func (s *Sync) syncVideo(v video, started time.Time, vlog *log.Entry) (*sources.SyncSummary, error) {
+	params := s.syncParams()
+	params.Log = vlog
+	params.DownloadProgress = s.downloadProgress(v.ID(), started)
+	err := s.checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if _, prefetched := v.(*prefetchedVideo); prefetched {
+		return v.Sync(s.daemon, params)
+	}
+	err = v.Download(params)
+	if err != nil {
+		return nil, err
+	}
+	s.reportProgress(v.ID(), ProgressDownloaded, started, nil)
+	// once the publish starts, it's seen through
+	err = s.checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return v.Publish(s.daemon, params)
+}
+
+// recoverLocallyPublished returns true if the local db says the video was published already. This happens when a sync
+// was interrupted after publishing a video but before the API was told about it, so the API is told now.
+func (s *Sync) recoverLocallyPublished(v video) (bool, error) {
+	if s.Manager.localDB == nil {
+		return false, nil
+	}
+	local, ok, err := s.Manager.localDB.Video(s.YoutubeChannelID, v.ID())
+	if err != nil || !ok || local.Status != localdb.VideoStatusPublished {
+		return false, err
+	}
+	s.logger().Println(v.ID() + " was published by a previous run, updating its status")
+	err = s.Manager.APIConfig.MarkVideoStatus(s.YoutubeChannelID, v.ID(), sdk.VideoStatusPublished, local.ClaimID, local.ClaimName, "")
+	if err != nil {
+		return false, err
+	}
 	s.AppendSyncedVideo(v.ID(), true, "")
+	return true, nil
+}
 
-	return nil
+// syncParams returns the settings videos need to sync themselves
+func (s *Sync) syncParams() sources.SyncParams {
+	return sources.SyncParams{
+		ClaimAddress:       s.claimAddress,
+		Amount:             s.ClaimAmounts.videoBid(),
+		Fee:                s.ClaimAmounts.fee(s.claimAddress),
+		ChannelID:          s.lbryChannelID,
+		MaxVideoSize:       s.maxVideoSize(),
+		DownloadTimeout:    s.Manager.DownloadTimeout,
+		VerifyDownloads:    s.VerifyDownloads,
+		GenerateThumbnails: s.GenerateThumbnails,
+		ThumbnailTimestamp: s.ThumbnailTimestamp,
+		SyncCaptions:       s.SyncCaptions,
+		Transcode:          s.TranscodeProfile,
+		Validation:         s.Manager.ValidationRules,
+		Flagged:            s.flagVideo,
+		AwsS3ID:            s.AwsS3ID,
+		AwsS3Secret:        s.AwsS3Secret,
+		ThumbnailHost:      s.Manager.ThumbnailHost,
+		DownloadLimiter:    s.Manager.downloadLimiter,
+		DownloadCounter:    downloadedBytes,
+		MetadataTransform:  s.metadataTransform(),
+		NameResolver:       s.VideoConflictResolver,
+		Dedup:              s.deduplicator(),
+		Duplicates:         s.Manager.Duplicates,
+		VideoLimiter:       s.Manager.videoLimiter,
+		YtDlp:              s.ytDlp(),
+		ShortsTags:         s.Manager.ShortsTags,
+		Stop:               s.grp.Ch(),
+	}
 }
 
-func startDaemonViaSystemd() error {
-	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", "start", "lbrynet.service").Run()
+// ytDlp returns the yt-dlp manager as a sources.YtDlp, nil if there is none
+func (s *Sync) ytDlp() sources.YtDlp {
+	if s.Manager.YtDlp == nil {
+		return nil
+	}
+	return s.Manager.YtDlp
+}
+
+func startDaemonViaSystemd(slot daemonSlot) error {
+	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", "start", slot.unit()).Run()
 	if err != nil {
 		return errors.Err(err)
 	}
 	return nil
 }
 
-func stopDaemonViaSystemd() error {
-	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", "stop", "lbrynet.service").Run()
+func stopDaemonViaSystemd(slot daemonSlot) error {
+	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", "stop", slot.unit()).Run()
 	if err != nil {
 		return errors.Err(err)
 	}
@@ -697,18 +1182,11 @@ func stopDaemonViaSystemd() error {
 }
 
 // waitForDaemonProcess observes the running processes and returns when the process is no longer running or when the timeout is up
-func waitForDaemonProcess(timeout time.Duration) error {
-	processes, err := ps.Processes()
+func waitForDaemonProcess(slot daemonSlot, timeout time.Duration) error {
+	daemonProcessId, err := slot.pid()
 	if err != nil {
 		return err
 	}
-	var daemonProcessId = -1
-	for _, p := range processes {
-		if p.Executable() == "lbrynet-daemon" {
-			daemonProcessId = p.Pid()
-			break
-		}
-	}
 	if daemonProcessId == -1 {
 		return nil
 	}